@@ -15,10 +15,16 @@ Redirection is an S3 Object Level Redirection implementation
 - If it is an internal resource, the prefix must be "/", for example, "/international/index.html", otherwise it will be considered a format error
 - Can redirect internal resource A to internal resource B, but the target internal resource B must exist, otherwise the redirection will fail
 - Can redirect internal resource A to external resource B, but the target external resource B must be accessible, otherwise the redirection will fail
+- The optional columns are "status_code" (301, 302, 307 or 308, 301 by default), "match_type" (exact, prefix, wildcard or regex, exact by default), "preserve_query" and the RFC 3339 dates "start_at" and "expire_at"
+- A prefix matches whole path segments, the source "/blog" matches "/blog" and "/blog/a.html" but not "/blogger"
+- The pattern redirections and the dates are served by the MiddlewareBackend only, the S3 backend rejects the rows using them, the redirections making loops or chains longer than MaxHops are rejected
+- The MiddlewareBackend caches the redirections, set its RefreshInterval to reload them when more than one instance of the application saves them
+- Use "Test URL" in the listing to see which redirections a URL follows
 - Only supports CSV files with the format:
 `),
 	ch.Code(" - Correct Example 1, redirect to external resource\n```csv\nsource,target\n/international/index.html,https://demo.qor5.com/"),
 	ch.Code(" - Correct Example 2, redirect to internal resource\n```csv\nsource,target\n/international/index.html,/international/index2.html"),
+	ch.Code(" - Correct Example 3, redirect with the captures of a wildcard and keep the query string\n```csv\nsource,target,status_code,match_type,preserve_query,start_at,expire_at\n/blog/*/*.html,/posts/$1-$2,302,wildcard,true,2024-01-01T00:00:00Z,"),
 	ch.Code(" - Incorrect Example 1, format error\n```csv\nsource,target\n/international/index.html,international/index2.html"),
 	ch.Code(" - Incorrect Example 2, duplicate source name\n```csv\nsource,target\n/international/index3.html,/international/index1.html\n/international/index3.html,/international/index2.html"),
 	ch.Code(" - Incorrect Example 3, pointing to external resource, external resource is inaccessible\n```csv\nsource,target\n/international/index3.html,https://wwwwwwww/"),
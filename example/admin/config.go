@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"os/exec"
 	"slices"
 	"strconv"
 	"strings"
@@ -36,12 +37,15 @@ import (
 	plogin "github.com/qor5/admin/v3/login"
 	"github.com/qor5/admin/v3/media"
 	"github.com/qor5/admin/v3/media/base"
+	"github.com/qor5/admin/v3/media/clamd"
+	"github.com/qor5/admin/v3/media/ffmpeg"
 	"github.com/qor5/admin/v3/media/media_library"
 	media_oss "github.com/qor5/admin/v3/media/oss"
 	"github.com/qor5/admin/v3/microsite"
 	microsite_utils "github.com/qor5/admin/v3/microsite/utils"
 	"github.com/qor5/admin/v3/pagebuilder"
 	"github.com/qor5/admin/v3/pagebuilder/example"
+	"github.com/qor5/admin/v3/pagebuilder/example/containers"
 	"github.com/qor5/admin/v3/presets"
 	"github.com/qor5/admin/v3/presets/gorm2op"
 	"github.com/qor5/admin/v3/publish"
@@ -64,6 +68,7 @@ type Config struct {
 	pageBuilder         *pagebuilder.Builder
 	Publisher           *publish.Builder
 	loginSessionBuilder *plogin.SessionBuilder
+	mediaBuilder        *media.Builder
 }
 
 func (c *Config) GetPresetsBuilder() *presets.Builder {
@@ -74,6 +79,10 @@ func (c *Config) GetLoginSessionBuilder() *plogin.SessionBuilder {
 	return c.loginSessionBuilder
 }
 
+func (c *Config) GetPageBuilder() *pagebuilder.Builder {
+	return c.pageBuilder
+}
+
 var (
 	s3Bucket                  = osenv.Get("S3_Bucket", "s3-bucket for media library storage", "example")
 	s3Region                  = osenv.Get("S3_Region", "s3-region for media library storage", "ap-northeast-1")
@@ -82,10 +91,27 @@ var (
 	s3PublishRegion           = osenv.Get("S3_Publish_Region", "s3-region for publish", "ap-northeast-1")
 	publishURL                = osenv.Get("PUBLISH_URL", "publish url", "")
 	dbReset                   = osenv.Get("DB_RESET", "db reset for show count down", "")
+	clamdAddress              = osenv.Get("CLAMD_ADDRESS", "clamd address to scan the media library uploads, such as localhost:3310", "")
+	s3QuarantineBucket        = osenv.Get("S3_Quarantine_Bucket", "private s3-bucket for the quarantined media library uploads", "example-quarantine")
 	resetAndImportInitialData = osenv.GetBool("RESET_AND_IMPORT_INITIAL_DATA",
 		"Will reset and import initial data if set to true", false)
 )
 
+// imageMediaBoxConfig is the config of the image fields, the regenerate job regenerates its sizes
+var imageMediaBoxConfig = &media_library.MediaBoxConfig{
+	AllowType: "image",
+	Sizes: map[string]*base.Size{
+		"thumb": {
+			Width:  400,
+			Height: 300,
+		},
+		"main": {
+			Width:  800,
+			Height: 500,
+		},
+	},
+}
+
 type ConfigOption func(opts *configOptions)
 
 type configOptions struct {
@@ -203,7 +229,7 @@ func NewConfig(db *gorm.DB, enableWork bool, opts ...ConfigOption) Config {
 			// return supportedLanguages
 			return b.GetI18n().GetSupportLanguages()
 		})
-	mediab := media.New(db).AutoMigrate().Activity(ab).CurrentUserID(func(ctx *web.EventContext) (id uint) {
+	mediab := media.New(db).AutoMigrate().TrackUsage(true).DeduplicateUploads(true).ChunkedUploads(mediaChunkedUploadsPath).Activity(ab).CurrentUserID(func(ctx *web.EventContext) (id uint) {
 		u := getCurrentUser(ctx.R)
 		if u == nil {
 			return
@@ -218,15 +244,71 @@ func NewConfig(db *gorm.DB, enableWork bool, opts ...ConfigOption) Config {
 			return db.Where("user_id = ?", u.ID)
 		}
 		return db
+	}).MetadataFields(
+		&media.MetadataField{Name: "Copyright", Label: "Copyright"},
+		&media.MetadataField{Name: "Photographer", Label: "Photographer"},
+		&media.MetadataField{Name: "UsageRights", Label: "Usage Rights", Type: media.MetadataFieldTypeSelect, Options: []string{"Royalty Free", "Rights Managed", "Editorial Only"}},
+	).UploaderNames(func(_ *web.EventContext, userIDs []uint) (names map[uint]string, err error) {
+		var users []*models.User
+		if err = db.Select("id", "name").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+			return
+		}
+		names = make(map[uint]string, len(users))
+		for _, u := range users {
+			names[u.ID] = u.Name
+		}
+		return
+	}).FolderPermissions(func(ctx *web.EventContext) *media.FolderSubject {
+		u := getCurrentUser(ctx.R)
+		if u == nil {
+			return nil
+		}
+		rs := u.GetRoles()
+		return &media.FolderSubject{
+			UserID:     u.ID,
+			Roles:      rs,
+			Superuser:  slices.Contains(rs, models.RoleAdmin) || slices.Contains(rs, models.RoleManager),
+			RootAccess: media.FolderAccessUpload,
+		}
+	}).FolderGrantees(func(_ *web.EventContext) (grantees []*media.FolderGrantee, err error) {
+		var users []*models.User
+		if err = db.Select("id", "name").Order("name").Find(&users).Error; err != nil {
+			return
+		}
+		for _, u := range users {
+			grantees = append(grantees, &media.FolderGrantee{Type: media.FolderGrantSubjectUser, ID: fmt.Sprint(u.ID), Label: u.Name})
+		}
+		for _, r := range models.DefaultRoles {
+			grantees = append(grantees, &media.FolderGrantee{Type: media.FolderGrantSubjectRole, ID: r, Label: r})
+		}
+		return
 	})
+	if clamdAddress != "" {
+		mediab.UploadScanners(clamd.New("tcp", clamdAddress)).
+			QuarantineStorage(s3.New(&s3.Config{
+				Bucket:   s3QuarantineBucket,
+				Region:   s3Region,
+				ACL:      string(types.ObjectCannedACLPrivate),
+				Endpoint: s3Endpoint,
+			}))
+	}
 	defer func() {
 		mediab.GetPresetsModelBuilder().Use(ab)
 		seoBuilder.GetPresetsModelBuilder().Use(ab)
 	}()
 
-	l10nBuilder := l10n.New(db)
+	l10nBuilder := l10n.New(db).AutoMigrate()
 	l10nBuilder.
 		Activity(ab).
+		// replace the stub with the machine translation service of the project
+		TranslationProvider(l10n.StubTranslationProvider{}).
+		TranslatableFields(&models.L10nModel{}, "Title").
+		TranslatableFields(&models.L10nModelWithVersion{}, "Title").
+		TranslatableFields(&containers.Heading{}, "Heading", "LinkText", "Text").
+		TranslatableFields(&containers.PageTitle{}, "NavigationLinkText", "Heading", "Text").
+		TrackSourceVersions(true).
+		ExchangeStorage(PublishStorage).
+		ExchangeLanguages(map[string]string{"Japan": "ja-JP", "China": "zh-CN"}).
 		// RegisterLocales("International", "international", "International", l10n.InternationalSvg).
 		RegisterLocales("Japan", "jp", "Japan", l10n.JapanSvg).
 		RegisterLocales("China", "cn", "China", l10n.ChinaSvg).
@@ -242,7 +324,7 @@ func NewConfig(db *gorm.DB, enableWork bool, opts ...ConfigOption) Config {
 
 	// media_view.MediaLibraryPerPage = 3
 	// vips.UseVips(vips.Config{EnableGenerateWebp: true})
-	configureSeo(b, db, l10nBuilder.GetSupportLocaleCodes()...)
+	configureSeo(b, db, l10nBuilder.GetLocaleFallbacks, l10nBuilder.GetSupportLocaleCodes()...)
 	configMenuOrder(b)
 
 	configPost(b, db, publisher, ab, seoBuilder)
@@ -253,7 +335,7 @@ func NewConfig(db *gorm.DB, enableWork bool, opts ...ConfigOption) Config {
 			{Text: "InputHarnesses", Value: "*:input_harnesses:*"},
 			{Text: "Posts", Value: "*:posts:*"},
 			{Text: "Settings", Value: "*:settings:*,*:site_management:"},
-			{Text: "SEO", Value: "*:qor_seo_settings:*,*:site_management:"},
+			{Text: "SEO", Value: "*:qor_seo_settings:*,*:qor_seo_robots:*,*:qor_seo_issues:*,*:site_management:"},
 			{Text: "Customers", Value: "*:customers:*"},
 			{Text: "Products", Value: "*:products:*,*:product_management:"},
 			{Text: "Categories", Value: "*:categories:*,*:product_management:"},
@@ -279,6 +361,18 @@ func NewConfig(db *gorm.DB, enableWork bool, opts ...ConfigOption) Config {
 		w := worker.New(db)
 		defer w.Listen()
 		addJobs(w)
+		// the sizes with the same name are taken from the last config
+		mediab.RegenerateJob(w, productImageMediaBoxConfig, imageMediaBoxConfig)
+		mediab.DeduplicateJob(w, &models.Post{}, &models.Product{}, &models.InputDemo{}, &seo.QorSEOSetting{})
+		mediab.CleanupChunkedUploadsJob(w)
+		seoBuilder.AuditJob(w)
+		l10nBuilder.ExportJob(w)
+		l10nBuilder.ImportJob(w)
+		if _, err := exec.LookPath("ffmpeg"); err == nil {
+			mediab.Processor(w, ffmpeg.New(ffmpeg.Config{
+				Renditions: []*ffmpeg.Rendition{{Name: "720p", Height: 720}},
+			}))
+		}
 		configProduct(b, db, w, publisher)
 		b.Use(w.Activity(ab))
 	}
@@ -354,6 +448,7 @@ func NewConfig(db *gorm.DB, enableWork bool, opts ...ConfigOption) Config {
 		})
 
 	b.Use(pageBuilder)
+	seoBuilder.SitemapProviders(pageBuilder.SitemapProvider()).RegenerateSitemapsOnPublish(publisher)
 
 	configListModel(b, ab, publisher)
 
@@ -400,6 +495,7 @@ func NewConfig(db *gorm.DB, enableWork bool, opts ...ConfigOption) Config {
 		pageBuilder:         pageBuilder,
 		Publisher:           publisher,
 		loginSessionBuilder: loginSessionBuilder,
+		mediaBuilder:        mediab,
 	}
 }
 
@@ -499,12 +595,15 @@ func configMenuOrder(b *presets.Builder) {
 			"DemoCase",
 			"Post",
 			"qor-seo-settings",
+			"qor-seo-robots",
+			"qor-seo-issues",
 			"List Editor Example",
 			"nested-field-demos",
 			"ListModels",
 			"MicrositeModels",
 			"L10nModel",
 			"L10nModelWithVersion",
+			"localization-status",
 		).Icon("featured_play_list"),
 		"Worker",
 		"ActivityLogs",
@@ -728,21 +827,7 @@ func configPost(
 	detailSection.EditingField("TitleWithSlug").LazyWrapComponentFunc(lazyWrapperEditCompoSync)
 	// TODO: need viewing field setting
 	detailSection.EditingField("HeroImage").
-		WithContextValue(
-			media.MediaBoxConfig,
-			&media_library.MediaBoxConfig{
-				AllowType: "image",
-				Sizes: map[string]*base.Size{
-					"thumb": {
-						Width:  400,
-						Height: 300,
-					},
-					"main": {
-						Width:  800,
-						Height: 500,
-					},
-				},
-			})
+		WithContextValue(media.MediaBoxConfig, imageMediaBoxConfig)
 	detailSection.EditingField("BodyImage").
 		WithContextValue(
 			media.MediaBoxConfig,
@@ -21,6 +21,17 @@ import (
 	"gorm.io/gorm"
 )
 
+// productImageMediaBoxConfig is the config of the product images, the regenerate job regenerates its sizes
+var productImageMediaBoxConfig = &media_library.MediaBoxConfig{
+	AllowType: "image",
+	Sizes: map[string]*base.Size{
+		"thumb": {
+			Width:  100,
+			Height: 100,
+		},
+	},
+}
+
 func configProduct(b *presets.Builder, _ *gorm.DB, wb *worker.Builder, publisher *publish.Builder) *presets.ModelBuilder {
 	p := b.Model(&models.Product{}).Use(publisher)
 	eb := p.Editing("StatusBar", "ScheduleBar", "Code", "Name", "Price", "Image")
@@ -164,17 +175,7 @@ func configProduct(b *presets.Builder, _ *gorm.DB, wb *worker.Builder, publisher
 	})
 
 	eb.Field("Image").
-		WithContextValue(
-			media.MediaBoxConfig,
-			&media_library.MediaBoxConfig{
-				AllowType: "image",
-				Sizes: map[string]*base.Size{
-					"thumb": {
-						Width:  100,
-						Height: 100,
-					},
-				},
-			})
+		WithContextValue(media.MediaBoxConfig, productImageMediaBoxConfig)
 
 	return p
 }
@@ -18,7 +18,8 @@ import (
 var favicon []byte
 
 const (
-	exportOrdersURL = "/export-orders"
+	exportOrdersURL         = "/export-orders"
+	mediaChunkedUploadsPath = "/media-uploads/"
 )
 
 func TestHandlerComplex(db *gorm.DB, u *models.User, enableWork bool, opts ...ConfigOption) (http.Handler, Config) {
@@ -88,6 +89,7 @@ func Router(db *gorm.DB) http.Handler {
 	})
 
 	mux.Handle(exportOrdersURL, exportOrders(db))
+	mux.Handle(mediaChunkedUploadsPath, c.mediaBuilder.ChunkedUploadHandler())
 
 	// example of sitemap and robot
 	sitemap.SiteMap("product").RegisterRawString("https://dev.qor5.com/admin", "/product").MountTo(mux)
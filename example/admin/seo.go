@@ -13,8 +13,10 @@ import (
 // @snippet_begin(SeoExample)
 var seoBuilder *seo.Builder
 
-func configureSeo(pb *presets.Builder, db *gorm.DB, locales ...string) {
-	seoBuilder = seo.New(db, seo.WithLocales(locales...)).AutoMigrate()
+func configureSeo(pb *presets.Builder, db *gorm.DB, fallbacks func(locale string) []string, locales ...string) {
+	seoBuilder = seo.New(db, seo.WithLocales(locales...), seo.WithLocaleFallbacks(fallbacks)).AutoMigrate().
+		Sitemap(PublishStorage).
+		Hreflangs(map[string]string{"Japan": "ja-JP", "China": "zh-CN"})
 	seoBuilder.RegisterSEO("Post", &models.Post{}).RegisterContextVariable(
 		"Title",
 		func(object interface{}, _ *seo.Setting, _ *http.Request) string {
@@ -23,8 +25,8 @@ func configureSeo(pb *presets.Builder, db *gorm.DB, locales ...string) {
 			}
 			return ""
 		},
-	).RegisterSettingVariables("Test")
-	seoBuilder.RegisterSEO("Product")
+	).RegisterSettingVariables("Test").StructuredData(seo.StructuredDataArticle)
+	seoBuilder.RegisterSEO("Product").StructuredData(seo.StructuredDataProduct)
 	seoBuilder.RegisterSEO("Announcement")
 	pb.Use(seoBuilder)
 }
@@ -12,5 +12,6 @@ func main() {
 	config := admin.NewConfig(db, false)
 	storage := admin.PublishStorage
 	publish.RunPublisher(context.Background(), db, storage, config.Publisher)
+	config.GetPageBuilder().RunContainerVisibilityScheduler(context.Background())
 	select {}
 }
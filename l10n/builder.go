@@ -7,10 +7,13 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"reflect"
 	"slices"
 	"time"
 
 	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/oss"
+	vx "github.com/qor5/x/v3/ui/vuetifyx"
 	"github.com/sunfmin/reflectutils"
 	. "github.com/theplant/htmlgo"
 	"golang.org/x/text/language"
@@ -19,25 +22,34 @@ import (
 	"github.com/qor5/admin/v3/activity"
 	"github.com/qor5/admin/v3/presets"
 	"github.com/qor5/admin/v3/utils"
+	"github.com/qor5/admin/v3/worker"
 )
 
 var IncorrectLocaleErr = errors.New("incorrect locale")
 
 type Builder struct {
-	db *gorm.DB
-	ab *activity.Builder
-	// models                               []*presets.ModelBuilder
+	db                                   *gorm.DB
+	ab                                   *activity.Builder
+	models                               []*presets.ModelBuilder
 	locales                              []*loc
 	getSupportLocaleCodesFromRequestFunc func(R *http.Request) []string
 	cookieName                           string
 	queryName                            string
+	translationProvider                  TranslationProvider
+	translatableFields                   map[reflect.Type][]string
+	trackSourceVersions                  bool
+	contentExtractors                    map[reflect.Type][]ContentExtractor
+	exchangeLanguages                    map[string]string
+	exchangeStorage                      oss.StorageInterface
+	exportJob                            *worker.JobBuilder
 }
 
 type loc struct {
-	code  string
-	path  string
-	label string
-	img   string
+	code      string
+	path      string
+	label     string
+	img       string
+	fallbacks []string
 }
 
 func New(db *gorm.DB) *Builder {
@@ -66,7 +78,9 @@ func (b *Builder) Activity(v *activity.Builder) (r *Builder) {
 	return b
 }
 
-func (b *Builder) RegisterLocales(localeCode, localePath, localeLabel, img string) (r *Builder) {
+// RegisterLocales registers a locale, fallbacks are the locales whose content is used
+// when the content is missing in it, in the order of preference.
+func (b *Builder) RegisterLocales(localeCode, localePath, localeLabel, img string, fallbacks ...string) (r *Builder) {
 	if slices.ContainsFunc(b.locales, func(l *loc) bool {
 		return l.code == localeCode
 	}) {
@@ -74,10 +88,11 @@ func (b *Builder) RegisterLocales(localeCode, localePath, localeLabel, img strin
 	}
 
 	b.locales = append(b.locales, &loc{
-		code:  localeCode,
-		path:  path.Join("/", localePath),
-		label: localeLabel,
-		img:   img,
+		code:      localeCode,
+		path:      path.Join("/", localePath),
+		label:     localeLabel,
+		img:       img,
+		fallbacks: fallbacks,
 	})
 	return b
 }
@@ -227,10 +242,7 @@ func (b *Builder) Install(pb *presets.Builder) error {
 		ComponentFunc(localeListFunc(db, b))
 	pb.FieldDefaults(presets.WRITE).
 		FieldType(Locale{}).
-		ComponentFunc(func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) HTMLComponent {
-			value := b.localeValue(obj, field, ctx)
-			return Input("").Type("hidden").Attr(web.VField("LocaleCode", value)...)
-		}).
+		ComponentFunc(b.localeWriteComponent).
 		SetterFunc(func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) (err error) {
 			value := EmbedLocale(obj).LocaleCode
 			if !slices.Contains(b.GetSupportLocaleCodesFromRequest(ctx.R), value) {
@@ -247,9 +259,20 @@ func (b *Builder) Install(pb *presets.Builder) error {
 		RegisterForModule(language.SimplifiedChinese, I18nLocalizeKey, Messages_zh_CN).
 		RegisterForModule(language.Japanese, I18nLocalizeKey, Messages_ja_JP)
 	pb.SwitchLocaleFunc(b.runSwitchLocaleFunc)
+	if b.trackSourceVersions {
+		b.installLocalizationStatus(pb)
+	}
 	return nil
 }
 
+func (b *Builder) localeWriteComponent(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) HTMLComponent {
+	value := b.localeValue(obj, field, ctx)
+	return Components(
+		b.translationReviewNotice(obj, field, ctx),
+		Input("").Type("hidden").Attr(web.VField("LocaleCode", value)...),
+	)
+}
+
 func (b *Builder) localeValue(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) string {
 	var value string
 	id, err := reflectutils.Get(obj, "ID")
@@ -321,17 +344,42 @@ func (b *Builder) ModelInstall(pb *presets.Builder, m *presets.ModelBuilder) err
 		}
 	})
 
+	if b.canPreTranslate(obj) {
+		m.Editing().WrapSaveFunc(func(in presets.SaveFunc) presets.SaveFunc {
+			return func(obj interface{}, id string, ctx *web.EventContext) (err error) {
+				if err = in(obj, id, ctx); err != nil || id == "" {
+					return
+				}
+				return b.reviewTranslations(ctx.R.Context(), m, obj, ctx.R.FormValue(ParamTranslationReviewed) == "true")
+			}
+		})
+	}
+
+	if b.canTrackSource(obj) {
+		m.Listing().WrapFilterDataFunc(func(in presets.FilterDataFunc) presets.FilterDataFunc {
+			return func(ctx *web.EventContext) vx.FilterData {
+				item, err := b.NewStaleFilterItem(ctx.R.Context(), m, "")
+				if err != nil {
+					panic(err)
+				}
+				item.Key = "f_" + item.Key
+				return append(in(ctx), item)
+			}
+		})
+		m.RegisterEventFunc(SourceChanges, sourceChangesDialog(b, m))
+		m.RegisterEventFunc(MarkUpToDate, markUpToDate(b, m))
+	}
+
 	registerEventFuncs(db, m, b, ab)
+	b.models = append(b.models, m)
+	b.installExportAction(m)
 
 	pb.FieldDefaults(presets.LIST).
 		FieldType(Locale{}).
 		ComponentFunc(localeListFunc(db, b))
 	pb.FieldDefaults(presets.WRITE).
 		FieldType(Locale{}).
-		ComponentFunc(func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) HTMLComponent {
-			value := b.localeValue(obj, field, ctx)
-			return Input("").Type("hidden").Attr(web.VField("LocaleCode", value)...)
-		}).
+		ComponentFunc(b.localeWriteComponent).
 		SetterFunc(func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) (err error) {
 			value := EmbedLocale(obj).LocaleCode
 			if !slices.Contains(b.GetSupportLocaleCodesFromRequest(ctx.R), value) {
@@ -342,6 +390,9 @@ func (b *Builder) ModelInstall(pb *presets.Builder, m *presets.ModelBuilder) err
 		})
 	rmb := m.Listing().RowMenu()
 	rmb.RowMenuItem("Localize").ComponentFunc(localizeRowMenuItemFunc(m.Info(), "", url.Values{}))
+	if b.canTrackSource(obj) {
+		rmb.RowMenuItem("SourceChanges").ComponentFunc(sourceChangesRowMenuItemFunc(m.Info()))
+	}
 
 	pb.AddWrapHandler(WrapHandlerKey, b.EnsureLocale)
 	pb.AddMenuTopItemFunc(MenuTopItemFunc, runSwitchLocaleFunc(b))
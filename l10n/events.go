@@ -9,6 +9,7 @@ import (
 	v "github.com/qor5/x/v3/ui/vuetify"
 	vx "github.com/qor5/x/v3/ui/vuetifyx"
 	"github.com/sunfmin/reflectutils"
+	h "github.com/theplant/htmlgo"
 	"gorm.io/gorm"
 
 	"github.com/qor5/admin/v3/activity"
@@ -66,6 +67,7 @@ func doLocalizeTo(db *gorm.DB, mb *presets.ModelBuilder, lb *Builder, ab *activi
 			return
 		}
 
+		preTranslate := ctx.R.FormValue(ParamPreTranslate) == "true" && lb.canPreTranslate(mb.NewModel())
 		fromObj := mb.NewModel()
 
 		if err = utils.PrimarySluggerWhere(db, mb.NewModel(), fromParamID).First(fromObj).Error; err != nil {
@@ -94,36 +96,13 @@ func doLocalizeTo(db *gorm.DB, mb *presets.ModelBuilder, lb *Builder, ab *activi
 				}
 			}
 		}(reflect.Indirect(reflect.ValueOf(fromObj)).Interface())
-		me := mb.Editing()
-
 		for toLocale := range to {
-			toObj := mb.NewModel()
-			fakeToObj := fromObj
-			if err = reflectutils.Set(fakeToObj, "LocaleCode", toLocale); err != nil {
+			toObj, vErr, lErr := lb.localizeTo(ctx, mb, fromObj, fromID, fromVersion, fromLocale, toLocale, preTranslate, nil)
+			if err = lErr; err != nil {
 				return
 			}
-
-			toParamID := fakeToObj.(presets.SlugEncoder).PrimarySlug()
-			if err = utils.SetPrimaryKeys(fromObj, toObj, db, toParamID); err != nil {
-				return
-			}
-
-			me.SetObjectFields(fromObj, toObj, &presets.FieldContext{
-				ModelInfo: mb.Info(),
-			}, false, presets.ContextModifiedIndexesBuilder(ctx).FromHidden(ctx.R), ctx)
-
-			if me.Validator != nil {
-				if vErr := me.Validator(toObj, ctx); vErr.HaveErrors() {
-					presets.ShowMessage(&r, vErr.Error(), "error")
-					return
-				}
-			}
-			newContext := context.WithValue(ctx.R.Context(), FromID, fromID)
-			newContext = context.WithValue(newContext, FromVersion, fromVersion)
-			newContext = context.WithValue(newContext, FromLocale, fromLocale)
-			ctx.R = ctx.R.WithContext(newContext)
-
-			if err = me.Saver(toObj, "", ctx); err != nil {
+			if vErr.HaveErrors() {
+				presets.ShowMessage(&r, vErr.Error(), "error")
 				return
 			}
 			toObjs = append(toObjs, toObj)
@@ -137,6 +116,74 @@ func doLocalizeTo(db *gorm.DB, mb *presets.ModelBuilder, lb *Builder, ab *activi
 	}
 }
 
+// localizeTo creates the record of toLocale from fromObj, fill modifies the new record before it is validated and saved
+func (lb *Builder) localizeTo(ctx *web.EventContext, mb *presets.ModelBuilder, fromObj interface{}, fromID, fromVersion, fromLocale, toLocale string,
+	preTranslate bool, fill func(toObj interface{}) error,
+) (toObj interface{}, vErr web.ValidationErrors, err error) {
+	me := mb.Editing()
+	if toObj, err = lb.newLocalizedObject(ctx, mb, fromObj, toLocale); err != nil {
+		return
+	}
+
+	var marks []*QorTranslationMark
+	if preTranslate {
+		if marks, err = lb.preTranslate(ctx.R.Context(), toObj, fromLocale, toLocale); err != nil {
+			return
+		}
+	}
+	if fill != nil {
+		if err = fill(toObj); err != nil {
+			return
+		}
+	}
+
+	if me.Validator != nil {
+		if vErr = me.Validator(toObj, ctx); vErr.HaveErrors() {
+			return
+		}
+	}
+	newContext := context.WithValue(ctx.R.Context(), FromID, fromID)
+	newContext = context.WithValue(newContext, FromVersion, fromVersion)
+	newContext = context.WithValue(newContext, FromLocale, fromLocale)
+	ctx.R = ctx.R.WithContext(newContext)
+
+	if err = me.Saver(toObj, "", ctx); err != nil {
+		return
+	}
+	if preTranslate {
+		if err = lb.saveTranslationMarks(ctx.R.Context(), mb, toObj, marks); err != nil {
+			return
+		}
+	}
+	if lb.canTrackSource(toObj) {
+		if err = lb.recordSource(ctx.R.Context(), mb, fromObj, toObj, fromLocale, fromVersion); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// newLocalizedObject copies fromObj into the new record of toLocale without saving it
+func (lb *Builder) newLocalizedObject(ctx *web.EventContext, mb *presets.ModelBuilder, fromObj interface{}, toLocale string) (toObj interface{}, err error) {
+	toObj = mb.NewModel()
+	// a copy takes the new locale, fromObj stays the source record
+	fakeToObj := reflect.New(reflect.TypeOf(fromObj).Elem()).Interface()
+	reflect.ValueOf(fakeToObj).Elem().Set(reflect.ValueOf(fromObj).Elem())
+	if err = reflectutils.Set(fakeToObj, "LocaleCode", toLocale); err != nil {
+		return
+	}
+
+	toParamID := fakeToObj.(presets.SlugEncoder).PrimarySlug()
+	if err = utils.SetPrimaryKeys(fromObj, toObj, lb.db, toParamID); err != nil {
+		return
+	}
+
+	mb.Editing().SetObjectFields(fakeToObj, toObj, &presets.FieldContext{
+		ModelInfo: mb.Info(),
+	}, false, presets.ContextModifiedIndexesBuilder(ctx).FromHidden(ctx.R), ctx)
+	return
+}
+
 func localizeToConfirmation(db *gorm.DB, lb *Builder, mb *presets.ModelBuilder) web.EventFunc {
 	return func(ctx *web.EventContext) (r web.EventResponse, err error) {
 		presetsMsgr := presets.MustGetMessages(ctx.R)
@@ -181,6 +228,13 @@ func localizeToConfirmation(db *gorm.DB, lb *Builder, mb *presets.ModelBuilder)
 								Items(selectLocales).
 								ItemTitle("Label").
 								ItemValue("Code"),
+							h.If(lb.canPreTranslate(mb.NewModel()),
+								v.VCheckbox().
+									Attr(web.VField(ParamPreTranslate, false)...).
+									Label(MustGetTranslation(ctx.R, "PreTranslate")).
+									Hint(MustGetTranslation(ctx.R, "PreTranslateHint")).
+									PersistentHint(true),
+							),
 						),
 					).Title(MustGetTranslation(ctx.R, "LocalizeFrom")).
 						Subtitle(MustGetTranslation(ctx.R, lb.GetLocaleLabel(fromLocale))).Elevation(0),
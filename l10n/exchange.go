@@ -0,0 +1,544 @@
+package l10n
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/qor5/web/v3"
+	"github.com/sunfmin/reflectutils"
+	"gorm.io/gorm"
+
+	"github.com/qor5/admin/v3/presets"
+	"github.com/qor5/admin/v3/presets/gorm2op"
+	"github.com/qor5/admin/v3/utils"
+)
+
+const (
+	ExchangeFormatXLIFF = "xliff"
+	ExchangeFormatJSON  = "json"
+
+	xliffNamespace = "urn:oasis:names:tc:xliff:document:2.0"
+)
+
+// ContentSegment is a localizable text of a record, Key identifies it in the record,
+// it is the field name for the fields of the record.
+type ContentSegment struct {
+	Key    string
+	Note   string
+	Source string
+	Target string
+}
+
+// ContentExtractor extracts the localizable texts related to a record but not in its fields,
+// such as the containers of a page, and applies their translations to the localized record.
+type ContentExtractor interface {
+	ExtractContent(ctx context.Context, obj interface{}) ([]*ContentSegment, error)
+	ApplyContent(ctx context.Context, obj interface{}, segments []*ContentSegment) error
+}
+
+// ExchangeRecord is the localizable content of a record, Slug is the primary slug of the source record
+type ExchangeRecord struct {
+	Model    string
+	Slug     string
+	Segments []*ContentSegment
+}
+
+// ExchangeDocument is the content exchanged with the translators, it is encoded as XLIFF 2.0 or flat JSON
+type ExchangeDocument struct {
+	SourceLocale string
+	TargetLocale string
+	Records      []*ExchangeRecord
+}
+
+type ImportIssue struct {
+	Record  string
+	Key     string
+	Message string
+}
+
+// ImportReport is the result of an import, nothing is saved in the dry run
+type ImportReport struct {
+	DryRun  bool
+	Created int
+	Updated int
+	Skipped int
+	Issues  []*ImportIssue
+}
+
+func (r *ImportReport) issue(rec *ExchangeRecord, key string, format string, a ...interface{}) {
+	r.Issues = append(r.Issues, &ImportIssue{
+		Record:  rec.Model + "/" + rec.Slug,
+		Key:     key,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// ContentExtractors adds the extractors of the localizable content related to the records of the model
+func (b *Builder) ContentExtractors(model interface{}, vs ...ContentExtractor) (r *Builder) {
+	if b.contentExtractors == nil {
+		b.contentExtractors = make(map[reflect.Type][]ContentExtractor)
+	}
+	t := reflect.Indirect(reflect.ValueOf(model)).Type()
+	b.contentExtractors[t] = append(b.contentExtractors[t], vs...)
+	return b
+}
+
+func (b *Builder) getContentExtractors(model interface{}) []ContentExtractor {
+	return b.contentExtractors[reflect.Indirect(reflect.ValueOf(model)).Type()]
+}
+
+// ExchangeLanguages maps the locale codes to the language tags in the XLIFF files,
+// such as "Japan" to "ja-JP". The locale code is used if it is not mapped.
+func (b *Builder) ExchangeLanguages(v map[string]string) (r *Builder) {
+	b.exchangeLanguages = v
+	return b
+}
+
+func (b *Builder) exchangeLanguage(locale string) string {
+	if lang, ok := b.exchangeLanguages[locale]; ok {
+		return lang
+	}
+	return locale
+}
+
+func (b *Builder) exchangeLocale(lang string) string {
+	for locale, l := range b.exchangeLanguages {
+		if strings.EqualFold(l, lang) {
+			return locale
+		}
+	}
+	return lang
+}
+
+func (b *Builder) getModelBuilder(uriName string) *presets.ModelBuilder {
+	for _, mb := range b.models {
+		if mb.Info().URIName() == uriName {
+			return mb
+		}
+	}
+	return nil
+}
+
+// contentSegments returns the translatable fields of obj and the content of its extractors
+func (b *Builder) contentSegments(ctx context.Context, obj interface{}) (r []*ContentSegment, err error) {
+	for _, field := range b.GetTranslatableFields(obj) {
+		value, gErr := reflectutils.Get(obj, field)
+		if gErr != nil {
+			return nil, gErr
+		}
+		if text, ok := value.(string); ok && strings.TrimSpace(text) != "" {
+			r = append(r, &ContentSegment{Key: field, Source: text})
+		}
+	}
+	for _, e := range b.getContentExtractors(obj) {
+		segments, eErr := e.ExtractContent(ctx, obj)
+		if eErr != nil {
+			return nil, eErr
+		}
+		r = append(r, segments...)
+	}
+	return
+}
+
+// ExportContent exports the localizable content of the records of the model in the source locale,
+// slugs selects the records, all the records are exported if it is empty.
+func (b *Builder) ExportContent(ctx context.Context, mb *presets.ModelBuilder, sourceLocale, targetLocale string, slugs []string) (doc *ExchangeDocument, err error) {
+	db := b.db.WithContext(ctx)
+	var objs []interface{}
+	if len(slugs) > 0 {
+		for _, slug := range slugs {
+			obj := mb.NewModel()
+			if err = utils.PrimarySluggerWhere(db, mb.NewModel(), slug).First(obj).Error; err != nil {
+				return
+			}
+			if EmbedLocale(obj).LocaleCode != sourceLocale {
+				return nil, fmt.Errorf("record %s is not in locale %s", slug, sourceLocale)
+			}
+			objs = append(objs, obj)
+		}
+	} else {
+		list := mb.NewModelSlice()
+		q := db.Where("locale_code = ?", sourceLocale)
+		if hasUpdatedAt(mb.NewModel()) {
+			// the latest version of the records with versions
+			q = q.Order("id, updated_at DESC")
+		} else {
+			q = q.Order("id")
+		}
+		if err = q.Find(list).Error; err != nil {
+			return
+		}
+		seen := make(map[string]bool)
+		rv := reflect.ValueOf(list).Elem()
+		for i := 0; i < rv.Len(); i++ {
+			obj := rv.Index(i).Interface()
+			if id := objectID(obj); !seen[id] {
+				seen[id] = true
+				objs = append(objs, obj)
+			}
+		}
+	}
+
+	doc = &ExchangeDocument{SourceLocale: sourceLocale, TargetLocale: targetLocale}
+	for _, obj := range objs {
+		segments, sErr := b.contentSegments(ctx, obj)
+		if sErr != nil {
+			return nil, sErr
+		}
+		if len(segments) == 0 {
+			continue
+		}
+		doc.Records = append(doc.Records, &ExchangeRecord{
+			Model:    mb.Info().URIName(),
+			Slug:     obj.(presets.SlugEncoder).PrimarySlug(),
+			Segments: segments,
+		})
+	}
+	return
+}
+
+// exchangeEventContext is the context to save the records outside the requests, eventFunc
+// is the event the saving runs in, such as DoLocalize to run the hooks of the localization.
+// contextDB returns the transaction of the import in ctx, or the db of the builder
+func (b *Builder) contextDB(ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(gorm2op.CtxKeyDB{}).(*gorm.DB); ok && tx != nil {
+		return tx
+	}
+	return b.db.WithContext(ctx)
+}
+
+func exchangeEventContext(ctx context.Context, mb *presets.ModelBuilder, eventFunc string) *web.EventContext {
+	target := mb.Info().ListingHref()
+	if eventFunc != "" {
+		target += "?" + url.Values{web.EventFuncIDName: []string{eventFunc}}.Encode()
+	}
+	r := httptest.NewRequest(http.MethodPost, target, http.NoBody).WithContext(ctx)
+	return &web.EventContext{R: r, W: httptest.NewRecorder()}
+}
+
+// ImportContent imports the translations into the records of the target locale, the missing records
+// are localized from the source records like the Localize action and the existing ones are updated.
+func (b *Builder) ImportContent(ctx context.Context, doc *ExchangeDocument, dryRun bool) (report *ImportReport, err error) {
+	if !slices.Contains(b.GetSupportLocaleCodes(), doc.TargetLocale) {
+		return nil, fmt.Errorf("unsupported target locale %q", doc.TargetLocale)
+	}
+	report = &ImportReport{DryRun: dryRun}
+	for _, rec := range doc.Records {
+		var (
+			created, imported bool
+			rErr              error
+		)
+		if dryRun {
+			created, imported, rErr = b.importRecord(ctx, doc, rec, true, report)
+		} else {
+			rErr = b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) (txErr error) {
+				// the savers of the presets and the content extractors take the transaction from the context
+				created, imported, txErr = b.importRecord(context.WithValue(ctx, gorm2op.CtxKeyDB{}, tx), doc, rec, false, report)
+				return
+			})
+		}
+		switch {
+		case rErr != nil:
+			report.issue(rec, "", "%s", rErr.Error())
+			report.Skipped++
+		case !imported:
+			report.Skipped++
+		case created:
+			report.Created++
+		default:
+			report.Updated++
+		}
+	}
+	return
+}
+
+func (b *Builder) importRecord(ctx context.Context, doc *ExchangeDocument, rec *ExchangeRecord, dryRun bool, report *ImportReport) (created, imported bool, err error) {
+	mb := b.getModelBuilder(rec.Model)
+	if mb == nil {
+		return false, false, fmt.Errorf("unknown model %q", rec.Model)
+	}
+	db := b.contextDB(ctx)
+	fromObj := mb.NewModel()
+	if err = utils.PrimarySluggerWhere(db, mb.NewModel(), rec.Slug).First(fromObj).Error; err != nil {
+		return false, false, fmt.Errorf("source record: %w", err)
+	}
+	cs := fromObj.(presets.SlugDecoder).PrimaryColumnValuesBySlug(rec.Slug)
+	fromID, fromVersion, fromLocale := cs["id"], cs["version"], cs["locale_code"]
+	if doc.SourceLocale != "" && fromLocale != doc.SourceLocale {
+		return false, false, fmt.Errorf("the source record is not in locale %s", doc.SourceLocale)
+	}
+	if fromLocale == doc.TargetLocale {
+		return false, false, fmt.Errorf("the source record is in the target locale %s", doc.TargetLocale)
+	}
+
+	// the segments are validated against the current content of the source record
+	extractors := b.getContentExtractors(fromObj)
+	owners := make(map[string]int)
+	for _, field := range b.GetTranslatableFields(fromObj) {
+		owners[field] = -1
+	}
+	for i, e := range extractors {
+		segments, eErr := e.ExtractContent(ctx, fromObj)
+		if eErr != nil {
+			return false, false, eErr
+		}
+		for _, s := range segments {
+			owners[s.Key] = i
+		}
+	}
+	var (
+		fieldSegments []*ContentSegment
+		extracted     = make([][]*ContentSegment, len(extractors))
+		count         int
+	)
+	for _, s := range rec.Segments {
+		owner, ok := owners[s.Key]
+		switch {
+		case !ok:
+			report.issue(rec, s.Key, "unknown segment")
+			continue
+		case strings.TrimSpace(s.Target) == "":
+			report.issue(rec, s.Key, "not translated")
+			continue
+		case owner < 0:
+			fieldSegments = append(fieldSegments, s)
+		default:
+			extracted[owner] = append(extracted[owner], s)
+		}
+		count++
+	}
+	if count == 0 {
+		return
+	}
+
+	toObj := mb.NewModel()
+	q := db.Where("id = ? AND locale_code = ?", fromID, doc.TargetLocale)
+	if hasUpdatedAt(toObj) {
+		q = q.Order("updated_at DESC")
+	}
+	if err = q.First(toObj).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+	created, imported, err = errors.Is(err, gorm.ErrRecordNotFound), true, nil
+
+	fill := func(obj interface{}) error {
+		for _, s := range fieldSegments {
+			if err := reflectutils.Set(obj, s.Key, s.Target); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var (
+		vErr web.ValidationErrors
+		me   = mb.Editing()
+	)
+	switch {
+	case dryRun:
+		// the record is filled and validated as imported without saving it,
+		// the content of the extractors is not applied.
+		eventFunc := ""
+		if created {
+			eventFunc = DoLocalize
+		}
+		evCtx := exchangeEventContext(ctx, mb, eventFunc)
+		if created {
+			if toObj, err = b.newLocalizedObject(evCtx, mb, fromObj, doc.TargetLocale); err != nil {
+				return
+			}
+		}
+		if err = fill(toObj); err != nil {
+			return
+		}
+		if me.Validator != nil {
+			vErr = me.Validator(toObj, evCtx)
+		}
+	case created:
+		evCtx := exchangeEventContext(ctx, mb, DoLocalize)
+		toObj, vErr, err = b.localizeTo(evCtx, mb, fromObj, fromID, fromVersion, fromLocale, doc.TargetLocale, false, fill)
+	default:
+		evCtx := exchangeEventContext(ctx, mb, "")
+		if err = fill(toObj); err != nil {
+			return
+		}
+		if me.Validator != nil {
+			vErr = me.Validator(toObj, evCtx)
+		}
+		if !vErr.HaveErrors() {
+			err = me.Saver(toObj, toObj.(presets.SlugEncoder).PrimarySlug(), evCtx)
+		}
+	}
+	if err != nil {
+		return
+	}
+	if vErr.HaveErrors() {
+		return false, false, errors.New(vErr.Error())
+	}
+	if dryRun {
+		return
+	}
+	for i, segments := range extracted {
+		if len(segments) == 0 {
+			continue
+		}
+		if err = extractors[i].ApplyContent(ctx, toObj, segments); err != nil {
+			return
+		}
+	}
+	return
+}
+
+type xliffDocument struct {
+	XMLName xml.Name     `xml:"urn:oasis:names:tc:xliff:document:2.0 xliff"`
+	Version string       `xml:"version,attr"`
+	SrcLang string       `xml:"srcLang,attr"`
+	TrgLang string       `xml:"trgLang,attr,omitempty"`
+	Files   []*xliffFile `xml:"file"`
+}
+
+type xliffFile struct {
+	ID       string       `xml:"id,attr"`
+	Original string       `xml:"original,attr"`
+	Units    []*xliffUnit `xml:"unit"`
+}
+
+type xliffUnit struct {
+	ID       string          `xml:"id,attr"`
+	Name     string          `xml:"name,attr"`
+	Notes    *xliffNotes     `xml:"notes,omitempty"`
+	Segments []*xliffSegment `xml:"segment"`
+}
+
+type xliffNotes struct {
+	Notes []string `xml:"note"`
+}
+
+type xliffSegment struct {
+	Source string `xml:"source"`
+	Target string `xml:"target,omitempty"`
+}
+
+// EncodeXLIFF writes the document as XLIFF 2.0, a file for a record and a unit for a segment
+func (b *Builder) EncodeXLIFF(w io.Writer, doc *ExchangeDocument) error {
+	x := &xliffDocument{
+		Version: "2.0",
+		SrcLang: b.exchangeLanguage(doc.SourceLocale),
+	}
+	if doc.TargetLocale != "" {
+		x.TrgLang = b.exchangeLanguage(doc.TargetLocale)
+	}
+	for i, rec := range doc.Records {
+		f := &xliffFile{ID: fmt.Sprintf("f%d", i+1), Original: rec.Model + "/" + rec.Slug}
+		for j, s := range rec.Segments {
+			u := &xliffUnit{
+				ID:       fmt.Sprintf("u%d", j+1),
+				Name:     s.Key,
+				Segments: []*xliffSegment{{Source: s.Source, Target: s.Target}},
+			}
+			if s.Note != "" {
+				u.Notes = &xliffNotes{Notes: []string{s.Note}}
+			}
+			f.Units = append(f.Units, u)
+		}
+		x.Files = append(x.Files, f)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(x)
+}
+
+// DecodeXLIFF reads the XLIFF 2.0 written by EncodeXLIFF, the segments of a unit are joined
+func (b *Builder) DecodeXLIFF(r io.Reader) (doc *ExchangeDocument, err error) {
+	x := &xliffDocument{}
+	if err = xml.NewDecoder(r).Decode(x); err != nil {
+		return
+	}
+	if x.XMLName.Space != xliffNamespace || !strings.HasPrefix(x.Version, "2.") {
+		return nil, errors.New("not a XLIFF 2 document")
+	}
+	doc = &ExchangeDocument{
+		SourceLocale: b.exchangeLocale(x.SrcLang),
+	}
+	if x.TrgLang != "" {
+		doc.TargetLocale = b.exchangeLocale(x.TrgLang)
+	}
+	for _, f := range x.Files {
+		model, slug, ok := strings.Cut(f.Original, "/")
+		if !ok {
+			return nil, fmt.Errorf("file %s: invalid original %q", f.ID, f.Original)
+		}
+		rec := &ExchangeRecord{Model: model, Slug: slug}
+		for _, u := range f.Units {
+			s := &ContentSegment{Key: u.Name}
+			for _, seg := range u.Segments {
+				s.Source += seg.Source
+				s.Target += seg.Target
+			}
+			rec.Segments = append(rec.Segments, s)
+		}
+		doc.Records = append(doc.Records, rec)
+	}
+	return
+}
+
+func flatJSONKey(rec *ExchangeRecord, s *ContentSegment) string {
+	return rec.Model + "/" + rec.Slug + "/" + s.Key
+}
+
+// EncodeFlatJSON writes the document as a JSON object of the source texts by model/slug/key,
+// the translators replace the texts with the translations.
+func EncodeFlatJSON(w io.Writer, doc *ExchangeDocument) error {
+	m := make(map[string]string)
+	for _, rec := range doc.Records {
+		for _, s := range rec.Segments {
+			m[flatJSONKey(rec, s)] = s.Source
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// DecodeFlatJSON reads the translations written in the JSON of EncodeFlatJSON into the document of the target locale
+func DecodeFlatJSON(r io.Reader, targetLocale string) (doc *ExchangeDocument, err error) {
+	m := make(map[string]string)
+	if err = json.NewDecoder(r).Decode(&m); err != nil {
+		return
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	doc = &ExchangeDocument{TargetLocale: targetLocale}
+	records := make(map[string]*ExchangeRecord)
+	for _, key := range keys {
+		parts := strings.SplitN(key, "/", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid key %q", key)
+		}
+		rec := records[parts[0]+"/"+parts[1]]
+		if rec == nil {
+			rec = &ExchangeRecord{Model: parts[0], Slug: parts[1]}
+			records[parts[0]+"/"+parts[1]] = rec
+			doc.Records = append(doc.Records, rec)
+		}
+		rec.Segments = append(rec.Segments, &ContentSegment{Key: parts[2], Target: m[key]})
+	}
+	return
+}
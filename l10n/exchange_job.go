@@ -0,0 +1,335 @@
+package l10n
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/oss"
+	v "github.com/qor5/x/v3/ui/vuetify"
+	vx "github.com/qor5/x/v3/ui/vuetifyx"
+	"github.com/sunfmin/reflectutils"
+	h "github.com/theplant/htmlgo"
+
+	"github.com/qor5/admin/v3/presets"
+	"github.com/qor5/admin/v3/worker"
+)
+
+const (
+	ExportJobName = "L10nExport"
+	ImportJobName = "L10nImport"
+
+	ExportForTranslation = "ExportForTranslation"
+
+	exchangeStoragePath = "l10n"
+)
+
+var ErrNoExchangeStorage = errors.New("no exchange storage")
+
+type ExportJobArgs struct {
+	Model        string
+	SourceLocale string
+	TargetLocale string
+	Format       string
+	// Slugs selects the records to export, all the records of the model are exported if it is empty
+	Slugs []string
+}
+
+type ImportJobArgs struct {
+	// File is the path of the uploaded file in the exchange storage
+	File         string
+	TargetLocale string
+	DryRun       bool
+}
+
+// ExchangeStorage sets the storage of the exported files and the uploaded files to import
+func (b *Builder) ExchangeStorage(v oss.StorageInterface) (r *Builder) {
+	b.exchangeStorage = v
+	return b
+}
+
+type selectItem struct {
+	Label string
+	Value string
+}
+
+func (b *Builder) localeItems(ctx *web.EventContext) (r []selectItem) {
+	for _, locale := range b.GetSupportLocaleCodesFromRequest(ctx.R) {
+		r = append(r, selectItem{Label: MustGetTranslation(ctx.R, b.GetLocaleLabel(locale)), Value: locale})
+	}
+	return
+}
+
+var formatItems = []selectItem{
+	{Label: "XLIFF 2.0", Value: ExchangeFormatXLIFF},
+	{Label: "JSON", Value: ExchangeFormatJSON},
+}
+
+func selectField(label string, items func(ctx *web.EventContext) []selectItem) presets.FieldComponentFunc {
+	return func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) h.HTMLComponent {
+		return vx.VXSelect().
+			Attr(web.VField(field.Name, field.Value(obj))...).
+			Label(MustGetTranslation(ctx.R, label)).
+			Items(items(ctx)).
+			ItemTitle("Label").
+			ItemValue("Value").
+			ErrorMessages(field.Errors...)
+	}
+}
+
+func requiredSetter(label string) presets.FieldSetterFunc {
+	return func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) (err error) {
+		value := ctx.R.FormValue(field.Name)
+		if value == "" {
+			return errors.New(MustGetTranslation(ctx.R, label) + " " + MustGetTranslation(ctx.R, "IsRequired"))
+		}
+		return reflectutils.Set(obj, field.Name, value)
+	}
+}
+
+// ExportJob registers the job that exports the localizable content of a model to the exchange storage,
+// the listings of the localized models get the bulk action to export the selected records.
+func (b *Builder) ExportJob(w *worker.Builder) *worker.JobBuilder {
+	jb := w.NewJob(ExportJobName).Resource(&ExportJobArgs{Format: ExchangeFormatXLIFF}).Handler(b.runExport)
+	eb := jb.GetResourceBuilder().Editing("Model", "SourceLocale", "TargetLocale", "Format")
+	eb.Field("Model").ComponentFunc(selectField("ExchangeModel", func(ctx *web.EventContext) (r []selectItem) {
+		for _, mb := range b.models {
+			r = append(r, selectItem{Label: mb.Info().LabelName(ctx, false), Value: mb.Info().URIName()})
+		}
+		return
+	})).SetterFunc(requiredSetter("ExchangeModel"))
+	eb.Field("SourceLocale").ComponentFunc(selectField("ExchangeSourceLocale", b.localeItems)).SetterFunc(requiredSetter("ExchangeSourceLocale"))
+	eb.Field("TargetLocale").ComponentFunc(selectField("ExchangeTargetLocale", b.localeItems))
+	eb.Field("Format").ComponentFunc(selectField("ExchangeFormat", func(*web.EventContext) []selectItem { return formatItems })).
+		SetterFunc(requiredSetter("ExchangeFormat"))
+
+	b.exportJob = jb
+	for _, mb := range b.models {
+		b.installExportAction(mb)
+	}
+	return jb
+}
+
+// installExportAction adds the bulk action that exports the selected records in the current locale
+func (b *Builder) installExportAction(mb *presets.ModelBuilder) {
+	if b.exportJob == nil {
+		return
+	}
+	mb.Listing().BulkAction(ExportForTranslation).Label("Export for Translation").
+		ComponentFunc(func(selectedIds []string, ctx *web.EventContext) h.HTMLComponent {
+			var errorMessage string
+			if msg, ok := ctx.Flash.(string); ok {
+				errorMessage = msg
+			}
+			format := ctx.R.FormValue("Format")
+			if format == "" {
+				format = ExchangeFormatXLIFF
+			}
+			return h.Div(
+				vx.VXSelect().
+					Attr(web.VField("TargetLocale", ctx.R.FormValue("TargetLocale"))...).
+					Label(MustGetTranslation(ctx.R, "ExchangeTargetLocale")).
+					Items(b.localeItems(ctx)).ItemTitle("Label").ItemValue("Value").
+					ErrorMessages(errorMessage),
+				vx.VXSelect().
+					Attr(web.VField("Format", format)...).
+					Label(MustGetTranslation(ctx.R, "ExchangeFormat")).
+					Items(formatItems).ItemTitle("Label").ItemValue("Value"),
+			)
+		}).
+		UpdateFunc(func(selectedIds []string, ctx *web.EventContext, r *web.EventResponse) (err error) {
+			sourceLocale := b.GetCorrectLocaleCode(ctx.R)
+			targetLocale := ctx.R.FormValue("TargetLocale")
+			if targetLocale == sourceLocale {
+				ctx.Flash = MustGetTranslation(ctx.R, "ExchangeSameLocale")
+				return
+			}
+			if _, err = b.exportJob.Enqueue(ctx.R, &ExportJobArgs{
+				Model:        mb.Info().URIName(),
+				SourceLocale: sourceLocale,
+				TargetLocale: targetLocale,
+				Format:       ctx.R.FormValue("Format"),
+				Slugs:        selectedIds,
+			}); err != nil {
+				return
+			}
+			presets.ShowMessage(r, MustGetTranslation(ctx.R, "ExportJobCreated"), "")
+			return
+		})
+}
+
+func (b *Builder) runExport(ctx context.Context, job worker.QorJobInterface) (err error) {
+	if b.exchangeStorage == nil {
+		return ErrNoExchangeStorage
+	}
+	info, err := job.GetJobInfo()
+	if err != nil {
+		return
+	}
+	args := info.Argument.(*ExportJobArgs)
+	mb := b.getModelBuilder(args.Model)
+	if mb == nil {
+		return fmt.Errorf("unknown model %q", args.Model)
+	}
+	doc, err := b.ExportContent(ctx, mb, args.SourceLocale, args.TargetLocale, args.Slugs)
+	if err != nil {
+		return
+	}
+	job.SetProgress(50)
+
+	var (
+		buf bytes.Buffer
+		ext string
+	)
+	switch args.Format {
+	case ExchangeFormatJSON:
+		ext = ".json"
+		err = EncodeFlatJSON(&buf, doc)
+	default:
+		ext = ".xlf"
+		err = b.EncodeXLIFF(&buf, doc)
+	}
+	if err != nil {
+		return
+	}
+	name := strings.Join(nonEmpty(info.JobID, args.Model, args.SourceLocale, args.TargetLocale), "-") + ext
+	p := path.Join(exchangeStoragePath, "exports", name)
+	if _, err = b.exchangeStorage.Put(ctx, p, &buf); err != nil {
+		return
+	}
+	u, err := b.exchangeStorage.GetURL(ctx, p)
+	if err != nil {
+		return
+	}
+	job.AddLogf("%d records are exported", len(doc.Records))
+	job.SetProgress(100)
+	return job.SetProgressText(fmt.Sprintf(`<a href="%s" target="_blank">%s</a>`, u, name))
+}
+
+func nonEmpty(vs ...string) (r []string) {
+	for _, v := range vs {
+		if v != "" {
+			r = append(r, v)
+		}
+	}
+	return
+}
+
+func exchangeFormat(file string) (string, error) {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".xlf", ".xliff", ".xml":
+		return ExchangeFormatXLIFF, nil
+	case ".json":
+		return ExchangeFormatJSON, nil
+	}
+	return "", fmt.Errorf("unsupported file %s", filepath.Base(file))
+}
+
+// ImportJob registers the job that imports the translated XLIFF or JSON files, the dry run
+// only reports the records to create or update, the issues of the file and the validation errors.
+func (b *Builder) ImportJob(w *worker.Builder) *worker.JobBuilder {
+	jb := w.NewJob(ImportJobName).Resource(&ImportJobArgs{}).Handler(b.runImport)
+	eb := jb.GetResourceBuilder().Editing("File", "TargetLocale", "DryRun")
+	eb.Field("File").ComponentFunc(func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) h.HTMLComponent {
+		return v.VFileInput().Chips(true).ErrorMessages(field.Errors...).
+			Label(MustGetTranslation(ctx.R, "ExchangeFile")).
+			Attr("accept", ".xlf,.xliff,.xml,.json").Clearable(false).
+			On("change", fmt.Sprintf("form.%s = $event.target.files[0]", field.Name))
+	}).SetterFunc(func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) (err error) {
+		args := obj.(*ImportJobArgs)
+		var fs []*multipart.FileHeader
+		if ctx.R.MultipartForm != nil {
+			fs = ctx.R.MultipartForm.File[field.Name]
+		}
+		if len(fs) == 0 {
+			if args.File == "" {
+				return errors.New(MustGetTranslation(ctx.R, "ExchangeFile") + " " + MustGetTranslation(ctx.R, "IsRequired"))
+			}
+			return
+		}
+		if _, err = exchangeFormat(fs[0].Filename); err != nil {
+			return
+		}
+		if b.exchangeStorage == nil {
+			return ErrNoExchangeStorage
+		}
+		f, err := fs[0].Open()
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		p := path.Join(exchangeStoragePath, "imports", fmt.Sprintf("%d-%s", time.Now().UnixNano(), filepath.Base(fs[0].Filename)))
+		if _, err = b.exchangeStorage.Put(ctx.R.Context(), p, f); err != nil {
+			return
+		}
+		args.File = p
+		return
+	})
+	eb.Field("TargetLocale").ComponentFunc(selectField("ExchangeTargetLocale", b.localeItems)).SetterFunc(requiredSetter("ExchangeTargetLocale"))
+	eb.Field("DryRun").ComponentFunc(func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) h.HTMLComponent {
+		return v.VCheckbox().Attr(web.VField(field.Name, field.Value(obj))...).
+			Label(MustGetTranslation(ctx.R, "ExchangeDryRun")).
+			Hint(MustGetTranslation(ctx.R, "ExchangeDryRunHint")).PersistentHint(true)
+	})
+	return jb
+}
+
+func (b *Builder) runImport(ctx context.Context, job worker.QorJobInterface) (err error) {
+	if b.exchangeStorage == nil {
+		return ErrNoExchangeStorage
+	}
+	info, err := job.GetJobInfo()
+	if err != nil {
+		return
+	}
+	args := info.Argument.(*ImportJobArgs)
+	format, err := exchangeFormat(args.File)
+	if err != nil {
+		return
+	}
+	rc, err := b.exchangeStorage.GetStream(ctx, args.File)
+	if err != nil {
+		return
+	}
+	defer rc.Close()
+
+	var doc *ExchangeDocument
+	if format == ExchangeFormatXLIFF {
+		if doc, err = b.DecodeXLIFF(rc); err != nil {
+			return
+		}
+		if doc.TargetLocale != "" && doc.TargetLocale != args.TargetLocale {
+			return fmt.Errorf("the target locale of the file is %s", doc.TargetLocale)
+		}
+		doc.TargetLocale = args.TargetLocale
+	} else if doc, err = DecodeFlatJSON(rc, args.TargetLocale); err != nil {
+		return
+	}
+	job.SetProgress(10)
+
+	report, err := b.ImportContent(ctx, doc, args.DryRun)
+	if err != nil {
+		return
+	}
+	for _, issue := range report.Issues {
+		if issue.Key != "" {
+			job.AddLogf("%s %s: %s", issue.Record, issue.Key, issue.Message)
+		} else {
+			job.AddLogf("%s: %s", issue.Record, issue.Message)
+		}
+	}
+	summary := fmt.Sprintf("%d created, %d updated, %d skipped, %d issues", report.Created, report.Updated, report.Skipped, len(report.Issues))
+	if report.DryRun {
+		summary = "Dry run: " + summary
+	}
+	job.AddLog(summary)
+	job.SetProgress(100)
+	return job.SetProgressText(summary)
+}
@@ -0,0 +1,226 @@
+package l10n
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qor5/web/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/qor5/admin/v3/presets"
+	"github.com/qor5/admin/v3/presets/gorm2op"
+)
+
+type exchangeTestPost struct {
+	ID        uint `gorm:"primarykey;autoIncrement:false"`
+	Title     string
+	Body      string
+	UpdatedAt time.Time
+	Locale
+}
+
+func (p *exchangeTestPost) PrimarySlug() string {
+	return fmt.Sprintf("%d_%s", p.ID, p.LocaleCode)
+}
+
+func (p *exchangeTestPost) PrimaryColumnValuesBySlug(slug string) map[string]string {
+	id, locale, _ := strings.Cut(slug, "_")
+	return map[string]string{"id": id, "locale_code": locale}
+}
+
+func TestImportContent(t *testing.T) {
+	require.NoError(t, testDB.AutoMigrate(&exchangeTestPost{}))
+	t.Cleanup(func() {
+		if err := testDB.Migrator().DropTable(&exchangeTestPost{}); err != nil {
+			t.Errorf("drop posts table: %v", err)
+		}
+	})
+	b := New(testDB).
+		RegisterLocales("en", "en", "English", "").
+		RegisterLocales("ja", "ja", "Japanese", "").
+		TranslatableFields(&exchangeTestPost{}, "Title", "Body")
+	pb := presets.New().DataOperator(gorm2op.DataOperator(testDB))
+	mb := pb.Model(&exchangeTestPost{})
+	mb.Editing().ValidateFunc(func(obj interface{}, ctx *web.EventContext) (err web.ValidationErrors) {
+		if len(obj.(*exchangeTestPost).Title) > 20 {
+			err.FieldError("Title", "title is too long")
+		}
+		return
+	})
+	require.NoError(t, b.ModelInstall(pb, mb))
+
+	posts := []*exchangeTestPost{
+		{ID: 1, Title: "Hello", Body: "Welcome", Locale: Locale{LocaleCode: "en"}},
+		{ID: 2, Title: "News", Body: "Today", Locale: Locale{LocaleCode: "en"}},
+		{ID: 2, Title: "ニュース", Body: "今日", Locale: Locale{LocaleCode: "ja"}},
+		{ID: 3, Title: "Long", Body: "Story", Locale: Locale{LocaleCode: "en"}},
+	}
+	require.NoError(t, testDB.Create(&posts).Error)
+
+	record := func(slug, title, body string) *ExchangeRecord {
+		return &ExchangeRecord{Model: mb.Info().URIName(), Slug: slug, Segments: []*ContentSegment{
+			{Key: "Title", Target: title},
+			{Key: "Body", Target: body},
+		}}
+	}
+	doc := &ExchangeDocument{SourceLocale: "en", TargetLocale: "ja", Records: []*ExchangeRecord{
+		record("1_en", "こんにちは", "ようこそ"),
+		record("2_en", "お知らせ", ""),
+		record("3_en", "とてもとても長いタイトルです", "物語"),
+	}}
+	titles := func() map[string]string {
+		t.Helper()
+		var got []*exchangeTestPost
+		require.NoError(t, testDB.Where("locale_code = ?", "ja").Order("id").Find(&got).Error)
+		r := make(map[string]string)
+		for _, p := range got {
+			r[p.PrimarySlug()] = p.Title + "/" + p.Body
+		}
+		return r
+	}
+
+	// the dry run reports the validation errors without saving the records
+	report, err := b.ImportContent(context.Background(), doc, true)
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Created)
+	require.Equal(t, 1, report.Updated)
+	require.Equal(t, 1, report.Skipped)
+	require.Len(t, report.Issues, 2)
+	require.Equal(t, "Body", report.Issues[0].Key)
+	require.Equal(t, "not translated", report.Issues[0].Message)
+	require.Equal(t, mb.Info().URIName()+"/3_en", report.Issues[1].Record)
+	require.Contains(t, report.Issues[1].Message, "title is too long")
+	require.Equal(t, map[string]string{"2_ja": "ニュース/今日"}, titles())
+
+	report, err = b.ImportContent(context.Background(), doc, false)
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Created)
+	require.Equal(t, 1, report.Updated)
+	require.Equal(t, 1, report.Skipped)
+	require.Contains(t, report.Issues[1].Message, "title is too long")
+	require.Equal(t, map[string]string{"1_ja": "こんにちは/ようこそ", "2_ja": "お知らせ/今日"}, titles())
+}
+
+// summaryExtractor is the content extractor of a summary kept outside the record
+type summaryExtractor struct{}
+
+func (summaryExtractor) ExtractContent(context.Context, interface{}) ([]*ContentSegment, error) {
+	return []*ContentSegment{{Key: "Summary", Source: "Summary"}}, nil
+}
+
+func (summaryExtractor) ApplyContent(ctx context.Context, _ interface{}, segments []*ContentSegment) error {
+	if ctx.Value(gorm2op.CtxKeyDB{}) == nil {
+		return errors.New("no transaction")
+	}
+	if segments[0].Target == "fail" {
+		return errors.New("summary failed")
+	}
+	return nil
+}
+
+func TestImportContentInTransaction(t *testing.T) {
+	require.NoError(t, testDB.Exec("DELETE FROM qor_localization_sources").Error)
+	require.NoError(t, testDB.AutoMigrate(&exchangeTestPost{}))
+	t.Cleanup(func() {
+		if err := testDB.Migrator().DropTable(&exchangeTestPost{}); err != nil {
+			t.Errorf("drop posts table: %v", err)
+		}
+	})
+	b := New(testDB).
+		RegisterLocales("en", "en", "English", "").
+		RegisterLocales("ja", "ja", "Japanese", "").
+		TrackSourceVersions(true).
+		TranslatableFields(&exchangeTestPost{}, "Title").
+		ContentExtractors(&exchangeTestPost{}, summaryExtractor{})
+	pb := presets.New().DataOperator(gorm2op.DataOperator(testDB))
+	mb := pb.Model(&exchangeTestPost{})
+	require.NoError(t, b.ModelInstall(pb, mb))
+	require.NoError(t, testDB.Create(&exchangeTestPost{ID: 1, Title: "Hello", Locale: Locale{LocaleCode: "en"}}).Error)
+
+	doc := func(summary string) *ExchangeDocument {
+		return &ExchangeDocument{SourceLocale: "en", TargetLocale: "ja", Records: []*ExchangeRecord{
+			{Model: mb.Info().URIName(), Slug: "1_en", Segments: []*ContentSegment{
+				{Key: "Title", Target: "こんにちは"},
+				{Key: "Summary", Target: summary},
+			}},
+		}}
+	}
+	count := func(model interface{}) (n int64) {
+		t.Helper()
+		require.NoError(t, testDB.Model(model).Where("locale_code = ?", "ja").Count(&n).Error)
+		return
+	}
+
+	report, err := b.ImportContent(context.Background(), doc("fail"), false)
+	require.NoError(t, err)
+	require.Zero(t, report.Created)
+	require.Equal(t, "summary failed", report.Issues[0].Message)
+	require.Zero(t, count(&exchangeTestPost{}), "the localized record is rolled back with the content of the extractors")
+	require.Zero(t, count(&QorLocalizationSource{}))
+
+	report, err = b.ImportContent(context.Background(), doc("要約"), false)
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Created)
+	require.EqualValues(t, 1, count(&exchangeTestPost{}))
+	var source QorLocalizationSource
+	require.NoError(t, testDB.Where("locale_code = ?", "ja").First(&source).Error)
+	require.Equal(t, "en", source.FromLocale)
+	require.Contains(t, source.Snapshot, `"LocaleCode":"en"`, "the snapshot is the source record")
+}
+
+func exchangeTestDocument() *ExchangeDocument {
+	return &ExchangeDocument{SourceLocale: "en", TargetLocale: "ja", Records: []*ExchangeRecord{
+		{Model: "posts", Slug: "1_en", Segments: []*ContentSegment{
+			{Key: "Title", Source: "Hello & <welcome>", Target: "こんにちは"},
+			{Key: "containers/2/Text", Note: "Heading", Source: "Line 1\nLine 2"},
+		}},
+		{Model: "pages", Slug: "3_2024-01-01-v01_en", Segments: []*ContentSegment{
+			{Key: "Title", Source: "About"},
+		}},
+	}}
+}
+
+func TestXLIFFRoundTrip(t *testing.T) {
+	b := New(testDB).ExchangeLanguages(map[string]string{"en": "en-US", "ja": "ja-JP"})
+	doc := exchangeTestDocument()
+
+	var buf bytes.Buffer
+	require.NoError(t, b.EncodeXLIFF(&buf, doc))
+	require.Contains(t, buf.String(), `srcLang="en-US" trgLang="ja-JP"`)
+	require.Contains(t, buf.String(), "<note>Heading</note>")
+
+	got, err := b.DecodeXLIFF(&buf)
+	require.NoError(t, err)
+	// the notes are for the translators only
+	doc.Records[0].Segments[1].Note = ""
+	require.Equal(t, doc, got)
+
+	_, err = b.DecodeXLIFF(strings.NewReader(`<xliff xmlns="urn:oasis:names:tc:xliff:document:2.0" version="1.2"></xliff>`))
+	require.EqualError(t, err, "not a XLIFF 2 document")
+}
+
+func TestFlatJSONRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, EncodeFlatJSON(&buf, exchangeTestDocument()))
+
+	got, err := DecodeFlatJSON(&buf, "ja")
+	require.NoError(t, err)
+	// the translators replace the source texts, the keys are sorted
+	require.Equal(t, &ExchangeDocument{TargetLocale: "ja", Records: []*ExchangeRecord{
+		{Model: "pages", Slug: "3_2024-01-01-v01_en", Segments: []*ContentSegment{
+			{Key: "Title", Target: "About"},
+		}},
+		{Model: "posts", Slug: "1_en", Segments: []*ContentSegment{
+			{Key: "Title", Target: "Hello & <welcome>"},
+			{Key: "containers/2/Text", Target: "Line 1\nLine 2"},
+		}},
+	}}, got)
+
+	_, err = DecodeFlatJSON(strings.NewReader(`{"posts/1_en": "Hello"}`), "ja")
+	require.EqualError(t, err, `invalid key "posts/1_en"`)
+}
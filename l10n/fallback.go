@@ -0,0 +1,97 @@
+package l10n
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetLocaleFallbacks returns the locales to fall back to when the content is missing in the locale,
+// the fallbacks of the fallbacks are followed, the unregistered locales are skipped.
+func (b *Builder) GetLocaleFallbacks(localeCode string) (r []string) {
+	if b == nil {
+		return
+	}
+	visited := map[string]bool{localeCode: true}
+	queue := []string{localeCode}
+	for len(queue) > 0 {
+		code := queue[0]
+		queue = queue[1:]
+		i := slices.IndexFunc(b.locales, func(l *loc) bool {
+			return l.code == code
+		})
+		if i < 0 {
+			continue
+		}
+		for _, f := range b.locales[i].fallbacks {
+			if visited[f] || !slices.ContainsFunc(b.locales, func(l *loc) bool { return l.code == f }) {
+				continue
+			}
+			visited[f] = true
+			r = append(r, f)
+			queue = append(queue, f)
+		}
+	}
+	return
+}
+
+// GetLocaleChain returns the locale followed by its fallbacks
+func (b *Builder) GetLocaleChain(localeCode string) []string {
+	return append([]string{localeCode}, b.GetLocaleFallbacks(localeCode)...)
+}
+
+// GetFallingBackLocales returns the locales falling back to the locale
+func (b *Builder) GetFallingBackLocales(localeCode string) (r []string) {
+	for _, l := range b.GetSupportLocaleCodes() {
+		if l != localeCode && slices.Contains(b.GetLocaleFallbacks(l), localeCode) {
+			r = append(r, l)
+		}
+	}
+	return
+}
+
+// FallbackScope limits the query to the locale chain of the locale, the records are ordered from
+// the locale to its last fallback, so First returns the record in the nearest locale.
+func (b *Builder) FallbackScope(localeCode string) func(db *gorm.DB) *gorm.DB {
+	chain := b.GetLocaleChain(localeCode)
+	return func(db *gorm.DB) *gorm.DB {
+		column := clause.Column{Table: clause.CurrentTable, Name: "locale_code"}
+		if len(chain) == 1 {
+			return db.Where(clause.Eq{Column: column, Value: localeCode})
+		}
+		var (
+			cases []string
+			vars  = []interface{}{column}
+			codes []interface{}
+		)
+		for i, code := range chain {
+			cases = append(cases, fmt.Sprintf("WHEN ? THEN %d", i))
+			vars = append(vars, code)
+			codes = append(codes, code)
+		}
+		return db.Where(clause.IN{Column: column, Values: codes}).
+			Clauses(clause.OrderBy{Expression: clause.Expr{
+				SQL:                "CASE ? " + strings.Join(cases, " ") + " END",
+				Vars:               vars,
+				WithoutParentheses: true,
+			}})
+	}
+}
+
+// LocaleFallbackScope is FallbackScope of the locale in the context, it leaves the query untouched
+// if the context has no l10n builder or locale, which are the ones LocalePathFromContext uses.
+func LocaleFallbackScope(ctx context.Context) func(db *gorm.DB) *gorm.DB {
+	b, ok := builderFromContext(ctx)
+	if !ok {
+		return func(db *gorm.DB) *gorm.DB { return db }
+	}
+	locale, ok := IsLocalizableFromContext(ctx)
+	if !ok {
+		return func(db *gorm.DB) *gorm.DB { return db }
+	}
+	return b.FallbackScope(locale)
+}
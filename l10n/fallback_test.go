@@ -0,0 +1,63 @@
+package l10n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetLocaleFallbacks(t *testing.T) {
+	b := New(testDB).
+		RegisterLocales("en", "en", "English", "").
+		RegisterLocales("fr", "fr", "French", "", "en").
+		RegisterLocales("fr-CA", "fr-ca", "French (Canada)", "", "fr", "unknown", "de").
+		RegisterLocales("de", "de", "German", "", "en").
+		// a cycle between the locales
+		RegisterLocales("pt", "pt", "Portuguese", "", "br").
+		RegisterLocales("br", "br", "Brazilian", "", "pt")
+
+	require.Empty(t, b.GetLocaleFallbacks("en"))
+	require.Equal(t, []string{"en"}, b.GetLocaleFallbacks("fr"))
+	require.Equal(t, []string{"fr", "de", "en"}, b.GetLocaleFallbacks("fr-CA"), "the unregistered locales are skipped, the nearer fallbacks come first")
+	require.Equal(t, []string{"br"}, b.GetLocaleFallbacks("pt"), "the cycles end at the visited locales")
+	require.Empty(t, b.GetLocaleFallbacks("unknown"))
+	require.Equal(t, []string{"fr-CA", "fr", "de", "en"}, b.GetLocaleChain("fr-CA"))
+	require.Equal(t, []string{"fr", "fr-CA", "de"}, b.GetFallingBackLocales("en"))
+	require.Equal(t, []string{"br"}, b.GetFallingBackLocales("pt"))
+
+	var nilBuilder *Builder
+	require.Empty(t, nilBuilder.GetLocaleFallbacks("en"))
+}
+
+func TestFallbackScope(t *testing.T) {
+	require.NoError(t, testDB.AutoMigrate(&l10nTestPost{}))
+	t.Cleanup(func() {
+		if err := testDB.Migrator().DropTable(&l10nTestPost{}); err != nil {
+			t.Errorf("drop posts table: %v", err)
+		}
+	})
+	require.NoError(t, testDB.Create([]*l10nTestPost{
+		{ID: 1, Title: "en", Locale: Locale{LocaleCode: "en"}},
+		{ID: 2, Title: "fr", Locale: Locale{LocaleCode: "fr"}},
+		{ID: 3, Title: "ja", Locale: Locale{LocaleCode: "ja"}},
+		{ID: 4, Title: "fr-CA", Locale: Locale{LocaleCode: "fr-CA"}},
+	}).Error)
+	b := New(testDB).
+		RegisterLocales("en", "en", "English", "").
+		RegisterLocales("fr", "fr", "French", "", "en").
+		RegisterLocales("fr-CA", "fr-ca", "French (Canada)", "", "fr").
+		RegisterLocales("ja", "ja", "Japanese", "")
+
+	titles := func(locale string) (r []string) {
+		t.Helper()
+		require.NoError(t, testDB.Model(&l10nTestPost{}).Scopes(b.FallbackScope(locale)).Pluck("title", &r).Error)
+		return
+	}
+	require.Equal(t, []string{"fr-CA", "fr", "en"}, titles("fr-CA"), "the records are ordered from the locale to its last fallback")
+	require.Equal(t, []string{"fr", "en"}, titles("fr"))
+	require.Equal(t, []string{"ja"}, titles("ja"))
+
+	var nearest l10nTestPost
+	require.NoError(t, testDB.Scopes(b.FallbackScope("fr")).First(&nearest).Error)
+	require.Equal(t, "fr", nearest.Title)
+}
@@ -9,51 +9,163 @@ import (
 const I18nLocalizeKey i18n.ModuleKey = "I18nLocalizeKey"
 
 type Messages struct {
-	Localize              string
-	LocalizeFrom          string
-	LocalizeTo            string
-	SuccessfullyLocalized string
-	Location              string
-	Colon                 string
-	International         string
-	China                 string
-	Japan                 string
+	Localize                     string
+	LocalizeFrom                 string
+	LocalizeTo                   string
+	SuccessfullyLocalized        string
+	Location                     string
+	Colon                        string
+	International                string
+	China                        string
+	Japan                        string
+	PreTranslate                 string
+	PreTranslateHint             string
+	MachineTranslated            string
+	MarkTranslationsAsReviewed   string
+	LocalizationStatus           string
+	LocalizationMissing          string
+	LocalizationUpToDate         string
+	LocalizationStale            string
+	StaleInLocale                string
+	SourceChanges                string
+	SourceChangesField           string
+	SourceWhenLocalized          string
+	SourceNow                    string
+	SourceChangesTranslation     string
+	NoSourceChanges              string
+	NotLocalizedFromSource       string
+	MarkAsUpToDate               string
+	SuccessfullyMarkedAsUpToDate string
+	IsRequired                   string
+	ExchangeModel                string
+	ExchangeSourceLocale         string
+	ExchangeTargetLocale         string
+	ExchangeFormat               string
+	ExchangeFile                 string
+	ExchangeDryRun               string
+	ExchangeDryRunHint           string
+	ExchangeSameLocale           string
+	ExportJobCreated             string
 }
 
 var Messages_en_US = &Messages{
-	Localize:              "Localize",
-	LocalizeFrom:          "From",
-	LocalizeTo:            "To",
-	SuccessfullyLocalized: "Successfully Localized",
-	Location:              "Location",
-	Colon:                 ":",
-	International:         "International",
-	China:                 "China",
-	Japan:                 "Japan",
+	Localize:                     "Localize",
+	LocalizeFrom:                 "From",
+	LocalizeTo:                   "To",
+	SuccessfullyLocalized:        "Successfully Localized",
+	Location:                     "Location",
+	Colon:                        ":",
+	International:                "International",
+	China:                        "China",
+	Japan:                        "Japan",
+	PreTranslate:                 "Pre-translate text fields",
+	PreTranslateHint:             "Filled by the translation memory and machine translation, the machine translated fields need review",
+	MachineTranslated:            "Machine translated, needs review",
+	MarkTranslationsAsReviewed:   "Mark the translations as reviewed",
+	LocalizationStatus:           "Localization Status",
+	LocalizationMissing:          "Missing",
+	LocalizationUpToDate:         "Up to date",
+	LocalizationStale:            "Stale",
+	StaleInLocale:                "Stale in locale",
+	SourceChanges:                "Source Changes",
+	SourceChangesField:           "Field",
+	SourceWhenLocalized:          "Source when localized",
+	SourceNow:                    "Source now",
+	SourceChangesTranslation:     "Translation",
+	NoSourceChanges:              "The source has not changed since it was localized",
+	NotLocalizedFromSource:       "The record was not localized from another locale",
+	MarkAsUpToDate:               "Mark as up to date",
+	SuccessfullyMarkedAsUpToDate: "Successfully marked as up to date",
+	IsRequired:                   "is required",
+	ExchangeModel:                "Model",
+	ExchangeSourceLocale:         "Source Locale",
+	ExchangeTargetLocale:         "Target Locale",
+	ExchangeFormat:               "Format",
+	ExchangeFile:                 "File",
+	ExchangeDryRun:               "Dry run",
+	ExchangeDryRunHint:           "Validate the file and report the changes without saving them",
+	ExchangeSameLocale:           "The target locale is the current locale",
+	ExportJobCreated:             "The export job is created, download the file from the job in Workers",
 }
 
 var Messages_zh_CN = &Messages{
-	Localize:              "本地化",
-	LocalizeFrom:          "从",
-	LocalizeTo:            "到",
-	SuccessfullyLocalized: "本地化成功",
-	Location:              "地区",
-	Colon:                 "：",
-	International:         "全球",
-	China:                 "中国",
-	Japan:                 "日本",
+	Localize:                     "本地化",
+	LocalizeFrom:                 "从",
+	LocalizeTo:                   "到",
+	SuccessfullyLocalized:        "本地化成功",
+	Location:                     "地区",
+	Colon:                        "：",
+	International:                "全球",
+	China:                        "中国",
+	Japan:                        "日本",
+	PreTranslate:                 "预翻译文本字段",
+	PreTranslateHint:             "使用翻译记忆和机器翻译填充，机器翻译的字段需要审核",
+	MachineTranslated:            "机器翻译，需要审核",
+	MarkTranslationsAsReviewed:   "将翻译标记为已审核",
+	LocalizationStatus:           "本地化状态",
+	LocalizationMissing:          "缺失",
+	LocalizationUpToDate:         "最新",
+	LocalizationStale:            "过期",
+	StaleInLocale:                "在地区中过期",
+	SourceChanges:                "源内容变更",
+	SourceChangesField:           "字段",
+	SourceWhenLocalized:          "本地化时的源内容",
+	SourceNow:                    "当前源内容",
+	SourceChangesTranslation:     "翻译",
+	NoSourceChanges:              "自本地化以来源内容没有变更",
+	NotLocalizedFromSource:       "该记录不是从其他地区本地化而来",
+	MarkAsUpToDate:               "标记为最新",
+	SuccessfullyMarkedAsUpToDate: "已标记为最新",
+	IsRequired:                   "是必填项",
+	ExchangeModel:                "模型",
+	ExchangeSourceLocale:         "源地区",
+	ExchangeTargetLocale:         "目标地区",
+	ExchangeFormat:               "格式",
+	ExchangeFile:                 "文件",
+	ExchangeDryRun:               "试运行",
+	ExchangeDryRunHint:           "校验文件并报告变更，但不保存",
+	ExchangeSameLocale:           "目标地区与当前地区相同",
+	ExportJobCreated:             "导出任务已创建，请在 Workers 中从任务下载文件",
 }
 
 var Messages_ja_JP = &Messages{
-	Localize:              "ローカライズ",
-	LocalizeFrom:          "から",
-	LocalizeTo:            "に",
-	SuccessfullyLocalized: "ローカライズに成功しました",
-	Location:              "場所",
-	Colon:                 ":",
-	International:         "国際的",
-	China:                 "中国",
-	Japan:                 "日本",
+	Localize:                     "ローカライズ",
+	LocalizeFrom:                 "から",
+	LocalizeTo:                   "に",
+	SuccessfullyLocalized:        "ローカライズに成功しました",
+	Location:                     "場所",
+	Colon:                        ":",
+	International:                "国際的",
+	China:                        "中国",
+	Japan:                        "日本",
+	PreTranslate:                 "テキストフィールドを事前翻訳",
+	PreTranslateHint:             "翻訳メモリと機械翻訳で入力され、機械翻訳されたフィールドはレビューが必要です",
+	MachineTranslated:            "機械翻訳、レビューが必要",
+	MarkTranslationsAsReviewed:   "翻訳をレビュー済みにする",
+	LocalizationStatus:           "ローカライズ状況",
+	LocalizationMissing:          "未作成",
+	LocalizationUpToDate:         "最新",
+	LocalizationStale:            "古い",
+	StaleInLocale:                "ロケールで古い",
+	SourceChanges:                "ソースの変更",
+	SourceChangesField:           "フィールド",
+	SourceWhenLocalized:          "ローカライズ時のソース",
+	SourceNow:                    "現在のソース",
+	SourceChangesTranslation:     "翻訳",
+	NoSourceChanges:              "ローカライズ以降、ソースは変更されていません",
+	NotLocalizedFromSource:       "このレコードは他のロケールからローカライズされていません",
+	MarkAsUpToDate:               "最新としてマーク",
+	SuccessfullyMarkedAsUpToDate: "最新としてマークしました",
+	IsRequired:                   "は必須です",
+	ExchangeModel:                "モデル",
+	ExchangeSourceLocale:         "ソースロケール",
+	ExchangeTargetLocale:         "ターゲットロケール",
+	ExchangeFormat:               "フォーマット",
+	ExchangeFile:                 "ファイル",
+	ExchangeDryRun:               "ドライラン",
+	ExchangeDryRunHint:           "ファイルを検証し、保存せずに変更を報告します",
+	ExchangeSameLocale:           "ターゲットロケールが現在のロケールと同じです",
+	ExportJobCreated:             "エクスポートジョブを作成しました。Workers のジョブからファイルをダウンロードしてください",
 }
 
 func MustGetTranslation(r *http.Request, key string) string {
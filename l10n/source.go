@@ -0,0 +1,461 @@
+package l10n
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"time"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/i18n"
+	v "github.com/qor5/x/v3/ui/vuetify"
+	vx "github.com/qor5/x/v3/ui/vuetifyx"
+	h "github.com/theplant/htmlgo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/qor5/admin/v3/presets"
+	"github.com/qor5/admin/v3/utils"
+)
+
+const (
+	SourceChanges = "l10n_SourceChangesEvent"
+	MarkUpToDate  = "l10n_MarkUpToDateEvent"
+
+	FilterKeyStale = "l10n_stale"
+
+	localizationStatusURIName = "localization-status"
+)
+
+// QorLocalizationSource records the source record a localized record is derived from,
+// the localized record is stale once its source is updated after FromUpdatedAt.
+type QorLocalizationSource struct {
+	gorm.Model
+	ModelName     string `gorm:"size:128;uniqueIndex:idx_qor_localization_source_record"`
+	ObjectID      string `gorm:"size:64;uniqueIndex:idx_qor_localization_source_record"`
+	LocaleCode    string `gorm:"size:20;uniqueIndex:idx_qor_localization_source_record"`
+	FromLocale    string `gorm:"size:20"`
+	FromVersion   string
+	FromUpdatedAt time.Time
+	// Snapshot is the source record in JSON when it is localized, to show the source changes
+	Snapshot string
+}
+
+// TrackSourceVersions records the source of the localized records, which enables the localization status
+// dashboard, the stale filter of the listings and the source changes of the localized records.
+func (b *Builder) TrackSourceVersions(v bool) (r *Builder) {
+	b.trackSourceVersions = v
+	return b
+}
+
+// canTrackSource reports whether the changes of the model can be tracked, which requires the update time
+func (b *Builder) canTrackSource(model interface{}) bool {
+	return b.trackSourceVersions && hasUpdatedAt(model)
+}
+
+func hasUpdatedAt(model interface{}) bool {
+	f := reflect.Indirect(reflect.ValueOf(model)).FieldByName("UpdatedAt")
+	return f.IsValid() && f.Type() == reflect.TypeOf(time.Time{})
+}
+
+func updatedAt(obj interface{}) time.Time {
+	t, _ := reflect.Indirect(reflect.ValueOf(obj)).FieldByName("UpdatedAt").Interface().(time.Time)
+	return t
+}
+
+func hasDeletedAt(model interface{}) bool {
+	return reflect.Indirect(reflect.ValueOf(model)).FieldByName("DeletedAt").IsValid()
+}
+
+func tableName(db *gorm.DB, model interface{}) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return "", err
+	}
+	return stmt.Schema.Table, nil
+}
+
+// recordSource saves the source of the localized record toObj, the source is localized again
+// into the same locale only after the old localized record is deleted.
+func (b *Builder) recordSource(ctx context.Context, mb *presets.ModelBuilder, fromObj, toObj interface{}, fromLocale, fromVersion string) error {
+	snapshot, err := json.Marshal(fromObj)
+	if err != nil {
+		return err
+	}
+	return b.contextDB(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "model_name"}, {Name: "object_id"}, {Name: "locale_code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"from_locale", "from_version", "from_updated_at", "snapshot", "updated_at"}),
+	}).Create(&QorLocalizationSource{
+		ModelName:     mb.Info().URIName(),
+		ObjectID:      objectID(toObj),
+		LocaleCode:    EmbedLocale(toObj).LocaleCode,
+		FromLocale:    fromLocale,
+		FromVersion:   fromVersion,
+		FromUpdatedAt: updatedAt(fromObj),
+		Snapshot:      string(snapshot),
+	}).Error
+}
+
+// staleCondition is the SQL condition of the sources tracked whose source records are updated after localized,
+// s is the alias of the tracking table.
+func (b *Builder) staleCondition(mb *presets.ModelBuilder) (string, error) {
+	table, err := tableName(b.db, mb.NewModel())
+	if err != nil {
+		return "", err
+	}
+	var notDeleted string
+	if hasDeletedAt(mb.NewModel()) {
+		notDeleted = " AND src.deleted_at IS NULL"
+	}
+	return fmt.Sprintf(`EXISTS (SELECT 1 FROM %s src WHERE CAST(src.id AS TEXT) = s.object_id AND src.locale_code = s.from_locale AND src.updated_at > s.from_updated_at%s)`,
+		table, notDeleted), nil
+}
+
+// NewStaleFilterItem filters the records whose localized records in the selected locale fall behind their sources
+func (b *Builder) NewStaleFilterItem(ctx context.Context, mb *presets.ModelBuilder, columnPrefix string) (*vx.FilterItem, error) {
+	evCtx := web.MustGetEventContext(ctx)
+	stale, err := b.staleCondition(mb)
+	if err != nil {
+		return nil, err
+	}
+	var options []*vx.SelectItem
+	for _, locale := range b.GetSupportLocaleCodesFromRequest(evCtx.R) {
+		options = append(options, &vx.SelectItem{Text: MustGetTranslation(evCtx.R, b.GetLocaleLabel(locale)), Value: locale})
+	}
+	return &vx.FilterItem{
+		Key:      FilterKeyStale,
+		Label:    MustGetTranslation(evCtx.R, "StaleInLocale"),
+		ItemType: vx.ItemTypeSelect,
+		SQLCondition: fmt.Sprintf(`CAST(%sid AS TEXT) IN (SELECT s.object_id FROM qor_localization_sources s WHERE s.deleted_at IS NULL AND s.model_name = '%s' AND s.locale_code {op} ? AND %s)`,
+			columnPrefix, mb.Info().URIName(), stale),
+		Options: options,
+	}, nil
+}
+
+// StaleListingHref is the listing of the model filtered by the records stale in the locale
+func StaleListingHref(mb *presets.ModelBuilder, locale string) string {
+	return mb.Info().ListingHref() + "?" + url.Values{
+		"locale":              []string{locale},
+		"f_" + FilterKeyStale: []string{locale},
+	}.Encode()
+}
+
+type localizationStatus struct {
+	Missing  int
+	UpToDate int
+	Stale    int
+}
+
+// localizationStatuses counts the records of the model by locale, a record is missing in the locales
+// it is not localized into and stale in the ones its source is updated after localized.
+func (b *Builder) localizationStatuses(ctx context.Context, mb *presets.ModelBuilder, locales []string) (r map[string]*localizationStatus, err error) {
+	model := mb.NewModel()
+	table, err := tableName(b.db, model)
+	if err != nil {
+		return
+	}
+	db := b.db.WithContext(ctx)
+
+	var records []*struct {
+		ID         string
+		LocaleCode string
+	}
+	q := db.Table(table).Select("CAST(id AS TEXT) AS id, locale_code").Group("id, locale_code")
+	if hasDeletedAt(model) {
+		q = q.Where("deleted_at IS NULL")
+	}
+	if err = q.Scan(&records).Error; err != nil {
+		return
+	}
+	ids := make(map[string]struct{})
+	localized := make(map[string]int)
+	for _, rec := range records {
+		ids[rec.ID] = struct{}{}
+		localized[rec.LocaleCode]++
+	}
+
+	var staleCounts []*struct {
+		LocaleCode string
+		Count      int
+	}
+	stale, err := b.staleCondition(mb)
+	if err != nil {
+		return
+	}
+	var notDeleted string
+	if hasDeletedAt(model) {
+		notDeleted = " AND loc.deleted_at IS NULL"
+	}
+	if err = db.Raw(fmt.Sprintf(`SELECT s.locale_code, COUNT(*) AS count FROM qor_localization_sources s
+WHERE s.deleted_at IS NULL AND s.model_name = ?
+AND EXISTS (SELECT 1 FROM %s loc WHERE CAST(loc.id AS TEXT) = s.object_id AND loc.locale_code = s.locale_code%s)
+AND %s
+GROUP BY s.locale_code`, table, notDeleted, stale), mb.Info().URIName()).Scan(&staleCounts).Error; err != nil {
+		return
+	}
+	staled := make(map[string]int)
+	for _, c := range staleCounts {
+		staled[c.LocaleCode] = c.Count
+	}
+
+	r = make(map[string]*localizationStatus, len(locales))
+	for _, locale := range locales {
+		r[locale] = &localizationStatus{
+			Missing:  len(ids) - localized[locale],
+			UpToDate: localized[locale] - staled[locale],
+			Stale:    staled[locale],
+		}
+	}
+	return
+}
+
+// installLocalizationStatus adds the dashboard of the models by locales to the presets
+func (b *Builder) installLocalizationStatus(pb *presets.Builder) {
+	pm := pb.Model(&QorLocalizationSource{}).URIName(localizationStatusURIName).Label("Localization Status").MenuIcon("mdi-translate")
+	pm.LabelName(func(evCtx *web.EventContext, singular bool) string {
+		return MustGetTranslation(evCtx.R, "LocalizationStatus")
+	})
+	pm.Listing().PageFunc(func(ctx *web.EventContext) (r web.PageResponse, err error) {
+		r.PageTitle = MustGetTranslation(ctx.R, "LocalizationStatus")
+		locales := b.GetSupportLocaleCodesFromRequest(ctx.R)
+
+		header := h.Tr(h.Th(""))
+		for _, locale := range locales {
+			header.AppendChildren(h.Th(MustGetTranslation(ctx.R, b.GetLocaleLabel(locale))))
+		}
+		rows := h.Tbody()
+		for _, mb := range b.models {
+			if mb.Info().Verifier().Do(presets.PermList).WithReq(ctx.R).IsAllowed() != nil {
+				continue
+			}
+			statuses, sErr := b.localizationStatuses(ctx.R.Context(), mb, locales)
+			if sErr != nil {
+				return r, sErr
+			}
+			row := h.Tr(h.Td(h.A(h.Text(mb.Info().LabelName(ctx, false))).Href(mb.Info().ListingHref())))
+			for _, locale := range locales {
+				s := statuses[locale]
+				staleChip := v.VChip(h.Text(fmt.Sprintf("%s %d", MustGetTranslation(ctx.R, "LocalizationStale"), s.Stale))).
+					Size(v.SizeSmall).Color(v.ColorWarning)
+				if s.Stale > 0 {
+					staleChip.Attr("@click", web.Plaid().URL(StaleListingHref(mb, locale)).PushState(true).Go())
+				}
+				row.AppendChildren(h.Td(
+					h.Div(
+						v.VChip(h.Text(fmt.Sprintf("%s %d", MustGetTranslation(ctx.R, "LocalizationMissing"), s.Missing))).Size(v.SizeSmall),
+						v.VChip(h.Text(fmt.Sprintf("%s %d", MustGetTranslation(ctx.R, "LocalizationUpToDate"), s.UpToDate))).
+							Size(v.SizeSmall).Color(v.ColorSuccess),
+						staleChip,
+					).Class("d-flex flex-wrap ga-1 py-2"),
+				))
+			}
+			rows.AppendChildren(row)
+		}
+
+		r.Body = v.VContainer(
+			v.VCard(
+				v.VTable(h.Thead(header), rows),
+			).Variant(v.VariantOutlined),
+		).Fluid(true)
+		return
+	})
+}
+
+func sourceChangesRowMenuItemFunc(mi *presets.ModelInfo) vx.RowMenuItemFunc {
+	return func(obj interface{}, id string, ctx *web.EventContext) h.HTMLComponent {
+		if mi.Verifier().Do(presets.PermGet).ObjectOn(obj).WithReq(ctx.R).IsAllowed() != nil {
+			return nil
+		}
+		return v.VListItem(
+			web.Slot(
+				v.VIcon("mdi-file-compare"),
+			).Name("prepend"),
+			v.VListItemTitle(h.Text(MustGetTranslation(ctx.R, "SourceChanges"))),
+		).Attr("@click", web.Plaid().
+			EventFunc(SourceChanges).
+			Query(presets.ParamID, id).
+			URL(mi.ListingHref()).
+			Go())
+	}
+}
+
+// untrackedColumns are the columns changed by localizing and publishing, not by editing the content
+var untrackedColumns = map[string]bool{
+	"id": true, "created_at": true, "updated_at": true, "deleted_at": true, "locale_code": true,
+	"version": true, "version_name": true, "parent_version": true,
+	"status": true, "online_url": true,
+	"scheduled_start_at": true, "scheduled_end_at": true, "actual_start_at": true, "actual_end_at": true,
+}
+
+type sourceChange struct {
+	Field       string
+	Snapshot    string
+	Source      string
+	Translation string
+}
+
+func displayValue(value interface{}) string {
+	if valuer, ok := value.(driver.Valuer); ok {
+		if dv, err := valuer.Value(); err == nil {
+			value = dv
+		}
+	}
+	switch vv := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(vv)
+	case string:
+		return vv
+	}
+	switch reflect.Indirect(reflect.ValueOf(value)).Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		bs, _ := json.Marshal(value)
+		return string(bs)
+	}
+	return fmt.Sprint(value)
+}
+
+// sourceChanges compares the snapshot of the source when localized with the current source
+func (b *Builder) sourceChanges(ctx context.Context, snapshot, source, translation interface{}) (r []*sourceChange, err error) {
+	stmt := &gorm.Statement{DB: b.db}
+	if err = stmt.Parse(source); err != nil {
+		return
+	}
+	for _, f := range stmt.Schema.Fields {
+		if f.DBName == "" || untrackedColumns[f.DBName] {
+			continue
+		}
+		was, _ := f.ValueOf(ctx, reflect.ValueOf(snapshot))
+		now, _ := f.ValueOf(ctx, reflect.ValueOf(source))
+		c := &sourceChange{Field: f.Name, Snapshot: displayValue(was), Source: displayValue(now)}
+		if c.Snapshot == c.Source {
+			continue
+		}
+		current, _ := f.ValueOf(ctx, reflect.ValueOf(translation))
+		c.Translation = displayValue(current)
+		r = append(r, c)
+	}
+	return
+}
+
+// localizedSource returns the tracked source of the localized record and the latest version of the source record
+func (b *Builder) localizedSource(ctx context.Context, mb *presets.ModelBuilder, slug string) (obj interface{}, s *QorLocalizationSource, source interface{}, err error) {
+	db := b.db.WithContext(ctx)
+	obj = mb.NewModel()
+	if err = utils.PrimarySluggerWhere(db, mb.NewModel(), slug).First(obj).Error; err != nil {
+		return
+	}
+	s = &QorLocalizationSource{}
+	if err = db.Where("model_name = ? AND object_id = ? AND locale_code = ?", mb.Info().URIName(), objectID(obj), EmbedLocale(obj).LocaleCode).
+		First(s).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			s, err = nil, nil
+		}
+		return
+	}
+	source = mb.NewModel()
+	if err = db.Where("id = ? AND locale_code = ?", s.ObjectID, s.FromLocale).Order("updated_at DESC").First(source).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			source, err = nil, nil
+		}
+	}
+	return
+}
+
+func sourceChangesDialog(lb *Builder, mb *presets.ModelBuilder) web.EventFunc {
+	return func(ctx *web.EventContext) (r web.EventResponse, err error) {
+		presetsMsgr := presets.MustGetMessages(ctx.R)
+		paramID := ctx.Param(presets.ParamID)
+		obj, s, source, err := lb.localizedSource(ctx.R.Context(), mb, paramID)
+		if err != nil {
+			return
+		}
+
+		var (
+			body    h.HTMLComponent
+			changes []*sourceChange
+		)
+		switch {
+		case s == nil || source == nil:
+			body = h.Div(h.Text(MustGetTranslation(ctx.R, "NotLocalizedFromSource")))
+		default:
+			snapshot := mb.NewModel()
+			if err = json.Unmarshal([]byte(s.Snapshot), snapshot); err != nil {
+				return
+			}
+			if changes, err = lb.sourceChanges(ctx.R.Context(), snapshot, source, obj); err != nil {
+				return
+			}
+			if len(changes) == 0 {
+				body = h.Div(h.Text(MustGetTranslation(ctx.R, "NoSourceChanges")))
+				break
+			}
+			rows := h.Tbody()
+			for _, c := range changes {
+				rows.AppendChildren(h.Tr(
+					h.Td(h.Text(i18n.PT(ctx.R, presets.ModelsI18nModuleKey, mb.Info().Label(), c.Field))),
+					h.Td(h.Del(c.Snapshot).Class("text-error")),
+					h.Td(h.Text(c.Source)).Class("text-success"),
+					h.Td(h.Text(c.Translation)),
+				))
+			}
+			body = v.VTable(
+				h.Thead(h.Tr(
+					h.Th(MustGetTranslation(ctx.R, "SourceChangesField")),
+					h.Th(fmt.Sprintf("%s (%s)", MustGetTranslation(ctx.R, "SourceWhenLocalized"), MustGetTranslation(ctx.R, lb.GetLocaleLabel(s.FromLocale)))),
+					h.Th(fmt.Sprintf("%s (%s)", MustGetTranslation(ctx.R, "SourceNow"), MustGetTranslation(ctx.R, lb.GetLocaleLabel(s.FromLocale)))),
+					h.Th(fmt.Sprintf("%s (%s)", MustGetTranslation(ctx.R, "SourceChangesTranslation"), MustGetTranslation(ctx.R, lb.GetLocaleLabel(s.LocaleCode)))),
+				)),
+				rows,
+			).Density(v.DensityCompact)
+		}
+
+		canMark := len(changes) > 0 && mb.Info().Verifier().Do(presets.PermUpdate).ObjectOn(obj).WithReq(ctx.R).IsAllowed() == nil
+		r.UpdatePortals = append(r.UpdatePortals, &web.PortalUpdate{
+			Name: presets.DialogPortalName,
+			Body: web.Scope(
+				vx.VXDialog(body).
+					Title(MustGetTranslation(ctx.R, "SourceChanges")).
+					Width(960).
+					CancelText(presetsMsgr.Cancel).
+					OkText(MustGetTranslation(ctx.R, "MarkAsUpToDate")).
+					HideOk(!canMark).
+					Attr("v-model", "dialogLocals.dialog").
+					Attr("@click:ok", "dialogLocals.dialog=false;"+web.Plaid().
+						EventFunc(MarkUpToDate).
+						Query(presets.ParamID, paramID).
+						URL(mb.Info().ListingHref()).
+						Go()),
+			).VSlot("{locals:dialogLocals}").Init("{dialog:true}"),
+		})
+		return
+	}
+}
+
+// markUpToDate records the latest source of the localized record, after the translation is updated with the source changes
+func markUpToDate(lb *Builder, mb *presets.ModelBuilder) web.EventFunc {
+	return func(ctx *web.EventContext) (r web.EventResponse, err error) {
+		obj, s, source, err := lb.localizedSource(ctx.R.Context(), mb, ctx.Param(presets.ParamID))
+		if err != nil {
+			return
+		}
+		if mb.Info().Verifier().Do(presets.PermUpdate).ObjectOn(obj).WithReq(ctx.R).IsAllowed() != nil {
+			presets.ShowMessage(&r, presets.MustGetMessages(ctx.R).PermissionDenied, "error")
+			return
+		}
+		if s == nil || source == nil {
+			return
+		}
+		fromVersion := source.(presets.SlugDecoder).PrimaryColumnValuesBySlug(source.(presets.SlugEncoder).PrimarySlug())["version"]
+		if err = lb.recordSource(ctx.R.Context(), mb, source, obj, s.FromLocale, fromVersion); err != nil {
+			return
+		}
+		presets.ShowMessage(&r, MustGetTranslation(ctx.R, "SuccessfullyMarkedAsUpToDate"), "")
+		web.AppendRunScripts(&r, web.Plaid().MergeQuery(true).Go())
+		return
+	}
+}
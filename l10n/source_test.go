@@ -0,0 +1,150 @@
+package l10n
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/qor5/web/v3"
+	vx "github.com/qor5/x/v3/ui/vuetifyx"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/qor5/admin/v3/presets"
+)
+
+type sourceTestPost struct {
+	ID        uint `gorm:"primarykey;autoIncrement:false"`
+	Title     string
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt
+	Locale
+}
+
+type sourceTestPage struct {
+	ID        uint   `gorm:"primarykey;autoIncrement:false"`
+	Version   string `gorm:"primarykey"`
+	Title     string
+	UpdatedAt time.Time
+	Locale
+}
+
+var (
+	localizedAt   = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sourceUpdated = localizedAt.Add(time.Hour)
+)
+
+func sourceTestBuilder(t *testing.T, models ...interface{}) *Builder {
+	t.Helper()
+	require.NoError(t, testDB.Exec("DELETE FROM qor_localization_sources").Error)
+	require.NoError(t, testDB.AutoMigrate(models...))
+	t.Cleanup(func() {
+		if err := testDB.Migrator().DropTable(models...); err != nil {
+			t.Errorf("drop tables: %v", err)
+		}
+	})
+	return New(testDB).
+		RegisterLocales("en", "en", "English", "").
+		RegisterLocales("ja", "ja", "Japanese", "").
+		RegisterLocales("zh", "zh", "Chinese", "").
+		TrackSourceVersions(true)
+}
+
+// localize creates the localized record and tracks its source as localized then
+func localize(t *testing.T, b *Builder, mb *presets.ModelBuilder, from, to interface{}) {
+	t.Helper()
+	require.NoError(t, testDB.Create(to).Error)
+	require.NoError(t, b.recordSource(context.Background(), mb, from, to, EmbedLocale(from).LocaleCode, ""))
+}
+
+func updateSource(t *testing.T, obj interface{}) {
+	t.Helper()
+	require.NoError(t, testDB.Model(obj).UpdateColumn("updated_at", sourceUpdated).Error)
+}
+
+func staleFilter(t *testing.T, b *Builder, mb *presets.ModelBuilder, locale string) (ids []uint) {
+	t.Helper()
+	evCtx := &web.EventContext{R: httptest.NewRequest(http.MethodGet, "/", http.NoBody), W: httptest.NewRecorder()}
+	item, err := b.NewStaleFilterItem(web.WrapEventContext(context.Background(), evCtx), mb, "")
+	require.NoError(t, err)
+	require.Len(t, item.Options, 3)
+	cond, args, vErr := vx.FilterData{item}.SetByQueryString(evCtx, FilterKeyStale+"="+locale)
+	require.False(t, vErr.HaveErrors())
+	// the listing shows the records of the selected locale
+	require.NoError(t, testDB.Model(mb.NewModel()).Distinct("id").Where("locale_code = ?", locale).Where(cond, args...).
+		Order("id").Pluck("id", &ids).Error)
+	return
+}
+
+func TestLocalizationStatuses(t *testing.T) {
+	b := sourceTestBuilder(t, &sourceTestPost{})
+	mb := presets.New().Model(&sourceTestPost{})
+	post := func(id uint, locale string) *sourceTestPost {
+		return &sourceTestPost{ID: id, Title: locale, UpdatedAt: localizedAt, Locale: Locale{LocaleCode: locale}}
+	}
+	for id := uint(1); id <= 5; id++ {
+		require.NoError(t, testDB.Create(post(id, "en")).Error)
+	}
+
+	// stale
+	localize(t, b, mb, post(1, "en"), post(1, "ja"))
+	updateSource(t, post(1, "en"))
+	// up to date
+	localize(t, b, mb, post(2, "en"), post(2, "ja"))
+	localize(t, b, mb, post(2, "en"), post(2, "zh"))
+	// the tracking of the source is removed
+	localize(t, b, mb, post(3, "en"), post(3, "ja"))
+	require.NoError(t, testDB.Where("object_id = ?", "3").Delete(&QorLocalizationSource{}).Error)
+	updateSource(t, post(3, "en"))
+	// the source record is deleted
+	localize(t, b, mb, post(4, "en"), post(4, "ja"))
+	updateSource(t, post(4, "en"))
+	require.NoError(t, testDB.Where("id = ? AND locale_code = ?", 4, "en").Delete(&sourceTestPost{}).Error)
+	// the stale localized record is deleted
+	localize(t, b, mb, post(5, "en"), post(5, "ja"))
+	updateSource(t, post(5, "en"))
+	require.NoError(t, testDB.Where("id = ? AND locale_code = ?", 5, "ja").Delete(&sourceTestPost{}).Error)
+
+	statuses, err := b.localizationStatuses(context.Background(), mb, []string{"en", "ja", "zh"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]*localizationStatus{
+		"en": {Missing: 1, UpToDate: 4},
+		"ja": {Missing: 1, UpToDate: 3, Stale: 1},
+		"zh": {Missing: 4, UpToDate: 1},
+	}, statuses)
+
+	require.Equal(t, []uint{1}, staleFilter(t, b, mb, "ja"))
+	require.Empty(t, staleFilter(t, b, mb, "zh"))
+}
+
+func TestLocalizationStatusesOfVersionedModels(t *testing.T) {
+	b := sourceTestBuilder(t, &sourceTestPage{})
+	mb := presets.New().Model(&sourceTestPage{})
+	page := func(id uint, version, locale string) *sourceTestPage {
+		return &sourceTestPage{ID: id, Version: version, Title: locale, UpdatedAt: localizedAt, Locale: Locale{LocaleCode: locale}}
+	}
+
+	// a new version of the source is created after localized
+	require.NoError(t, testDB.Create(page(1, "v1", "en")).Error)
+	localize(t, b, mb, page(1, "v1", "en"), page(1, "v1", "ja"))
+	require.NoError(t, testDB.Create(page(1, "v2", "ja")).Error)
+	v2 := page(1, "v2", "en")
+	require.NoError(t, testDB.Create(v2).Error)
+	updateSource(t, v2)
+	// the versions of the localized record are counted once
+	require.NoError(t, testDB.Create(page(2, "v1", "en")).Error)
+	localize(t, b, mb, page(2, "v1", "en"), page(2, "v1", "ja"))
+	require.NoError(t, testDB.Create(page(2, "v2", "ja")).Error)
+
+	statuses, err := b.localizationStatuses(context.Background(), mb, []string{"en", "ja", "zh"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]*localizationStatus{
+		"en": {UpToDate: 2},
+		"ja": {UpToDate: 1, Stale: 1},
+		"zh": {Missing: 2},
+	}, statuses)
+
+	require.Equal(t, []uint{1}, staleFilter(t, b, mb, "ja"))
+}
@@ -0,0 +1,338 @@
+package l10n
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/i18n"
+	v "github.com/qor5/x/v3/ui/vuetify"
+	"github.com/sunfmin/reflectutils"
+	h "github.com/theplant/htmlgo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/qor5/admin/v3/presets"
+)
+
+const (
+	ParamPreTranslate        = "l10n_pre_translate"
+	ParamTranslationReviewed = "l10n_translation_reviewed"
+)
+
+var ErrNoTranslationProvider = errors.New("no translation provider")
+
+// TranslationProvider translates the texts from a locale to another, the translations are in the order of the texts
+type TranslationProvider interface {
+	Translate(ctx context.Context, fromLocale, toLocale string, texts []string) ([]string, error)
+}
+
+type TranslationProviderFunc func(ctx context.Context, fromLocale, toLocale string, texts []string) ([]string, error)
+
+func (f TranslationProviderFunc) Translate(ctx context.Context, fromLocale, toLocale string, texts []string) ([]string, error) {
+	return f(ctx, fromLocale, toLocale, texts)
+}
+
+// StubTranslationProvider prefixes the texts with the target locale,
+// it is used by the tests and the demos without a translation service.
+type StubTranslationProvider struct{}
+
+func (StubTranslationProvider) Translate(_ context.Context, _, toLocale string, texts []string) ([]string, error) {
+	r := make([]string, len(texts))
+	for i, text := range texts {
+		r[i] = fmt.Sprintf("[%s] %s", toLocale, text)
+	}
+	return r, nil
+}
+
+// QorTranslationMemory is a translated segment reused by the later translations,
+// the ones translated or reviewed by the editors take precedence over the machine ones.
+type QorTranslationMemory struct {
+	gorm.Model
+	FromLocale string `gorm:"size:20;uniqueIndex:idx_qor_translation_memory_segment"`
+	ToLocale   string `gorm:"size:20;uniqueIndex:idx_qor_translation_memory_segment"`
+	SourceHash string `gorm:"size:64;uniqueIndex:idx_qor_translation_memory_segment"`
+	Source     string
+	Target     string
+	Machine    bool
+}
+
+// QorTranslationMark marks a field of a localized record translated by the machine, until it is reviewed
+type QorTranslationMark struct {
+	gorm.Model
+	ModelName  string `gorm:"index:idx_qor_translation_mark_record"`
+	ObjectID   string `gorm:"index:idx_qor_translation_mark_record"`
+	LocaleCode string `gorm:"size:20;index:idx_qor_translation_mark_record"`
+	FromLocale string `gorm:"size:20"`
+	Field      string
+	Source     string
+	Target     string
+}
+
+// TranslationProvider sets the machine translation used to pre-translate the localized records
+func (b *Builder) TranslationProvider(v TranslationProvider) (r *Builder) {
+	b.translationProvider = v
+	return b
+}
+
+// TranslatableFields sets the text fields of the model pre-translated when it is localized
+func (b *Builder) TranslatableFields(model interface{}, fields ...string) (r *Builder) {
+	if b.translatableFields == nil {
+		b.translatableFields = make(map[reflect.Type][]string)
+	}
+	t := reflect.Indirect(reflect.ValueOf(model)).Type()
+	b.translatableFields[t] = append(b.translatableFields[t], fields...)
+	return b
+}
+
+// GetTranslatableFields returns the text fields of the model set by TranslatableFields
+func (b *Builder) GetTranslatableFields(model interface{}) []string {
+	return b.translatableFields[reflect.Indirect(reflect.ValueOf(model)).Type()]
+}
+
+func (b *Builder) canPreTranslate(model interface{}) bool {
+	return b.translationProvider != nil && len(b.GetTranslatableFields(model)) > 0
+}
+
+func segmentHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// Translate translates the texts with the translation memory, the segments missed
+// are translated by the provider and remembered as machine translations.
+func (b *Builder) Translate(ctx context.Context, fromLocale, toLocale string, texts []string) (r []string, machine []bool, err error) {
+	r = make([]string, len(texts))
+	machine = make([]bool, len(texts))
+
+	var hashes []string
+	for _, text := range texts {
+		if strings.TrimSpace(text) != "" {
+			hashes = append(hashes, segmentHash(text))
+		}
+	}
+	if len(hashes) == 0 {
+		return
+	}
+	var memories []*QorTranslationMemory
+	if err = b.db.WithContext(ctx).Where("from_locale = ? AND to_locale = ? AND source_hash IN ?", fromLocale, toLocale, hashes).
+		Find(&memories).Error; err != nil {
+		return
+	}
+	remembered := make(map[string]*QorTranslationMemory, len(memories))
+	for _, m := range memories {
+		remembered[m.SourceHash] = m
+	}
+
+	var (
+		missed        []string
+		missedIndexes = map[string][]int{}
+	)
+	for i, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			r[i] = text
+			continue
+		}
+		hash := segmentHash(text)
+		if m, ok := remembered[hash]; ok {
+			r[i], machine[i] = m.Target, m.Machine
+			continue
+		}
+		if _, ok := missedIndexes[hash]; !ok {
+			missed = append(missed, text)
+		}
+		missedIndexes[hash] = append(missedIndexes[hash], i)
+	}
+	if len(missed) == 0 {
+		return
+	}
+	if b.translationProvider == nil {
+		err = ErrNoTranslationProvider
+		return
+	}
+	translated, err := b.translationProvider.Translate(ctx, fromLocale, toLocale, missed)
+	if err != nil {
+		return
+	}
+	if len(translated) != len(missed) {
+		err = fmt.Errorf("%d texts are translated into %d", len(missed), len(translated))
+		return
+	}
+	newMemories := make([]*QorTranslationMemory, 0, len(missed))
+	for i, text := range missed {
+		hash := segmentHash(text)
+		for _, j := range missedIndexes[hash] {
+			r[j], machine[j] = translated[i], true
+		}
+		newMemories = append(newMemories, &QorTranslationMemory{
+			FromLocale: fromLocale,
+			ToLocale:   toLocale,
+			SourceHash: hash,
+			Source:     text,
+			Target:     translated[i],
+			Machine:    true,
+		})
+	}
+	err = b.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&newMemories).Error
+	return
+}
+
+// RememberTranslation remembers the translation of an editor, which replaces the one in the memory
+func (b *Builder) RememberTranslation(ctx context.Context, fromLocale, toLocale, source, target string) error {
+	if strings.TrimSpace(source) == "" || strings.TrimSpace(target) == "" {
+		return nil
+	}
+	return b.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "from_locale"}, {Name: "to_locale"}, {Name: "source_hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"target", "machine", "updated_at"}),
+	}).Create(&QorTranslationMemory{
+		FromLocale: fromLocale,
+		ToLocale:   toLocale,
+		SourceHash: segmentHash(source),
+		Source:     source,
+		Target:     target,
+	}).Error
+}
+
+// preTranslate translates the translatable fields of obj copied from the source locale,
+// it returns the marks of the machine translated fields.
+func (b *Builder) preTranslate(ctx context.Context, obj interface{}, fromLocale, toLocale string) (marks []*QorTranslationMark, err error) {
+	var (
+		fields []string
+		texts  []string
+	)
+	for _, field := range b.GetTranslatableFields(obj) {
+		value, gErr := reflectutils.Get(obj, field)
+		if gErr != nil {
+			return nil, gErr
+		}
+		if text, ok := value.(string); ok {
+			fields = append(fields, field)
+			texts = append(texts, text)
+		}
+	}
+	translated, machine, err := b.Translate(ctx, fromLocale, toLocale, texts)
+	if err != nil {
+		return
+	}
+	for i, field := range fields {
+		if err = reflectutils.Set(obj, field, translated[i]); err != nil {
+			return
+		}
+		if machine[i] {
+			marks = append(marks, &QorTranslationMark{
+				LocaleCode: toLocale,
+				FromLocale: fromLocale,
+				Field:      field,
+				Source:     texts[i],
+				Target:     translated[i],
+			})
+		}
+	}
+	return
+}
+
+func objectID(obj interface{}) string {
+	id, err := reflectutils.Get(obj, "ID")
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprint(id)
+}
+
+// saveTranslationMarks replaces the marks of the localized record
+func (b *Builder) saveTranslationMarks(ctx context.Context, mb *presets.ModelBuilder, obj interface{}, marks []*QorTranslationMark) error {
+	modelName, id, locale := mb.Info().URIName(), objectID(obj), EmbedLocale(obj).LocaleCode
+	return b.contextDB(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("model_name = ? AND object_id = ? AND locale_code = ?", modelName, id, locale).
+			Delete(&QorTranslationMark{}).Error; err != nil {
+			return err
+		}
+		if len(marks) == 0 {
+			return nil
+		}
+		for _, m := range marks {
+			m.ModelName, m.ObjectID = modelName, id
+		}
+		return tx.Create(&marks).Error
+	})
+}
+
+func (b *Builder) translationMarks(ctx context.Context, modelName string, obj interface{}) (marks []*QorTranslationMark, err error) {
+	id := objectID(obj)
+	if id == "" || id == "0" {
+		return
+	}
+	err = b.db.WithContext(ctx).Where("model_name = ? AND object_id = ? AND locale_code = ?", modelName, id, EmbedLocale(obj).LocaleCode).
+		Order("id").Find(&marks).Error
+	return
+}
+
+// reviewTranslations clears the marks of the fields changed by the editor or of all fields if reviewed,
+// the translations are remembered for the later translations.
+func (b *Builder) reviewTranslations(ctx context.Context, mb *presets.ModelBuilder, obj interface{}, reviewed bool) error {
+	marks, err := b.translationMarks(ctx, mb.Info().URIName(), obj)
+	if err != nil || len(marks) == 0 {
+		return err
+	}
+	var ids []uint
+	for _, m := range marks {
+		value, gErr := reflectutils.Get(obj, m.Field)
+		if gErr != nil {
+			continue
+		}
+		target := fmt.Sprint(value)
+		if !reviewed && target == m.Target {
+			continue
+		}
+		if err = b.RememberTranslation(ctx, m.FromLocale, m.LocaleCode, m.Source, target); err != nil {
+			return err
+		}
+		ids = append(ids, m.ID)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return b.db.WithContext(ctx).Delete(&QorTranslationMark{}, ids).Error
+}
+
+// translationReviewNotice lists the machine translated fields of the record to review in the editing form
+func (b *Builder) translationReviewNotice(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) h.HTMLComponent {
+	if b.translationProvider == nil || field.ModelInfo == nil {
+		return nil
+	}
+	marks, err := b.translationMarks(ctx.R.Context(), field.ModelInfo.URIName(), obj)
+	if err != nil {
+		panic(err)
+	}
+	if len(marks) == 0 {
+		return nil
+	}
+	var labels []string
+	for _, m := range marks {
+		labels = append(labels, i18n.PT(ctx.R, presets.ModelsI18nModuleKey, field.ModelInfo.Label(), m.Field))
+	}
+	return v.VAlert(
+		h.Div(h.Text(fmt.Sprintf("%s: %s", MustGetTranslation(ctx.R, "MachineTranslated"), strings.Join(labels, ", ")))),
+		v.VCheckbox().Attr(web.VField(ParamTranslationReviewed, false)...).
+			Label(MustGetTranslation(ctx.R, "MarkTranslationsAsReviewed")).
+			Density(v.DensityCompact).HideDetails(true),
+	).Type(v.TypeWarning).Variant(v.VariantTonal).Density(v.DensityCompact).Class("mb-4")
+}
+
+func AutoMigrate(db *gorm.DB) (err error) {
+	return db.AutoMigrate(&QorTranslationMemory{}, &QorTranslationMark{}, &QorLocalizationSource{})
+}
+
+// AutoMigrate creates the tables of the translation memory, the marks and the localization sources
+func (b *Builder) AutoMigrate() (r *Builder) {
+	if err := AutoMigrate(b.db); err != nil {
+		panic(err)
+	}
+	return b
+}
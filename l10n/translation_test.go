@@ -0,0 +1,166 @@
+package l10n
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/qor5/x/v3/gormx"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/qor5/admin/v3/presets"
+)
+
+var testDB *gorm.DB
+
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+	testSuite := gormx.MustStartTestSuite(ctx)
+	testDB = testSuite.DB()
+	if err := AutoMigrate(testDB); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	if err := testSuite.Stop(context.Background()); err != nil {
+		fmt.Printf("Error during teardown: %v\n", err)
+	}
+	os.Exit(code)
+}
+
+type l10nTestPost struct {
+	ID    uint `gorm:"primarykey"`
+	Title string
+	Body  string
+	Views int
+	Locale
+}
+
+func resetTranslations(t *testing.T) {
+	t.Helper()
+	require.NoError(t, testDB.Exec("DELETE FROM qor_translation_memories").Error)
+	require.NoError(t, testDB.Exec("DELETE FROM qor_translation_marks").Error)
+}
+
+// countingProvider is the stub provider recording the texts it was asked to translate
+type countingProvider struct {
+	calls [][]string
+}
+
+func (p *countingProvider) Translate(ctx context.Context, fromLocale, toLocale string, texts []string) ([]string, error) {
+	p.calls = append(p.calls, texts)
+	return StubTranslationProvider{}.Translate(ctx, fromLocale, toLocale, texts)
+}
+
+func TestTranslate(t *testing.T) {
+	resetTranslations(t)
+	ctx := context.Background()
+	provider := &countingProvider{}
+	b := New(testDB).TranslationProvider(provider)
+	require.NoError(t, b.RememberTranslation(ctx, "en", "ja", "Hello", "こんにちは"))
+
+	texts := []string{"Hello", "World", "", "World", "  "}
+	r, machine, err := b.Translate(ctx, "en", "ja", texts)
+	require.NoError(t, err)
+	require.Equal(t, []string{"こんにちは", "[ja] World", "", "[ja] World", "  "}, r)
+	require.Equal(t, []bool{false, true, false, true, false}, machine, "the translations of the editors are not machine ones")
+	require.Equal(t, [][]string{{"World"}}, provider.calls, "a duplicated segment is translated once, the remembered ones not at all")
+
+	// the machine translations are remembered
+	r, machine, err = b.Translate(ctx, "en", "ja", []string{"World"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"[ja] World"}, r)
+	require.Equal(t, []bool{true}, machine)
+	require.Len(t, provider.calls, 1)
+
+	// the memory is kept per locales
+	r, _, err = b.Translate(ctx, "en", "zh", []string{"Hello"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"[zh] Hello"}, r)
+
+	// an editor translation replaces the machine one
+	require.NoError(t, b.RememberTranslation(ctx, "en", "ja", "World", "世界"))
+	r, machine, err = b.Translate(ctx, "en", "ja", []string{"World"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"世界"}, r)
+	require.Equal(t, []bool{false}, machine)
+
+	withoutProvider := New(testDB)
+	r, _, err = withoutProvider.Translate(ctx, "en", "ja", []string{"Hello"})
+	require.NoError(t, err, "the memory is used without a provider")
+	require.Equal(t, []string{"こんにちは"}, r)
+	_, _, err = withoutProvider.Translate(ctx, "en", "ja", []string{"Missed"})
+	require.ErrorIs(t, err, ErrNoTranslationProvider)
+
+	broken := New(testDB).TranslationProvider(TranslationProviderFunc(func(context.Context, string, string, []string) ([]string, error) {
+		return nil, nil
+	}))
+	_, _, err = broken.Translate(ctx, "en", "ja", []string{"Missed"})
+	require.EqualError(t, err, "1 texts are translated into 0")
+}
+
+func TestPreTranslate(t *testing.T) {
+	resetTranslations(t)
+	ctx := context.Background()
+	b := New(testDB).TranslationProvider(StubTranslationProvider{}).TranslatableFields(&l10nTestPost{}, "Title", "Body")
+	require.NoError(t, b.RememberTranslation(ctx, "en", "ja", "Hello", "こんにちは"))
+
+	post := &l10nTestPost{Title: "Hello", Body: "Welcome", Views: 3, Locale: Locale{LocaleCode: "ja"}}
+	marks, err := b.preTranslate(ctx, post, "en", "ja")
+	require.NoError(t, err)
+	require.Equal(t, "こんにちは", post.Title)
+	require.Equal(t, "[ja] Welcome", post.Body)
+	require.Equal(t, 3, post.Views)
+	require.Len(t, marks, 1, "only the machine translated fields are marked")
+	require.Equal(t, QorTranslationMark{LocaleCode: "ja", FromLocale: "en", Field: "Body", Source: "Welcome", Target: "[ja] Welcome"}, *marks[0])
+
+	require.False(t, New(testDB).TranslatableFields(&l10nTestPost{}, "Title").canPreTranslate(&l10nTestPost{}), "a provider is required")
+	require.False(t, New(testDB).TranslationProvider(StubTranslationProvider{}).canPreTranslate(&l10nTestPost{}), "translatable fields are required")
+}
+
+func TestReviewTranslations(t *testing.T) {
+	resetTranslations(t)
+	ctx := context.Background()
+	b := New(testDB).TranslationProvider(StubTranslationProvider{}).TranslatableFields(&l10nTestPost{}, "Title", "Body")
+	mb := presets.New().Model(&l10nTestPost{})
+
+	post := &l10nTestPost{ID: 1, Title: "Hello", Body: "Welcome", Locale: Locale{LocaleCode: "ja"}}
+	marks, err := b.preTranslate(ctx, post, "en", "ja")
+	require.NoError(t, err)
+	require.NoError(t, b.saveTranslationMarks(ctx, mb, post, marks))
+	fields := func() (r []string) {
+		t.Helper()
+		marks, err := b.translationMarks(ctx, mb.Info().URIName(), post)
+		require.NoError(t, err)
+		for _, m := range marks {
+			r = append(r, m.Field)
+		}
+		return
+	}
+	require.Equal(t, []string{"Title", "Body"}, fields())
+
+	// the fields changed by the editor are reviewed and their translations remembered
+	post.Title = "ようこそ"
+	require.NoError(t, b.reviewTranslations(ctx, mb, post, false))
+	require.Equal(t, []string{"Body"}, fields())
+	r, machine, err := b.Translate(ctx, "en", "ja", []string{"Hello"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"ようこそ"}, r)
+	require.Equal(t, []bool{false}, machine)
+
+	// the other locales keep their marks
+	other := &l10nTestPost{ID: 1, Title: "[zh] Hello", Locale: Locale{LocaleCode: "zh"}}
+	require.NoError(t, b.saveTranslationMarks(ctx, mb, other, []*QorTranslationMark{{LocaleCode: "zh", FromLocale: "en", Field: "Title", Source: "Hello", Target: "[zh] Hello"}}))
+
+	// marking the record reviewed clears all the marks
+	require.NoError(t, b.reviewTranslations(ctx, mb, post, true))
+	require.Empty(t, fields())
+	_, machine, err = b.Translate(ctx, "en", "ja", []string{"Welcome"})
+	require.NoError(t, err)
+	require.Equal(t, []bool{false}, machine, "the reviewed machine translation is kept as the editor one")
+	otherMarks, err := b.translationMarks(ctx, mb.Info().URIName(), other)
+	require.NoError(t, err)
+	require.Len(t, otherMarks, 1)
+}
@@ -272,6 +272,10 @@ func (b *Base) IsVideo() bool {
 	return IsVideoFormat(b.URL())
 }
 
+func (b *Base) IsAudio() bool {
+	return IsAudioFormat(b.URL())
+}
+
 func (b *Base) IsSVG() bool {
 	return IsSVGFormat(b.URL())
 }
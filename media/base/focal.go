@@ -0,0 +1,41 @@
+package base
+
+import (
+	"image"
+	"math"
+)
+
+// FocalPoint is the most important point of an image, relative to its width and height from 0 to 1.
+// Sizes without a crop of their own are cropped around it.
+type FocalPoint struct {
+	X float64
+	Y float64
+}
+
+// Valid reports if the point is inside the image
+func (fp FocalPoint) Valid() bool {
+	return fp.X >= 0 && fp.X <= 1 && fp.Y >= 0 && fp.Y <= 1
+}
+
+// FocalCrop returns the largest rectangle of an image of width x height that has the aspect
+// ratio of size, centered on the focal point as far as the image borders allow.
+func FocalCrop(width, height int, size *Size, fp FocalPoint) image.Rectangle {
+	if width <= 0 || height <= 0 || size.Width <= 0 || size.Height <= 0 {
+		return image.Rect(0, 0, width, height)
+	}
+	ratio := float64(size.Width) / float64(size.Height)
+	cropWidth, cropHeight := width, height
+	if float64(width)/float64(height) > ratio {
+		cropWidth = int(math.Round(float64(height) * ratio))
+	} else {
+		cropHeight = int(math.Round(float64(width) / ratio))
+	}
+	x := focalOffset(width, cropWidth, fp.X)
+	y := focalOffset(height, cropHeight, fp.Y)
+	return image.Rect(x, y, x+cropWidth, y+cropHeight)
+}
+
+func focalOffset(total, crop int, at float64) int {
+	offset := int(math.Round(at*float64(total) - float64(crop)/2))
+	return max(0, min(offset, total-crop))
+}
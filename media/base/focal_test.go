@@ -0,0 +1,29 @@
+package base
+
+import (
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFocalCrop(t *testing.T) {
+	cases := []struct {
+		name   string
+		size   *Size
+		fp     FocalPoint
+		expect image.Rectangle
+	}{
+		{name: "square of a landscape around the center", size: &Size{Width: 100, Height: 100}, fp: FocalPoint{X: 0.5, Y: 0.5}, expect: image.Rect(100, 0, 300, 200)},
+		{name: "square clamped to the left", size: &Size{Width: 50, Height: 50}, fp: FocalPoint{X: 0.1, Y: 0.5}, expect: image.Rect(0, 0, 200, 200)},
+		{name: "square clamped to the right", size: &Size{Width: 50, Height: 50}, fp: FocalPoint{X: 0.9, Y: 0.5}, expect: image.Rect(200, 0, 400, 200)},
+		{name: "wide banner around the top", size: &Size{Width: 400, Height: 50}, fp: FocalPoint{X: 0.5, Y: 0.2}, expect: image.Rect(0, 15, 400, 65)},
+		{name: "same ratio keeps the image", size: &Size{Width: 200, Height: 100}, fp: FocalPoint{X: 0, Y: 1}, expect: image.Rect(0, 0, 400, 200)},
+		{name: "size without height keeps the image", size: &Size{Width: 200}, fp: FocalPoint{X: 0.5, Y: 0.5}, expect: image.Rect(0, 0, 400, 200)},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.expect, FocalCrop(400, 200, c.size, c.fp))
+		})
+	}
+}
@@ -2,6 +2,7 @@ package base
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"image"
 	"image/color"
@@ -26,6 +27,21 @@ type MediaHandler interface {
 	Handle(media Media, file FileInterface, option *Option) error
 }
 
+// Processing status of the files a MediaProcessor handles
+const (
+	ProcessingPending    = "pending"
+	ProcessingInProgress = "processing"
+	ProcessingDone       = "done"
+	ProcessingFailed     = "failed"
+)
+
+// MediaProcessor is a MediaHandler whose work is too slow for the upload request,
+// Handle only stores the file and Process is run later, such as by a worker job.
+type MediaProcessor interface {
+	MediaHandler
+	Process(ctx context.Context, media Media, option *Option) error
+}
+
 // RegisterMediaHandler register Media library handler
 func RegisterMediaHandler(name string, handler MediaHandler) {
 	mediaHandlers[name] = handler
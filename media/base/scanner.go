@@ -0,0 +1,25 @@
+package base
+
+import (
+	"context"
+	"io"
+)
+
+// ScanResult is the verdict of an UploadScanner, Reason tells why a file is not clean, such as the virus found
+type ScanResult struct {
+	Clean  bool
+	Reason string
+}
+
+// UploadScanner scans uploads before they are committed, such as for viruses or
+// against a content policy. The uploads it does not find clean are quarantined.
+type UploadScanner interface {
+	Scan(ctx context.Context, fileName string, r io.Reader) (*ScanResult, error)
+}
+
+// ScanFunc is an UploadScanner of a function
+type ScanFunc func(ctx context.Context, fileName string, r io.Reader) (*ScanResult, error)
+
+func (f ScanFunc) Scan(ctx context.Context, fileName string, r io.Reader) (*ScanResult, error) {
+	return f(ctx, fileName, r)
+}
@@ -0,0 +1,285 @@
+package base
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/qor5/imaging"
+)
+
+const (
+	TransformFitCover   = "cover"
+	TransformFitContain = "contain"
+
+	TransformFormatJPEG = "jpeg"
+	TransformFormatPNG  = "png"
+	TransformFormatGIF  = "gif"
+	TransformFormatWebp = "webp"
+	// TransformFormatAvif is not encoded by the built-in transformers, register an ImageTransformer encoding it
+	TransformFormatAvif = "avif"
+
+	transformParamWidth     = "w"
+	transformParamHeight    = "h"
+	transformParamFit       = "fit"
+	transformParamCrop      = "crop"
+	transformParamFormat    = "fmt"
+	transformParamQuality   = "q"
+	transformParamSignature = "s"
+)
+
+var (
+	// TransformPathPrefix is the path the transform handler is mounted on
+	TransformPathPrefix = "/system/transform"
+	// TransformSecret signs transform URLs, the handler rejects every request when it is empty
+	TransformSecret []byte
+	// TransformMaxDimension limits the width and height that could be requested
+	TransformMaxDimension = 4096
+
+	ErrTransformInvalidSignature = errors.New("invalid transform signature")
+	ErrTransformUnsupported      = errors.New("unsupported transform")
+
+	imageTransformers []namedImageTransformer
+	transformPresets  = make(map[string]TransformOptions)
+)
+
+// TransformOptions describes an image variant generated on request
+type TransformOptions struct {
+	Width   int
+	Height  int
+	Fit     string
+	Crop    *CropOption
+	Format  string
+	Quality int
+}
+
+// Values encode options into URL query values
+func (o TransformOptions) Values() url.Values {
+	v := url.Values{}
+	if o.Width > 0 {
+		v.Set(transformParamWidth, strconv.Itoa(o.Width))
+	}
+	if o.Height > 0 {
+		v.Set(transformParamHeight, strconv.Itoa(o.Height))
+	}
+	if o.Fit != "" {
+		v.Set(transformParamFit, o.Fit)
+	}
+	if o.Crop != nil {
+		v.Set(transformParamCrop, fmt.Sprintf("%d,%d,%d,%d", o.Crop.X, o.Crop.Y, o.Crop.Width, o.Crop.Height))
+	}
+	if o.Format != "" {
+		v.Set(transformParamFormat, o.Format)
+	}
+	if o.Quality > 0 {
+		v.Set(transformParamQuality, strconv.Itoa(o.Quality))
+	}
+	return v
+}
+
+// ParseTransformOptions decode and validate options from URL query values
+func ParseTransformOptions(v url.Values) (o TransformOptions, err error) {
+	atoi := func(key string, max int) (int, error) {
+		s := v.Get(key)
+		if s == "" {
+			return 0, nil
+		}
+		i, err := strconv.Atoi(s)
+		if err != nil || i < 0 || i > max {
+			return 0, fmt.Errorf("invalid %s %q", key, s)
+		}
+		return i, nil
+	}
+	if o.Width, err = atoi(transformParamWidth, TransformMaxDimension); err != nil {
+		return
+	}
+	if o.Height, err = atoi(transformParamHeight, TransformMaxDimension); err != nil {
+		return
+	}
+	if o.Quality, err = atoi(transformParamQuality, 100); err != nil {
+		return
+	}
+	switch o.Fit = v.Get(transformParamFit); o.Fit {
+	case "", TransformFitCover, TransformFitContain:
+	default:
+		return o, fmt.Errorf("invalid %s %q", transformParamFit, o.Fit)
+	}
+	switch o.Format = v.Get(transformParamFormat); o.Format {
+	case "", TransformFormatJPEG, TransformFormatPNG, TransformFormatGIF, TransformFormatWebp, TransformFormatAvif:
+	default:
+		return o, fmt.Errorf("invalid %s %q", transformParamFormat, o.Format)
+	}
+	if s := v.Get(transformParamCrop); s != "" {
+		var c CropOption
+		if _, err = fmt.Sscanf(s, "%d,%d,%d,%d", &c.X, &c.Y, &c.Width, &c.Height); err != nil || c.X < 0 || c.Y < 0 || c.Width <= 0 || c.Height <= 0 {
+			return o, fmt.Errorf("invalid %s %q", transformParamCrop, s)
+		}
+		o.Crop = &c
+	}
+	return o, nil
+}
+
+// Ext returns the extension of the variant generated from the file name
+func (o TransformOptions) Ext(name string) string {
+	switch o.Format {
+	case "":
+		return strings.ToLower(path.Ext(name))
+	case TransformFormatJPEG:
+		return ".jpg"
+	default:
+		return "." + o.Format
+	}
+}
+
+// RegisterTransformPreset registers named options, MediaBox.URL falls back to them for sizes that were not generated at upload time
+func RegisterTransformPreset(name string, opts TransformOptions) {
+	transformPresets[name] = opts
+}
+
+func GetTransformPreset(name string) (opts TransformOptions, ok bool) {
+	opts, ok = transformPresets[name]
+	return
+}
+
+func signTransform(p string, v url.Values) string {
+	mac := hmac.New(sha256.New, TransformSecret)
+	mac.Write([]byte(p + "?" + v.Encode()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// TransformURL returns the signed URL of the file variant served by the transform handler
+func TransformURL(fileURL string, opts TransformOptions) string {
+	if fileURL == "" {
+		return ""
+	}
+	p := fileURL
+	if u, err := url.Parse(fileURL); err == nil {
+		p = u.Path
+	}
+	v := opts.Values()
+	v.Set(transformParamSignature, signTransform(p, opts.Values()))
+	return strings.TrimSuffix(TransformPathPrefix, "/") + "/" + strings.TrimPrefix(p, "/") + "?" + v.Encode()
+}
+
+// VerifyTransformURL checks the signature of the request and returns the file path and the options of the variant
+func VerifyTransformURL(u *url.URL) (p string, opts TransformOptions, err error) {
+	if len(TransformSecret) == 0 {
+		return "", opts, ErrTransformInvalidSignature
+	}
+	p = "/" + strings.TrimPrefix(strings.TrimPrefix(u.Path, strings.TrimSuffix(TransformPathPrefix, "/")), "/")
+	q := u.Query()
+	if opts, err = ParseTransformOptions(q); err != nil {
+		return
+	}
+	if !hmac.Equal([]byte(q.Get(transformParamSignature)), []byte(signTransform(p, opts.Values()))) {
+		return "", opts, ErrTransformInvalidSignature
+	}
+	return
+}
+
+// TransformCachePath returns the path the variant is stored in
+func TransformCachePath(p string, opts TransformOptions) string {
+	sum := sha256.Sum256([]byte(opts.Values().Encode()))
+	ext := path.Ext(p)
+	return fmt.Sprintf("%s.t_%x%s", strings.TrimSuffix(p, ext), sum[:6], opts.Ext(p))
+}
+
+// ImageTransformer generates image variants on request
+type ImageTransformer interface {
+	CouldTransform(name string, opts TransformOptions) bool
+	Transform(name string, file io.Reader, opts TransformOptions) ([]byte, error)
+}
+
+type namedImageTransformer struct {
+	name string
+	ImageTransformer
+}
+
+// RegisterImageTransformer register image transformer, the transformers are tried in the order they are registered,
+// the one registered with the name of a registered one replaces it at its place
+func RegisterImageTransformer(name string, transformer ImageTransformer) {
+	for i, t := range imageTransformers {
+		if t.name == name {
+			imageTransformers[i].ImageTransformer = transformer
+			return
+		}
+	}
+	imageTransformers = append(imageTransformers, namedImageTransformer{name: name, ImageTransformer: transformer})
+}
+
+// TransformImage transforms the file with the first registered transformer that could handle it
+func TransformImage(name string, file io.Reader, opts TransformOptions) ([]byte, error) {
+	for _, t := range imageTransformers {
+		if t.CouldTransform(name, opts) {
+			return t.Transform(name, file, opts)
+		}
+	}
+	return nil, ErrTransformUnsupported
+}
+
+// imageTransformer default image transformer, could not encode webp
+type imageTransformer struct{}
+
+func (imageTransformer) CouldTransform(name string, opts TransformOptions) bool {
+	if _, err := GetImageFormat(name); err != nil {
+		return false
+	}
+	switch opts.Format {
+	case "", TransformFormatJPEG, TransformFormatPNG, TransformFormatGIF:
+		return true
+	}
+	return false
+}
+
+func (imageTransformer) Transform(name string, file io.Reader, opts TransformOptions) ([]byte, error) {
+	format, err := imaging.FormatFromFilename("file" + opts.Ext(name))
+	if err != nil {
+		return nil, err
+	}
+	img, err := imaging.Decode(file, imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, err
+	}
+	img = applyTransform(img, opts)
+	var buffer bytes.Buffer
+	encodeOptions := []imaging.EncodeOption{}
+	if opts.Quality > 0 {
+		encodeOptions = append(encodeOptions, imaging.JPEGQuality(opts.Quality))
+	}
+	if err = imaging.Encode(&buffer, img, format, encodeOptions...); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func applyTransform(img image.Image, opts TransformOptions) image.Image {
+	if c := opts.Crop; c != nil {
+		img = imaging.Crop(img, image.Rect(c.X, c.Y, c.X+c.Width, c.Y+c.Height))
+	}
+	if opts.Width == 0 && opts.Height == 0 {
+		return img
+	}
+	if opts.Width == 0 || opts.Height == 0 {
+		return imaging.Resize(img, opts.Width, opts.Height, imaging.Lanczos)
+	}
+	if opts.Fit == TransformFitContain {
+		return imaging.Fit(img, opts.Width, opts.Height, imaging.Lanczos)
+	}
+	if opts.Fit == TransformFitCover {
+		return imaging.Fill(img, opts.Width, opts.Height, imaging.Center, imaging.Lanczos)
+	}
+	return imaging.Resize(img, opts.Width, opts.Height, imaging.Lanczos)
+}
+
+func init() {
+	RegisterImageTransformer("image_transformer", imageTransformer{})
+}
@@ -0,0 +1,129 @@
+package base
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformURL(t *testing.T) {
+	TransformSecret = []byte("secret")
+	defer func() { TransformSecret = nil }()
+
+	opts := TransformOptions{Width: 300, Height: 200, Fit: TransformFitCover, Crop: &CropOption{X: 1, Y: 2, Width: 30, Height: 40}, Format: TransformFormatWebp, Quality: 80}
+	s := TransformURL("//cdn.example.com/system/media_libraries/1/file.jpg", opts)
+	u, err := url.Parse(s)
+	require.NoError(t, err)
+	require.Equal(t, "/system/transform/system/media_libraries/1/file.jpg", u.Path)
+
+	p, got, err := VerifyTransformURL(u)
+	require.NoError(t, err)
+	require.Equal(t, "/system/media_libraries/1/file.jpg", p)
+	require.Equal(t, opts, got)
+
+	q := u.Query()
+	q.Set("w", "3000")
+	u.RawQuery = q.Encode()
+	_, _, err = VerifyTransformURL(u)
+	require.ErrorIs(t, err, ErrTransformInvalidSignature)
+
+	cachePath := TransformCachePath("/a/file.jpg", opts)
+	require.True(t, strings.HasPrefix(cachePath, "/a/file.t_"), cachePath)
+	require.True(t, strings.HasSuffix(cachePath, ".webp"), cachePath)
+}
+
+func TestParseTransformOptions(t *testing.T) {
+	for _, c := range []struct {
+		query string
+		err   bool
+	}{
+		{query: "w=100&h=100&fit=contain&fmt=png&q=90"},
+		{query: "w=-1", err: true},
+		{query: "w=100000", err: true},
+		{query: "q=101", err: true},
+		{query: "fit=stretch", err: true},
+		{query: "fmt=bmp", err: true},
+		{query: "fmt=avif"},
+		{query: "crop=1,2,3", err: true},
+		{query: "crop=0,0,0,10", err: true},
+	} {
+		v, _ := url.ParseQuery(c.query)
+		_, err := ParseTransformOptions(v)
+		require.Equal(t, c.err, err != nil, c.query)
+	}
+}
+
+func TestTransformImage(t *testing.T) {
+	var buffer bytes.Buffer
+	require.NoError(t, png.Encode(&buffer, image.NewRGBA(image.Rect(0, 0, 400, 200))))
+
+	for _, c := range []struct {
+		opts          TransformOptions
+		width, height int
+	}{
+		{opts: TransformOptions{Width: 100}, width: 100, height: 50},
+		{opts: TransformOptions{Width: 100, Height: 100, Fit: TransformFitCover}, width: 100, height: 100},
+		{opts: TransformOptions{Width: 100, Height: 100, Fit: TransformFitContain}, width: 100, height: 50},
+		{opts: TransformOptions{Crop: &CropOption{Width: 50, Height: 60}}, width: 50, height: 60},
+	} {
+		b, err := TransformImage("file.png", bytes.NewReader(buffer.Bytes()), c.opts)
+		require.NoError(t, err)
+		img, _, err := image.Decode(bytes.NewReader(b))
+		require.NoError(t, err)
+		require.Equal(t, c.width, img.Bounds().Dx())
+		require.Equal(t, c.height, img.Bounds().Dy())
+	}
+
+	_, err := TransformImage("file.png", bytes.NewReader(buffer.Bytes()), TransformOptions{Format: TransformFormatWebp})
+	require.ErrorIs(t, err, ErrTransformUnsupported)
+}
+
+// stubTransformer is the transformer of a format responding its name
+type stubTransformer struct {
+	format, name string
+}
+
+func (s stubTransformer) CouldTransform(_ string, opts TransformOptions) bool {
+	return opts.Format == s.format
+}
+
+func (s stubTransformer) Transform(string, io.Reader, TransformOptions) ([]byte, error) {
+	return []byte(s.name), nil
+}
+
+func TestRegisterImageTransformer(t *testing.T) {
+	registered := slices.Clone(imageTransformers)
+	defer func() { imageTransformers = registered }()
+	transform := func(format string) string {
+		t.Helper()
+		b, err := TransformImage("file.png", strings.NewReader(""), TransformOptions{Format: format})
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	_, err := TransformImage("file.png", strings.NewReader(""), TransformOptions{Format: TransformFormatAvif})
+	require.ErrorIs(t, err, ErrTransformUnsupported, "the built-in transformers do not encode avif")
+
+	// the first registered transformer that could transform the variant is used
+	RegisterImageTransformer("first", stubTransformer{format: TransformFormatAvif, name: "first"})
+	RegisterImageTransformer("second", stubTransformer{format: TransformFormatAvif, name: "second"})
+	require.Equal(t, "first", transform(TransformFormatAvif))
+
+	// the transformer registered with the same name replaces the registered one at its place
+	RegisterImageTransformer("second", stubTransformer{format: TransformFormatWebp, name: "second"})
+	RegisterImageTransformer("first", stubTransformer{format: TransformFormatAvif, name: "replaced"})
+	require.Equal(t, "replaced", transform(TransformFormatAvif))
+	require.Equal(t, "second", transform(TransformFormatWebp))
+	var names []string
+	for _, t := range imageTransformers {
+		names = append(names, t.name)
+	}
+	require.Equal(t, []string{"image_transformer", "first", "second"}, names)
+}
@@ -50,6 +50,21 @@ func IsVideoFormat(name string) bool {
 	return false
 }
 
+// IsAudioFormat check filename is audio or not
+func IsAudioFormat(name string) bool {
+	formats := []string{".mp3", ".m4a", ".aac", ".wav", ".flac", ".oga", ".opus"}
+
+	ext := strings.ToLower(regexp.MustCompile(`(\?.*?$)`).ReplaceAllString(filepath.Ext(name), ""))
+
+	for _, format := range formats {
+		if format == ext {
+			return true
+		}
+	}
+
+	return false
+}
+
 func IsSVGFormat(name string) bool {
 	formats := []string{".svg", ".svgz"}
 
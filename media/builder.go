@@ -3,14 +3,17 @@ package media
 import (
 	"slices"
 	"strconv"
+	"sync"
 
 	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/oss"
 	"gorm.io/gorm"
 
 	"github.com/qor5/admin/v3/activity"
 	"github.com/qor5/admin/v3/media/base"
 	"github.com/qor5/admin/v3/media/media_library"
 	"github.com/qor5/admin/v3/presets"
+	"github.com/qor5/admin/v3/worker"
 )
 
 type (
@@ -27,6 +30,22 @@ type (
 		allowTypes          []string
 		fileAccept          string
 		ab                  *activity.Builder
+
+		usageTracking            bool
+		preventDeletingUsedFiles bool
+		deduplicateUploads       bool
+		metadataFields           []*MetadataField
+		uploaderNames            UploaderNamesFunc
+		processor                base.MediaProcessor
+		processJob               *worker.JobBuilder
+		chunkedUploadPrefix      string
+		chunkSize                int64
+		chunkedUploadDir         string
+		chunkedUploadLocks       sync.Map
+		folderSubject            FolderSubjectFunc
+		folderGrantees           FolderGranteesFunc
+		uploadScanners           []base.UploadScanner
+		quarantineStorage        oss.StorageInterface
 	}
 )
 
@@ -62,9 +81,9 @@ func (b *Builder) Searcher(v SearchFunc) *Builder {
 	return b
 }
 
-// scopedDB returns a media_libraries query with the configured searcher
-// applied, so by-ID lookups and folder-tree queries see the same subset of rows
-// the searcher gives the listing. Without a searcher the query is unscoped,
+// scopedDB returns a media_libraries query with the configured searcher and
+// folder permissions applied, so by-ID lookups and folder-tree queries see the
+// same subset of rows the listing does. Without them the query is unscoped,
 // preserving the historical behavior — including for a builder that only sets
 // CurrentUserID, whose listing-only user_id filter is deliberately not extended
 // to these queries.
@@ -73,18 +92,33 @@ func (b *Builder) scopedDB(db *gorm.DB, ctx *web.EventContext) *gorm.DB {
 	if b.searcher != nil {
 		q = b.searcher(q, ctx)
 	}
-	return q
+	return b.scopeFolderACL(q, ctx)
+}
+
+// scoped reports if the rows a request sees are restricted
+func (b *Builder) scoped() bool {
+	return b.searcher != nil || b.folderSubject != nil
 }
 
 // folderIsVisible reports whether folderID may be used as an upload / move
-// target for this request: the root folder always may, and any other folder
-// only when the searcher lets the request see it. Without a searcher every id
-// is accepted, keeping the historical behavior.
+// target for this request: the folder permissions must allow uploading into
+// it, and any folder but the root must be visible to the request. Without a
+// searcher and folder permissions every id is accepted, keeping the historical
+// behavior.
 //
 // Column names are table-qualified so that a searcher adding a Joins clause
 // cannot make them ambiguous.
 func (b *Builder) folderIsVisible(ctx *web.EventContext, folderID uint) (bool, error) {
-	if b.searcher == nil || folderID == 0 {
+	if b.folderSubject != nil {
+		acl, err := b.folderACL(ctx)
+		if err != nil {
+			return false, err
+		}
+		if acl != nil && acl.folderAccess(folderID) < FolderAccessUpload {
+			return false, nil
+		}
+	}
+	if !b.scoped() || folderID == 0 {
 		return true, nil
 	}
 	var count int64
@@ -96,13 +130,14 @@ func (b *Builder) folderIsVisible(ctx *web.EventContext, folderID uint) (bool, e
 	return count > 0, nil
 }
 
-// recordIsVisible reports whether the searcher lets this request see the row
-// addressed by the primary-key slug id. It backs the presets CRUD event funcs,
-// which address rows by id through the generic DataOperator and would otherwise
-// bypass the searcher entirely. Without a searcher every id is accepted,
-// keeping the historical behavior.
+// recordIsVisible reports whether the searcher and folder permissions let this
+// request see the row addressed by the primary-key slug id. It backs the
+// presets CRUD event funcs, which address rows by id through the generic
+// DataOperator and would otherwise bypass the searcher entirely. Without a
+// searcher and folder permissions every id is accepted, keeping the historical
+// behavior.
 func (b *Builder) recordIsVisible(ctx *web.EventContext, id string) (bool, error) {
-	if b.searcher == nil || id == "" {
+	if !b.scoped() || id == "" {
 		return true, nil
 	}
 	recordID, err := strconv.ParseUint(id, 10, 64)
@@ -132,6 +167,10 @@ func (b *Builder) AutoMigrate() *Builder {
 }
 
 func (b *Builder) Install(pb *presets.Builder) error {
+	// the quarantined files must not fall back to a local directory other instances could not read
+	if len(b.uploadScanners) > 0 && b.quarantineStorage == nil {
+		return errNoQuarantineStorage
+	}
 	configure(pb, b, b.db)
 	return nil
 }
@@ -174,6 +213,9 @@ func (b *Builder) allowTypeSelectOptions(msgr *Messages) (items []selectItem) {
 				selectItem{Text: msgr.Files, Value: typeFile})
 		}
 	}
+	if b.usageTracking {
+		items = append(items, selectItem{Text: msgr.Unused, Value: typeUnused})
+	}
 	return
 }
 
@@ -0,0 +1,426 @@
+package media
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/i18n"
+	h "github.com/theplant/htmlgo"
+
+	"github.com/qor5/admin/v3/worker"
+)
+
+const (
+	CleanupChunkedUploadsJobName = "MediaLibraryCleanupChunkedUploads"
+
+	tusVersion              = "1.0.0"
+	defaultChunkSize        = 8 << 20
+	defaultChunkedUploadAge = 24 * time.Hour
+	headerUploadMessage     = "Media-Upload-Message"
+	headerMediaLibraryID    = "Media-Library-Id"
+)
+
+// ChunkedUpload is an upload in progress of the chunked upload handler,
+// the received bytes are kept in a file of the temporary directory.
+type ChunkedUpload struct {
+	ID        string `gorm:"primaryKey;size:32"`
+	FileName  string
+	Size      int64
+	Received  int64
+	ParentID  uint
+	UserID    uint
+	CreatedAt time.Time
+	UpdatedAt time.Time `gorm:"index"`
+}
+
+func (*ChunkedUpload) TableName() string {
+	return "media_library_chunked_uploads"
+}
+
+// CleanupChunkedUploadsJobArgs are the arguments of the cleanup job
+type CleanupChunkedUploadsJobArgs struct {
+	// OlderThanHours removes the uploads that have not received a chunk for the hours, it defaults to 24
+	OlderThanHours int
+}
+
+// ChunkedUploads makes the file chooser send files in chunks to the
+// handler of ChunkedUploadHandler mounted on prefix, following the core of
+// the tus protocol. Uploads are not limited by the request body size, and
+// an interrupted upload resumes from the last chunk when the file is chosen again.
+func (b *Builder) ChunkedUploads(prefix string) *Builder {
+	b.chunkedUploadPrefix = "/" + strings.Trim(prefix, "/") + "/"
+	return b
+}
+
+// ChunkSize sets the size of the chunks, it defaults to 8MB
+func (b *Builder) ChunkSize(v int64) *Builder {
+	b.chunkSize = v
+	return b
+}
+
+// ChunkedUploadDir sets the directory the chunks are written to, it defaults to a directory of os.TempDir
+func (b *Builder) ChunkedUploadDir(v string) *Builder {
+	b.chunkedUploadDir = v
+	return b
+}
+
+func (b *Builder) getChunkSize() int64 {
+	if b.chunkSize > 0 {
+		return b.chunkSize
+	}
+	return defaultChunkSize
+}
+
+func (b *Builder) getChunkedUploadDir() string {
+	if b.chunkedUploadDir != "" {
+		return b.chunkedUploadDir
+	}
+	return filepath.Join(os.TempDir(), "media-chunked-uploads")
+}
+
+func (b *Builder) chunkedUploadPath(id string) string {
+	return filepath.Join(b.getChunkedUploadDir(), id+".part")
+}
+
+func (b *Builder) lockChunkedUpload(id string) func() {
+	v, _ := b.chunkedUploadLocks.LoadOrStore(id, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// parseUploadMetadata parses the Upload-Metadata header, pairs of keys and base64 values separated by commas
+func parseUploadMetadata(header string) map[string]string {
+	md := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		kv := strings.Fields(pair)
+		if len(kv) == 0 {
+			continue
+		}
+		var value string
+		if len(kv) > 1 {
+			bs, err := base64.StdEncoding.DecodeString(kv[1])
+			if err != nil {
+				continue
+			}
+			value = string(bs)
+		}
+		md[kv[0]] = value
+	}
+	return md
+}
+
+func newChunkedUploadID() (string, error) {
+	bs := make([]byte, 16)
+	if _, err := rand.Read(bs); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bs), nil
+}
+
+// ChunkedUploadHandler handles the chunked uploads, mount it on the prefix of ChunkedUploads
+// behind the same authentication as the admin. Uploads are held by the process that received
+// them, run a single instance or make the instances share the directory of ChunkedUploadDir.
+func (b *Builder) ChunkedUploadHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Resumable", tusVersion)
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Tus-Version", tusVersion)
+			w.Header().Set("Tus-Extension", "creation,termination")
+			w.Header().Set("Tus-Max-Size", "0")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		ctx := &web.EventContext{R: r, W: w}
+		if err := b.uploadIsAllowed(r); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, b.chunkedUploadPrefix), "/")
+		if id == "" {
+			if r.Method != http.MethodPost {
+				http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+				return
+			}
+			b.createChunkedUpload(ctx)
+			return
+		}
+
+		defer b.lockChunkedUpload(id)()
+		var upload ChunkedUpload
+		if err := b.db.Where("id = ?", id).Limit(1).Find(&upload).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if upload.ID == "" || (b.currentUserID != nil && upload.UserID != b.currentUserID(ctx)) {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodHead:
+			w.Header().Set("Cache-Control", "no-store")
+			w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Received, 10))
+			w.Header().Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+			w.WriteHeader(http.StatusOK)
+		case http.MethodPatch:
+			b.receiveChunk(ctx, &upload)
+		case http.MethodDelete:
+			if err := b.removeChunkedUpload(&upload); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func (b *Builder) createChunkedUpload(ctx *web.EventContext) {
+	w, r := ctx.W, ctx.R
+	size, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || size <= 0 {
+		http.Error(w, "invalid Upload-Length", http.StatusBadRequest)
+		return
+	}
+	md := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	fileName := filepath.Base(md["filename"])
+	if fileName == "" || fileName == "." {
+		http.Error(w, "missing filename metadata", http.StatusBadRequest)
+		return
+	}
+	parentID, _ := strconv.ParseUint(md["parent_id"], 10, 64)
+	visible, err := b.folderIsVisible(ctx, uint(parentID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !visible {
+		http.NotFound(w, r)
+		return
+	}
+
+	upload := ChunkedUpload{FileName: fileName, Size: size, ParentID: uint(parentID)}
+	if upload.ID, err = newChunkedUploadID(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if b.currentUserID != nil {
+		upload.UserID = b.currentUserID(ctx)
+	}
+	if err = os.MkdirAll(b.getChunkedUploadDir(), 0o700); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	f, err := os.OpenFile(b.chunkedUploadPath(upload.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	f.Close()
+	if err = b.db.Create(&upload).Error; err != nil {
+		os.Remove(b.chunkedUploadPath(upload.ID))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Location", b.chunkedUploadPrefix+upload.ID)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (b *Builder) receiveChunk(ctx *web.EventContext, upload *ChunkedUpload) {
+	w, r := ctx.W, ctx.R
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		http.Error(w, "invalid Content-Type", http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset != upload.Received {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Received, 10))
+		http.Error(w, "Upload-Offset does not match", http.StatusConflict)
+		return
+	}
+
+	f, err := os.OpenFile(b.chunkedUploadPath(upload.ID), os.O_WRONLY, 0o600)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	}
+	limit := min(b.getChunkSize(), upload.Size-upload.Received)
+	written, err := io.Copy(io.NewOffsetWriter(f, offset), io.LimitReader(r.Body, limit))
+	f.Close()
+	// keep what was written before a broken connection, the client resumes from it
+	upload.Received += written
+	if dbErr := b.db.Model(upload).Update("received", upload.Received).Error; dbErr != nil {
+		http.Error(w, dbErr.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Received, 10))
+	if upload.Received < upload.Size {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	message, err := b.completeChunkedUpload(ctx, upload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if message != "" {
+		w.Header().Set(headerUploadMessage, url.PathEscape(message))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// completeChunkedUpload creates the media library file of the received bytes, message is a warning for the editor
+func (b *Builder) completeChunkedUpload(ctx *web.EventContext, upload *ChunkedUpload) (message string, err error) {
+	msgr := i18n.MustGetModuleMessages(ctx.R, I18nMediaLibraryKey, Messages_en_US).(*Messages)
+	defer func() {
+		err = errors.Join(err, b.removeChunkedUpload(upload))
+	}()
+	f, err := os.Open(b.chunkedUploadPath(upload.ID))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fileHash, err := hashReader(f)
+	if err != nil {
+		return
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	m, existing, err := b.createUploadedFile(ctx, upload.ParentID, upload.FileName, f, fileHash)
+	if errors.Is(err, errUnsupportedFileType) {
+		return "", errors.New(msgr.UnSupportFileType)
+	}
+	if err != nil {
+		return
+	}
+	ctx.W.Header().Set(headerMediaLibraryID, fmt.Sprint(m.ID))
+	if existing != nil {
+		message = msgr.DuplicateFileUploaded(upload.FileName, existing.File.FileName)
+	}
+	if m.Quarantined() {
+		message = msgr.FileQuarantined(upload.FileName)
+	}
+	return
+}
+
+func (b *Builder) removeChunkedUpload(upload *ChunkedUpload) error {
+	if err := os.Remove(b.chunkedUploadPath(upload.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	b.chunkedUploadLocks.Delete(upload.ID)
+	return b.db.Delete(&ChunkedUpload{}, "id = ?", upload.ID).Error
+}
+
+// CleanupChunkedUploads removes the uploads that have not received a chunk since olderThan
+func (b *Builder) CleanupChunkedUploads(olderThan time.Duration) (count int, err error) {
+	var uploads []*ChunkedUpload
+	if err = b.db.Where("updated_at < ?", time.Now().Add(-olderThan)).Find(&uploads).Error; err != nil {
+		return
+	}
+	for _, upload := range uploads {
+		if err = b.removeChunkedUpload(upload); err != nil {
+			return
+		}
+		count++
+	}
+	return
+}
+
+// CleanupChunkedUploadsJob registers the job removing the abandoned chunked uploads
+func (b *Builder) CleanupChunkedUploadsJob(w *worker.Builder) *worker.JobBuilder {
+	return w.NewJob(CleanupChunkedUploadsJobName).
+		Resource(&CleanupChunkedUploadsJobArgs{}).
+		Handler(func(_ context.Context, job worker.QorJobInterface) error {
+			jobInfo, err := job.GetJobInfo()
+			if err != nil {
+				return err
+			}
+			olderThan := defaultChunkedUploadAge
+			if args, _ := jobInfo.Argument.(*CleanupChunkedUploadsJobArgs); args != nil && args.OlderThanHours > 0 {
+				olderThan = time.Duration(args.OlderThanHours) * time.Hour
+			}
+			count, err := b.CleanupChunkedUploads(olderThan)
+			return errors.Join(err, job.AddLogf("removed %d abandoned uploads", count))
+		})
+}
+
+// chunkedUploadScript uploads the chosen files in chunks, resuming the uploads
+// started before, and runs done once all of them are uploaded.
+func (b *Builder) chunkedUploadScript(parentID int, done string) string {
+	return fmt.Sprintf(`(async (w, files) => {
+	const endpoint = %s, chunkSize = %d, parentID = %d, headers = {"Tus-Resumable": %q};
+	const encode = (s) => w.btoa(String.fromCharCode(...new w.TextEncoder().encode(String(s))));
+	const showMessage = (message, color) => { vars.presetsMessage = {show: true, message, color} };
+	locals.fileChooserUploadingFiles = files;
+	locals.fileChooserUploadProgress = {};
+	for (const f of files) {
+		const key = "media-chunked-upload:" + [f.name, f.size, f.lastModified, parentID].join(":");
+		try {
+			let location = w.localStorage.getItem(key), offset = 0;
+			if (location) {
+				const res = await w.fetch(location, {method: "HEAD", headers});
+				if (res.ok) {
+					offset = parseInt(res.headers.get("Upload-Offset"), 10);
+				} else {
+					location = null;
+				}
+			}
+			if (!location) {
+				const res = await w.fetch(endpoint, {method: "POST", headers: {...headers,
+					"Upload-Length": String(f.size),
+					"Upload-Metadata": "filename " + encode(f.name) + ",parent_id " + encode(parentID)}});
+				if (!res.ok) throw new Error(await res.text());
+				location = res.headers.get("Location");
+				w.localStorage.setItem(key, location);
+			}
+			let retries = 0;
+			while (offset < f.size) {
+				locals.fileChooserUploadProgress[f.name] = Math.floor(offset * 100 / f.size);
+				const res = await w.fetch(location, {method: "PATCH", body: f.slice(offset, offset + chunkSize),
+					headers: {...headers, "Content-Type": "application/offset+octet-stream", "Upload-Offset": String(offset)}}).catch(() => null);
+				if (res && res.headers.get("Upload-Offset") !== null && (res.ok || res.status === 409)) {
+					offset = parseInt(res.headers.get("Upload-Offset"), 10);
+				}
+				if (res && res.ok) {
+					retries = 0;
+					const message = res.headers.get(%q);
+					if (message) showMessage(decodeURIComponent(message), "warning");
+					continue;
+				}
+				if (res && res.status !== 409 && res.status < 500) {
+					w.localStorage.removeItem(key);
+					throw new Error(await res.text());
+				}
+				if (++retries > 5) throw new Error(f.name);
+				await new w.Promise((resolve) => w.setTimeout(resolve, 1000 * retries));
+			}
+			w.localStorage.removeItem(key);
+			locals.fileChooserUploadProgress[f.name] = 100;
+		} catch (e) {
+			showMessage(e.message, "error");
+		}
+	}
+	%s
+})($event.view.window, [...$event.target.files])`, h.JSONString(b.chunkedUploadPrefix), b.getChunkSize(), parentID, tusVersion, headerUploadMessage, done)
+}
@@ -0,0 +1,146 @@
+package media
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/qor5/admin/v3/media/media_library"
+	"github.com/qor5/admin/v3/presets"
+	"github.com/qor5/admin/v3/presets/gorm2op"
+)
+
+func TestChunkedUploadHandler(t *testing.T) {
+	require.NoError(t, testDB.Exec("DELETE FROM media_libraries").Error)
+	require.NoError(t, testDB.Exec("DELETE FROM media_library_chunked_uploads").Error)
+	storage := useTestStorage(t)
+	chunks := t.TempDir()
+	pb := presets.New().DataOperator(gorm2op.DataOperator(testDB))
+	b := New(testDB).ChunkedUploads("media-uploads").ChunkSize(4).ChunkedUploadDir(chunks)
+	require.NoError(t, b.Install(pb))
+	handler := pb.GetI18n().EnsureLanguage(b.ChunkedUploadHandler())
+
+	do := func(method, path string, headers map[string]string, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Tus-Resumable", tusVersion)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	create := func(name string, size int) string {
+		t.Helper()
+		w := do(http.MethodPost, "/media-uploads/", map[string]string{
+			"Upload-Length":   strconv.Itoa(size),
+			"Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte(name)) + ",parent_id MA==",
+		}, "")
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		location := w.Header().Get("Location")
+		require.True(t, strings.HasPrefix(location, "/media-uploads/"), location)
+		return location
+	}
+	patch := func(location string, offset int, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		return do(http.MethodPatch, location, map[string]string{
+			"Content-Type":  "application/offset+octet-stream",
+			"Upload-Offset": strconv.Itoa(offset),
+		}, body)
+	}
+
+	w := do(http.MethodPost, "/media-uploads/", map[string]string{"Upload-Metadata": "filename bm90ZXMudHh0"}, "")
+	require.Equal(t, http.StatusBadRequest, w.Code, "the length is required")
+	w = do(http.MethodPost, "/media-uploads/", map[string]string{"Upload-Length": "10"}, "")
+	require.Equal(t, http.StatusBadRequest, w.Code, "the file name is required")
+
+	location := create("notes.txt", 10)
+	w = do(http.MethodHead, location, nil, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "0", w.Header().Get("Upload-Offset"))
+	require.Equal(t, "10", w.Header().Get("Upload-Length"))
+
+	// a chunk is limited to the chunk size
+	w = patch(location, 0, "0123456789")
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	require.Equal(t, "4", w.Header().Get("Upload-Offset"))
+
+	w = patch(location, 0, "0123")
+	require.Equal(t, http.StatusConflict, w.Code, "a chunk at another offset is refused")
+	require.Equal(t, "4", w.Header().Get("Upload-Offset"))
+
+	// an interrupted upload resumes from the received offset
+	w = do(http.MethodHead, location, nil, "")
+	require.Equal(t, "4", w.Header().Get("Upload-Offset"))
+	w = patch(location, 4, "456789")
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	require.Equal(t, "8", w.Header().Get("Upload-Offset"))
+	require.Empty(t, w.Header().Get(headerMediaLibraryID))
+
+	w = patch(location, 8, "89")
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	require.Equal(t, "10", w.Header().Get("Upload-Offset"))
+	id, err := strconv.ParseUint(w.Header().Get(headerMediaLibraryID), 10, 64)
+	require.NoError(t, err, "the completed upload creates a media library file")
+	m := reload(t, uint(id))
+	require.Equal(t, "notes.txt", m.File.FileName)
+	require.Equal(t, media_library.ALLOW_TYPE_FILE, m.SelectedType)
+	objects := storedObjects(t, storage)
+	require.Len(t, objects, 1)
+	content, err := os.ReadFile(filepath.Join(storage, objects[0]))
+	require.NoError(t, err)
+	require.Equal(t, "0123456789", string(content))
+
+	// the completed upload is removed
+	entries, err := os.ReadDir(chunks)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+	w = do(http.MethodHead, location, nil, "")
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	// a terminated upload is removed
+	location = create("cancelled.txt", 10)
+	w = do(http.MethodDelete, location, nil, "")
+	require.Equal(t, http.StatusNoContent, w.Code)
+	w = do(http.MethodHead, location, nil, "")
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCleanupChunkedUploads(t *testing.T) {
+	require.NoError(t, testDB.Exec("DELETE FROM media_library_chunked_uploads").Error)
+	chunks := t.TempDir()
+	b := New(testDB).ChunkedUploadDir(chunks)
+
+	mkUpload := func(id string, updatedAt time.Time) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(b.chunkedUploadPath(id), []byte("part"), 0o600))
+		require.NoError(t, testDB.Create(&ChunkedUpload{ID: id, FileName: id + ".txt", Size: 10, Received: 4}).Error)
+		require.NoError(t, testDB.Model(&ChunkedUpload{}).Where("id = ?", id).UpdateColumn("updated_at", updatedAt).Error)
+	}
+	mkUpload("abandoned", time.Now().Add(-48*time.Hour))
+	mkUpload("active", time.Now().Add(-time.Hour))
+	// the row of an upload whose file is already gone is removed too
+	require.NoError(t, testDB.Create(&ChunkedUpload{ID: "lost", FileName: "lost.txt", Size: 10}).Error)
+	require.NoError(t, testDB.Model(&ChunkedUpload{}).Where("id = ?", "lost").UpdateColumn("updated_at", time.Now().Add(-48*time.Hour)).Error)
+
+	count, err := b.CleanupChunkedUploads(defaultChunkedUploadAge)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	var ids []string
+	require.NoError(t, testDB.Model(&ChunkedUpload{}).Pluck("id", &ids).Error)
+	require.Equal(t, []string{"active"}, ids)
+	_, err = os.Stat(b.chunkedUploadPath("abandoned"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(b.chunkedUploadPath("active"))
+	require.NoError(t, err)
+}
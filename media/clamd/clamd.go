@@ -0,0 +1,137 @@
+package clamd
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/qor5/admin/v3/media/base"
+)
+
+const (
+	defaultTimeout   = time.Minute
+	defaultChunkSize = 64 << 10
+)
+
+// Scanner is a base.UploadScanner that streams the uploads to clamd with the INSTREAM command
+type Scanner struct {
+	network   string
+	address   string
+	timeout   time.Duration
+	chunkSize int
+}
+
+var _ base.UploadScanner = (*Scanner)(nil)
+
+// New returns the scanner of the clamd listening on address, such as
+// New("unix", "/var/run/clamav/clamd.ctl") or New("tcp", "127.0.0.1:3310")
+func New(network, address string) *Scanner {
+	return &Scanner{
+		network:   network,
+		address:   address,
+		timeout:   defaultTimeout,
+		chunkSize: defaultChunkSize,
+	}
+}
+
+// Timeout limits a scan including the connection, it defaults to a minute
+func (s *Scanner) Timeout(v time.Duration) *Scanner {
+	s.timeout = v
+	return s
+}
+
+// Ping checks that clamd answers
+func (s *Scanner) Ping(ctx context.Context) error {
+	reply, err := s.command(ctx, func(w io.Writer) error {
+		_, err := io.WriteString(w, "zPING\x00")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("clamd: unexpected reply %q", reply)
+	}
+	return nil
+}
+
+func (s *Scanner) Scan(ctx context.Context, _ string, r io.Reader) (*base.ScanResult, error) {
+	reply, err := s.command(ctx, func(w io.Writer) error {
+		return s.writeStream(w, r)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseReply(reply)
+}
+
+func (s *Scanner) command(ctx context.Context, write func(w io.Writer) error) (reply string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return "", fmt.Errorf("clamd: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = conn.SetDeadline(deadline); err != nil {
+			return
+		}
+	}
+	if err = write(conn); err != nil {
+		return "", fmt.Errorf("clamd: %w", err)
+	}
+	reply, err = bufio.NewReader(conn).ReadString(0)
+	if err != nil && !(errors.Is(err, io.EOF) && reply != "") {
+		return "", fmt.Errorf("clamd: %w", err)
+	}
+	return strings.TrimRight(reply, "\x00\n"), nil
+}
+
+// writeStream sends r as chunks prefixed by their length, a chunk of zero length ends the stream
+func (s *Scanner) writeStream(w io.Writer, r io.Reader) (err error) {
+	if _, err = io.WriteString(w, "zINSTREAM\x00"); err != nil {
+		return
+	}
+	buf := make([]byte, s.chunkSize)
+	size := make([]byte, 4)
+	for {
+		n, rErr := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err = w.Write(size); err != nil {
+				return
+			}
+			if _, err = w.Write(buf[:n]); err != nil {
+				return
+			}
+		}
+		if errors.Is(rErr, io.EOF) {
+			break
+		}
+		if rErr != nil {
+			return rErr
+		}
+	}
+	_, err = w.Write([]byte{0, 0, 0, 0})
+	return
+}
+
+// parseReply parses the replies such as "stream: OK" and "stream: Eicar-Signature FOUND"
+func parseReply(reply string) (*base.ScanResult, error) {
+	_, verdict, _ := strings.Cut(reply, ": ")
+	switch {
+	case verdict == "OK":
+		return &base.ScanResult{Clean: true}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return &base.ScanResult{Reason: strings.TrimSuffix(verdict, " FOUND")}, nil
+	}
+	return nil, fmt.Errorf("clamd: %s", strings.TrimSpace(reply))
+}
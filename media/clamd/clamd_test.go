@@ -0,0 +1,85 @@
+package clamd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/qor5/admin/v3/media/base"
+)
+
+// fakeClamd answers INSTREAM with FOUND if the stream contains the signature
+func fakeClamd(t *testing.T, signature string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				cmd, err := r.ReadString(0)
+				if err != nil {
+					return
+				}
+				if cmd == "zPING\x00" {
+					io.WriteString(conn, "PONG\x00")
+					return
+				}
+				var data bytes.Buffer
+				size := make([]byte, 4)
+				for {
+					if _, err = io.ReadFull(r, size); err != nil {
+						return
+					}
+					n := binary.BigEndian.Uint32(size)
+					if n == 0 {
+						break
+					}
+					if _, err = io.CopyN(&data, r, int64(n)); err != nil {
+						return
+					}
+				}
+				if strings.Contains(data.String(), signature) {
+					io.WriteString(conn, "stream: Test-Signature FOUND\x00")
+					return
+				}
+				io.WriteString(conn, "stream: OK\x00")
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestScanner(t *testing.T) {
+	addr := fakeClamd(t, "EVIL")
+	s := New("tcp", addr)
+	s.chunkSize = 4
+	ctx := context.Background()
+
+	require.NoError(t, s.Ping(ctx))
+
+	r, err := s.Scan(ctx, "clean.txt", strings.NewReader("a harmless file"))
+	require.NoError(t, err)
+	require.Equal(t, &base.ScanResult{Clean: true}, r)
+
+	r, err = s.Scan(ctx, "infected.txt", strings.NewReader("an EVIL file split across chunks"))
+	require.NoError(t, err)
+	require.Equal(t, &base.ScanResult{Reason: "Test-Signature"}, r)
+}
+
+func TestParseReply(t *testing.T) {
+	_, err := parseReply("INSTREAM size limit exceeded. ERROR")
+	require.EqualError(t, err, "clamd: INSTREAM size limit exceeded. ERROR")
+}
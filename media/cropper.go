@@ -76,6 +76,11 @@ func loadImageCropper(mb *Builder) web.EventFunc {
 		// Attr("style", "max-width: 800px; max-height: 600px;")
 
 		cropOption := mediaBox.CropOptions[thumb]
+		if cropOption == nil && size != nil && m.File.FocalPoint != nil {
+			// start from the crop around the focal point
+			rect := base.FocalCrop(m.File.Width, m.File.Height, size, *m.File.FocalPoint)
+			cropOption = &base.CropOption{X: rect.Min.X, Y: rect.Min.Y, Width: rect.Dx(), Height: rect.Dy()}
+		}
 		if cropOption != nil {
 			c.ModelValue(cropper.Value{
 				X:      float64(cropOption.X),
@@ -195,7 +200,7 @@ func cropImage(b *Builder) web.EventFunc {
 
 		r.UpdatePortals = append(r.UpdatePortals, &web.PortalUpdate{
 			Name: mediaBoxThumbnailsPortalName(field),
-			Body: mediaBoxThumbnails(ctx, mb, field, cfg, false, false),
+			Body: mediaBoxThumbnails(ctx, db, mb, field, cfg, false, false),
 		})
 		return
 	}
@@ -0,0 +1,320 @@
+package media
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"reflect"
+	"slices"
+	"strconv"
+
+	"gorm.io/gorm"
+
+	"github.com/qor5/admin/v3/media/base"
+	"github.com/qor5/admin/v3/media/media_library"
+	"github.com/qor5/admin/v3/worker"
+)
+
+const (
+	DeduplicateJobName = "MediaLibraryDeduplicate"
+
+	deduplicateBatchSize = 100
+)
+
+// DeduplicateJobArgs are the arguments of the deduplicate job
+type DeduplicateJobArgs struct {
+	// DryRun only reports the duplicates, nothing is changed
+	DryRun bool
+}
+
+// DeduplicateUploads makes uploads whose content already exists in the media
+// library point at the storage object of the existing file instead of storing it again.
+func (b *Builder) DeduplicateUploads(v bool) *Builder {
+	b.deduplicateUploads = v
+	return b
+}
+
+func hashReader(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFileHeader(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return hashReader(f)
+}
+
+// storedFilePath returns the path of the file as it was uploaded, image handlers keep it as the original size
+func storedFilePath(m *media_library.MediaLibrary) string {
+	if m.File.FileSizes[base.OriginalSizeKey] > 0 {
+		return m.File.URL(base.OriginalSizeKey)
+	}
+	return m.File.URL()
+}
+
+// shareStorage makes m point at the storage object of the existing file
+func shareStorage(m, existing *media_library.MediaLibrary) {
+	fileName, description := m.File.FileName, m.File.Description
+	m.File = existing.File
+	m.File.FileHeader = nil
+	m.File.FileName = fileName
+	m.File.Description = description
+	m.SelectedType = existing.SelectedType
+	m.FileHash = existing.FileHash
+}
+
+// DeduplicateJob registers the job that hashes the files uploaded before
+// hashing existed and merges the files with the same content into the oldest one.
+// MediaBox values of the models that point to a merged file are rewritten to the
+// file that is kept, storage objects are left in place so their URLs keep working.
+func (b *Builder) DeduplicateJob(w *worker.Builder, models ...interface{}) *worker.JobBuilder {
+	return w.NewJob(DeduplicateJobName).
+		Resource(&DeduplicateJobArgs{}).
+		Handler(func(ctx context.Context, job worker.QorJobInterface) error {
+			return b.deduplicate(ctx, job, models)
+		})
+}
+
+func (b *Builder) backfillFileHashes(ctx context.Context, job worker.QorJobInterface) (err error) {
+	var lastID uint
+	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		var records []*media_library.MediaLibrary
+		if err = b.db.Where("folder = false AND (file_hash IS NULL OR file_hash = '') AND id > ?", lastID).
+			Order("id").Limit(deduplicateBatchSize).Find(&records).Error; err != nil {
+			return
+		}
+		if len(records) == 0 {
+			return
+		}
+		for _, m := range records {
+			lastID = m.ID
+			f, rErr := m.File.Retrieve(storedFilePath(m))
+			if rErr != nil {
+				if err = job.AddLogf("media library %d %s: %v", m.ID, m.File.FileName, rErr); err != nil {
+					return
+				}
+				continue
+			}
+			hash, hErr := hashReader(f)
+			f.Close()
+			if hErr != nil {
+				if err = job.AddLogf("media library %d %s: %v", m.ID, m.File.FileName, hErr); err != nil {
+					return
+				}
+				continue
+			}
+			if err = b.db.Model(&media_library.MediaLibrary{}).Where("id = ?", m.ID).UpdateColumn("file_hash", hash).Error; err != nil {
+				return
+			}
+		}
+	}
+}
+
+type duplicateGroup struct {
+	FileHash string
+	Count    int
+}
+
+func (b *Builder) deduplicate(ctx context.Context, job worker.QorJobInterface, models []interface{}) (err error) {
+	jobInfo, err := job.GetJobInfo()
+	if err != nil {
+		return
+	}
+	args, _ := jobInfo.Argument.(*DeduplicateJobArgs)
+	if args == nil {
+		args = &DeduplicateJobArgs{}
+	}
+	if err = b.backfillFileHashes(ctx, job); err != nil {
+		return
+	}
+	if err = job.SetProgress(20); err != nil {
+		return
+	}
+
+	var groups []*duplicateGroup
+	if err = b.db.Model(&media_library.MediaLibrary{}).
+		Select("file_hash, count(*) AS count").
+		Where("folder = false AND quarantine_status = '' AND file_hash <> ''").
+		Group("file_hash").
+		Having("count(*) > 1").
+		Scan(&groups).Error; err != nil {
+		return
+	}
+
+	merged := map[uint]uint{}
+	for _, g := range groups {
+		var files []*media_library.MediaLibrary
+		if err = b.db.Where("folder = false AND quarantine_status = '' AND file_hash = ?", g.FileHash).Order("id").Find(&files).Error; err != nil {
+			return
+		}
+		for _, f := range files[1:] {
+			merged[f.ID] = files[0].ID
+			if err = job.AddLogf("%s (%d) is a duplicate of %s (%d)", f.File.FileName, f.ID, files[0].File.FileName, files[0].ID); err != nil {
+				return
+			}
+		}
+	}
+	if args.DryRun || len(merged) == 0 {
+		return errors.Join(
+			job.AddLogf("found %d duplicated files in %d groups", len(merged), len(groups)),
+			job.SetProgress(100),
+		)
+	}
+
+	var rewritten int
+	for i, model := range models {
+		var n int
+		if n, err = b.rewriteMediaBoxes(ctx, model, merged); err != nil {
+			return
+		}
+		rewritten += n
+		if err = job.SetProgress(uint(20 + (i+1)*70/len(models))); err != nil {
+			return
+		}
+	}
+
+	ids := make([]uint, 0, len(merged))
+	for id := range merged {
+		ids = append(ids, id)
+	}
+	var kept []uint
+	if err = b.db.Transaction(func(tx *gorm.DB) error {
+		if b.usageTracking {
+			// the files still referenced by the models not rewritten are kept
+			var tables []string
+			for _, model := range models {
+				stmt := &gorm.Statement{DB: tx}
+				if err := stmt.Parse(model); err != nil {
+					return err
+				}
+				tables = append(tables, stmt.Schema.Table)
+			}
+			q := tx.Model(&MediaUsage{}).Where("media_library_id IN ?", ids)
+			if len(tables) > 0 {
+				q = q.Where("source_table NOT IN ?", tables)
+			}
+			if err := q.Distinct().Pluck("media_library_id", &kept).Error; err != nil {
+				return err
+			}
+			ids = slices.DeleteFunc(ids, func(id uint) bool { return slices.Contains(kept, id) })
+			if len(ids) == 0 {
+				return nil
+			}
+			if err := tx.Where("media_library_id IN ?", ids).Delete(&MediaUsage{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(&media_library.MediaLibrary{}, "id IN ?", ids).Error; err != nil {
+			return err
+		}
+		return tx.Where("media_library_id IN ?", ids).Delete(&MediaTag{}).Error
+	}); err != nil {
+		return
+	}
+	for _, id := range kept {
+		if err = job.AddLogf("media library %d is kept, it is still used by the models not deduplicated", id); err != nil {
+			return
+		}
+	}
+	return errors.Join(
+		job.AddLogf("merged %d duplicated files in %d groups, rewrote %d records", len(merged)-len(kept), len(groups), rewritten),
+		job.SetProgress(100),
+	)
+}
+
+// rewriteMediaBoxes points the MediaBoxes of the model records from merged files to the files that are kept
+func (b *Builder) rewriteMediaBoxes(ctx context.Context, model interface{}, merged map[uint]uint) (count int, err error) {
+	stmt := &gorm.Statement{DB: b.db}
+	if err = stmt.Parse(model); err != nil {
+		return
+	}
+	s := stmt.Schema
+	if !hasMediaBox(s.ModelType) {
+		return
+	}
+	records := reflect.New(reflect.SliceOf(reflect.PointerTo(s.ModelType))).Interface()
+	err = b.db.Model(model).FindInBatches(records, deduplicateBatchSize, func(tx *gorm.DB, _ int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for _, rv := range statementRecords(tx) {
+			var fields []string
+			for i := 0; i < rv.NumField(); i++ {
+				f := rv.Type().Field(i)
+				if !f.IsExported() || !replaceMediaBoxIDs(rv.Field(i), merged) {
+					continue
+				}
+				if sf := s.LookUpField(f.Name); sf != nil && sf.DBName != "" {
+					fields = append(fields, sf.DBName)
+					continue
+				}
+				// embedded structs are flattened into the columns of the record
+				for _, sf := range s.Fields {
+					if len(sf.BindNames) > 1 && sf.BindNames[0] == f.Name && sf.DBName != "" {
+						fields = append(fields, sf.DBName)
+					}
+				}
+			}
+			if len(fields) == 0 {
+				continue
+			}
+			if err := b.db.Model(rv.Addr().Interface()).Select(fields).Updates(rv.Addr().Interface()).Error; err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	}).Error
+	return
+}
+
+// replaceMediaBoxIDs rewrites the ids of the MediaBoxes inside v, it reports if any was changed
+func replaceMediaBoxIDs(v reflect.Value, merged map[uint]uint) (changed bool) {
+	if !v.IsValid() || !hasMediaBox(v.Type()) {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			changed = replaceMediaBoxIDs(v.Elem(), merged)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			changed = replaceMediaBoxIDs(v.Index(i), merged) || changed
+		}
+	case reflect.Struct:
+		if v.Type() == mediaBoxType {
+			mb := v.Addr().Interface().(*media_library.MediaBox)
+			id, err := strconv.ParseUint(mb.ID.String(), 10, 64)
+			if err != nil {
+				return
+			}
+			if to, ok := merged[uint(id)]; ok {
+				mb.ID = json.Number(fmt.Sprint(to))
+				changed = true
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				changed = replaceMediaBoxIDs(v.Field(i), merged) || changed
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,202 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/qor5/x/v3/oss/filesystem"
+	"github.com/stretchr/testify/require"
+
+	"github.com/qor5/admin/v3/media/media_library"
+	"github.com/qor5/admin/v3/media/oss"
+	"github.com/qor5/admin/v3/presets"
+	"github.com/qor5/admin/v3/presets/gorm2op"
+)
+
+// useTestStorage stores the uploads of the test in a temporary directory
+func useTestStorage(t *testing.T) (dir string) {
+	t.Helper()
+	dir = t.TempDir()
+	old := oss.Storage
+	oss.Storage = filesystem.New(dir)
+	t.Cleanup(func() { oss.Storage = old })
+	return
+}
+
+func storedObjects(t *testing.T, dir string) (paths []string) {
+	t.Helper()
+	require.NoError(t, filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			paths = append(paths, strings.TrimPrefix(path, dir))
+		}
+		return err
+	}))
+	return
+}
+
+func uploadTestFile(t *testing.T, b *Builder, pb *presets.Builder, name, content string) (m media_library.MediaLibrary, existing *media_library.MediaLibrary) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	hash, err := hashReader(strings.NewReader(content))
+	require.NoError(t, err)
+	m, existing, err = b.createUploadedFile(scopeEventContext(t, pb, 1, nil), 0, name, f, hash)
+	require.NoError(t, err)
+	return
+}
+
+func TestDeduplicateUploads(t *testing.T) {
+	require.NoError(t, testDB.Exec("DELETE FROM media_libraries").Error)
+	dir := useTestStorage(t)
+	pb := presets.New().DataOperator(gorm2op.DataOperator(testDB))
+	b := New(testDB).DeduplicateUploads(true)
+	require.NoError(t, b.Install(pb))
+
+	first, existing := uploadTestFile(t, b, pb, "report.txt", "quarterly report")
+	require.Nil(t, existing)
+	require.NotEmpty(t, first.FileHash)
+	require.Len(t, storedObjects(t, dir), 1)
+
+	again, existing := uploadTestFile(t, b, pb, "report-copy.txt", "quarterly report")
+	require.NotNil(t, existing, "the same content is found by its hash")
+	require.Equal(t, first.ID, existing.ID)
+	require.NotEqual(t, first.ID, again.ID)
+	require.Equal(t, first.File.Url, reload(t, again.ID).File.Url, "the duplicate points at the stored object")
+	require.Equal(t, "report-copy.txt", reload(t, again.ID).File.FileName, "the duplicate keeps its own name")
+	require.Len(t, storedObjects(t, dir), 1, "the content is stored once")
+
+	other, existing := uploadTestFile(t, b, pb, "other.txt", "another report")
+	require.Nil(t, existing)
+	require.NotEqual(t, first.File.Url, other.File.Url)
+	require.Len(t, storedObjects(t, dir), 2)
+
+	b.DeduplicateUploads(false)
+	_, existing = uploadTestFile(t, b, pb, "report-again.txt", "quarterly report")
+	require.Nil(t, existing)
+	require.Len(t, storedObjects(t, dir), 3)
+}
+
+type dedupTestPost struct {
+	ID    uint
+	Title string
+	Hero  media_library.MediaBox `sql:"type:text;"`
+	Body  usageTestBody          `gorm:"serializer:json"`
+}
+
+func TestDeduplicateJob(t *testing.T) {
+	require.NoError(t, testDB.Exec("DELETE FROM media_libraries").Error)
+	require.NoError(t, testDB.AutoMigrate(&dedupTestPost{}))
+	t.Cleanup(func() {
+		if err := testDB.Migrator().DropTable(&dedupTestPost{}); err != nil {
+			t.Errorf("drop posts table: %v", err)
+		}
+	})
+	useTestStorage(t)
+
+	mkFile := func(name, hash string) *media_library.MediaLibrary {
+		m := &media_library.MediaLibrary{FileHash: hash}
+		m.File.FileName = name
+		m.File.Url = "/system/media_libraries/" + name
+		require.NoError(t, testDB.Create(m).Error)
+		return m
+	}
+	keep := mkFile("keep.txt", "same")
+	dup := mkFile("dup.txt", "same")
+	other := mkFile("other.txt", "other")
+	// uploaded before hashing existed, hashed from the stored object
+	legacyHash, err := hashReader(strings.NewReader("legacy"))
+	require.NoError(t, err)
+	legacyKeep := mkFile("legacy.txt", legacyHash)
+	legacy := mkFile("legacy-copy.txt", "")
+	_, err = oss.Storage.Put(context.Background(), legacy.File.Url, strings.NewReader("legacy"))
+	require.NoError(t, err)
+
+	box := func(m *media_library.MediaLibrary) media_library.MediaBox {
+		return media_library.MediaBox{ID: json.Number(fmt.Sprint(m.ID)), Url: m.File.Url}
+	}
+	posts := []*dedupTestPost{
+		{Title: "hero", Hero: box(dup)},
+		{Title: "body", Body: usageTestBody{Images: []media_library.MediaBox{box(other), box(legacy)}}},
+		{Title: "untouched", Hero: box(keep)},
+	}
+	require.NoError(t, testDB.Create(&posts).Error)
+	require.NoError(t, setFileTags(testDB, dup.ID, []string{"duplicate"}, true))
+
+	b := New(testDB)
+	job := &testJob{args: &DeduplicateJobArgs{DryRun: true}}
+	require.NoError(t, b.deduplicate(context.Background(), job, []interface{}{&dedupTestPost{}}))
+	require.Contains(t, job.logs[len(job.logs)-1], "found 2 duplicated files in 2 groups")
+	require.NotZero(t, reload(t, dup.ID).ID, "a dry run changes nothing")
+	require.Equal(t, legacyHash, reload(t, legacy.ID).FileHash)
+
+	job = &testJob{args: &DeduplicateJobArgs{}}
+	require.NoError(t, b.deduplicate(context.Background(), job, []interface{}{&dedupTestPost{}}))
+	require.Contains(t, job.logs[len(job.logs)-1], "merged 2 duplicated files in 2 groups, rewrote 2 records")
+	require.Equal(t, uint(100), job.progress)
+
+	var ids []uint
+	require.NoError(t, testDB.Model(&media_library.MediaLibrary{}).Order("id").Pluck("id", &ids).Error)
+	require.Equal(t, []uint{keep.ID, other.ID, legacyKeep.ID}, ids, "the duplicates are merged into the oldest file")
+	var tags int64
+	require.NoError(t, testDB.Model(&MediaTag{}).Where("media_library_id = ?", dup.ID).Count(&tags).Error)
+	require.Zero(t, tags, "the tags of the merged files are removed")
+
+	var got []*dedupTestPost
+	require.NoError(t, testDB.Order("id").Find(&got).Error)
+	require.Equal(t, fmt.Sprint(keep.ID), got[0].Hero.ID.String())
+	require.Equal(t, fmt.Sprint(other.ID), got[1].Body.Images[0].ID.String())
+	require.Equal(t, fmt.Sprint(legacyKeep.ID), got[1].Body.Images[1].ID.String())
+	require.Equal(t, fmt.Sprint(keep.ID), got[2].Hero.ID.String())
+}
+
+func TestDeduplicateJobKeepsUsedFiles(t *testing.T) {
+	require.NoError(t, testDB.Exec("DELETE FROM media_libraries").Error)
+	require.NoError(t, testDB.Exec("DELETE FROM media_library_usages").Error)
+	require.NoError(t, testDB.AutoMigrate(&dedupTestPost{}, &usageTestPost{}))
+	t.Cleanup(func() {
+		if err := testDB.Migrator().DropTable(&dedupTestPost{}, &usageTestPost{}); err != nil {
+			t.Errorf("drop posts tables: %v", err)
+		}
+	})
+	useTestStorage(t)
+	b := New(testDB).TrackUsage(true)
+
+	mkFile := func(name, hash string) *media_library.MediaLibrary {
+		m := &media_library.MediaLibrary{FileHash: hash}
+		m.File.FileName = name
+		m.File.Url = "/system/media_libraries/" + name
+		require.NoError(t, testDB.Create(m).Error)
+		return m
+	}
+	keep := mkFile("keep.txt", "same")
+	shared := mkFile("shared.txt", "same")
+	merged := mkFile("merged.txt", "same")
+	box := func(m *media_library.MediaLibrary) media_library.MediaBox {
+		return media_library.MediaBox{ID: json.Number(fmt.Sprint(m.ID)), Url: m.File.Url}
+	}
+	require.NoError(t, testDB.Create(&[]*dedupTestPost{{Title: "shared", Hero: box(shared)}, {Title: "merged", Hero: box(merged)}}).Error)
+	// a model the job is not told about
+	require.NoError(t, testDB.Create(&usageTestPost{Title: "other", Hero: box(shared)}).Error)
+
+	job := &testJob{args: &DeduplicateJobArgs{}}
+	require.NoError(t, b.deduplicate(context.Background(), job, []interface{}{&dedupTestPost{}}))
+	require.Contains(t, job.logs[len(job.logs)-1], "merged 1 duplicated files in 1 groups, rewrote 2 records")
+
+	var ids []uint
+	require.NoError(t, testDB.Model(&media_library.MediaLibrary{}).Order("id").Pluck("id", &ids).Error)
+	require.Equal(t, []uint{keep.ID, shared.ID}, ids, "a file used by other models is kept")
+	require.Len(t, usageRows(t, keep.ID), 2, "the usages of the rewritten records point at the kept file")
+	usages := usageRows(t, shared.ID)
+	require.Len(t, usages, 1)
+	require.Equal(t, "usageTestPost", usages[0].SourceModel)
+	require.Empty(t, usageRows(t, merged.ID))
+}
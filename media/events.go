@@ -24,6 +24,17 @@ const (
 	MoveToFolderDialogEvent      = "mediaLibrary_MoveToFolderDialogEvent"
 	MoveToFolderEvent            = "mediaLibrary_MoveToFolderEvent"
 	NextFolderEvent              = "mediaLibrary_NextFolderEvent"
+	UsedInDialogEvent            = "mediaLibrary_UsedInDialogEvent"
+	EditMetadataDialogEvent      = "mediaLibrary_EditMetadataDialogEvent"
+	SaveMetadataEvent            = "mediaLibrary_SaveMetadataEvent"
+	ReprocessEvent               = "mediaLibrary_ReprocessEvent"
+	FocalPointDialogEvent        = "mediaLibrary_FocalPointDialogEvent"
+	SaveFocalPointEvent          = "mediaLibrary_SaveFocalPointEvent"
+	ShareFolderDialogEvent       = "mediaLibrary_ShareFolderDialogEvent"
+	AddFolderGrantEvent          = "mediaLibrary_AddFolderGrantEvent"
+	RemoveFolderGrantEvent       = "mediaLibrary_RemoveFolderGrantEvent"
+	ReleaseQuarantineEvent       = "mediaLibrary_ReleaseQuarantineEvent"
+	RescanEvent                  = "mediaLibrary_RescanEvent"
 )
 
 func registerEventFuncs(hub web.EventFuncHub, mb *Builder) {
@@ -46,4 +57,15 @@ func registerEventFuncs(hub web.EventFuncHub, mb *Builder) {
 	hub.RegisterEventFunc(MoveToFolderDialogEvent, moveToFolderDialog(mb))
 	hub.RegisterEventFunc(MoveToFolderEvent, moveToFolder(mb))
 	hub.RegisterEventFunc(NextFolderEvent, nextFolder(mb))
+	hub.RegisterEventFunc(UsedInDialogEvent, usedInDialog(mb))
+	hub.RegisterEventFunc(EditMetadataDialogEvent, editMetadataDialog(mb))
+	hub.RegisterEventFunc(SaveMetadataEvent, saveMetadata(mb))
+	hub.RegisterEventFunc(ReprocessEvent, reprocess(mb))
+	hub.RegisterEventFunc(FocalPointDialogEvent, focalPointDialog(mb))
+	hub.RegisterEventFunc(SaveFocalPointEvent, saveFocalPoint(mb))
+	hub.RegisterEventFunc(ShareFolderDialogEvent, shareFolderDialog(mb))
+	hub.RegisterEventFunc(AddFolderGrantEvent, addFolderGrant(mb))
+	hub.RegisterEventFunc(RemoveFolderGrantEvent, removeFolderGrant(mb))
+	hub.RegisterEventFunc(ReleaseQuarantineEvent, releaseQuarantine(mb))
+	hub.RegisterEventFunc(RescanEvent, rescan(mb))
 }
@@ -0,0 +1,266 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qor5/admin/v3/media/base"
+)
+
+const (
+	PosterName      = "poster"
+	posterExt       = ".jpg"
+	defaultPosterAt = time.Second
+)
+
+// Config of the video and audio processor
+type Config struct {
+	// FFmpegPath and FFprobePath default to the binaries found in PATH
+	FFmpegPath  string
+	FFprobePath string
+	// PosterAt is the position of the frame used as the poster of videos, videos shorter than it use their middle frame
+	PosterAt time.Duration
+	// Renditions are transcoded from the uploaded videos
+	Renditions []*Rendition
+}
+
+// Rendition is a transcoded variant of a video, stored next to the original as {name}.{Name}{Ext}
+type Rendition struct {
+	Name string
+	// Height scales the video keeping its aspect ratio, 0 keeps the height
+	Height int
+	// Ext defaults to .mp4
+	Ext string
+	// Args are the output arguments of ffmpeg, they default to H.264 and AAC
+	Args []string
+}
+
+var defaultRenditionArgs = []string{"-c:v", "libx264", "-preset", "veryfast", "-crf", "23", "-c:a", "aac", "-b:a", "128k", "-movflags", "+faststart"}
+
+// Handler is a base.MediaProcessor of videos and audios, uploads are stored as
+// they are and Process reads their duration and dimensions, extracts the poster
+// of videos and transcodes the renditions with ffmpeg.
+type Handler struct {
+	cfg Config
+}
+
+var _ base.MediaProcessor = (*Handler)(nil)
+
+func New(cfg Config) *Handler {
+	if cfg.FFmpegPath == "" {
+		cfg.FFmpegPath = "ffmpeg"
+	}
+	if cfg.FFprobePath == "" {
+		cfg.FFprobePath = "ffprobe"
+	}
+	if cfg.PosterAt <= 0 {
+		cfg.PosterAt = defaultPosterAt
+	}
+	return &Handler{cfg: cfg}
+}
+
+func (*Handler) CouldHandle(media base.Media) bool {
+	return base.IsVideoFormat(media.URL()) || base.IsAudioFormat(media.URL())
+}
+
+// Handle stores the upload and leaves the processing to Process
+func (*Handler) Handle(media base.Media, file base.FileInterface, option *base.Option) (err error) {
+	if err = media.Store(media.URL(), option, file); err != nil {
+		return
+	}
+	return scanResult(media, map[string]interface{}{
+		"ProcessingStatus": base.ProcessingPending,
+		"ProcessingError":  "",
+	})
+}
+
+// Process runs ffmpeg on the stored file and scans the results into the media
+func (h *Handler) Process(ctx context.Context, media base.Media, option *base.Option) (err error) {
+	dir, err := os.MkdirTemp("", "media-ffmpeg")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input"+path.Ext(media.URL()))
+	if err = retrieveTo(media, input); err != nil {
+		return
+	}
+	probe, err := h.probe(ctx, input)
+	if err != nil {
+		return
+	}
+	result := map[string]interface{}{
+		"Duration":         probe.Duration,
+		"ProcessingStatus": base.ProcessingDone,
+		"ProcessingError":  "",
+	}
+	fileSizes := media.GetFileSizes()
+	if probe.Width > 0 && probe.Height > 0 {
+		result["Width"], result["Height"] = probe.Width, probe.Height
+
+		poster := filepath.Join(dir, PosterName+posterExt)
+		if err = h.run(ctx, h.cfg.FFmpegPath, posterArgs(input, poster, posterPosition(h.cfg.PosterAt, probe.Duration))...); err != nil {
+			return
+		}
+		posterURL := SiblingURL(media.URL(), PosterName, posterExt)
+		if fileSizes[PosterName], err = storeFile(media, posterURL, option, poster); err != nil {
+			return
+		}
+		result["Poster"] = posterURL
+
+		renditions := map[string]string{}
+		for _, r := range h.cfg.Renditions {
+			ext := r.Ext
+			if ext == "" {
+				ext = ".mp4"
+			}
+			out := filepath.Join(dir, r.Name+ext)
+			if err = h.run(ctx, h.cfg.FFmpegPath, renditionArgs(input, out, r)...); err != nil {
+				return fmt.Errorf("rendition %s: %w", r.Name, err)
+			}
+			u := SiblingURL(media.URL(), r.Name, ext)
+			if fileSizes[r.Name], err = storeFile(media, u, option, out); err != nil {
+				return
+			}
+			renditions[r.Name] = u
+		}
+		result["Renditions"] = renditions
+	}
+	result["FileSizes"] = fileSizes
+	return scanResult(media, result)
+}
+
+// SiblingURL returns the url of a file generated from the file of url, such as the poster of a video
+func SiblingURL(url, name, ext string) string {
+	return fmt.Sprintf("%s.%s%s", strings.TrimSuffix(url, path.Ext(url)), name, ext)
+}
+
+func scanResult(media base.Media, result map[string]interface{}) error {
+	bs, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return media.Scan(bs)
+}
+
+func retrieveTo(media base.Media, name string) (err error) {
+	in, err := media.Retrieve(media.URL())
+	if err != nil {
+		return
+	}
+	defer in.Close()
+	out, err := os.Create(name)
+	if err != nil {
+		return
+	}
+	defer out.Close()
+	_, err = io.Copy(out, in)
+	return
+}
+
+func storeFile(media base.Media, url string, option *base.Option, name string) (size int, err error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return
+	}
+	return int(info.Size()), media.Store(url, option, f)
+}
+
+func (h *Handler) run(ctx context.Context, name string, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w: %s", filepath.Base(name), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+type probeResult struct {
+	Duration float64
+	Width    int
+	Height   int
+}
+
+func (h *Handler) probe(ctx context.Context, input string) (r *probeResult, err error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, h.cfg.FFprobePath,
+		"-v", "error", "-print_format", "json", "-show_format", "-show_streams", input)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err = cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffprobe: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return parseProbe(stdout.Bytes())
+}
+
+func parseProbe(data []byte) (r *probeResult, err error) {
+	var out struct {
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+		Streams []struct {
+			CodecType   string `json:"codec_type"`
+			Width       int    `json:"width"`
+			Height      int    `json:"height"`
+			Duration    string `json:"duration"`
+			Disposition struct {
+				AttachedPic int `json:"attached_pic"`
+			} `json:"disposition"`
+		} `json:"streams"`
+	}
+	if err = json.Unmarshal(data, &out); err != nil {
+		return
+	}
+	r = &probeResult{}
+	r.Duration, _ = strconv.ParseFloat(out.Format.Duration, 64)
+	for _, s := range out.Streams {
+		if r.Duration == 0 && s.Duration != "" {
+			r.Duration, _ = strconv.ParseFloat(s.Duration, 64)
+		}
+		// cover art of audios is a video stream as well
+		if s.CodecType == "video" && s.Disposition.AttachedPic == 0 && r.Width == 0 && s.Width > 0 {
+			r.Width, r.Height = s.Width, s.Height
+		}
+	}
+	return
+}
+
+func posterPosition(at time.Duration, duration float64) float64 {
+	if d := at.Seconds(); d < duration {
+		return d
+	}
+	return duration / 2
+}
+
+func posterArgs(input, output string, at float64) []string {
+	return []string{"-y", "-ss", strconv.FormatFloat(at, 'f', 3, 64), "-i", input, "-frames:v", "1", "-q:v", "2", output}
+}
+
+func renditionArgs(input, output string, r *Rendition) []string {
+	args := []string{"-y", "-i", input}
+	if r.Height > 0 {
+		args = append(args, "-vf", fmt.Sprintf("scale=-2:%d", r.Height))
+	}
+	if len(r.Args) > 0 {
+		args = append(args, r.Args...)
+	} else {
+		args = append(args, defaultRenditionArgs...)
+	}
+	return append(args, output)
+}
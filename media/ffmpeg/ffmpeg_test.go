@@ -0,0 +1,48 @@
+package ffmpeg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProbe(t *testing.T) {
+	r, err := parseProbe([]byte(`{
+		"streams": [
+			{"codec_type": "audio", "duration": "12.5"},
+			{"codec_type": "video", "width": 1920, "height": 1080}
+		],
+		"format": {"duration": "12.512"}
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, &probeResult{Duration: 12.512, Width: 1920, Height: 1080}, r)
+
+	r, err = parseProbe([]byte(`{
+		"streams": [
+			{"codec_type": "audio", "duration": "180.2"},
+			{"codec_type": "video", "width": 600, "height": 600, "disposition": {"attached_pic": 1}}
+		],
+		"format": {}
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, &probeResult{Duration: 180.2}, r)
+}
+
+func TestPosterPosition(t *testing.T) {
+	require.Equal(t, 1.0, posterPosition(time.Second, 10))
+	require.Equal(t, 0.25, posterPosition(time.Second, 0.5))
+}
+
+func TestRenditionArgs(t *testing.T) {
+	require.Equal(t,
+		[]string{"-y", "-i", "in.mov", "-vf", "scale=-2:720", "-c:v", "libvpx-vp9", "out.webm"},
+		renditionArgs("in.mov", "out.webm", &Rendition{Name: "720p", Height: 720, Ext: ".webm", Args: []string{"-c:v", "libvpx-vp9"}}),
+	)
+	require.Equal(t, append([]string{"-y", "-i", "in.mov"}, append(defaultRenditionArgs, "out.mp4")...),
+		renditionArgs("in.mov", "out.mp4", &Rendition{Name: "full"}))
+}
+
+func TestSiblingURL(t *testing.T) {
+	require.Equal(t, "//cdn/system/media_libraries/1/file.poster.jpg", SiblingURL("//cdn/system/media_libraries/1/file.mp4", PosterName, ".jpg"))
+}
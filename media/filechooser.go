@@ -3,10 +3,12 @@ package media
 import (
 	"cmp"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"mime/multipart"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/qor5/web/v3"
 	"github.com/qor5/x/v3/i18n"
@@ -66,6 +68,8 @@ const (
 	typeImage = "image"
 	typeVideo = "video"
 	typeFile  = "file"
+	// typeUnused lists the files without usages, it is available when usage tracking is enabled
+	typeUnused = "unused"
 
 	tabFiles   = "files"
 	tabFolders = "folders"
@@ -131,32 +135,25 @@ func uploadFile(mb *Builder) web.EventFunc {
 		var uf uploadFiles
 		ctx.MustUnmarshalForm(&uf)
 		for _, fh := range uf.NewFiles {
-			m := media_library.MediaLibrary{ParentId: uint(parentID)}
-
-			if base.IsImageFormat(fh.Filename) {
-				m.SelectedType = media_library.ALLOW_TYPE_IMAGE
-			} else if base.IsVideoFormat(fh.Filename) {
-				m.SelectedType = media_library.ALLOW_TYPE_VIDEO
-			} else {
-				m.SelectedType = media_library.ALLOW_TYPE_FILE
+			var fileHash string
+			if fileHash, err = hashFileHeader(fh); err != nil {
+				return
 			}
-			if !mb.checkAllowType(m.SelectedType) {
+			m, existing, cErr := mb.createUploadedFile(ctx, uint(parentID), fh.Filename, fh, fileHash)
+			if errors.Is(cErr, errUnsupportedFileType) {
 				presets.ShowMessage(&r, msgr.UnSupportFileType, ColorError)
 				return r, nil
 			}
-			err = m.File.Scan(fh)
-			if err != nil {
-				panic(err)
+			if cErr != nil {
+				presets.ShowMessage(&r, cErr.Error(), ColorError)
+				return r, nil
 			}
-			if mb.currentUserID != nil {
-				m.UserID = mb.currentUserID(ctx)
+			if existing != nil {
+				presets.ShowMessage(&r, msgr.DuplicateFileUploaded(fh.Filename, existing.File.FileName), ColorWarning)
 			}
-			err = mb.saverFunc(mb.db, &m, "", ctx)
-			if err != nil {
-				presets.ShowMessage(&r, err.Error(), ColorError)
-				return r, nil
+			if m.Quarantined() {
+				presets.ShowMessage(&r, msgr.FileQuarantined(fh.Filename), ColorWarning)
 			}
-			mb.onCreate(ctx, m)
 		}
 
 		renderFileChooserDialogContent(ctx, &r, field, mb, cfg)
@@ -165,6 +162,67 @@ func uploadFile(mb *Builder) web.EventFunc {
 	}
 }
 
+var errUnsupportedFileType = errors.New("unsupported file type")
+
+// createUploadedFile creates the media library record of an uploaded file, file is
+// a *multipart.FileHeader or an *os.File, existing is the file it duplicates.
+func (mb *Builder) createUploadedFile(ctx *web.EventContext, parentID uint, fileName string, file interface{}, fileHash string) (m media_library.MediaLibrary, existing *media_library.MediaLibrary, err error) {
+	m = media_library.MediaLibrary{ParentId: parentID}
+	if base.IsImageFormat(fileName) {
+		m.SelectedType = media_library.ALLOW_TYPE_IMAGE
+	} else if base.IsVideoFormat(fileName) {
+		m.SelectedType = media_library.ALLOW_TYPE_VIDEO
+	} else {
+		m.SelectedType = media_library.ALLOW_TYPE_FILE
+	}
+	if !mb.checkAllowType(m.SelectedType) {
+		err = errUnsupportedFileType
+		return
+	}
+	if err = m.File.Scan(file); err != nil {
+		return
+	}
+	m.File.FileName = fileName
+	if mb.currentUserID != nil {
+		m.UserID = mb.currentUserID(ctx)
+	}
+	m.FileHash = fileHash
+	if m.QuarantineStatus, m.QuarantineReason, err = mb.scanUpload(ctx.R.Context(), fileName, file); err != nil {
+		return
+	}
+	if mb.deduplicateUploads && !m.Quarantined() {
+		var dup media_library.MediaLibrary
+		if err = mb.scopedDB(mb.db, ctx).
+			Where("media_libraries.folder = false AND media_libraries.quarantine_status = '' AND media_libraries.file_hash = ?", m.FileHash).
+			Order("media_libraries.id").Limit(1).Find(&dup).Error; err != nil {
+			return
+		}
+		if dup.ID != 0 {
+			// without a file header the saver only stores the record
+			shareStorage(&m, &dup)
+			existing = &dup
+		}
+	}
+	quarantined := m.Quarantined()
+	if quarantined {
+		// the file is held back in the quarantine storage, without a file header the saver only stores the record
+		m.File.FileHeader = nil
+	}
+	if err = mb.saverFunc(mb.db, &m, "", ctx); err != nil {
+		return
+	}
+	if quarantined {
+		if err = mb.holdQuarantinedFile(ctx.R.Context(), m.ID, file); err != nil {
+			return m, nil, errors.Join(err, mb.db.Delete(&media_library.MediaLibrary{}, m.ID).Error)
+		}
+	}
+	if err = mb.enqueueProcessing(ctx.R, &m); err != nil {
+		return
+	}
+	mb.onCreate(ctx, m)
+	return
+}
+
 func mergeNewSizes(m *media_library.MediaLibrary, cfg *media_library.MediaBoxConfig) (sizes map[string]*base.Size, r bool) {
 	sizes = make(map[string]*base.Size)
 	for k, size := range cfg.Sizes {
@@ -199,6 +257,11 @@ func chooseFile(mb *Builder) web.EventFunc {
 			presets.ShowMessage(&r, pMsgr.RecordNotFound, ColorError)
 			return r, nil
 		}
+		if m.Quarantined() {
+			msgr := i18n.MustGetModuleMessages(ctx.R, I18nMediaLibraryKey, Messages_en_US).(*Messages)
+			presets.ShowMessage(&r, msgr.FileQuarantined(m.File.FileName), ColorError)
+			return r, nil
+		}
 		sizes, needCrop := mergeNewSizes(&m, cfg)
 
 		if needCrop {
@@ -233,7 +296,7 @@ func chooseFile(mb *Builder) web.EventFunc {
 		}
 		r.UpdatePortals = append(r.UpdatePortals, &web.PortalUpdate{
 			Name: mediaBoxThumbnailsPortalName(field),
-			Body: mediaBoxThumbnails(ctx, &mediaBox, field, cfg, false, false),
+			Body: mediaBoxThumbnails(ctx, mb.db, &mediaBox, field, cfg, false, false),
 		})
 		r.RunScript = `vars.showFileChooser = false`
 		return
@@ -306,7 +369,71 @@ func fileComponent(mb *Builder, field string, tab string, ctx *web.EventContext,
 			VListItem(
 				h.Text(msgr.CopyImageURL)).
 				Attr("@click", fmt.Sprintf(`$event.view.window.navigator.clipboard.writeText(%s);vars.presetsMessage = { show: true, message: "success", color: %q}`, fullSrc, ColorSuccess)),
+		),
+		h.If(mb.usageTracking,
+			VListItem(
+				h.Text(msgr.UsedIn)).
+				Attr("@click", web.Plaid().
+					EventFunc(UsedInDialogEvent).
+					Query(ParamField, field).
+					Query(ParamMediaIDS, fmt.Sprint(f.ID)).
+					Go()),
+		),
+		h.If(mb.processor != nil && mb.processor.CouldHandle(&f.File) && mb.uploadIsAllowed(ctx.R) == nil &&
+			(f.File.ProcessingStatus == base.ProcessingFailed || f.File.ProcessingStatus == base.ProcessingDone),
+			VListItem(
+				h.Text(msgr.ProcessAgain)).
+				Attr("@click", web.Plaid().
+					EventFunc(ReprocessEvent).
+					Query(ParamField, field).
+					Query(paramTab, tab).
+					Query(ParamCfg, h.JSONString(cfg)).
+					Query(ParamParentID, ctx.Param(ParamParentID)).
+					Query(ParamSelectIDS, ctx.Param(ParamSelectIDS)).
+					Query(ParamMediaIDS, fmt.Sprint(f.ID)).
+					Query(searchKeywordName(inMediaLibrary, field), ctx.Param(searchKeywordName(inMediaLibrary, field))).
+					Go()),
+		),
+		h.If(base.IsImageFormat(f.File.FileName) && !base.IsSVGFormat(f.File.FileName) && mb.updateFocalPointIsAllowed(ctx.R, f) == nil,
+			VListItem(
+				h.Text(msgr.SetFocalPoint)).
+				Attr("@click", web.Plaid().
+					EventFunc(FocalPointDialogEvent).
+					Query(ParamField, field).
+					Query(paramTab, tab).
+					Query(ParamCfg, h.JSONString(cfg)).
+					Query(ParamParentID, ctx.Param(ParamParentID)).
+					Query(ParamSelectIDS, ctx.Param(ParamSelectIDS)).
+					Query(ParamMediaIDS, fmt.Sprint(f.ID)).
+					Query(searchKeywordName(inMediaLibrary, field), ctx.Param(searchKeywordName(inMediaLibrary, field))).
+					Go()),
+		),
+		h.If(mb.updateMetadataIsAllowed(ctx.R, f) == nil,
+			VListItem(
+				h.Text(msgr.EditMetadata)).
+				Attr("@click", web.Plaid().
+					EventFunc(EditMetadataDialogEvent).
+					Query(ParamField, field).
+					Query(paramTab, tab).
+					Query(ParamCfg, h.JSONString(cfg)).
+					Query(ParamParentID, ctx.Param(ParamParentID)).
+					Query(ParamSelectIDS, ctx.Param(ParamSelectIDS)).
+					Query(ParamMediaIDS, fmt.Sprint(f.ID)).
+					Query(searchKeywordName(inMediaLibrary, field), ctx.Param(searchKeywordName(inMediaLibrary, field))).
+					Go()),
 		))
+	*menus = append(*menus, mb.quarantineMenus(ctx, f, msgr, func(eventFunc string) string {
+		return web.Plaid().
+			EventFunc(eventFunc).
+			Query(ParamField, field).
+			Query(paramTab, tab).
+			Query(ParamCfg, h.JSONString(cfg)).
+			Query(ParamParentID, ctx.Param(ParamParentID)).
+			Query(ParamSelectIDS, ctx.Param(ParamSelectIDS)).
+			Query(ParamMediaIDS, fmt.Sprint(f.ID)).
+			Query(searchKeywordName(inMediaLibrary, field), ctx.Param(searchKeywordName(inMediaLibrary, field))).
+			Go()
+	})...)
 	clickEvent := fmt.Sprintf(`vars.imageSrc=%q;vars.imagePreview=true;`, src)
 	if base.IsImageFormat(f.File.FileName) && inMediaLibrary {
 		*event = clickEvent
@@ -338,7 +465,11 @@ func fileComponent(mb *Builder, field string, tab string, ctx *web.EventContext,
 				),
 			).Src(src).Height(cardTitleHeight).Cover(true),
 		).Else(
-			fileThumb(f.File.FileName),
+			h.If(f.File.Poster != "",
+				VImg().Src(f.File.Poster).Height(cardTitleHeight).Cover(true),
+			).Else(
+				fileThumb(f.File.FileName),
+			),
 		),
 	)
 
@@ -346,8 +477,15 @@ func fileComponent(mb *Builder, field string, tab string, ctx *web.EventContext,
 		web.Slot(
 			fileNameComp,
 		).Name("title"),
-		web.Slot(h.If(base.IsImageFormat(f.File.FileName),
-			fileChips(f))).Name("subtitle"),
+		web.Slot(
+			h.If(base.IsImageFormat(f.File.FileName),
+				fileChips(f)),
+			processingChip(f, msgr),
+			quarantineChip(f, msgr),
+			h.If(f.RightsExpired(time.Now()),
+				VChip(h.Text(msgr.RightsExpired)).Color(ColorError).Size(SizeXSmall).Class("ml-1"),
+			),
+		).Name("subtitle"),
 	)
 
 	return
@@ -370,8 +508,9 @@ func fileOrFolderComponent(
 		checkEvent                = fmt.Sprintf(`let arr=locals.select_ids;let find_id=%v;arr.includes(find_id)?arr.splice(arr.indexOf(find_id), 1):arr.push(find_id);`, f.ID)
 		moveToEvent               = fmt.Sprintf(`let arr=locals.select_ids;let find_id=%v;if(!arr.includes(find_id)){arr.push(find_id)};`, f.ID)
 		clickCardWithoutMoveEvent = "null"
+		manageable                = mb.recordAccess(ctx, f) >= FolderAccessManage
 	)
-	if mb.updateNameIsAllowed(ctx.R, f) == nil {
+	if manageable && mb.updateNameIsAllowed(ctx.R, f) == nil {
 		menus = append(menus, VListItem(h.Text(msgr.Rename)).Attr("@click", web.Plaid().
 			EventFunc(RenameDialogEvent).
 			Query(ParamField, field).
@@ -384,10 +523,10 @@ func fileOrFolderComponent(
 			Go()))
 	}
 
-	if mb.moveToIsAllowed(ctx.R) == nil {
+	if manageable && mb.moveToIsAllowed(ctx.R) == nil {
 		menus = append(menus, VListItem(h.Text(msgr.MoveTo)).Attr("@click", moveToEvent))
 	}
-	if mb.deleteIsAllowed(ctx.R, f) == nil {
+	if manageable && mb.deleteIsAllowed(ctx.R, f) == nil {
 		menus = append(menus, VListItem(h.Text(msgr.Delete)).Attr("@click",
 			web.Plaid().
 				EventFunc(DeleteConfirmationEvent).
@@ -401,6 +540,20 @@ func fileOrFolderComponent(
 				Go()))
 	}
 
+	if f.Folder && manageable && mb.folderSubject != nil {
+		menus = append(menus, VListItem(h.Text(msgr.Share)).Attr("@click",
+			web.Plaid().
+				EventFunc(ShareFolderDialogEvent).
+				Query(ParamField, field).
+				Query(paramTab, tab).
+				Query(ParamCfg, h.JSONString(cfg)).
+				Query(ParamParentID, ctx.Param(ParamParentID)).
+				Query(ParamMediaIDS, fmt.Sprint(f.ID)).
+				Query(ParamSelectIDS, ctx.Param(ParamSelectIDS)).
+				Query(searchKeywordName(inMediaLibrary, field), ctx.Param(searchKeywordName(inMediaLibrary, field))).
+				Go()))
+	}
+
 	if f.Folder {
 		title, content = folderComponent(mb, ctx, f)
 		clickCardWithoutMoveEvent = web.Plaid().
@@ -559,6 +712,8 @@ func (mb *Builder) mediaLibraryFilter(tab, selectedType, keyword, orderByVal str
 	} else if mb.currentUserID != nil {
 		wh = wh.Where("user_id = ? ", mb.currentUserID(ctx))
 	}
+	wh = mb.scopeFolderACL(wh, ctx)
+	wh = mb.scopeQuarantine(wh, ctx, tab == tabFolders)
 	switch orderByVal {
 	case orderByCreatedAt:
 		wh = wh.Order("created_at")
@@ -642,6 +797,41 @@ func (mb *Builder) mediaLibraryTopOperations(clickTabEvent, field, tab, typeVal,
 		changeAllowTypeEvent += ";" + web.Plaid().MergeQuery(true).Query(paramTypeKey, web.Var("$event")).PushState(true).RunPushState()
 		changeOrderEvent += ";" + web.Plaid().MergeQuery(true).Query(paramOrderByKey, web.Var("$event")).PushState(true).RunPushState()
 	}
+	uploadEvent := "form.NewFiles = [...$event.target.files];" +
+		web.Plaid().
+			BeforeScript("locals.fileChooserUploadingFiles = $event.target.files").
+			EventFunc(UploadFileEvent).
+			Query(paramTab, tab).
+			Query(ParamParentID, parentID).
+			Query(ParamField, field).
+			Query(ParamCfg, h.JSONString(cfg)).
+			Query(ParamSelectIDS, ctx.Param(ParamSelectIDS)).
+			Go()
+	if mb.chunkedUploadPrefix != "" {
+		uploadEvent = mb.chunkedUploadScript(parentID,
+			web.Plaid().EventFunc(ImageJumpPageEvent).
+				Query(paramTab, tab).
+				Query(ParamParentID, parentID).
+				Query(ParamField, field).
+				Query(ParamCfg, h.JSONString(cfg)).
+				Query(ParamSelectIDS, ctx.Param(ParamSelectIDS)).
+				Go())
+	}
+	uploadable := mb.folderAccess(ctx, uint(parentID)) >= FolderAccessUpload
+	changeFacetEvent := func(param string) string {
+		event := web.Plaid().EventFunc(ImageJumpPageEvent).
+			Query(paramTab, tab).
+			Query(ParamField, field).
+			Query(ParamCfg, h.JSONString(cfg)).
+			Query(ParamSelectIDS, ctx.Param(ParamSelectIDS)).
+			Query(searchKeywordName(inMediaLibrary, field), ctx.Param(searchKeywordName(inMediaLibrary, field))).
+			Query(param, web.Var("$event")).
+			Go()
+		if inMediaLibrary {
+			event += ";" + web.Plaid().MergeQuery(true).Query(param, web.Var("$event")).PushState(true).RunPushState()
+		}
+		return event
+	}
 	return VRow(
 		h.If(!inMediaLibrary,
 			VCol(
@@ -682,7 +872,7 @@ func (mb *Builder) mediaLibraryTopOperations(clickTabEvent, field, tab, typeVal,
 					Attr("@update:model-value", changeOrderEvent).
 					Density(DensityCompact).Variant(FieldVariantSolo).Flat(true),
 				h.If(
-					tab == tabFolders && mb.newFolderIsAllowed(ctx.R) == nil,
+					tab == tabFolders && mb.newFolderIsAllowed(ctx.R) == nil && uploadable,
 					VBtn(msgr.NewFolder).PrependIcon("mdi-plus").
 						Variant(VariantOutlined).Class("mr-2").
 						Attr("@click",
@@ -694,7 +884,7 @@ func (mb *Builder) mediaLibraryTopOperations(clickTabEvent, field, tab, typeVal,
 								Query(ParamParentID, ctx.Param(ParamParentID)).
 								Go()),
 				),
-				h.If(mb.uploadIsAllowed(ctx.R) == nil,
+				h.If(mb.uploadIsAllowed(ctx.R) == nil && uploadable,
 					h.Div(
 						VBtn(msgr.UploadFile).PrependIcon("mdi-upload").Color(ColorPrimary).
 							Attr("@click", "$refs.uploadInput.click()"),
@@ -704,21 +894,17 @@ func (mb *Builder) mediaLibraryTopOperations(clickTabEvent, field, tab, typeVal,
 							Type("file").
 							Attr("multiple", true).
 							Style("display:none").
-							Attr("@change",
-								"form.NewFiles = [...$event.target.files];"+
-									web.Plaid().
-										BeforeScript("locals.fileChooserUploadingFiles = $event.target.files").
-										EventFunc(UploadFileEvent).
-										Query(paramTab, tab).
-										Query(ParamParentID, parentID).
-										Query(ParamField, field).
-										Query(ParamCfg, h.JSONString(cfg)).
-										Query(ParamSelectIDS, ctx.Param(ParamSelectIDS)).
-										Go()),
+							Attr("@change", uploadEvent),
 					),
 				),
 			).Class("d-inline-flex"),
 		).Cols(12).Class("d-flex justify-space-between"),
+		VCol(
+			h.Div(
+				mb.quarantineSwitch(ctx, msgr, changeFacetEvent),
+				mb.mediaFacetsComponent(ctx, msgr, facetsFromContext(ctx), changeFacetEvent),
+			).Class("d-flex align-center"),
+		).Cols(12),
 	).Class("position-sticky top-0", "bg-"+ColorBackground).Attr("style", "z-index:2")
 }
 
@@ -773,6 +959,18 @@ func (mb *Builder) mediaLibraryBottomOperations(field string, ctx *web.EventCont
 							Query(ParamCfg, h.JSONString(cfg)).
 							Query(ParamSelectIDS, web.Var(`locals.select_ids.join(",")`)).Go()),
 				),
+				h.If(mb.updateMetadataIsAllowed(ctx.R, nil) == nil,
+					VBtn(msgr.EditMetadata).Size(SizeSmall).Variant(VariantOutlined).
+						Color(ColorSecondary).Class("ml-2").
+						Attr("@click", web.Plaid().EventFunc(EditMetadataDialogEvent).
+							Query(ParamField, field).
+							Query(ParamParentID, parentID).
+							Query(paramTab, tab).
+							Query(ParamSelectIDS, ctx.Param(ParamSelectIDS)).
+							Query(searchKeywordName(inMediaLibrary, field), ctx.Param(searchKeywordName(inMediaLibrary, field))).
+							Query(ParamCfg, h.JSONString(cfg)).
+							Query(ParamMediaIDS, web.Var(`locals.select_ids.join(",")`)).Go()),
+				),
 				h.If(mb.deleteIsAllowed(ctx.R, nil) == nil,
 					VBtn(msgr.Delete).Size(SizeSmall).Variant(VariantOutlined).
 						Color(ColorWarning).Class("ml-2").
@@ -823,6 +1021,10 @@ func mediaLibraryContent(mb *Builder, field string, ctx *web.EventContext,
 		selectedType = media_library.ALLOW_TYPE_VIDEO
 	case typeFile:
 		selectedType = media_library.ALLOW_TYPE_FILE
+	case typeUnused:
+		if !mb.usageTracking {
+			typeVal = typeAll
+		}
 	default:
 		typeVal = typeAll
 	}
@@ -834,6 +1036,10 @@ func mediaLibraryContent(mb *Builder, field string, ctx *web.EventContext,
 		))
 	}
 	wh := mb.mediaLibraryFilter(tab, selectedType, keyword, orderByVal, parentID, ctx, cfg)
+	if typeVal == typeUnused {
+		wh = mb.unusedScope(wh, tab == tabFolders)
+	}
+	wh = facetsFromContext(ctx).apply(wh, tab == tabFolders)
 
 	var count int64
 
@@ -860,9 +1066,15 @@ func mediaLibraryContent(mb *Builder, field string, ctx *web.EventContext,
 		h.If(mb.uploadIsAllowed(ctx.R) == nil,
 			VCol(
 				VCard(
-					VProgressCircular().
+					VProgressCircular(
+						h.Span("{{locals.fileChooserUploadProgress[f.name]}}%").
+							Class("text-caption").
+							Attr("v-if", "locals.fileChooserUploadProgress[f.name] !== undefined"),
+					).
 						Color(ColorPrimary).
-						Indeterminate(true),
+						Size(48).
+						Attr(":model-value", "locals.fileChooserUploadProgress[f.name]").
+						Attr(":indeterminate", "locals.fileChooserUploadProgress[f.name] === undefined"),
 				).
 					Class("d-flex align-center justify-center").
 					Height(cardHeight).Width(cardWidth),
@@ -903,6 +1115,10 @@ func mediaLibraryContent(mb *Builder, field string, ctx *web.EventContext,
 		web.Portal().Name(moveToFolderDialogPortalName),
 		web.Portal().Name(renameDialogPortalName),
 		web.Portal().Name(updateDescriptionDialogPortalName),
+		web.Portal().Name(usedInDialogPortalName),
+		web.Portal().Name(editMetadataDialogPortalName),
+		web.Portal().Name(focalPointDialogPortalName),
+		web.Portal().Name(shareFolderDialogPortalName),
 		VContainer(
 			mb.mediaLibraryTopOperations(clickTabEvent, field, tab, typeVal, orderByVal, parentID, ctx, cfg),
 			VRow(
@@ -913,7 +1129,7 @@ func mediaLibraryContent(mb *Builder, field string, ctx *web.EventContext,
 			rowFile,
 			mb.mediaLibraryBottomOperations(field, ctx, cfg, len(files) > 0, pagesCount, currentPageInt),
 		).Fluid(true),
-	).Init(fmt.Sprintf(`{fileChooserUploadingFiles: [], %s}`, strings.Join(initCroppingVars, ", "))).
+	).Init(fmt.Sprintf(`{fileChooserUploadingFiles: [], fileChooserUploadProgress: {}, %s}`, strings.Join(initCroppingVars, ", "))).
 		VSlot("{ locals,form}").Init(`{select_ids:[]}`)
 }
 
@@ -0,0 +1,135 @@
+package media
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/i18n"
+	. "github.com/qor5/x/v3/ui/vuetify"
+	vx "github.com/qor5/x/v3/ui/vuetifyx"
+	h "github.com/theplant/htmlgo"
+
+	"github.com/qor5/admin/v3/media/base"
+	"github.com/qor5/admin/v3/presets"
+)
+
+const (
+	paramFocalX     = "focal_x"
+	paramFocalY     = "focal_y"
+	paramFocalReset = "focal_reset"
+)
+
+func focalPointDialog(mb *Builder) web.EventFunc {
+	return func(ctx *web.EventContext) (r web.EventResponse, err error) {
+		var (
+			pMsgr = i18n.MustGetModuleMessages(ctx.R, presets.CoreI18nModuleKey, Messages_en_US).(*presets.Messages)
+			msgr  = i18n.MustGetModuleMessages(ctx.R, I18nMediaLibraryKey, Messages_en_US).(*Messages)
+		)
+		obj, ok := wrapFirst(mb, ctx, &r)
+		if !ok {
+			return
+		}
+		if err = mb.updateFocalPointIsAllowed(ctx.R, &obj); err != nil {
+			return
+		}
+		x, y := "null", "null"
+		if fp := obj.File.FocalPoint; fp != nil {
+			x, y = fmt.Sprint(fp.X), fmt.Sprint(fp.Y)
+		}
+		save := func(reset bool) string {
+			return web.Plaid().
+				BeforeScript("dialogLocals.show=false").
+				EventFunc(SaveFocalPointEvent).
+				Queries(ctx.Queries()).
+				Query(paramFocalX, web.Var("dialogLocals.x")).
+				Query(paramFocalY, web.Var("dialogLocals.y")).
+				Query(paramFocalReset, reset).
+				Go()
+		}
+		r.UpdatePortals = append(r.UpdatePortals, &web.PortalUpdate{
+			Name: focalPointDialogPortalName,
+			Body: web.Scope(
+				vx.VXDialog(
+					h.Div(h.Text(msgr.FocalPointHint)).Class("text-body-2 mb-4"),
+					h.Div(
+						h.Div(
+							h.Img(obj.File.URL()).
+								Style("display:block;max-width:100%;max-height:60vh").
+								Attr("@click", "dialogLocals.x=Math.round($event.offsetX/$event.target.clientWidth*1000)/1000;"+
+									"dialogLocals.y=Math.round($event.offsetY/$event.target.clientHeight*1000)/1000"),
+							h.Div().
+								Attr("v-if", "dialogLocals.x!==null").
+								Attr(":style", "{left:(dialogLocals.x*100)+'%',top:(dialogLocals.y*100)+'%'}").
+								Style("position:absolute;width:24px;height:24px;margin:-12px 0 0 -12px;border:2px solid #fff;border-radius:50%;box-shadow:0 0 0 2px rgba(0,0,0,.5);pointer-events:none"),
+						).Style("position:relative;display:inline-block;cursor:crosshair"),
+					).Class("d-flex justify-center"),
+					h.If(obj.File.FocalPoint != nil,
+						h.Div(
+							VBtn(msgr.ResetFocalPoint).Variant(VariantText).Size(SizeSmall).
+								Attr("@click", save(true)),
+						).Class("d-flex justify-end mt-2"),
+					),
+				).
+					Attr("v-model", "dialogLocals.show").
+					Title(msgr.SetFocalPoint).
+					Width(760).
+					CancelText(pMsgr.Cancel).
+					OkText(pMsgr.Update).
+					Attr(":disable-ok", "dialogLocals.x===null").
+					Attr("@click:ok", save(false)),
+			).VSlot("{locals:dialogLocals}").Init(fmt.Sprintf("{show:true,x:%s,y:%s}", x, y)),
+		})
+		return
+	}
+}
+
+// saveFocalPoint stores the focal point and generates the sizes again, so the
+// sizes without a crop of their own are cropped around it
+func saveFocalPoint(mb *Builder) web.EventFunc {
+	return func(ctx *web.EventContext) (r web.EventResponse, err error) {
+		msgr := i18n.MustGetModuleMessages(ctx.R, I18nMediaLibraryKey, Messages_en_US).(*Messages)
+		obj, ok := wrapFirst(mb, ctx, &r)
+		if !ok {
+			return
+		}
+		if err = mb.updateFocalPointIsAllowed(ctx.R, &obj); err != nil {
+			return
+		}
+		if !base.IsImageFormat(obj.File.FileName) {
+			return
+		}
+		var fp *base.FocalPoint
+		if ctx.R.FormValue(paramFocalReset) != "true" {
+			fp = &base.FocalPoint{}
+			fp.X, err = strconv.ParseFloat(ctx.R.FormValue(paramFocalX), 64)
+			if err == nil {
+				fp.Y, err = strconv.ParseFloat(ctx.R.FormValue(paramFocalY), 64)
+			}
+			if err != nil || !fp.Valid() {
+				presets.ShowMessage(&r, msgr.InvalidFocalPoint, ColorError)
+				return r, nil
+			}
+		}
+		old := obj
+		moption := obj.GetMediaOption()
+		moption.FocalPoint = fp
+		moption.Crop = true
+		if err = obj.ScanMediaOptions(moption); err != nil {
+			return
+		}
+		obj.File.FocalPoint = fp
+		if err = mb.saverFunc(mb.db, &obj, fmt.Sprint(obj.ID), ctx); err != nil {
+			presets.ShowMessage(&r, err.Error(), ColorError)
+			return r, nil
+		}
+		mb.onEdit(ctx, old, obj)
+		presets.ShowMessage(&r, msgr.FocalPointUpdated, ColorSuccess)
+		web.AppendRunScripts(&r,
+			web.Plaid().EventFunc(ImageJumpPageEvent).
+				MergeQuery(true).
+				Queries(ctx.Queries()).
+				Go())
+		return
+	}
+}
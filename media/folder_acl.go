@@ -0,0 +1,494 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/i18n"
+	. "github.com/qor5/x/v3/ui/vuetify"
+	vx "github.com/qor5/x/v3/ui/vuetifyx"
+	h "github.com/theplant/htmlgo"
+	"gorm.io/gorm"
+
+	"github.com/qor5/admin/v3/media/media_library"
+	"github.com/qor5/admin/v3/presets"
+)
+
+// FolderAccess is the access of a request to the content of a folder, each level includes the lower ones
+type FolderAccess int
+
+const (
+	FolderAccessNone FolderAccess = iota
+	// FolderAccessRead lists and uses the files of the folder
+	FolderAccessRead
+	// FolderAccessUpload uploads files and creates folders in the folder, and manages the files uploaded by oneself
+	FolderAccessUpload
+	// FolderAccessManage moves, renames and deletes the files and folders of the folder, and shares it
+	FolderAccessManage
+)
+
+const (
+	FolderGrantSubjectUser = "user"
+	FolderGrantSubjectRole = "role"
+
+	paramGrantID          = "grant_id"
+	paramGrantSubjectType = "SubjectType"
+	paramGrantSubjectID   = "SubjectID"
+	paramGrantAccess      = "Access"
+)
+
+// FolderGrant gives a user or a role access to a folder and the folders below it.
+// The grants of a folder replace the ones inherited from its parents.
+type FolderGrant struct {
+	ID          uint   `gorm:"primarykey"`
+	FolderID    uint   `gorm:"index"`
+	SubjectType string `gorm:"size:32"`
+	SubjectID   string `gorm:"size:64"`
+	Access      FolderAccess
+	CreatedAt   time.Time
+}
+
+func (FolderGrant) TableName() string {
+	return "media_library_folder_grants"
+}
+
+// FolderSubject is who a request acts as for the folder permissions
+type FolderSubject struct {
+	UserID uint
+	Roles  []string
+	// Superuser is not restricted by the folder permissions
+	Superuser bool
+	// RootAccess is the access to the root folder, and to the folders without grants in their tree
+	RootAccess FolderAccess
+}
+
+// FolderGrantee is a user or a role offered by the share dialog of folders
+type FolderGrantee struct {
+	Type  string
+	ID    string
+	Label string
+}
+
+type (
+	FolderSubjectFunc  func(ctx *web.EventContext) *FolderSubject
+	FolderGranteesFunc func(ctx *web.EventContext) ([]*FolderGrantee, error)
+)
+
+// FolderPermissions restricts the folders of the media library to the grants of
+// their owners, users and roles, inherited down the folder tree. The subject of
+// a request is returned by v, nil denies everything.
+func (b *Builder) FolderPermissions(v FolderSubjectFunc) *Builder {
+	b.folderSubject = v
+	return b
+}
+
+// FolderGrantees lists the users and roles the share dialog of folders offers,
+// without it their ids are typed in
+func (b *Builder) FolderGrantees(v FolderGranteesFunc) *Builder {
+	b.folderGrantees = v
+	return b
+}
+
+type folderNode struct {
+	ID       uint
+	ParentId uint
+	UserID   uint
+}
+
+// folderACL is the access of a subject to every folder, computed once per request
+type folderACL struct {
+	subject *FolderSubject
+	folders map[uint]*folderNode
+	grants  map[uint][]*FolderGrant
+	access  map[uint]FolderAccess
+}
+
+type folderACLContextKey struct{}
+
+// folderACL returns the folder permissions of the request, nil if it is not restricted by them
+func (b *Builder) folderACL(ctx *web.EventContext) (*folderACL, error) {
+	if b.folderSubject == nil {
+		return nil, nil
+	}
+	if acl, ok := ctx.ContextValue(folderACLContextKey{}).(*folderACL); ok {
+		return acl, nil
+	}
+	subject := b.folderSubject(ctx)
+	if subject == nil {
+		subject = &FolderSubject{}
+	}
+	var acl *folderACL
+	if !subject.Superuser {
+		var (
+			folders []*folderNode
+			grants  []*FolderGrant
+		)
+		if err := b.db.Model(&media_library.MediaLibrary{}).Where("folder = true").
+			Select("id", "parent_id", "user_id").Find(&folders).Error; err != nil {
+			return nil, err
+		}
+		if err := b.db.Find(&grants).Error; err != nil {
+			return nil, err
+		}
+		acl = newFolderACL(subject, folders, grants)
+	}
+	ctx.WithContextValue(folderACLContextKey{}, acl)
+	return acl, nil
+}
+
+func newFolderACL(subject *FolderSubject, folders []*folderNode, grants []*FolderGrant) *folderACL {
+	acl := &folderACL{
+		subject: subject,
+		folders: make(map[uint]*folderNode, len(folders)),
+		grants:  make(map[uint][]*FolderGrant),
+		access:  map[uint]FolderAccess{0: subject.RootAccess},
+	}
+	for _, f := range folders {
+		acl.folders[f.ID] = f
+	}
+	for _, g := range grants {
+		acl.grants[g.FolderID] = append(acl.grants[g.FolderID], g)
+	}
+	return acl
+}
+
+func (acl *folderACL) granted(g *FolderGrant) bool {
+	switch g.SubjectType {
+	case FolderGrantSubjectUser:
+		return acl.subject.UserID != 0 && g.SubjectID == fmt.Sprint(acl.subject.UserID)
+	case FolderGrantSubjectRole:
+		return slices.Contains(acl.subject.Roles, g.SubjectID)
+	}
+	return false
+}
+
+// folderAccess returns the access to the content of the folder, 0 is the root folder
+func (acl *folderACL) folderAccess(id uint) FolderAccess {
+	if access, ok := acl.access[id]; ok {
+		return access
+	}
+	f := acl.folders[id]
+	if f == nil {
+		return FolderAccessNone
+	}
+	// guards against a cycle of parents
+	acl.access[id] = FolderAccessNone
+	var access FolderAccess
+	if grants, restricted := acl.grants[id]; restricted {
+		for _, g := range grants {
+			if acl.granted(g) {
+				access = max(access, g.Access)
+			}
+		}
+	} else {
+		access = acl.folderAccess(f.ParentId)
+	}
+	if acl.subject.UserID != 0 && f.UserID == acl.subject.UserID {
+		access = FolderAccessManage
+	}
+	acl.access[id] = access
+	return access
+}
+
+// recordAccess returns the access to a file or folder, a folder is governed by its own
+// permissions and a file by the ones of its folder
+func (acl *folderACL) recordAccess(m *media_library.MediaLibrary) FolderAccess {
+	if m.Folder {
+		return acl.folderAccess(m.ID)
+	}
+	access := acl.folderAccess(m.ParentId)
+	if access >= FolderAccessUpload && acl.subject.UserID != 0 && m.UserID == acl.subject.UserID {
+		return FolderAccessManage
+	}
+	return access
+}
+
+// visibleFolders returns the folders whose content is readable, and the folders listed on the
+// way to them, so that a folder shared deep in the tree could be navigated to
+func (acl *folderACL) visibleFolders() (readable, listed []uint) {
+	listedSet := map[uint]bool{}
+	if acl.folderAccess(0) >= FolderAccessRead {
+		readable = append(readable, 0)
+	}
+	for id := range acl.folders {
+		if acl.folderAccess(id) < FolderAccessRead {
+			continue
+		}
+		readable = append(readable, id)
+		for f := acl.folders[id]; f != nil && !listedSet[f.ID]; f = acl.folders[f.ParentId] {
+			listedSet[f.ID] = true
+		}
+	}
+	for id := range listedSet {
+		listed = append(listed, id)
+	}
+	slices.Sort(readable)
+	slices.Sort(listed)
+	return
+}
+
+// scope restricts a media_libraries query to the files of readable folders and the listed folders
+func (acl *folderACL) scope(q *gorm.DB) *gorm.DB {
+	readable, listed := acl.visibleFolders()
+	return q.Where("(media_libraries.folder = false AND media_libraries.parent_id IN ?) OR (media_libraries.folder = true AND media_libraries.id IN ?)",
+		readable, listed)
+}
+
+func (b *Builder) scopeFolderACL(q *gorm.DB, ctx *web.EventContext) *gorm.DB {
+	acl, err := b.folderACL(ctx)
+	if err != nil {
+		_ = q.AddError(err)
+		return q
+	}
+	if acl == nil {
+		return q
+	}
+	return acl.scope(q)
+}
+
+// folderAccess returns the access of the request to the content of the folder
+func (b *Builder) folderAccess(ctx *web.EventContext, folderID uint) FolderAccess {
+	acl, err := b.folderACL(ctx)
+	if err != nil {
+		return FolderAccessNone
+	}
+	if acl == nil {
+		return FolderAccessManage
+	}
+	return acl.folderAccess(folderID)
+}
+
+// recordAccess returns the access of the request to the file or folder
+func (b *Builder) recordAccess(ctx *web.EventContext, m *media_library.MediaLibrary) FolderAccess {
+	acl, err := b.folderACL(ctx)
+	if err != nil {
+		return FolderAccessNone
+	}
+	if acl == nil {
+		return FolderAccessManage
+	}
+	return acl.recordAccess(m)
+}
+
+func folderAccessItems(msgr *Messages) []selectItem {
+	return []selectItem{
+		{Text: msgr.FolderAccessRead, Value: fmt.Sprint(FolderAccessRead)},
+		{Text: msgr.FolderAccessUpload, Value: fmt.Sprint(FolderAccessUpload)},
+		{Text: msgr.FolderAccessManage, Value: fmt.Sprint(FolderAccessManage)},
+	}
+}
+
+func folderAccessText(msgr *Messages, access FolderAccess) string {
+	switch access {
+	case FolderAccessRead:
+		return msgr.FolderAccessRead
+	case FolderAccessUpload:
+		return msgr.FolderAccessUpload
+	case FolderAccessManage:
+		return msgr.FolderAccessManage
+	}
+	return ""
+}
+
+// shareFolderTarget returns the folder of the request if it could be shared by it
+func (mb *Builder) shareFolderTarget(ctx *web.EventContext, r *web.EventResponse) (obj media_library.MediaLibrary, ok bool) {
+	if mb.folderSubject == nil {
+		return
+	}
+	if obj, ok = wrapFirst(mb, ctx, r); !ok {
+		return
+	}
+	if !obj.Folder || mb.recordAccess(ctx, &obj) < FolderAccessManage {
+		return obj, false
+	}
+	return obj, true
+}
+
+func shareFolderDialog(mb *Builder) web.EventFunc {
+	return func(ctx *web.EventContext) (r web.EventResponse, err error) {
+		var (
+			pMsgr    = i18n.MustGetModuleMessages(ctx.R, presets.CoreI18nModuleKey, Messages_en_US).(*presets.Messages)
+			msgr     = i18n.MustGetModuleMessages(ctx.R, I18nMediaLibraryKey, Messages_en_US).(*Messages)
+			grants   []*FolderGrant
+			grantees []*FolderGrantee
+			names    = map[uint]string{}
+		)
+		obj, ok := mb.shareFolderTarget(ctx, &r)
+		if !ok {
+			return
+		}
+		if err = mb.db.Where("folder_id = ?", obj.ID).Order("id").Find(&grants).Error; err != nil {
+			return
+		}
+		if mb.folderGrantees != nil {
+			if grantees, err = mb.folderGrantees(ctx); err != nil {
+				return
+			}
+		}
+		if mb.uploaderNames != nil && obj.UserID != 0 {
+			if names, err = mb.uploaderNames(ctx, []uint{obj.UserID}); err != nil {
+				return
+			}
+		}
+		granteeLabel := func(g *FolderGrant) string {
+			for _, v := range grantees {
+				if v.Type == g.SubjectType && v.ID == g.SubjectID {
+					return v.Label
+				}
+			}
+			return g.SubjectID
+		}
+		subjectTypeText := func(t string) string {
+			if t == FolderGrantSubjectRole {
+				return msgr.Role
+			}
+			return msgr.User
+		}
+
+		rows := []h.HTMLComponent{}
+		if obj.UserID != 0 {
+			owner := names[obj.UserID]
+			if owner == "" {
+				owner = msgr.UploaderID(obj.UserID)
+			}
+			rows = append(rows, VListItem(
+				VListItemTitle(h.Text(owner)),
+				VListItemSubtitle(h.Text(msgr.Owner)),
+			).PrependIcon("mdi-account-star"))
+		}
+		for _, g := range grants {
+			icon := "mdi-account"
+			if g.SubjectType == FolderGrantSubjectRole {
+				icon = "mdi-account-group"
+			}
+			rows = append(rows, VListItem(
+				VListItemTitle(h.Text(granteeLabel(g))),
+				VListItemSubtitle(h.Text(fmt.Sprintf("%s · %s", subjectTypeText(g.SubjectType), folderAccessText(msgr, g.Access)))),
+				web.Slot(
+					VBtn("").Icon("mdi-close").Variant(VariantText).Size(SizeSmall).
+						Attr("@click", web.Plaid().
+							EventFunc(RemoveFolderGrantEvent).
+							Queries(ctx.Queries()).
+							Query(paramGrantID, g.ID).
+							Go()),
+				).Name(VSlotAppend),
+			).PrependIcon(icon))
+		}
+
+		var subjectField h.HTMLComponent = VTextField().Label(msgr.Grantee).
+			Attr(web.VField(paramGrantSubjectID, "")...).
+			Variant(FieldVariantOutlined).Density(DensityCompact).HideDetails(true)
+		if len(grantees) > 0 {
+			subjectField = VAutocomplete().Label(msgr.Grantee).
+				Attr(":items", fmt.Sprintf("%s.filter(g=>g.Type===form.%s)", h.JSONString(grantees), paramGrantSubjectType)).
+				ItemTitle("Label").ItemValue("ID").
+				Attr(web.VField(paramGrantSubjectID, "")...).
+				Variant(FieldVariantOutlined).Density(DensityCompact).HideDetails(true)
+		}
+		r.UpdatePortals = append(r.UpdatePortals, &web.PortalUpdate{
+			Name: shareFolderDialogPortalName,
+			Body: web.Scope(
+				vx.VXDialog(
+					h.If(len(grants) == 0,
+						VAlert(h.Text(msgr.FolderPermissionsInherited)).Density(DensityCompact).Variant(VariantTonal).Class("mb-2"),
+					),
+					VList(rows...).Density(DensityCompact),
+					h.Div(
+						VSelect().Items([]selectItem{
+							{Text: msgr.User, Value: FolderGrantSubjectUser},
+							{Text: msgr.Role, Value: FolderGrantSubjectRole},
+						}).ItemTitle("Text").ItemValue("Value").
+							Attr(web.VField(paramGrantSubjectType, FolderGrantSubjectUser)...).
+							Attr("@update:model-value", fmt.Sprintf("form.%s=''", paramGrantSubjectID)).
+							Variant(FieldVariantOutlined).Density(DensityCompact).HideDetails(true).Class("mr-2"),
+						subjectField,
+						VSelect().Items(folderAccessItems(msgr)).ItemTitle("Text").ItemValue("Value").
+							Attr(web.VField(paramGrantAccess, fmt.Sprint(FolderAccessRead))...).
+							Variant(FieldVariantOutlined).Density(DensityCompact).HideDetails(true).Class("mx-2"),
+						VBtn(msgr.AddGrant).Color(ColorPrimary).
+							Attr(":disabled", fmt.Sprintf("!form.%s", paramGrantSubjectID)).
+							Attr("@click", web.Plaid().
+								EventFunc(AddFolderGrantEvent).
+								Queries(ctx.Queries()).
+								Query(paramGrantSubjectType, web.Var("form."+paramGrantSubjectType)).
+								Query(paramGrantSubjectID, web.Var("form."+paramGrantSubjectID)).
+								Query(paramGrantAccess, web.Var("form."+paramGrantAccess)).
+								Go()),
+					).Class("d-flex align-center mt-4"),
+				).
+					Attr("v-model", "dialogLocals.show").
+					Title(msgr.ShareFolder(obj.File.FileName)).
+					Width(720).
+					HideCancel(true).
+					OkText(pMsgr.OK).
+					Attr("@click:ok", "dialogLocals.show=false"),
+			).VSlot("{locals:dialogLocals,form}").Init("{show:true}"),
+		})
+		return
+	}
+}
+
+func addFolderGrant(mb *Builder) web.EventFunc {
+	return func(ctx *web.EventContext) (r web.EventResponse, err error) {
+		msgr := i18n.MustGetModuleMessages(ctx.R, I18nMediaLibraryKey, Messages_en_US).(*Messages)
+		obj, ok := mb.shareFolderTarget(ctx, &r)
+		if !ok {
+			return
+		}
+		g := &FolderGrant{
+			FolderID:    obj.ID,
+			SubjectType: ctx.R.FormValue(paramGrantSubjectType),
+			SubjectID:   ctx.R.FormValue(paramGrantSubjectID),
+			Access:      FolderAccess(ctx.ParamAsInt(paramGrantAccess)),
+		}
+		if g.SubjectType != FolderGrantSubjectUser && g.SubjectType != FolderGrantSubjectRole ||
+			g.SubjectID == "" || g.Access < FolderAccessRead || g.Access > FolderAccessManage {
+			presets.ShowMessage(&r, msgr.InvalidFolderGrant, ColorError)
+			return r, nil
+		}
+		err = mb.db.Transaction(func(tx *gorm.DB) error {
+			// a subject has one grant per folder
+			if err := tx.Where("folder_id = ? AND subject_type = ? AND subject_id = ?", g.FolderID, g.SubjectType, g.SubjectID).
+				Delete(&FolderGrant{}).Error; err != nil {
+				return err
+			}
+			return tx.Create(g).Error
+		})
+		if err != nil {
+			return
+		}
+		return reloadShareFolderDialog(ctx, r)
+	}
+}
+
+func removeFolderGrant(mb *Builder) web.EventFunc {
+	return func(ctx *web.EventContext) (r web.EventResponse, err error) {
+		obj, ok := mb.shareFolderTarget(ctx, &r)
+		if !ok {
+			return
+		}
+		res := mb.db.Where("id = ? AND folder_id = ?", ctx.ParamAsInt(paramGrantID), obj.ID).Delete(&FolderGrant{})
+		if err = res.Error; err != nil {
+			return
+		}
+		if res.RowsAffected == 0 {
+			return r, errors.New("folder grant not found")
+		}
+		return reloadShareFolderDialog(ctx, r)
+	}
+}
+
+func reloadShareFolderDialog(ctx *web.EventContext, r web.EventResponse) (web.EventResponse, error) {
+	queries := ctx.Queries()
+	for _, k := range []string{paramGrantID, paramGrantSubjectType, paramGrantSubjectID, paramGrantAccess} {
+		queries.Del(k)
+	}
+	web.AppendRunScripts(&r,
+		web.Plaid().EventFunc(ShareFolderDialogEvent).Queries(queries).Go(),
+		web.Plaid().EventFunc(ImageJumpPageEvent).MergeQuery(true).Queries(queries).Go(),
+	)
+	return r, nil
+}
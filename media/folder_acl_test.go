@@ -0,0 +1,108 @@
+package media
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/qor5/web/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/qor5/admin/v3/media/media_library"
+	"github.com/qor5/admin/v3/presets"
+	"github.com/qor5/admin/v3/presets/gorm2op"
+)
+
+func TestFolderACL(t *testing.T) {
+	// 1 Agencies
+	// ├── 2 Agency A  (granted to the role agency-a)
+	// │   └── 3 Campaign
+	// └── 4 Agency B  (granted to the user 9)
+	// 5 Internal     (owned by the user 7)
+	folders := []*folderNode{
+		{ID: 1},
+		{ID: 2, ParentId: 1},
+		{ID: 3, ParentId: 2},
+		{ID: 4, ParentId: 1},
+		{ID: 5, UserID: 7},
+	}
+	grants := []*FolderGrant{
+		{FolderID: 2, SubjectType: FolderGrantSubjectRole, SubjectID: "agency-a", Access: FolderAccessUpload},
+		{FolderID: 4, SubjectType: FolderGrantSubjectUser, SubjectID: "9", Access: FolderAccessManage},
+	}
+
+	partner := newFolderACL(&FolderSubject{UserID: 8, Roles: []string{"agency-a"}}, folders, grants)
+	require.Equal(t, FolderAccessNone, partner.folderAccess(0))
+	require.Equal(t, FolderAccessNone, partner.folderAccess(1))
+	require.Equal(t, FolderAccessUpload, partner.folderAccess(2))
+	require.Equal(t, FolderAccessUpload, partner.folderAccess(3), "grants are inherited")
+	require.Equal(t, FolderAccessNone, partner.folderAccess(4))
+	readable, listed := partner.visibleFolders()
+	require.Equal(t, []uint{2, 3}, readable)
+	require.Equal(t, []uint{1, 2, 3}, listed, "the parents of a shared folder are listed to navigate to it")
+
+	own := &media_library.MediaLibrary{ParentId: 3, UserID: 8}
+	other := &media_library.MediaLibrary{ParentId: 3, UserID: 10}
+	require.Equal(t, FolderAccessManage, partner.recordAccess(own), "uploaders manage their own files")
+	require.Equal(t, FolderAccessUpload, partner.recordAccess(other))
+
+	staff := newFolderACL(&FolderSubject{UserID: 7, RootAccess: FolderAccessUpload}, folders, grants)
+	require.Equal(t, FolderAccessUpload, staff.folderAccess(1), "folders without grants inherit the root access")
+	require.Equal(t, FolderAccessNone, staff.folderAccess(2), "grants replace the inherited access")
+	require.Equal(t, FolderAccessManage, staff.folderAccess(5), "owners manage their folders")
+	readable, listed = staff.visibleFolders()
+	require.Equal(t, []uint{0, 1, 5}, readable)
+	require.Equal(t, []uint{1, 5}, listed)
+}
+
+func TestDeleteFolderWithPermissions(t *testing.T) {
+	require.NoError(t, testDB.Exec("DELETE FROM media_libraries").Error)
+	require.NoError(t, testDB.Exec("DELETE FROM media_library_folder_grants").Error)
+	pb := presets.New().DataOperator(gorm2op.DataOperator(testDB))
+	b := New(testDB).FolderPermissions(func(*web.EventContext) *FolderSubject {
+		return &FolderSubject{UserID: 1, RootAccess: FolderAccessUpload}
+	})
+	require.NoError(t, b.Install(pb))
+
+	// the folder of a partner holds a file the other users can not see
+	partner := mkRow(t, 1, true, 0, "partner")
+	file := mkRow(t, 2, false, partner.ID, "contract.pdf")
+	require.NoError(t, testDB.Create(&FolderGrant{FolderID: partner.ID, SubjectType: FolderGrantSubjectUser, SubjectID: "2", Access: FolderAccessUpload}).Error)
+	empty := mkRow(t, 1, true, 0, "empty")
+	require.NoError(t, testDB.Create(&FolderGrant{FolderID: empty.ID, SubjectType: FolderGrantSubjectUser, SubjectID: "2", Access: FolderAccessRead}).Error)
+
+	deleteIDs := func(ids ...uint) *web.EventResponse {
+		t.Helper()
+		var params []string
+		for _, id := range ids {
+			params = append(params, fmt.Sprint(id))
+		}
+		r, err := doDelete(b)(scopeEventContext(t, pb, 1, url.Values{ParamMediaIDS: {strings.Join(params, ",")}}))
+		require.NoError(t, err)
+		return &r
+	}
+	grants := func(folderID uint) (count int64) {
+		t.Helper()
+		require.NoError(t, testDB.Model(&FolderGrant{}).Where("folder_id = ?", folderID).Count(&count).Error)
+		return
+	}
+
+	r := deleteIDs(partner.ID)
+	require.Contains(t, r.RunScript, Messages_en_US.FolderNotEmptyCannotBeDeleted)
+	require.Equal(t, partner.ID, reload(t, file.ID).ParentId, "the file is not moved to the root folder")
+	require.NotZero(t, reload(t, partner.ID).ID)
+	require.EqualValues(t, 1, grants(partner.ID))
+
+	deleteIDs(empty.ID)
+	var count int64
+	require.NoError(t, testDB.Model(&media_library.MediaLibrary{}).Where("id = ?", empty.ID).Count(&count).Error)
+	require.Zero(t, count, "an empty folder is deleted")
+	require.Zero(t, grants(empty.ID))
+
+	// a folder is deleted with its content
+	deleteIDs(partner.ID, file.ID)
+	require.NoError(t, testDB.Model(&media_library.MediaLibrary{}).Where("id IN ?", []uint{partner.ID, file.ID}).Count(&count).Error)
+	require.Zero(t, count)
+	require.Zero(t, grants(partner.ID))
+}
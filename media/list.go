@@ -103,7 +103,7 @@ func configScopedCRUD(mb *Builder, mm *presets.ModelBuilder) {
 	// scoped query as a subquery keeps a Joins-based searcher intact.
 	mm.Listing().WrapSearchFunc(func(in presets.SearchFunc) presets.SearchFunc {
 		return func(ctx *web.EventContext, params *presets.SearchParams) (*presets.SearchResult, error) {
-			if mb.searcher != nil {
+			if mb.scoped() {
 				params.SQLConditions = append(params.SQLConditions, &presets.SQLCondition{
 					Query: "media_libraries.id in (?)",
 					Args:  []interface{}{mb.scopedDB(mb.db, ctx).Select("media_libraries.id")},
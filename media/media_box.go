@@ -45,6 +45,11 @@ const (
 func AutoMigrate(db *gorm.DB) (err error) {
 	return db.AutoMigrate(
 		&media_library.MediaLibrary{},
+		&RegenerateCheckpoint{},
+		&MediaUsage{},
+		&MediaTag{},
+		&ChunkedUpload{},
+		&FolderGrant{},
 	)
 }
 
@@ -187,7 +192,7 @@ func (b *QMediaBoxBuilder) MarshalHTML(c context.Context) (r []byte, err error)
 				h.Label(b.label).Class("v-label theme--light mb-2"),
 			),
 			web.Portal(
-				mediaBoxThumbnails(ctx, b.value, b.fieldName, b.config, b.disabled, b.readonly),
+				mediaBoxThumbnails(ctx, b.db, b.value, b.fieldName, b.config, b.disabled, b.readonly),
 			).Name(mediaBoxThumbnailsPortalName(b.fieldName)),
 			web.Portal().Name(portalName),
 			h.Div().Class("d-flex flex-column py-1 ga-1 text-caption").
@@ -284,10 +289,31 @@ func deleteConfirmation(mb *Builder) web.EventFunc {
 		} else {
 			message = msgr.DeleteObjects(len(ids))
 		}
+		var (
+			objs   []media_library.MediaLibrary
+			usages []*MediaUsage
+		)
+		if mb.usageTracking {
+			if err = mb.scopedDB(mb.db, ctx).Where("media_libraries.id in ?", ids).Find(&objs).Error; err != nil {
+				return
+			}
+			if usages, err = mb.checkFilesInUse(objs); err != nil {
+				if errors.Is(err, errFilesInUse) {
+					presets.ShowMessage(&r, msgr.FilesInUseCannotBeDeleted, ColorError)
+					return r, nil
+				}
+				return
+			}
+		}
 		r.UpdatePortals = append(r.UpdatePortals, &web.PortalUpdate{
 			Name: deleteConfirmPortalName(field),
 			Body: vx.VXDialog(
-				h.Span(message),
+				h.If(len(usages) == 0, h.Span(message)).Else(
+					h.Div(
+						VAlert(h.Text(msgr.DeleteFilesInUse(len(usages)))).Type(ColorWarning).Variant(VariantTonal).Density(DensityCompact),
+						mediaUsageList(usages),
+					),
+				),
 			).
 				Title(pMsgr.DialogTitleDefault).
 				Attr("v-model", "vars.mediaLibrary_deleteConfirmation").
@@ -334,7 +360,11 @@ func doDelete(mb *Builder) web.EventFunc {
 			}
 			panic(err)
 		}
-		// Act only on the rows the scoped query returned, not the raw request ids.
+		// Act only on the rows the scoped query returned, not the raw request ids,
+		// and only on the ones the folder permissions let the request manage.
+		objs = slices.DeleteFunc(objs, func(obj media_library.MediaLibrary) bool {
+			return mb.recordAccess(ctx, &obj) < FolderAccessManage
+		})
 		for _, obj := range objs {
 			visibleIDs = append(visibleIDs, uint64(obj.ID))
 			if obj.Folder {
@@ -347,6 +377,29 @@ func doDelete(mb *Builder) web.EventFunc {
 		if len(visibleIDs) == 0 {
 			return r, nil
 		}
+		if _, err = mb.checkFilesInUse(objs); err != nil {
+			if errors.Is(err, errFilesInUse) {
+				msgr := i18n.MustGetModuleMessages(ctx.R, I18nMediaLibraryKey, Messages_en_US).(*Messages)
+				presets.ShowMessage(&r, msgr.FilesInUseCannotBeDeleted, ColorError)
+				return r, nil
+			}
+			return
+		}
+		// The children of a deleted folder are moved to the root folder, which would
+		// give them the root access instead of the grants of the folder they were in.
+		if len(deleteFolderIDS) > 0 && mb.folderSubject != nil {
+			var children int64
+			if err = db.Model(&media_library.MediaLibrary{}).
+				Where("media_libraries.parent_id in ? AND media_libraries.id NOT IN ?", deleteFolderIDS, visibleIDs).
+				Count(&children).Error; err != nil {
+				return
+			}
+			if children > 0 {
+				msgr := i18n.MustGetModuleMessages(ctx.R, I18nMediaLibraryKey, Messages_en_US).(*Messages)
+				presets.ShowMessage(&r, msgr.FolderNotEmptyCannotBeDeleted, ColorError)
+				return r, nil
+			}
+		}
 		err = db.Transaction(func(tx *gorm.DB) (dbErr error) {
 			if len(deleteFolderIDS) > 0 {
 				// Resolve the children through the scoped query and reparent them
@@ -370,11 +423,20 @@ func doDelete(mb *Builder) web.EventFunc {
 			if dbErr = tx.Delete(&media_library.MediaLibrary{}, "media_libraries.id in ?", visibleIDs).Error; dbErr != nil {
 				return
 			}
+			if dbErr = tx.Where("media_library_id in ?", visibleIDs).Delete(&MediaTag{}).Error; dbErr != nil {
+				return
+			}
+			if len(deleteFolderIDS) > 0 && mb.folderSubject != nil {
+				return tx.Where("folder_id in ?", deleteFolderIDS).Delete(&FolderGrant{}).Error
+			}
 			return
 		})
 		if err != nil {
 			panic(err)
 		}
+		if err = mb.removeQuarantinedFiles(ctx.R.Context(), objs); err != nil {
+			return
+		}
 		mb.onDelete(ctx, objs)
 		return
 	}
@@ -384,7 +446,7 @@ func ChooseFileButtonID(field string) string {
 	return fmt.Sprintf("btnChooseFile_%s", field)
 }
 
-func mediaBoxThumbnails(ctx *web.EventContext, mediaBox *media_library.MediaBox, field string, cfg *media_library.MediaBoxConfig, disabled, readonly bool) h.HTMLComponent {
+func mediaBoxThumbnails(ctx *web.EventContext, db *gorm.DB, mediaBox *media_library.MediaBox, field string, cfg *media_library.MediaBoxConfig, disabled, readonly bool) h.HTMLComponent {
 	msgr := i18n.MustGetModuleMessages(ctx.R, I18nMediaLibraryKey, Messages_en_US).(*Messages)
 	c := VContainer().Class("media-box-wrap").Fluid(true)
 	if cfg.BackgroundColor != "" {
@@ -433,6 +495,7 @@ func mediaBoxThumbnails(ctx *web.EventContext, mediaBox *media_library.MediaBox,
 		c.AppendChildren(btnRow.Class())
 	}
 	if mediaBox.ID.String() != "" && mediaBox.ID.String() != "0" && !cfg.SimpleIMGURL {
+		c.AppendChildren(rightsExpiredAlert(db, mediaBox, msgr))
 		row := appendMediaBoxThumb(cfg, msgr, mediaBox, field, disabled)
 		c.AppendChildren(row)
 
@@ -545,7 +608,7 @@ func deleteFileField() web.EventFunc {
 		cfg := stringToCfg(ctx.Param(ParamCfg))
 		r.UpdatePortals = append(r.UpdatePortals, &web.PortalUpdate{
 			Name: mediaBoxThumbnailsPortalName(field),
-			Body: mediaBoxThumbnails(ctx, &media_library.MediaBox{}, field, cfg, false, false),
+			Body: mediaBoxThumbnails(ctx, nil, &media_library.MediaBox{}, field, cfg, false, false),
 		})
 
 		return
@@ -885,7 +948,7 @@ func moveToFolder(mb *Builder) web.EventFunc {
 			for _, findID := range ids {
 				var old, obj media_library.MediaLibrary
 				mb.scopedDB(db, ctx).Find(&obj, findID)
-				if obj.ID == 0 {
+				if obj.ID == 0 || mb.recordAccess(ctx, &obj) < FolderAccessManage {
 					continue
 				}
 				mb.scopedDB(db, ctx).Find(&old, findID)
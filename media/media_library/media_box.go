@@ -8,6 +8,8 @@ import (
 	"strings"
 	"time"
 
+	h "github.com/theplant/htmlgo"
+
 	"github.com/qor5/admin/v3/media/base"
 )
 
@@ -44,6 +46,22 @@ type MediaBoxConfig struct {
 	DisableCrop bool
 	// allow to accept media_box only with URL
 	SimpleIMGURL bool
+	// Breakpoints are the art direction of the image rendered by MediaBox.Picture
+	Breakpoints []*Breakpoint
+	// WebpSources adds the webp files generated next to the sizes as alternatives, such as by the vips handler
+	WebpSources bool
+}
+
+// Breakpoint serves the sizes of SizeNames to the viewports matching Media. The sizes
+// are cropped around the focal point of the image unless they are cropped by hand,
+// so each breakpoint could use its own aspect ratio.
+type Breakpoint struct {
+	// Media is the media query of the breakpoint, such as (max-width: 600px), empty matches all viewports
+	Media string
+	// SizeNames are the names of the sizes of the breakpoint, listed in srcset by their widths
+	SizeNames []string
+	// Sizes is the sizes attribute of the breakpoint, such as 100vw
+	Sizes string
 }
 
 func (mediaBox *MediaBox) Scan(data interface{}) (err error) {
@@ -96,11 +114,24 @@ func (mediaBox *MediaBox) URL(styles ...string) (s string) {
 		s = fmt.Sprintf("%v%v", s, ext)
 	}()
 	if mediaBox.Url != "" && len(styles) > 0 {
+		// sizes added after the upload have no generated file, serve them from the transform handler,
+		// which rejects every request unless the secret is set
+		if opts, ok := base.GetTransformPreset(styles[0]); ok && len(base.TransformSecret) > 0 &&
+			mediaBox.Sizes != nil && mediaBox.Sizes[styles[0]] == nil {
+			cropID = ""
+			ext = ""
+			return mediaBox.TransformURL(opts)
+		}
 		return fmt.Sprintf("%v.%v", strings.TrimSuffix(mediaBox.Url, ext), styles[0])
 	}
 	return strings.TrimSuffix(mediaBox.Url, ext)
 }
 
+// TransformURL returns the signed URL of the image variant generated on request
+func (mediaBox *MediaBox) TransformURL(opts base.TransformOptions, styles ...string) string {
+	return base.TransformURL(mediaBox.URL(styles...), opts)
+}
+
 func (mediaBox *MediaBox) URLNoCached(styles ...string) string {
 	i := mediaBox.URL(styles...)
 	if i != "" && !strings.Contains(i, "?") {
@@ -116,3 +147,63 @@ func (mediaBox MediaBox) WebpURL(styles ...string) string {
 	i := strings.LastIndex(url, ext)
 	return url[:i] + strings.Replace(url[i:], extArr[0], ".webp", 1)
 }
+
+// Picture renders the image as a <picture> with a source of srcset and sizes per breakpoint
+// of cfg, preceded by its webp alternative if cfg.WebpSources is set. The default image is the fallback.
+func (mediaBox *MediaBox) Picture(cfg *MediaBoxConfig) h.HTMLComponent {
+	if mediaBox.Url == "" {
+		return nil
+	}
+	img := h.Img(mediaBox.URL()).Alt(mediaBox.Description).Attr("loading", "lazy")
+	if mediaBox.Width > 0 && mediaBox.Height > 0 {
+		img.Attr("width", mediaBox.Width, "height", mediaBox.Height)
+	}
+	if cfg == nil || !mediaBox.IsImage() || mediaBox.IsSVG() {
+		return img
+	}
+	picture := h.Tag("picture")
+	for _, bp := range cfg.Breakpoints {
+		srcset := mediaBox.srcset(cfg, bp.SizeNames, mediaBox.URL)
+		if srcset == "" {
+			continue
+		}
+		if cfg.WebpSources {
+			picture.AppendChildren(pictureSource(bp, mediaBox.srcset(cfg, bp.SizeNames, mediaBox.WebpURL)).Attr("type", "image/webp"))
+		}
+		picture.AppendChildren(pictureSource(bp, srcset))
+	}
+	return picture.AppendChildren(img)
+}
+
+func pictureSource(bp *Breakpoint, srcset string) *h.HTMLTagBuilder {
+	return h.Tag("source").
+		Attr("srcset", srcset).
+		AttrIf("media", bp.Media, bp.Media != "").
+		AttrIf("sizes", bp.Sizes, bp.Sizes != "")
+}
+
+// srcset lists the urls of the sizes with their widths, the sizes of unknown widths are skipped
+func (mediaBox *MediaBox) srcset(cfg *MediaBoxConfig, names []string, url func(styles ...string) string) string {
+	var entries []string
+	for _, name := range names {
+		if width := mediaBox.sizeWidth(cfg, name); width > 0 {
+			entries = append(entries, fmt.Sprintf("%s %dw", url(name), width))
+		}
+	}
+	return strings.Join(entries, ", ")
+}
+
+func (mediaBox *MediaBox) sizeWidth(cfg *MediaBoxConfig, name string) int {
+	size := mediaBox.Sizes[name]
+	if size == nil {
+		size = cfg.Sizes[name]
+	}
+	if size == nil {
+		return 0
+	}
+	s := *size
+	if s.Width == 0 && mediaBox.Width > 0 && mediaBox.Height > 0 {
+		base.SaleUpDown(mediaBox.Width, mediaBox.Height, &s)
+	}
+	return s.Width
+}
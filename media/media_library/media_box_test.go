@@ -0,0 +1,60 @@
+package media_library
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/qor5/admin/v3/media/base"
+)
+
+func TestMediaBoxPicture(t *testing.T) {
+	mb := &MediaBox{
+		Url:         "/system/media_libraries/1/file.jpg",
+		Description: "A file",
+		Width:       2000,
+		Height:      1000,
+		CropID:      map[string]string{"mobile": "c1"},
+	}
+	cfg := &MediaBoxConfig{
+		Sizes: map[string]*base.Size{
+			"mobile":  {Width: 600, Height: 600},
+			"desktop": {Height: 500},
+		},
+		Breakpoints: []*Breakpoint{
+			{Media: "(max-width: 600px)", SizeNames: []string{"mobile"}, Sizes: "100vw"},
+			{SizeNames: []string{"desktop", "unknown"}},
+		},
+		WebpSources: true,
+	}
+	html, err := mb.Picture(cfg).MarshalHTML(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, `
+<picture>
+<source srcset='/system/media_libraries/1/file.mobile_c1.webp 600w' media='(max-width: 600px)' sizes='100vw' type='image/webp'></source>
+
+<source srcset='/system/media_libraries/1/file.mobile_c1.jpg 600w' media='(max-width: 600px)' sizes='100vw'></source>
+
+<source srcset='/system/media_libraries/1/file.desktop.webp 1000w' type='image/webp'></source>
+
+<source srcset='/system/media_libraries/1/file.desktop.jpg 1000w'></source>
+
+<img src='/system/media_libraries/1/file.jpg' alt='A file' loading='lazy' width='2000' height='1000'>
+</picture>
+`, string(html))
+}
+
+func TestMediaBoxURLTransformPreset(t *testing.T) {
+	base.RegisterTransformPreset("preset_test", base.TransformOptions{Width: 200, Height: 200, Fit: base.TransformFitCover})
+	mb := &MediaBox{
+		Url:   "/system/media_libraries/1/file.jpg",
+		Sizes: map[string]*base.Size{"original": {Width: 2000, Height: 1000}},
+	}
+	require.Equal(t, "/system/media_libraries/1/file.preset_test.jpg", mb.URL("preset_test"),
+		"the sized url is used without the transform secret")
+
+	base.TransformSecret = []byte("secret")
+	defer func() { base.TransformSecret = nil }()
+	require.Contains(t, mb.URL("preset_test"), base.TransformPathPrefix+"/system/media_libraries/1/file.jpg?")
+}
@@ -4,9 +4,11 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"image"
 	"math"
 	"path"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -26,6 +28,52 @@ type MediaLibrary struct {
 	UserID       uint                `gorm:"index"`
 	Folder       bool                `gorm:"default:false"`
 	ParentId     uint                `gorm:"index;default:0"`
+	FileHash     string              `gorm:"index;size:64"`
+	// Metadata holds the values of the custom metadata fields configured on the media builder
+	Metadata       Metadata   `gorm:"type:text"`
+	RightsExpireAt *time.Time `gorm:"index"`
+	// QuarantineStatus is set when the upload scanner did not find the file clean, the file is held back until it is released
+	QuarantineStatus string `gorm:"size:16;default:'';index"`
+	QuarantineReason string
+}
+
+// Quarantine statuses of the files the upload scanner did not find clean
+const (
+	QuarantineInfected   = "infected"
+	QuarantineScanFailed = "scan_failed"
+)
+
+// Quarantined reports if the file is held back by the upload scanner
+func (mediaLibrary *MediaLibrary) Quarantined() bool {
+	return mediaLibrary.QuarantineStatus != ""
+}
+
+// Metadata custom metadata of a media library file, keyed by field name
+type Metadata map[string]string
+
+func (m *Metadata) Scan(data interface{}) (err error) {
+	switch values := data.(type) {
+	case []byte:
+		if len(values) > 0 {
+			return json.Unmarshal(values, m)
+		}
+	case string:
+		return m.Scan([]byte(values))
+	}
+	return nil
+}
+
+func (m Metadata) Value() (driver.Value, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	results, err := json.Marshal(m)
+	return string(results), err
+}
+
+// RightsExpired reports if the usage rights of the file expired at t
+func (mediaLibrary *MediaLibrary) RightsExpired(t time.Time) bool {
+	return mediaLibrary.RightsExpireAt != nil && !mediaLibrary.RightsExpireAt.After(t)
 }
 
 type MediaOption struct {
@@ -37,6 +85,7 @@ type MediaOption struct {
 	Sizes        map[string]*base.Size       `json:",omitempty"`
 	SelectedType string                      `json:",omitempty"`
 	Description  string                      `json:",omitempty"`
+	FocalPoint   *base.FocalPoint            `json:",omitempty"`
 	Crop         bool
 }
 
@@ -58,6 +107,7 @@ func (mediaLibrary *MediaLibrary) GetMediaOption() MediaOption {
 		Sizes:        mediaLibrary.File.GetSizes(),
 		SelectedType: mediaLibrary.File.SelectedType,
 		Description:  mediaLibrary.File.Description,
+		FocalPoint:   mediaLibrary.File.FocalPoint,
 	}
 }
 
@@ -76,6 +126,14 @@ type MediaLibraryStorage struct {
 	Video        string
 	SelectedType string
 	Description  string
+	// filled in by the media processor of videos and audios
+	Duration         float64           `json:",omitempty"`
+	Poster           string            `json:",omitempty"`
+	Renditions       map[string]string `json:",omitempty"`
+	ProcessingStatus string            `json:",omitempty"`
+	ProcessingError  string            `json:",omitempty"`
+	// FocalPoint crops the sizes that have no crop option of their own around it
+	FocalPoint *base.FocalPoint `json:",omitempty"`
 }
 
 func (mediaLibraryStorage *MediaLibraryStorage) GetSizes() map[string]*base.Size {
@@ -102,6 +160,13 @@ func (mediaLibraryStorage *MediaLibraryStorage) Scan(data interface{}) (err erro
 		if len(values) != 0 {
 			mediaLibraryStorage.Base.Scan(values)
 			if err = json.Unmarshal(values, mediaLibraryStorage); err == nil {
+				// crop options are not stored with the file but come with the media options of a crop
+				var options struct {
+					CropOptions map[string]*base.CropOption
+				}
+				if json.Unmarshal(values, &options) == nil && options.CropOptions != nil {
+					mediaLibraryStorage.CropOptions = options.CropOptions
+				}
 				if mediaLibraryStorage.CropOptions == nil {
 					mediaLibraryStorage.CropOptions = map[string]*base.CropOption{}
 				}
@@ -149,6 +214,23 @@ func (mediaLibraryStorage *MediaLibraryStorage) Scan(data interface{}) (err erro
 	return nil
 }
 
+// GetCropOption returns the crop option of the size, the sizes without one are cropped around the focal point
+func (mediaLibraryStorage *MediaLibraryStorage) GetCropOption(name string) *image.Rectangle {
+	if cropOption := mediaLibraryStorage.Base.GetCropOption(name); cropOption != nil {
+		return cropOption
+	}
+	fp := mediaLibraryStorage.FocalPoint
+	if fp == nil || name == base.DefaultSizeKey || name == base.OriginalSizeKey {
+		return nil
+	}
+	size := mediaLibraryStorage.GetSizes()[name]
+	if size == nil || size.Padding || size.Width == 0 || size.Height == 0 {
+		return nil
+	}
+	rect := base.FocalCrop(mediaLibraryStorage.Width, mediaLibraryStorage.Height, size, *fp)
+	return &rect
+}
+
 func (mediaLibraryStorage MediaLibraryStorage) Value() (driver.Value, error) {
 	results, err := json.Marshal(mediaLibraryStorage)
 	return string(results), err
@@ -0,0 +1,32 @@
+package media_library
+
+import (
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/qor5/admin/v3/media/base"
+)
+
+func TestMediaLibraryStorageGetCropOption(t *testing.T) {
+	var m MediaLibrary
+	require.NoError(t, m.ScanMediaOptions(MediaOption{
+		Sizes: map[string]*base.Size{
+			"square": {Width: 100, Height: 100},
+			"banner": {Width: 400, Height: 100},
+			"wide":   {Width: 800},
+		},
+		CropOptions: map[string]*base.CropOption{"banner": {X: 1, Y: 2, Width: 40, Height: 10}},
+		FocalPoint:  &base.FocalPoint{X: 0.25, Y: 0.5},
+	}))
+	m.File.Width, m.File.Height = 400, 200
+
+	require.Equal(t, &image.Rectangle{Min: image.Pt(0, 0), Max: image.Pt(200, 200)}, m.File.GetCropOption("square"))
+	require.Equal(t, &image.Rectangle{Min: image.Pt(1, 2), Max: image.Pt(41, 12)}, m.File.GetCropOption("banner"))
+	require.Nil(t, m.File.GetCropOption("wide"))
+	require.Nil(t, m.File.GetCropOption(base.DefaultSizeKey))
+
+	m.File.FocalPoint = nil
+	require.Nil(t, m.File.GetCropOption("square"))
+}
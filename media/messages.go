@@ -40,6 +40,59 @@ type Messages struct {
 	MediaLibrary                          string
 	UnSupportFileType                     string
 	CopyImageURL                          string
+	UsedIn                                string
+	NotUsedAnywhere                       string
+	Unused                                string
+	DeleteFilesInUse                      func(v int) string
+	FilesInUseCannotBeDeleted             string
+	FolderNotEmptyCannotBeDeleted         string
+	DuplicateFileUploaded                 func(name, existing string) string
+	EditMetadata                          string
+	Tags                                  string
+	Uploader                              string
+	UploaderID                            func(id uint) string
+	Dimensions                            string
+	DimensionSmall                        string
+	DimensionMedium                       string
+	DimensionLarge                        string
+	UsageRights                           string
+	RightsExpired                         string
+	RightsValid                           string
+	RightsExpireAt                        string
+	RightsExpiredAt                       func(date string) string
+	UploadedFrom                          string
+	UploadedTo                            string
+	BulkMetadataHint                      func(v int) string
+	MetadataUpdated                       string
+	Processing                            string
+	ProcessingFailed                      string
+	ProcessAgain                          string
+	ProcessingQueued                      string
+	SetFocalPoint                         string
+	FocalPointHint                        string
+	ResetFocalPoint                       string
+	FocalPointUpdated                     string
+	InvalidFocalPoint                     string
+	Share                                 string
+	ShareFolder                           func(name string) string
+	Owner                                 string
+	User                                  string
+	Role                                  string
+	Grantee                               string
+	AddGrant                              string
+	FolderAccessRead                      string
+	FolderAccessUpload                    string
+	FolderAccessManage                    string
+	FolderPermissionsInherited            string
+	InvalidFolderGrant                    string
+	Quarantined                           string
+	ScanFailed                            string
+	QuarantinedFiles                      func(count int) string
+	FileQuarantined                       func(name string) string
+	FileStillQuarantined                  func(reason string) string
+	ReleaseFile                           string
+	ScanAgain                             string
+	FileReleased                          string
 }
 
 var Messages_en_US = &Messages{
@@ -82,6 +135,77 @@ var Messages_en_US = &Messages{
 	MediaLibrary:      "Media Library",
 	UnSupportFileType: "UnSupport FileType",
 	CopyImageURL:      "copy image URL",
+	UsedIn:            "Used In",
+	NotUsedAnywhere:   "This file is not used anywhere",
+	Unused:            "Unused",
+	DeleteFilesInUse: func(v int) string {
+		return fmt.Sprintf(`The files are still used in %v places, deleting them will break those references`, v)
+	},
+	FilesInUseCannotBeDeleted:     "Files in use can not be deleted",
+	FolderNotEmptyCannotBeDeleted: "Folders that are not empty can not be deleted, move or delete their content first",
+	DuplicateFileUploaded: func(name, existing string) string {
+		return fmt.Sprintf(`%s has the same content as %s, the existing file is reused`, name, existing)
+	},
+	EditMetadata: "Edit Metadata",
+	Tags:         "Tags",
+	Uploader:     "Uploader",
+	UploaderID: func(id uint) string {
+		return fmt.Sprintf("User %d", id)
+	},
+	Dimensions:      "Dimensions",
+	DimensionSmall:  "Small (up to 800px)",
+	DimensionMedium: "Medium (up to 1920px)",
+	DimensionLarge:  "Large (over 1920px)",
+	UsageRights:     "Usage Rights",
+	RightsExpired:   "Rights Expired",
+	RightsValid:     "Rights Valid",
+	RightsExpireAt:  "Rights Expire At",
+	RightsExpiredAt: func(date string) string {
+		return fmt.Sprintf("The usage rights of this file expired on %s", date)
+	},
+	UploadedFrom: "Uploaded From",
+	UploadedTo:   "Uploaded To",
+	BulkMetadataHint: func(v int) string {
+		return fmt.Sprintf("Editing %d files, only the filled in values are applied and tags are added", v)
+	},
+	MetadataUpdated:   "Metadata updated",
+	Processing:        "Processing",
+	ProcessingFailed:  "Processing Failed",
+	ProcessAgain:      "Process Again",
+	ProcessingQueued:  "The file will be processed in the background",
+	SetFocalPoint:     "Set Focal Point",
+	FocalPointHint:    "Click the most important point of the image, the sizes without a crop of their own are cropped around it.",
+	ResetFocalPoint:   "Reset Focal Point",
+	FocalPointUpdated: "Focal point updated",
+	InvalidFocalPoint: "Invalid focal point",
+	Share:             "Share",
+	ShareFolder: func(name string) string {
+		return fmt.Sprintf("Share %s", name)
+	},
+	Owner:                      "Owner",
+	User:                       "User",
+	Role:                       "Role",
+	Grantee:                    "User or role",
+	AddGrant:                   "Add",
+	FolderAccessRead:           "Can view",
+	FolderAccessUpload:         "Can upload",
+	FolderAccessManage:         "Can manage",
+	FolderPermissionsInherited: "This folder has no grants of its own, it inherits the permissions of its parent folder.",
+	InvalidFolderGrant:         "Choose a user or role and an access",
+	Quarantined:                "Quarantined",
+	ScanFailed:                 "Scan Failed",
+	QuarantinedFiles: func(count int) string {
+		return fmt.Sprintf("Quarantined (%d)", count)
+	},
+	FileQuarantined: func(name string) string {
+		return fmt.Sprintf("%s did not pass the scan and is quarantined", name)
+	},
+	FileStillQuarantined: func(reason string) string {
+		return fmt.Sprintf("The file is still quarantined: %s", reason)
+	},
+	ReleaseFile:  "Release",
+	ScanAgain:    "Scan Again",
+	FileReleased: "File released",
 }
 
 var Messages_zh_CN = &Messages{
@@ -123,6 +247,77 @@ var Messages_zh_CN = &Messages{
 	MediaLibrary:      "媒体库",
 	UnSupportFileType: "不支持的文件类型",
 	CopyImageURL:      "拷贝图片链接",
+	UsedIn:            "使用位置",
+	NotUsedAnywhere:   "该文件未被使用",
+	Unused:            "未使用",
+	DeleteFilesInUse: func(v int) string {
+		return fmt.Sprintf(`文件仍在 %v 处被使用，删除后这些引用将失效`, v)
+	},
+	FilesInUseCannotBeDeleted:     "正在使用的文件无法删除",
+	FolderNotEmptyCannotBeDeleted: "非空文件夹无法删除，请先移动或删除其中的内容",
+	DuplicateFileUploaded: func(name, existing string) string {
+		return fmt.Sprintf(`%s 与 %s 内容相同，已复用现有文件`, name, existing)
+	},
+	EditMetadata: "编辑元数据",
+	Tags:         "标签",
+	Uploader:     "上传者",
+	UploaderID: func(id uint) string {
+		return fmt.Sprintf("用户 %d", id)
+	},
+	Dimensions:      "尺寸",
+	DimensionSmall:  "小（800px 以内）",
+	DimensionMedium: "中（1920px 以内）",
+	DimensionLarge:  "大（超过 1920px）",
+	UsageRights:     "使用权",
+	RightsExpired:   "使用权已过期",
+	RightsValid:     "使用权有效",
+	RightsExpireAt:  "使用权到期日",
+	RightsExpiredAt: func(date string) string {
+		return fmt.Sprintf("该文件的使用权已于 %s 过期", date)
+	},
+	UploadedFrom: "上传起始日期",
+	UploadedTo:   "上传截止日期",
+	BulkMetadataHint: func(v int) string {
+		return fmt.Sprintf("正在编辑 %d 个文件，只会应用已填写的值，标签会被追加", v)
+	},
+	MetadataUpdated:   "元数据已更新",
+	Processing:        "处理中",
+	ProcessingFailed:  "处理失败",
+	ProcessAgain:      "重新处理",
+	ProcessingQueued:  "文件将在后台处理",
+	SetFocalPoint:     "设置焦点",
+	FocalPointHint:    "点击图片中最重要的位置，没有单独裁剪的尺寸会围绕该点自动裁剪。",
+	ResetFocalPoint:   "重置焦点",
+	FocalPointUpdated: "焦点已更新",
+	InvalidFocalPoint: "无效的焦点",
+	Share:             "共享",
+	ShareFolder: func(name string) string {
+		return fmt.Sprintf("共享 %s", name)
+	},
+	Owner:                      "所有者",
+	User:                       "用户",
+	Role:                       "角色",
+	Grantee:                    "用户或角色",
+	AddGrant:                   "添加",
+	FolderAccessRead:           "可查看",
+	FolderAccessUpload:         "可上传",
+	FolderAccessManage:         "可管理",
+	FolderPermissionsInherited: "此文件夹没有单独的授权，继承上级文件夹的权限。",
+	InvalidFolderGrant:         "请选择用户或角色以及权限",
+	Quarantined:                "已隔离",
+	ScanFailed:                 "扫描失败",
+	QuarantinedFiles: func(count int) string {
+		return fmt.Sprintf("已隔离 (%d)", count)
+	},
+	FileQuarantined: func(name string) string {
+		return fmt.Sprintf("%s 未通过扫描，已被隔离", name)
+	},
+	FileStillQuarantined: func(reason string) string {
+		return fmt.Sprintf("文件仍被隔离：%s", reason)
+	},
+	ReleaseFile:  "解除隔离",
+	ScanAgain:    "重新扫描",
+	FileReleased: "文件已解除隔离",
 }
 
 var Messages_ja_JP = &Messages{
@@ -164,4 +359,75 @@ var Messages_ja_JP = &Messages{
 	MediaLibrary:      "メディアライブラリ",
 	UnSupportFileType: "サポートされていないファイル形式",
 	CopyImageURL:      "画像のURLをコピー",
+	UsedIn:            "使用箇所",
+	NotUsedAnywhere:   "このファイルはどこにも使用されていません",
+	Unused:            "未使用",
+	DeleteFilesInUse: func(v int) string {
+		return fmt.Sprintf(`ファイルはまだ %v 箇所で使用されています。削除するとそれらの参照が壊れます`, v)
+	},
+	FilesInUseCannotBeDeleted:     "使用中のファイルは削除できません",
+	FolderNotEmptyCannotBeDeleted: "空でないフォルダは削除できません。先に中身を移動または削除してください",
+	DuplicateFileUploaded: func(name, existing string) string {
+		return fmt.Sprintf(`%s は %s と同じ内容のため、既存のファイルを再利用しました`, name, existing)
+	},
+	EditMetadata: "メタデータを編集",
+	Tags:         "タグ",
+	Uploader:     "アップロード者",
+	UploaderID: func(id uint) string {
+		return fmt.Sprintf("ユーザー %d", id)
+	},
+	Dimensions:      "サイズ",
+	DimensionSmall:  "小（800px まで）",
+	DimensionMedium: "中（1920px まで）",
+	DimensionLarge:  "大（1920px 超）",
+	UsageRights:     "利用権",
+	RightsExpired:   "利用権期限切れ",
+	RightsValid:     "利用権有効",
+	RightsExpireAt:  "利用権の有効期限",
+	RightsExpiredAt: func(date string) string {
+		return fmt.Sprintf("このファイルの利用権は %s に期限切れになりました", date)
+	},
+	UploadedFrom: "アップロード開始日",
+	UploadedTo:   "アップロード終了日",
+	BulkMetadataHint: func(v int) string {
+		return fmt.Sprintf("%d 件のファイルを編集しています。入力した値のみ適用され、タグは追加されます", v)
+	},
+	MetadataUpdated:   "メタデータを更新しました",
+	Processing:        "処理中",
+	ProcessingFailed:  "処理に失敗しました",
+	ProcessAgain:      "再処理",
+	ProcessingQueued:  "ファイルはバックグラウンドで処理されます",
+	SetFocalPoint:     "フォーカルポイントを設定",
+	FocalPointHint:    "画像の最も重要な位置をクリックしてください。個別にトリミングされていないサイズはその位置を中心に自動でトリミングされます。",
+	ResetFocalPoint:   "フォーカルポイントをリセット",
+	FocalPointUpdated: "フォーカルポイントを更新しました",
+	InvalidFocalPoint: "無効なフォーカルポイントです",
+	Share:             "共有",
+	ShareFolder: func(name string) string {
+		return fmt.Sprintf("%s を共有", name)
+	},
+	Owner:                      "所有者",
+	User:                       "ユーザー",
+	Role:                       "ロール",
+	Grantee:                    "ユーザーまたはロール",
+	AddGrant:                   "追加",
+	FolderAccessRead:           "閲覧可",
+	FolderAccessUpload:         "アップロード可",
+	FolderAccessManage:         "管理可",
+	FolderPermissionsInherited: "このフォルダーには独自の権限がなく、親フォルダーの権限を継承しています。",
+	InvalidFolderGrant:         "ユーザーまたはロールと権限を選択してください",
+	Quarantined:                "隔離済み",
+	ScanFailed:                 "スキャン失敗",
+	QuarantinedFiles: func(count int) string {
+		return fmt.Sprintf("隔離済み (%d)", count)
+	},
+	FileQuarantined: func(name string) string {
+		return fmt.Sprintf("%s はスキャンに合格せず、隔離されました", name)
+	},
+	FileStillQuarantined: func(reason string) string {
+		return fmt.Sprintf("ファイルは引き続き隔離されています：%s", reason)
+	},
+	ReleaseFile:  "隔離を解除",
+	ScanAgain:    "再スキャン",
+	FileReleased: "ファイルの隔離を解除しました",
 }
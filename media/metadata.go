@@ -0,0 +1,454 @@
+package media
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/i18n"
+	. "github.com/qor5/x/v3/ui/vuetify"
+	vx "github.com/qor5/x/v3/ui/vuetifyx"
+	h "github.com/theplant/htmlgo"
+	"gorm.io/gorm"
+
+	"github.com/qor5/admin/v3/media/media_library"
+	"github.com/qor5/admin/v3/presets"
+)
+
+const (
+	MetadataFieldTypeText     = "text"
+	MetadataFieldTypeTextarea = "textarea"
+	MetadataFieldTypeDate     = "date"
+	MetadataFieldTypeSelect   = "select"
+
+	paramTag          = "tag"
+	paramUploader     = "uploader"
+	paramDimension    = "dimension"
+	paramUploadedFrom = "uploaded_from"
+	paramUploadedTo   = "uploaded_to"
+	paramRights       = "rights"
+
+	paramMetadataTags      = "MetadataTags"
+	paramMetadataPrefix    = "Metadata."
+	paramRightsExpireAt    = "RightsExpireAt"
+	metadataDateFormat     = "2006-01-02"
+	dimensionSmall         = "small"
+	dimensionMedium        = "medium"
+	dimensionLarge         = "large"
+	dimensionSmallMaxPx    = 800
+	dimensionMediumMaxPx   = 1920
+	rightsExpired          = "expired"
+	rightsValid            = "valid"
+	dimensionSQLExpression = "GREATEST(COALESCE((media_libraries.file::json->>'Width')::int, 0), COALESCE((media_libraries.file::json->>'Height')::int, 0))"
+)
+
+// MetadataField is a custom metadata field of media library files, such as copyright or photographer
+type MetadataField struct {
+	Name    string
+	Label   string
+	Type    string
+	Options []string
+}
+
+// MediaTag is a tag of a media library file
+type MediaTag struct {
+	ID             uint   `gorm:"primarykey"`
+	MediaLibraryID uint   `gorm:"index"`
+	Name           string `gorm:"index"`
+}
+
+func (*MediaTag) TableName() string {
+	return "media_library_tags"
+}
+
+type (
+	UploaderNamesFunc func(ctx *web.EventContext, userIDs []uint) (map[uint]string, error)
+)
+
+// MetadataFields configures the custom metadata fields that could be edited on the files
+func (b *Builder) MetadataFields(v ...*MetadataField) *Builder {
+	b.metadataFields = append(b.metadataFields, v...)
+	return b
+}
+
+// UploaderNames returns the names shown in the uploader filter, user ids are shown without it
+func (b *Builder) UploaderNames(v UploaderNamesFunc) *Builder {
+	b.uploaderNames = v
+	return b
+}
+
+func normalizeTags(tags []string) (r []string) {
+	for _, t := range tags {
+		for _, s := range strings.Split(t, ",") {
+			if s = strings.TrimSpace(s); s != "" && !slices.Contains(r, s) {
+				r = append(r, s)
+			}
+		}
+	}
+	return
+}
+
+func (b *Builder) fileTags(id uint) (tags []string, err error) {
+	err = b.db.Model(&MediaTag{}).Where("media_library_id = ?", id).Order("name").Pluck("name", &tags).Error
+	return
+}
+
+func setFileTags(tx *gorm.DB, id uint, tags []string, replace bool) (err error) {
+	var existing []string
+	if replace {
+		if err = tx.Where("media_library_id = ?", id).Delete(&MediaTag{}).Error; err != nil {
+			return
+		}
+	} else if err = tx.Model(&MediaTag{}).Where("media_library_id = ?", id).Pluck("name", &existing).Error; err != nil {
+		return
+	}
+	var rows []*MediaTag
+	for _, t := range tags {
+		if !slices.Contains(existing, t) {
+			rows = append(rows, &MediaTag{MediaLibraryID: id, Name: t})
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+	return tx.Create(&rows).Error
+}
+
+// mediaFacets are the filters of the file chooser besides the type and the keyword
+type mediaFacets struct {
+	Tag          string
+	Uploader     uint
+	Dimension    string
+	UploadedFrom string
+	UploadedTo   string
+	Rights       string
+}
+
+func facetsFromContext(ctx *web.EventContext) mediaFacets {
+	uploader, _ := strconv.ParseUint(ctx.Param(paramUploader), 10, 64)
+	return mediaFacets{
+		Tag:          ctx.Param(paramTag),
+		Uploader:     uint(uploader),
+		Dimension:    ctx.Param(paramDimension),
+		UploadedFrom: ctx.Param(paramUploadedFrom),
+		UploadedTo:   ctx.Param(paramUploadedTo),
+		Rights:       ctx.Param(paramRights),
+	}
+}
+
+func (f mediaFacets) apply(db *gorm.DB, keepFolders bool) *gorm.DB {
+	var conds []*gorm.DB
+	newCond := func(query interface{}, args ...interface{}) {
+		conds = append(conds, db.Session(&gorm.Session{NewDB: true}).Where(query, args...))
+	}
+	if f.Tag != "" {
+		newCond("media_libraries.id IN (?)", db.Session(&gorm.Session{NewDB: true}).
+			Model(&MediaTag{}).Select("media_library_id").Where("name = ?", f.Tag))
+	}
+	if f.Uploader != 0 {
+		newCond("media_libraries.user_id = ?", f.Uploader)
+	}
+	switch f.Dimension {
+	case dimensionSmall:
+		newCond(dimensionSQLExpression+" BETWEEN 1 AND ?", dimensionSmallMaxPx)
+	case dimensionMedium:
+		newCond(dimensionSQLExpression+" BETWEEN ? AND ?", dimensionSmallMaxPx+1, dimensionMediumMaxPx)
+	case dimensionLarge:
+		newCond(dimensionSQLExpression+" > ?", dimensionMediumMaxPx)
+	}
+	if t, err := time.ParseInLocation(metadataDateFormat, f.UploadedFrom, time.Local); err == nil {
+		newCond("media_libraries.created_at >= ?", t)
+	}
+	if t, err := time.ParseInLocation(metadataDateFormat, f.UploadedTo, time.Local); err == nil {
+		newCond("media_libraries.created_at < ?", t.AddDate(0, 0, 1))
+	}
+	switch f.Rights {
+	case rightsExpired:
+		newCond("media_libraries.rights_expire_at <= ?", time.Now())
+	case rightsValid:
+		newCond("media_libraries.rights_expire_at IS NULL OR media_libraries.rights_expire_at > ?", time.Now())
+	}
+	if len(conds) == 0 {
+		return db
+	}
+	cond := db.Session(&gorm.Session{NewDB: true})
+	for _, c := range conds {
+		cond = cond.Where(c)
+	}
+	if keepFolders {
+		return db.Where(db.Session(&gorm.Session{NewDB: true}).Where("media_libraries.folder = true").Or(cond))
+	}
+	return db.Where(cond)
+}
+
+func (b *Builder) facetItems(ctx *web.EventContext, msgr *Messages) (tags []string, uploaders []selectItem, err error) {
+	scoped := b.scopedDB(b.db, ctx).Where("media_libraries.folder = false")
+	if err = b.db.Model(&MediaTag{}).Distinct("name").
+		Where("media_library_id IN (?)", scoped.Session(&gorm.Session{}).Select("media_libraries.id")).
+		Order("name").Pluck("name", &tags).Error; err != nil {
+		return
+	}
+	var userIDs []uint
+	if err = scoped.Session(&gorm.Session{}).Distinct("media_libraries.user_id").
+		Where("media_libraries.user_id <> 0").
+		Order("media_libraries.user_id").Pluck("media_libraries.user_id", &userIDs).Error; err != nil {
+		return
+	}
+	names := map[uint]string{}
+	if b.uploaderNames != nil && len(userIDs) > 0 {
+		if names, err = b.uploaderNames(ctx, userIDs); err != nil {
+			return
+		}
+	}
+	for _, id := range userIDs {
+		name := names[id]
+		if name == "" {
+			name = msgr.UploaderID(id)
+		}
+		uploaders = append(uploaders, selectItem{Text: name, Value: fmt.Sprint(id)})
+	}
+	return
+}
+
+// mediaFacetsComponent renders the faceted filters, changes reload the file list through the given event
+func (b *Builder) mediaFacetsComponent(ctx *web.EventContext, msgr *Messages, facets mediaFacets, changeEvent func(param string) string) h.HTMLComponent {
+	tags, uploaders, err := b.facetItems(ctx, msgr)
+	if err != nil {
+		panic(err)
+	}
+	selectFacet := func(param, label, value string, items interface{}) h.HTMLComponent {
+		return VCol(
+			VSelect().Items(items).ItemTitle("Text").ItemValue("Value").
+				Label(label).
+				Clearable(true).
+				Attr(web.VField(param, value)...).
+				Attr("@update:model-value", changeEvent(param)).
+				Density(DensityCompact).Variant(FieldVariantOutlined).HideDetails(true),
+		).Cols(2)
+	}
+	tagItems := make([]selectItem, 0, len(tags))
+	for _, t := range tags {
+		tagItems = append(tagItems, selectItem{Text: t, Value: t})
+	}
+	uploader := ""
+	if facets.Uploader != 0 {
+		uploader = fmt.Sprint(facets.Uploader)
+	}
+	return VRow(
+		selectFacet(paramTag, msgr.Tags, facets.Tag, tagItems),
+		selectFacet(paramUploader, msgr.Uploader, uploader, uploaders),
+		selectFacet(paramDimension, msgr.Dimensions, facets.Dimension, []selectItem{
+			{Text: msgr.DimensionSmall, Value: dimensionSmall},
+			{Text: msgr.DimensionMedium, Value: dimensionMedium},
+			{Text: msgr.DimensionLarge, Value: dimensionLarge},
+		}),
+		selectFacet(paramRights, msgr.UsageRights, facets.Rights, []selectItem{
+			{Text: msgr.RightsExpired, Value: rightsExpired},
+			{Text: msgr.RightsValid, Value: rightsValid},
+		}),
+		VCol(
+			VTextField().Type("date").Label(msgr.UploadedFrom).
+				Attr(web.VField(paramUploadedFrom, facets.UploadedFrom)...).
+				Attr("@update:model-value", changeEvent(paramUploadedFrom)).
+				Density(DensityCompact).Variant(FieldVariantOutlined).HideDetails(true),
+		).Cols(2),
+		VCol(
+			VTextField().Type("date").Label(msgr.UploadedTo).
+				Attr(web.VField(paramUploadedTo, facets.UploadedTo)...).
+				Attr("@update:model-value", changeEvent(paramUploadedTo)).
+				Density(DensityCompact).Variant(FieldVariantOutlined).HideDetails(true),
+		).Cols(2),
+	).Dense(true)
+}
+
+func (b *Builder) updateMetadataIDs(ctx *web.EventContext) (objs []media_library.MediaLibrary, err error) {
+	var ids []uint
+	for _, s := range strings.Split(ctx.Param(ParamMediaIDS), ",") {
+		if id, pErr := strconv.ParseUint(s, 10, 64); pErr == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	err = b.scopedDB(b.db, ctx).Where("media_libraries.id IN ? AND media_libraries.folder = false", ids).Find(&objs).Error
+	return
+}
+
+func metadataFieldComponent(f *MetadataField, value string) h.HTMLComponent {
+	name := paramMetadataPrefix + f.Name
+	label := f.Label
+	if label == "" {
+		label = f.Name
+	}
+	switch f.Type {
+	case MetadataFieldTypeTextarea:
+		return VTextarea().Label(label).Rows(2).Attr(web.VField(name, value)...).Variant(FieldVariantOutlined).Density(DensityCompact)
+	case MetadataFieldTypeDate:
+		return VTextField().Type("date").Label(label).Attr(web.VField(name, value)...).Variant(FieldVariantOutlined).Density(DensityCompact)
+	case MetadataFieldTypeSelect:
+		return VSelect().Items(f.Options).Label(label).Clearable(true).Attr(web.VField(name, value)...).Variant(FieldVariantOutlined).Density(DensityCompact)
+	default:
+		return VTextField().Label(label).Attr(web.VField(name, value)...).Variant(FieldVariantOutlined).Density(DensityCompact)
+	}
+}
+
+func editMetadataDialog(mb *Builder) web.EventFunc {
+	return func(ctx *web.EventContext) (r web.EventResponse, err error) {
+		var (
+			pMsgr = i18n.MustGetModuleMessages(ctx.R, presets.CoreI18nModuleKey, Messages_en_US).(*presets.Messages)
+			msgr  = i18n.MustGetModuleMessages(ctx.R, I18nMediaLibraryKey, Messages_en_US).(*Messages)
+			objs  []media_library.MediaLibrary
+			tags  []string
+		)
+		if objs, err = mb.updateMetadataIDs(ctx); err != nil {
+			return
+		}
+		if len(objs) == 0 {
+			presets.ShowMessage(&r, pMsgr.RecordNotFound, ColorError)
+			return
+		}
+		bulk := len(objs) > 1
+		// a single file is edited with its current values, several files start empty
+		var (
+			metadata  = media_library.Metadata{}
+			expireAt  string
+			allTags   []string
+			scopedIDs = mb.scopedDB(mb.db, ctx).Select("media_libraries.id")
+		)
+		if !bulk {
+			if tags, err = mb.fileTags(objs[0].ID); err != nil {
+				return
+			}
+			if objs[0].Metadata != nil {
+				metadata = objs[0].Metadata
+			}
+			if objs[0].RightsExpireAt != nil {
+				expireAt = objs[0].RightsExpireAt.Format(metadataDateFormat)
+			}
+		}
+		if err = mb.db.Model(&MediaTag{}).Distinct("name").Where("media_library_id IN (?)", scopedIDs).
+			Order("name").Pluck("name", &allTags).Error; err != nil {
+			return
+		}
+		fields := []h.HTMLComponent{
+			h.If(bulk, VAlert(h.Text(msgr.BulkMetadataHint(len(objs)))).Density(DensityCompact).Variant(VariantTonal).Class("mb-4")),
+			VCombobox().Items(allTags).Label(msgr.Tags).
+				Multiple(true).Chips(true).ClosableChips(true).
+				Attr(web.VField(paramMetadataTags, tags)...).
+				Variant(FieldVariantOutlined).Density(DensityCompact),
+		}
+		for _, f := range mb.metadataFields {
+			fields = append(fields, metadataFieldComponent(f, metadata[f.Name]))
+		}
+		fields = append(fields,
+			VTextField().Type("date").Label(msgr.RightsExpireAt).
+				Attr(web.VField(paramRightsExpireAt, expireAt)...).
+				Variant(FieldVariantOutlined).Density(DensityCompact),
+		)
+		r.UpdatePortals = append(r.UpdatePortals, &web.PortalUpdate{
+			Name: editMetadataDialogPortalName,
+			Body: web.Scope(
+				vx.VXDialog(fields...).
+					Attr("v-model", "dialogLocals.show").
+					Title(msgr.EditMetadata).
+					Width(560).
+					CancelText(pMsgr.Cancel).
+					OkText(pMsgr.Update).
+					Attr("@click:ok", web.Plaid().BeforeScript("dialogLocals.show=false").EventFunc(SaveMetadataEvent).Queries(ctx.Queries()).Go()),
+			).VSlot("{locals:dialogLocals}").Init("{show:true}"),
+		})
+		return
+	}
+}
+
+func saveMetadata(mb *Builder) web.EventFunc {
+	return func(ctx *web.EventContext) (r web.EventResponse, err error) {
+		var (
+			msgr     = i18n.MustGetModuleMessages(ctx.R, I18nMediaLibraryKey, Messages_en_US).(*Messages)
+			objs     []media_library.MediaLibrary
+			tags     = normalizeTags(ctx.R.Form[paramMetadataTags])
+			expireAt *time.Time
+		)
+		if objs, err = mb.updateMetadataIDs(ctx); err != nil {
+			return
+		}
+		for i := range objs {
+			if err = mb.updateMetadataIsAllowed(ctx.R, &objs[i]); err != nil {
+				return
+			}
+		}
+		if v := ctx.R.FormValue(paramRightsExpireAt); v != "" {
+			t, pErr := time.ParseInLocation(metadataDateFormat, v, time.Local)
+			if pErr != nil {
+				presets.ShowMessage(&r, pErr.Error(), ColorError)
+				return r, nil
+			}
+			expireAt = &t
+		}
+		bulk := len(objs) > 1
+		err = mb.db.Transaction(func(tx *gorm.DB) (dbErr error) {
+			for _, obj := range objs {
+				old := obj
+				metadata := media_library.Metadata{}
+				for k, v := range obj.Metadata {
+					metadata[k] = v
+				}
+				for _, f := range mb.metadataFields {
+					v := strings.TrimSpace(ctx.R.FormValue(paramMetadataPrefix + f.Name))
+					if v == "" {
+						// several files only get the values that were filled in
+						if !bulk {
+							delete(metadata, f.Name)
+						}
+						continue
+					}
+					metadata[f.Name] = v
+				}
+				obj.Metadata = metadata
+				if expireAt != nil || !bulk {
+					obj.RightsExpireAt = expireAt
+				}
+				if dbErr = tx.Model(&media_library.MediaLibrary{}).Where("id = ?", obj.ID).
+					Updates(map[string]interface{}{"metadata": obj.Metadata, "rights_expire_at": obj.RightsExpireAt}).Error; dbErr != nil {
+					return
+				}
+				if dbErr = setFileTags(tx, obj.ID, tags, !bulk); dbErr != nil {
+					return
+				}
+				mb.onEdit(ctx, old, obj)
+			}
+			return
+		})
+		if err != nil {
+			return
+		}
+		presets.ShowMessage(&r, msgr.MetadataUpdated, ColorSuccess)
+		web.AppendRunScripts(&r,
+			web.Plaid().EventFunc(ImageJumpPageEvent).
+				MergeQuery(true).
+				Queries(ctx.Queries()).
+				Go())
+		return
+	}
+}
+
+// rightsExpiredAlert flags a file used in a MediaBox whose usage rights expired
+func rightsExpiredAlert(db *gorm.DB, mediaBox *media_library.MediaBox, msgr *Messages) h.HTMLComponent {
+	if db == nil || mediaBox == nil {
+		return nil
+	}
+	id, err := strconv.ParseUint(mediaBox.ID.String(), 10, 64)
+	if err != nil || id == 0 {
+		return nil
+	}
+	var m media_library.MediaLibrary
+	if err = db.Select("id", "rights_expire_at").Where("id = ?", id).Limit(1).Find(&m).Error; err != nil || !m.RightsExpired(time.Now()) {
+		return nil
+	}
+	return VAlert(h.Text(msgr.RightsExpiredAt(m.RightsExpireAt.Format(metadataDateFormat)))).
+		Type(ColorWarning).Variant(VariantTonal).Density(DensityCompact).Class("mb-2")
+}
@@ -0,0 +1,166 @@
+package media
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/qor5/admin/v3/media/media_library"
+	"github.com/qor5/admin/v3/presets"
+	"github.com/qor5/admin/v3/presets/gorm2op"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	require.Equal(t, []string{"summer", "beach", "sea"}, normalizeTags([]string{"summer, beach", " beach ", "", "sea,,summer"}))
+	require.Empty(t, normalizeTags(nil))
+}
+
+func metadataTestBuilder(t *testing.T) (*Builder, *presets.Builder) {
+	t.Helper()
+	require.NoError(t, testDB.Exec("DELETE FROM media_libraries").Error)
+	require.NoError(t, testDB.Exec("DELETE FROM media_library_tags").Error)
+	pb := presets.New().DataOperator(gorm2op.DataOperator(testDB))
+	b := New(testDB).MetadataFields(
+		&MetadataField{Name: "Copyright"},
+		&MetadataField{Name: "Photographer"},
+	)
+	require.NoError(t, b.Install(pb))
+	return b, pb
+}
+
+func TestSaveMetadata(t *testing.T) {
+	b, pb := metadataTestBuilder(t)
+	a := mkRow(t, 1, false, 0, "a.png")
+	c := mkRow(t, 1, false, 0, "c.png")
+
+	save := func(ids string, params url.Values) {
+		t.Helper()
+		params.Set(ParamMediaIDS, ids)
+		ctx := scopeEventContext(t, pb, 1, params)
+		require.NoError(t, ctx.R.ParseForm())
+		_, err := saveMetadata(b)(ctx)
+		require.NoError(t, err)
+	}
+	tags := func(id uint) []string {
+		t.Helper()
+		tags, err := b.fileTags(id)
+		require.NoError(t, err)
+		return tags
+	}
+
+	save(fmt.Sprint(a.ID), url.Values{
+		paramMetadataTags:                    {"summer, beach", "beach"},
+		paramMetadataPrefix + "Copyright":    {" ACME "},
+		paramMetadataPrefix + "Unconfigured": {"ignored"},
+		paramRightsExpireAt:                  {"2020-01-02"},
+	})
+	got := reload(t, a.ID)
+	require.Equal(t, media_library.Metadata{"Copyright": "ACME"}, got.Metadata)
+	require.Equal(t, "2020-01-02", got.RightsExpireAt.Format(metadataDateFormat))
+	require.True(t, got.RightsExpired(time.Now()))
+	require.Equal(t, []string{"beach", "summer"}, tags(a.ID))
+
+	// several files only get the values that are filled in, tags are added
+	save(fmt.Sprintf("%d,%d", a.ID, c.ID), url.Values{
+		paramMetadataTags:                    {"archive"},
+		paramMetadataPrefix + "Photographer": {"Bob"},
+	})
+	got = reload(t, a.ID)
+	require.Equal(t, media_library.Metadata{"Copyright": "ACME", "Photographer": "Bob"}, got.Metadata)
+	require.NotNil(t, got.RightsExpireAt, "the expiry is kept when it is not filled in")
+	require.Equal(t, []string{"archive", "beach", "summer"}, tags(a.ID))
+	require.Equal(t, media_library.Metadata{"Photographer": "Bob"}, reload(t, c.ID).Metadata)
+	require.Equal(t, []string{"archive"}, tags(c.ID))
+
+	// a single file is saved as edited
+	save(fmt.Sprint(a.ID), url.Values{
+		paramMetadataTags:                    {"beach"},
+		paramMetadataPrefix + "Photographer": {"Bob"},
+	})
+	got = reload(t, a.ID)
+	require.Equal(t, media_library.Metadata{"Photographer": "Bob"}, got.Metadata)
+	require.Nil(t, got.RightsExpireAt)
+	require.Equal(t, []string{"beach"}, tags(a.ID))
+
+	// the tags of deleted files are removed with them
+	_, err := doDelete(b)(scopeEventContext(t, pb, 1, url.Values{ParamMediaIDS: {fmt.Sprint(a.ID)}}))
+	require.NoError(t, err)
+	require.Empty(t, tags(a.ID))
+	require.Equal(t, []string{"archive"}, tags(c.ID))
+}
+
+func TestMediaFacets(t *testing.T) {
+	b, pb := metadataTestBuilder(t)
+	var (
+		past   = time.Now().AddDate(0, 0, -1)
+		future = time.Now().AddDate(0, 0, 1)
+	)
+	mkFile := func(userID uint, name string, width, height int, createdAt time.Time, expireAt *time.Time, tags ...string) uint {
+		m := &media_library.MediaLibrary{UserID: userID, RightsExpireAt: expireAt}
+		m.File.FileName = name
+		m.File.Width, m.File.Height = width, height
+		require.NoError(t, testDB.Create(m).Error)
+		require.NoError(t, testDB.Model(m).UpdateColumn("created_at", createdAt).Error)
+		require.NoError(t, setFileTags(testDB, m.ID, tags, true))
+		return m.ID
+	}
+	jan := time.Date(2024, 1, 15, 12, 0, 0, 0, time.Local)
+	feb := time.Date(2024, 2, 15, 12, 0, 0, 0, time.Local)
+	small := mkFile(1, "small.png", 640, 480, jan, &past, "beach")
+	medium := mkFile(1, "medium.png", 1920, 1080, feb, &future, "beach", "summer")
+	large := mkFile(2, "large.png", 4000, 3000, feb, nil)
+	folder := mkRow(t, 1, true, 0, "folder").ID
+
+	for _, c := range []struct {
+		name        string
+		facets      mediaFacets
+		keepFolders bool
+		expected    []uint
+	}{
+		{name: "none", facets: mediaFacets{}, expected: []uint{small, medium, large, folder}},
+		{name: "tag", facets: mediaFacets{Tag: "beach"}, expected: []uint{small, medium}},
+		{name: "uploader", facets: mediaFacets{Uploader: 2}, expected: []uint{large}},
+		{name: "small", facets: mediaFacets{Dimension: dimensionSmall}, expected: []uint{small}},
+		{name: "medium", facets: mediaFacets{Dimension: dimensionMedium}, expected: []uint{medium}},
+		{name: "large", facets: mediaFacets{Dimension: dimensionLarge}, expected: []uint{large}},
+		{name: "uploaded from", facets: mediaFacets{UploadedFrom: "2024-02-15"}, expected: []uint{medium, large, folder}},
+		{name: "uploaded to", facets: mediaFacets{UploadedTo: "2024-01-15"}, expected: []uint{small}},
+		{name: "rights expired", facets: mediaFacets{Rights: rightsExpired}, expected: []uint{small}},
+		{name: "rights valid", facets: mediaFacets{Rights: rightsValid}, expected: []uint{medium, large, folder}},
+		{name: "combined", facets: mediaFacets{Tag: "beach", UploadedFrom: "2024-02-01"}, expected: []uint{medium}},
+		{name: "keep folders", facets: mediaFacets{Uploader: 2}, keepFolders: true, expected: []uint{large, folder}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var ids []uint
+			require.NoError(t, c.facets.apply(testDB.Model(&media_library.MediaLibrary{}), c.keepFolders).
+				Order("media_libraries.id").Pluck("media_libraries.id", &ids).Error)
+			require.Equal(t, c.expected, ids)
+		})
+	}
+
+	tags, uploaders, err := b.facetItems(scopeEventContext(t, pb, 1, nil), Messages_en_US)
+	require.NoError(t, err)
+	require.Equal(t, []string{"beach", "summer"}, tags)
+	require.Equal(t, []selectItem{{Text: Messages_en_US.UploaderID(1), Value: "1"}, {Text: Messages_en_US.UploaderID(2), Value: "2"}}, uploaders)
+}
+
+func TestRightsExpiredAlert(t *testing.T) {
+	require.NoError(t, testDB.Exec("DELETE FROM media_libraries").Error)
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	expired := &media_library.MediaLibrary{RightsExpireAt: &past}
+	valid := &media_library.MediaLibrary{RightsExpireAt: &future}
+	require.NoError(t, testDB.Create(expired).Error)
+	require.NoError(t, testDB.Create(valid).Error)
+
+	box := func(id uint) *media_library.MediaBox {
+		return &media_library.MediaBox{ID: json.Number(fmt.Sprint(id))}
+	}
+	require.NotNil(t, rightsExpiredAlert(testDB, box(expired.ID), Messages_en_US))
+	require.Nil(t, rightsExpiredAlert(testDB, box(valid.ID), Messages_en_US))
+	require.Nil(t, rightsExpiredAlert(testDB, &media_library.MediaBox{}, Messages_en_US))
+	require.Nil(t, rightsExpiredAlert(testDB, nil, Messages_en_US))
+}
@@ -51,4 +51,8 @@ const (
 	renameDialogPortalName            = "media_rename_dialog_portal_name"
 	updateDescriptionDialogPortalName = "media_update_description_dialog_portal_name"
 	moveToFolderDialogPortalName      = "media_move_to_folder_dialog_portal_name"
+	usedInDialogPortalName            = "media_used_in_dialog_portal_name"
+	editMetadataDialogPortalName      = "media_edit_metadata_dialog_portal_name"
+	focalPointDialogPortalName        = "media_focal_point_dialog_portal_name"
+	shareFolderDialogPortalName       = "media_share_folder_dialog_portal_name"
 )
@@ -8,14 +8,17 @@ import "net/http"
 // right: permPolicy.On("*:media_libraries:*")
 // right: permPolicy.On("*:media_libraries:1")
 const (
-	PermUpload      = "perm_media_library_upload"
-	PermDelete      = "perm_media_library_delete"
-	PermUpdateDesc  = "perm_media_library_update_desc"
-	PermUpdateName  = "perm_media_library_update_name"
-	PermMovieTo     = "perm_media_library_move_to"
-	PermCopyURL     = "perm_media_library_copy_url"
-	PermNewFolder   = "perm_media_library_new_folder"
-	PermListFolders = "perm_media_library_list_folders"
+	PermUpload         = "perm_media_library_upload"
+	PermDelete         = "perm_media_library_delete"
+	PermUpdateDesc     = "perm_media_library_update_desc"
+	PermUpdateName     = "perm_media_library_update_name"
+	PermMovieTo        = "perm_media_library_move_to"
+	PermCopyURL        = "perm_media_library_copy_url"
+	PermNewFolder      = "perm_media_library_new_folder"
+	PermListFolders    = "perm_media_library_list_folders"
+	PermUpdateMetadata = "perm_media_library_update_metadata"
+	PermFocalPoint     = "perm_media_library_focal_point"
+	PermQuarantine     = "perm_media_library_quarantine"
 )
 
 func (mb *Builder) uploadIsAllowed(r *http.Request) error {
@@ -52,3 +55,18 @@ func (mb *Builder) newFolderIsAllowed(r *http.Request) error {
 func (mb *Builder) listFoldersIsAllowed(r *http.Request) error {
 	return mb.mb.Info().Verifier().Do(PermListFolders).WithReq(r).IsAllowed()
 }
+
+func (mb *Builder) updateMetadataIsAllowed(r *http.Request, obj interface{}) error {
+	if obj == nil {
+		return mb.mb.Info().Verifier().Do(PermUpdateMetadata).WithReq(r).IsAllowed()
+	}
+	return mb.mb.Info().Verifier().Do(PermUpdateMetadata).ObjectOn(obj).WithReq(r).IsAllowed()
+}
+
+func (mb *Builder) updateFocalPointIsAllowed(r *http.Request, obj interface{}) error {
+	return mb.mb.Info().Verifier().Do(PermFocalPoint).ObjectOn(obj).WithReq(r).IsAllowed()
+}
+
+func (mb *Builder) quarantineIsAllowed(r *http.Request) error {
+	return mb.mb.Info().Verifier().Do(PermQuarantine).WithReq(r).IsAllowed()
+}
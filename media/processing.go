@@ -0,0 +1,140 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/i18n"
+	. "github.com/qor5/x/v3/ui/vuetify"
+	h "github.com/theplant/htmlgo"
+
+	"github.com/qor5/admin/v3/media/base"
+	"github.com/qor5/admin/v3/media/media_library"
+	"github.com/qor5/admin/v3/presets"
+	"github.com/qor5/admin/v3/worker"
+)
+
+const (
+	ProcessJobName = "MediaLibraryProcess"
+
+	processorHandlerName = "media_processor"
+)
+
+// ProcessJobArgs are the arguments of the process job
+type ProcessJobArgs struct {
+	MediaLibraryID uint
+}
+
+// Processor registers p as the media handler of the files it could handle, such
+// as the ffmpeg handler of videos and audios. Uploads are stored as they are and
+// a job of the worker processes them, the status is shown on the files.
+func (b *Builder) Processor(w *worker.Builder, p base.MediaProcessor) *worker.JobBuilder {
+	base.RegisterMediaHandler(processorHandlerName, p)
+	b.processor = p
+	b.processJob = w.NewJob(ProcessJobName).
+		Resource(&ProcessJobArgs{}).
+		Handler(func(ctx context.Context, job worker.QorJobInterface) error {
+			jobInfo, err := job.GetJobInfo()
+			if err != nil {
+				return err
+			}
+			args, _ := jobInfo.Argument.(*ProcessJobArgs)
+			if args == nil || args.MediaLibraryID == 0 {
+				return errors.New("no media library file to process")
+			}
+			return b.process(ctx, args.MediaLibraryID)
+		})
+	return b.processJob
+}
+
+func (b *Builder) saveProcessingResult(m *media_library.MediaLibrary) error {
+	return b.db.Model(&media_library.MediaLibrary{}).Where("id = ?", m.ID).UpdateColumn("file", m.File).Error
+}
+
+func (b *Builder) process(ctx context.Context, id uint) (err error) {
+	var m media_library.MediaLibrary
+	if err = b.db.First(&m, id).Error; err != nil {
+		return
+	}
+	m.File.ProcessingStatus = base.ProcessingInProgress
+	if err = b.saveProcessingResult(&m); err != nil {
+		return
+	}
+	if err = b.processor.Process(ctx, &m.File, &base.Option{}); err != nil {
+		m.File.ProcessingStatus = base.ProcessingFailed
+		m.File.ProcessingError = err.Error()
+		return errors.Join(err, b.saveProcessingResult(&m))
+	}
+	return b.saveProcessingResult(&m)
+}
+
+// enqueueProcessing adds a process job of the file if it waits for the processor
+func (b *Builder) enqueueProcessing(r *http.Request, m *media_library.MediaLibrary) error {
+	if b.processJob == nil || m.File.ProcessingStatus != base.ProcessingPending || m.Quarantined() {
+		return nil
+	}
+	_, err := b.processJob.Enqueue(r, &ProcessJobArgs{MediaLibraryID: m.ID})
+	return err
+}
+
+func reprocess(mb *Builder) web.EventFunc {
+	return func(ctx *web.EventContext) (r web.EventResponse, err error) {
+		obj, ok := wrapFirst(mb, ctx, &r)
+		if !ok {
+			return
+		}
+		if err = mb.uploadIsAllowed(ctx.R); err != nil {
+			return
+		}
+		if mb.processor == nil || !mb.processor.CouldHandle(&obj.File) {
+			return
+		}
+		msgr := i18n.MustGetModuleMessages(ctx.R, I18nMediaLibraryKey, Messages_en_US).(*Messages)
+		obj.File.ProcessingStatus = base.ProcessingPending
+		obj.File.ProcessingError = ""
+		if err = mb.saveProcessingResult(&obj); err != nil {
+			return
+		}
+		if err = mb.enqueueProcessing(ctx.R, &obj); err != nil {
+			return
+		}
+		presets.ShowMessage(&r, msgr.ProcessingQueued, ColorSuccess)
+		web.AppendRunScripts(&r,
+			web.Plaid().EventFunc(ImageJumpPageEvent).
+				MergeQuery(true).
+				Queries(ctx.Queries()).
+				Go())
+		return
+	}
+}
+
+// formatDuration formats seconds as m:ss or h:mm:ss
+func formatDuration(seconds float64) string {
+	d := time.Duration(math.Round(seconds)) * time.Second
+	hours, minutes, secs := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, secs)
+	}
+	return fmt.Sprintf("%d:%02d", minutes, secs)
+}
+
+// processingChip shows the processing status of videos and audios, the duration once they are processed
+func processingChip(f *media_library.MediaLibrary, msgr *Messages) h.HTMLComponent {
+	switch f.File.ProcessingStatus {
+	case base.ProcessingPending, base.ProcessingInProgress:
+		return VChip(h.Text(msgr.Processing)).Color(ColorInfo).Size(SizeXSmall).PrependIcon("mdi-timer-sand")
+	case base.ProcessingFailed:
+		return VChip(h.Text(msgr.ProcessingFailed)).Color(ColorError).Size(SizeXSmall).
+			Attr("v-tooltip:bottom", h.JSONString(f.File.ProcessingError))
+	case base.ProcessingDone:
+		if f.File.Duration > 0 {
+			return VChip(h.Text(formatDuration(f.File.Duration))).Size(SizeXSmall).PrependIcon("mdi-clock-outline")
+		}
+	}
+	return nil
+}
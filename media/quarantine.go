@@ -0,0 +1,304 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"strings"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/i18n"
+	"github.com/qor5/x/v3/oss"
+	. "github.com/qor5/x/v3/ui/vuetify"
+	h "github.com/theplant/htmlgo"
+	"gorm.io/gorm"
+
+	"github.com/qor5/admin/v3/media/base"
+	"github.com/qor5/admin/v3/media/media_library"
+	"github.com/qor5/admin/v3/presets"
+)
+
+const (
+	paramQuarantine = "quarantine"
+	quarantineOnly  = "true"
+)
+
+// UploadScanners scans every upload with the scanners before it is committed.
+// The uploads a scanner does not find clean, or fails to scan, are quarantined:
+// they are held back in the quarantine storage instead of the storage, hidden
+// from the file choosers, and the editors allowed PermQuarantine release or
+// delete them in the media library. QuarantineStorage is required with them.
+func (b *Builder) UploadScanners(v ...base.UploadScanner) *Builder {
+	b.uploadScanners = append(b.uploadScanners, v...)
+	return b
+}
+
+// QuarantineStorage sets the private storage the quarantined files are held in until they are released,
+// Install fails without it if UploadScanners is set. It must not be served, and instances must share it.
+func (b *Builder) QuarantineStorage(v oss.StorageInterface) *Builder {
+	b.quarantineStorage = v
+	return b
+}
+
+var errNoQuarantineStorage = errors.New("media: QuarantineStorage is required to quarantine the uploads")
+
+func (b *Builder) getQuarantineStorage() (oss.StorageInterface, error) {
+	if b.quarantineStorage == nil {
+		return nil, errNoQuarantineStorage
+	}
+	return b.quarantineStorage, nil
+}
+
+func quarantinePath(id uint) string {
+	return fmt.Sprintf("/%d", id)
+}
+
+// openUpload opens an uploaded file, a *multipart.FileHeader or an *os.File, from its start
+func openUpload(file interface{}) (io.ReadCloser, error) {
+	switch f := file.(type) {
+	case *multipart.FileHeader:
+		return f.Open()
+	case *os.File:
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return io.NopCloser(f), nil
+	}
+	return nil, fmt.Errorf("unsupported upload %T", file)
+}
+
+// scanUpload scans an uploaded file, status is empty if all the scanners found it clean
+func (b *Builder) scanUpload(ctx context.Context, fileName string, file interface{}) (status, reason string, err error) {
+	if len(b.uploadScanners) == 0 {
+		return
+	}
+	status, reason, err = b.scan(ctx, fileName, func() (io.ReadCloser, error) {
+		return openUpload(file)
+	})
+	if f, ok := file.(*os.File); ok && err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	return
+}
+
+// holdQuarantinedFile writes the uploaded file to the quarantine storage
+func (b *Builder) holdQuarantinedFile(ctx context.Context, id uint, file interface{}) error {
+	r, err := openUpload(file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	storage, err := b.getQuarantineStorage()
+	if err != nil {
+		return err
+	}
+	_, err = storage.Put(ctx, quarantinePath(id), r)
+	return err
+}
+
+// storeReleasedFile copies a released file from the quarantine storage to the storage, and saves it
+func (b *Builder) storeReleasedFile(ctx *web.EventContext, obj *media_library.MediaLibrary) (err error) {
+	storage, err := b.getQuarantineStorage()
+	if err != nil {
+		return
+	}
+	f, err := storage.Get(ctx.R.Context(), quarantinePath(obj.ID))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fileName := obj.File.FileName
+	if err = obj.File.Scan(f); err != nil {
+		return
+	}
+	obj.File.FileName = fileName
+	if err = b.saverFunc(b.db, obj, fmt.Sprint(obj.ID), ctx); err != nil {
+		return
+	}
+	return storage.Delete(ctx.R.Context(), quarantinePath(obj.ID))
+}
+
+// removeQuarantinedFiles removes the files held back by the quarantine of the deleted files
+func (b *Builder) removeQuarantinedFiles(ctx context.Context, objs []media_library.MediaLibrary) (err error) {
+	for _, obj := range objs {
+		if !obj.Quarantined() {
+			continue
+		}
+		storage, sErr := b.getQuarantineStorage()
+		if sErr != nil {
+			return sErr
+		}
+		if dErr := storage.Delete(ctx, quarantinePath(obj.ID)); dErr != nil && !errors.Is(dErr, os.ErrNotExist) {
+			err = errors.Join(err, dErr)
+		}
+	}
+	return
+}
+
+func (b *Builder) scan(ctx context.Context, fileName string, open func() (io.ReadCloser, error)) (status, reason string, err error) {
+	for _, s := range b.uploadScanners {
+		var r io.ReadCloser
+		if r, err = open(); err != nil {
+			return
+		}
+		res, sErr := s.Scan(ctx, fileName, r)
+		r.Close()
+		// a file that could not be scanned is not trusted either
+		if sErr != nil {
+			return media_library.QuarantineScanFailed, sErr.Error(), nil
+		}
+		if !res.Clean {
+			return media_library.QuarantineInfected, res.Reason, nil
+		}
+	}
+	return
+}
+
+// scopeQuarantine hides the quarantined files, except in the media library of the editors who could release them
+func (b *Builder) scopeQuarantine(db *gorm.DB, ctx *web.EventContext, keepFolders bool) *gorm.DB {
+	if !b.quarantineIsVisible(ctx) {
+		return db.Where("media_libraries.quarantine_status = ''")
+	}
+	if ctx.Param(paramQuarantine) != quarantineOnly {
+		return db
+	}
+	if keepFolders {
+		return db.Where("media_libraries.folder = true OR media_libraries.quarantine_status <> ''")
+	}
+	return db.Where("media_libraries.quarantine_status <> ''")
+}
+
+func (b *Builder) quarantineIsVisible(ctx *web.EventContext) bool {
+	inMediaLibrary := strings.Contains(ctx.R.RequestURI, "/"+b.mb.Info().URIName())
+	return inMediaLibrary && b.quarantineIsAllowed(ctx.R) == nil
+}
+
+// quarantineSwitch switches the media library between all the files and the quarantined ones
+func (b *Builder) quarantineSwitch(ctx *web.EventContext, msgr *Messages, changeEvent func(param string) string) h.HTMLComponent {
+	if !b.quarantineIsVisible(ctx) {
+		return nil
+	}
+	var count int64
+	if err := b.scopedDB(b.db, ctx).Where("media_libraries.quarantine_status <> ''").Count(&count).Error; err != nil {
+		panic(err)
+	}
+	only := ctx.Param(paramQuarantine) == quarantineOnly
+	if count == 0 && !only {
+		return nil
+	}
+	return VSwitch().Label(msgr.QuarantinedFiles(int(count))).
+		Color(ColorError).
+		Attr(web.VField(paramQuarantine, only)...).
+		Attr("@update:model-value", changeEvent(paramQuarantine)).
+		Density(DensityCompact).HideDetails(true).Class("mr-4")
+}
+
+func quarantineChip(f *media_library.MediaLibrary, msgr *Messages) h.HTMLComponent {
+	if !f.Quarantined() {
+		return nil
+	}
+	text := msgr.Quarantined
+	if f.QuarantineStatus == media_library.QuarantineScanFailed {
+		text = msgr.ScanFailed
+	}
+	return VChip(h.Text(text)).Color(ColorError).Size(SizeXSmall).Class("ml-1").
+		PrependIcon("mdi-shield-alert").
+		Attr("v-tooltip:bottom", h.JSONString(f.QuarantineReason))
+}
+
+// quarantineMenus are the menu items of the quarantined files
+func (b *Builder) quarantineMenus(ctx *web.EventContext, f *media_library.MediaLibrary, msgr *Messages, event func(eventFunc string) string) []h.HTMLComponent {
+	if !f.Quarantined() || b.quarantineIsAllowed(ctx.R) != nil {
+		return nil
+	}
+	return []h.HTMLComponent{
+		VListItem(h.Text(msgr.ReleaseFile)).Attr("@click", event(ReleaseQuarantineEvent)),
+		h.If(len(b.uploadScanners) > 0,
+			VListItem(h.Text(msgr.ScanAgain)).Attr("@click", event(RescanEvent)),
+		),
+	}
+}
+
+func releaseQuarantine(mb *Builder) web.EventFunc {
+	return func(ctx *web.EventContext) (r web.EventResponse, err error) {
+		msgr := i18n.MustGetModuleMessages(ctx.R, I18nMediaLibraryKey, Messages_en_US).(*Messages)
+		obj, ok := wrapFirst(mb, ctx, &r)
+		if !ok {
+			return
+		}
+		if err = mb.quarantineIsAllowed(ctx.R); err != nil {
+			return
+		}
+		if err = mb.updateQuarantine(ctx, obj, "", ""); err != nil {
+			return
+		}
+		presets.ShowMessage(&r, msgr.FileReleased, ColorSuccess)
+		web.AppendRunScripts(&r,
+			web.Plaid().EventFunc(ImageJumpPageEvent).
+				MergeQuery(true).
+				Queries(ctx.Queries()).
+				Go())
+		return
+	}
+}
+
+// rescan scans the stored file again, such as after the signatures of the scanner were updated
+func rescan(mb *Builder) web.EventFunc {
+	return func(ctx *web.EventContext) (r web.EventResponse, err error) {
+		msgr := i18n.MustGetModuleMessages(ctx.R, I18nMediaLibraryKey, Messages_en_US).(*Messages)
+		obj, ok := wrapFirst(mb, ctx, &r)
+		if !ok {
+			return
+		}
+		if err = mb.quarantineIsAllowed(ctx.R); err != nil {
+			return
+		}
+		status, reason, err := mb.scan(ctx.R.Context(), obj.File.FileName, func() (io.ReadCloser, error) {
+			storage, err := mb.getQuarantineStorage()
+			if err != nil {
+				return nil, err
+			}
+			return storage.GetStream(ctx.R.Context(), quarantinePath(obj.ID))
+		})
+		if err != nil {
+			return
+		}
+		if err = mb.updateQuarantine(ctx, obj, status, reason); err != nil {
+			return
+		}
+		if status == "" {
+			presets.ShowMessage(&r, msgr.FileReleased, ColorSuccess)
+		} else {
+			presets.ShowMessage(&r, msgr.FileStillQuarantined(reason), ColorWarning)
+		}
+		web.AppendRunScripts(&r,
+			web.Plaid().EventFunc(ImageJumpPageEvent).
+				MergeQuery(true).
+				Queries(ctx.Queries()).
+				Go())
+		return
+	}
+}
+
+func (b *Builder) updateQuarantine(ctx *web.EventContext, obj media_library.MediaLibrary, status, reason string) (err error) {
+	old := obj
+	obj.QuarantineStatus, obj.QuarantineReason = status, reason
+	if obj.Quarantined() {
+		err = b.db.Model(&media_library.MediaLibrary{}).Where("id = ?", obj.ID).
+			UpdateColumns(map[string]interface{}{"quarantine_status": status, "quarantine_reason": reason}).Error
+	} else {
+		err = b.storeReleasedFile(ctx, &obj)
+	}
+	if err != nil {
+		return
+	}
+	b.onEdit(ctx, old, obj)
+	if !obj.Quarantined() {
+		// the processing is held back with the file
+		return b.enqueueProcessing(ctx.R, &obj)
+	}
+	return
+}
@@ -0,0 +1,131 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/oss/filesystem"
+	"github.com/stretchr/testify/require"
+
+	"github.com/qor5/admin/v3/media/base"
+	"github.com/qor5/admin/v3/presets"
+	"github.com/qor5/admin/v3/presets/gorm2op"
+)
+
+func TestQuarantinedUploadsAreHeldBack(t *testing.T) {
+	require.NoError(t, testDB.Exec("DELETE FROM media_libraries").Error)
+	storage := useTestStorage(t)
+	quarantine := t.TempDir()
+	signatures := []string{"EICAR"}
+	scanner := base.ScanFunc(func(_ context.Context, _ string, r io.Reader) (*base.ScanResult, error) {
+		bs, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range signatures {
+			if strings.Contains(string(bs), s) {
+				return &base.ScanResult{Reason: s + " found"}, nil
+			}
+		}
+		return &base.ScanResult{Clean: true}, nil
+	})
+	pb := presets.New().DataOperator(gorm2op.DataOperator(testDB))
+	b := New(testDB).UploadScanners(scanner).QuarantineStorage(filesystem.New(quarantine))
+	require.NoError(t, b.Install(pb))
+
+	infected, _ := uploadTestFile(t, b, pb, "infected.txt", "EICAR test file")
+	require.True(t, infected.Quarantined())
+	require.Equal(t, "EICAR found", reload(t, infected.ID).QuarantineReason)
+	require.Empty(t, storedObjects(t, storage), "a rejected file never reaches the storage")
+	require.Empty(t, reload(t, infected.ID).File.Url)
+	content, err := os.ReadFile(filepath.Join(quarantine, quarantinePath(infected.ID)))
+	require.NoError(t, err, "a rejected file is held back in the quarantine storage")
+	require.Equal(t, "EICAR test file", string(content))
+
+	clean, _ := uploadTestFile(t, b, pb, "clean.txt", "a clean file")
+	require.False(t, clean.Quarantined())
+	require.Len(t, storedObjects(t, storage), 1)
+	require.Len(t, storedObjects(t, quarantine), 1)
+
+	event := func(ef func(*Builder) web.EventFunc, id uint) {
+		t.Helper()
+		_, err := ef(b)(scopeEventContext(t, pb, 1, url.Values{ParamMediaIDS: {fmt.Sprint(id)}}))
+		require.NoError(t, err)
+	}
+
+	// a file still found infected stays held back
+	event(rescan, infected.ID)
+	require.True(t, reload(t, infected.ID).Quarantined())
+	require.Len(t, storedObjects(t, storage), 1)
+
+	// a clean rescan copies the file to the storage
+	signatures = nil
+	event(rescan, infected.ID)
+	released := reload(t, infected.ID)
+	require.False(t, released.Quarantined())
+	require.NotEmpty(t, released.File.Url)
+	require.Equal(t, "infected.txt", released.File.FileName)
+	require.Empty(t, storedObjects(t, quarantine))
+	content, err = os.ReadFile(filepath.Join(storage, released.File.Url))
+	require.NoError(t, err)
+	require.Equal(t, "EICAR test file", string(content))
+
+	// a released file is copied to the storage
+	signatures = []string{"EICAR"}
+	held, _ := uploadTestFile(t, b, pb, "held.txt", "EICAR again")
+	event(releaseQuarantine, held.ID)
+	released = reload(t, held.ID)
+	require.False(t, released.Quarantined())
+	require.Empty(t, storedObjects(t, quarantine))
+	content, err = os.ReadFile(filepath.Join(storage, released.File.Url))
+	require.NoError(t, err)
+	require.Equal(t, "EICAR again", string(content))
+
+	// a deleted quarantined file is removed from the quarantine storage
+	deleted, _ := uploadTestFile(t, b, pb, "deleted.txt", "EICAR deleted")
+	require.Len(t, storedObjects(t, quarantine), 1)
+	event(doDelete, deleted.ID)
+	require.Empty(t, storedObjects(t, quarantine))
+}
+
+func TestDeduplicateUploadsSkipQuarantined(t *testing.T) {
+	require.NoError(t, testDB.Exec("DELETE FROM media_libraries").Error)
+	storage := useTestStorage(t)
+	infected := true
+	scanner := base.ScanFunc(func(context.Context, string, io.Reader) (*base.ScanResult, error) {
+		if infected {
+			return &base.ScanResult{Reason: "false positive"}, nil
+		}
+		return &base.ScanResult{Clean: true}, nil
+	})
+	pb := presets.New().DataOperator(gorm2op.DataOperator(testDB))
+	b := New(testDB).DeduplicateUploads(true).UploadScanners(scanner).QuarantineStorage(filesystem.New(t.TempDir()))
+	require.NoError(t, b.Install(pb))
+
+	held, _ := uploadTestFile(t, b, pb, "report.txt", "quarterly report")
+	require.True(t, held.Quarantined())
+
+	// the same file uploaded clean is stored, not shared with the held back record without an object
+	infected = false
+	clean, existing := uploadTestFile(t, b, pb, "report.txt", "quarterly report")
+	require.Nil(t, existing, "a quarantined file is not a duplicate")
+	require.False(t, clean.Quarantined())
+	require.NotEmpty(t, reload(t, clean.ID).File.Url)
+	require.Len(t, storedObjects(t, storage), 1)
+}
+
+func TestQuarantineStorageIsRequired(t *testing.T) {
+	scanner := base.ScanFunc(func(context.Context, string, io.Reader) (*base.ScanResult, error) {
+		return &base.ScanResult{Clean: true}, nil
+	})
+	pb := presets.New().DataOperator(gorm2op.DataOperator(testDB))
+	require.ErrorIs(t, New(testDB).UploadScanners(scanner).Install(pb), errNoQuarantineStorage)
+	require.NoError(t, New(testDB).Install(pb), "the quarantine storage is not required without scanners")
+}
@@ -0,0 +1,275 @@
+package media
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	"github.com/qor5/web/v3"
+	"gorm.io/gorm"
+
+	"github.com/qor5/admin/v3/media/base"
+	"github.com/qor5/admin/v3/media/media_library"
+	"github.com/qor5/admin/v3/worker"
+)
+
+const (
+	RegenerateJobName = "MediaLibraryRegenerate"
+
+	regenerateBatchSize  = 100
+	regenerateDateFormat = "2006-01-02"
+)
+
+// RegenerateJobArgs filters the files the regenerate job walks through
+type RegenerateJobArgs struct {
+	// FolderID limits the job to the folder and its sub folders, 0 means the whole media library
+	FolderID uint
+	// SelectedType is one of image, video and file, empty means all types
+	SelectedType string
+	// CreatedAfter and CreatedBefore are dates formatted as 2006-01-02, both inclusive
+	CreatedAfter  string
+	CreatedBefore string
+	// AddNewSizes generates the configured sizes a file does not have yet
+	AddNewSizes bool
+	// Force regenerates every size even if it is unchanged
+	Force bool
+}
+
+// RegenerateCheckpoint keeps the progress of a regenerate job, a job that is
+// interrupted continues after LastID when it runs again with the same arguments.
+type RegenerateCheckpoint struct {
+	CheckpointKey string `gorm:"primaryKey;size:64"`
+	LastID        uint
+	Processed     int
+	Skipped       int
+	Failed        int
+	UpdatedAt     time.Time
+}
+
+func (*RegenerateCheckpoint) TableName() string {
+	return "media_library_regenerate_checkpoints"
+}
+
+func (args *RegenerateJobArgs) checkpointKey() string {
+	bs, _ := json.Marshal(args)
+	return fmt.Sprintf("%x", sha256.Sum256(bs))
+}
+
+// regenerateSizes merges the configured sizes into the sizes of a file,
+// changed reports if any size has to be generated.
+func regenerateSizes(current, configured map[string]*base.Size, addNew, force bool) (sizes map[string]*base.Size, changed bool) {
+	sizes = make(map[string]*base.Size)
+	for k, size := range current {
+		if k == base.DefaultSizeKey || k == media_library.QorPreviewSizeName {
+			continue
+		}
+		sizes[k] = size
+	}
+	for k, size := range configured {
+		old, ok := sizes[k]
+		if !ok && !addNew {
+			continue
+		}
+		if !ok || old == nil || *old != *size {
+			sizes[k] = size
+			changed = true
+		}
+	}
+	return sizes, changed || force
+}
+
+// RegenerateJob registers the job that re-runs the registered media handlers on
+// existing files, so thumbnails follow the changes of MediaBoxConfig.Sizes.
+// Sizes of the configs replace the sizes with the same name, files missing a
+// generated size are regenerated as well. Crop options are kept in the media
+// boxes, cropped files are not touched by the job. The saver func is called
+// with an EventContext of the job context, it has no user since the job runs outside a request.
+func (b *Builder) RegenerateJob(w *worker.Builder, configs ...*media_library.MediaBoxConfig) *worker.JobBuilder {
+	sizes := make(map[string]*base.Size)
+	for _, cfg := range configs {
+		for k, size := range cfg.Sizes {
+			sizes[k] = size
+		}
+	}
+	return w.NewJob(RegenerateJobName).
+		Resource(&RegenerateJobArgs{}).
+		Handler(func(ctx context.Context, job worker.QorJobInterface) error {
+			return b.regenerate(ctx, job, sizes)
+		})
+}
+
+func (b *Builder) regenerateFolderIDs(folderID uint) (ids []uint, err error) {
+	ids = []uint{folderID}
+	parents := ids
+	for len(parents) > 0 {
+		var children []uint
+		if err = b.db.Model(&media_library.MediaLibrary{}).
+			Where("folder = true AND parent_id IN ?", parents).
+			Pluck("id", &children).Error; err != nil {
+			return
+		}
+		ids = append(ids, children...)
+		parents = children
+	}
+	return
+}
+
+func (b *Builder) regenerateScope(args *RegenerateJobArgs) (scope func() *gorm.DB, err error) {
+	var (
+		folderIDs     []uint
+		after, before time.Time
+	)
+	if args.FolderID != 0 {
+		if folderIDs, err = b.regenerateFolderIDs(args.FolderID); err != nil {
+			return
+		}
+	}
+	if args.CreatedAfter != "" {
+		if after, err = time.ParseInLocation(regenerateDateFormat, args.CreatedAfter, time.Local); err != nil {
+			return
+		}
+	}
+	if args.CreatedBefore != "" {
+		if before, err = time.ParseInLocation(regenerateDateFormat, args.CreatedBefore, time.Local); err != nil {
+			return
+		}
+	}
+	return func() *gorm.DB {
+		// the quarantined files are not in the storage
+		db := b.db.Model(&media_library.MediaLibrary{}).Where("folder = false AND quarantine_status = ''")
+		if len(folderIDs) > 0 {
+			db = db.Where("parent_id IN ?", folderIDs)
+		}
+		if args.SelectedType != "" {
+			db = db.Where("selected_type = ?", args.SelectedType)
+		}
+		if !after.IsZero() {
+			db = db.Where("created_at >= ?", after)
+		}
+		if !before.IsZero() {
+			db = db.Where("created_at < ?", before.AddDate(0, 0, 1))
+		}
+		return db
+	}, nil
+}
+
+func (b *Builder) regenerate(ctx context.Context, job worker.QorJobInterface, configured map[string]*base.Size) (err error) {
+	jobInfo, err := job.GetJobInfo()
+	if err != nil {
+		return
+	}
+	args, _ := jobInfo.Argument.(*RegenerateJobArgs)
+	if args == nil {
+		args = &RegenerateJobArgs{}
+	}
+	scope, err := b.regenerateScope(args)
+	if err != nil {
+		return
+	}
+
+	cp := RegenerateCheckpoint{CheckpointKey: args.checkpointKey()}
+	if err = b.db.Where("checkpoint_key = ?", cp.CheckpointKey).Find(&cp).Error; err != nil {
+		return
+	}
+	if cp.LastID > 0 {
+		if err = job.AddLogf("resume after media library %d, %d files processed before", cp.LastID, cp.Processed); err != nil {
+			return
+		}
+	}
+
+	var total, done int64
+	if err = scope().Count(&total).Error; err != nil {
+		return
+	}
+	if err = scope().Where("id <= ?", cp.LastID).Count(&done).Error; err != nil {
+		return
+	}
+
+	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		var records []*media_library.MediaLibrary
+		if err = scope().Where("id > ?", cp.LastID).Order("id").Limit(regenerateBatchSize).Find(&records).Error; err != nil {
+			return
+		}
+		if len(records) == 0 {
+			break
+		}
+		for _, m := range records {
+			regenerated, rErr := b.regenerateFile(ctx, m, configured, args)
+			switch {
+			case rErr != nil:
+				cp.Failed++
+				if err = job.AddLogf("media library %d %s: %v", m.ID, m.File.FileName, rErr); err != nil {
+					return
+				}
+			case regenerated:
+				cp.Processed++
+			default:
+				cp.Skipped++
+			}
+			cp.LastID = m.ID
+			done++
+		}
+		if err = b.db.Save(&cp).Error; err != nil {
+			return
+		}
+		if err = errors.Join(
+			job.SetProgress(uint(done*100/total)),
+			job.SetProgressText(fmt.Sprintf("%d/%d", done, total)),
+		); err != nil {
+			return
+		}
+	}
+
+	if err = b.db.Where("checkpoint_key = ?", cp.CheckpointKey).Delete(&RegenerateCheckpoint{}).Error; err != nil {
+		return
+	}
+	return errors.Join(
+		job.AddLogf("regenerated %d files, skipped %d, failed %d", cp.Processed, cp.Skipped, cp.Failed),
+		job.SetProgress(100),
+	)
+}
+
+// jobEventContext is the EventContext of the saver func for the jobs, which run outside the requests
+func jobEventContext(ctx context.Context) *web.EventContext {
+	r := httptest.NewRequest(http.MethodPost, "/", http.NoBody).WithContext(ctx)
+	return &web.EventContext{R: r, W: httptest.NewRecorder()}
+}
+
+func (b *Builder) regenerateFile(ctx context.Context, m *media_library.MediaLibrary, configured map[string]*base.Size, args *RegenerateJobArgs) (bool, error) {
+	// only images keep the original file the handlers generate sizes from
+	if !m.File.IsImage() {
+		return false, nil
+	}
+	sizes, changed := regenerateSizes(m.File.Sizes, configured, args.AddNewSizes, args.Force)
+	if !changed {
+		for k := range sizes {
+			if _, ok := m.File.FileSizes[k]; !ok {
+				changed = true
+				break
+			}
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+	m.File.Sizes = sizes
+	if err := m.ScanMediaOptions(media_library.MediaOption{
+		Sizes: sizes,
+		Crop:  true,
+	}); err != nil {
+		return false, err
+	}
+	if err := b.saverFunc(b.db, m, strconv.Itoa(int(m.ID)), jobEventContext(ctx)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,102 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/qor5/web/v3"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/qor5/admin/v3/media/base"
+	"github.com/qor5/admin/v3/media/media_library"
+	"github.com/qor5/admin/v3/worker"
+)
+
+type testJob struct {
+	args     interface{}
+	logs     []string
+	progress uint
+}
+
+func (j *testJob) GetJobInfo() (*worker.JobInfo, error) {
+	return &worker.JobInfo{Argument: j.args}, nil
+}
+
+func (j *testJob) SetProgress(v uint) error {
+	j.progress = v
+	return nil
+}
+
+func (*testJob) SetProgressText(string) error { return nil }
+
+func (j *testJob) AddLog(s string) error {
+	j.logs = append(j.logs, s)
+	return nil
+}
+
+func (j *testJob) AddLogf(format string, a ...interface{}) error {
+	return j.AddLog(fmt.Sprintf(format, a...))
+}
+
+func TestRegenerateResumesFromCheckpoint(t *testing.T) {
+	require.NoError(t, testDB.Exec("DELETE FROM media_libraries").Error)
+	require.NoError(t, testDB.Exec("DELETE FROM media_library_regenerate_checkpoints").Error)
+	var ids []uint
+	for i := range 3 {
+		m := &media_library.MediaLibrary{}
+		m.File.FileName = fmt.Sprintf("%d.png", i)
+		m.File.Url = fmt.Sprintf("/system/media_libraries/%d/file.png", i)
+		m.File.Sizes = map[string]*base.Size{"thumb": {Width: 100, Height: 100}}
+		require.NoError(t, testDB.Create(m).Error)
+		ids = append(ids, m.ID)
+	}
+
+	var (
+		saved       []uint
+		ctx, cancel = context.WithCancel(context.Background())
+	)
+	defer cancel()
+	b := New(testDB).WrapSaverFunc(func(in SaverFunc) SaverFunc {
+		return func(_ *gorm.DB, obj interface{}, _ string, evCtx *web.EventContext) error {
+			require.NotNil(t, evCtx, "the saver func must get an EventContext")
+			saved = append(saved, obj.(*media_library.MediaLibrary).ID)
+			// the job stops before the next batch, after the checkpoint of this one is saved
+			cancel()
+			return nil
+		}
+	})
+	configured := map[string]*base.Size{"thumb": {Width: 200, Height: 200}}
+	args := &RegenerateJobArgs{}
+
+	// an interrupted run keeps the checkpoint of the processed files
+	require.NoError(t, testDB.Create(&RegenerateCheckpoint{CheckpointKey: args.checkpointKey(), LastID: ids[0], Processed: 1}).Error)
+	job := &testJob{args: args}
+	require.ErrorIs(t, b.regenerate(ctx, job, configured), context.Canceled)
+	require.Equal(t, ids[1:], saved, "the files up to the checkpoint are skipped")
+	require.True(t, strings.HasPrefix(job.logs[0], fmt.Sprintf("resume after media library %d", ids[0])), job.logs)
+	var cp RegenerateCheckpoint
+	require.NoError(t, testDB.Where("checkpoint_key = ?", args.checkpointKey()).First(&cp).Error)
+	require.Equal(t, ids[2], cp.LastID)
+	require.Equal(t, 3, cp.Processed)
+
+	// the next run continues after the checkpoint and removes it when it finishes
+	saved = nil
+	job = &testJob{args: args}
+	require.NoError(t, b.regenerate(context.Background(), job, configured))
+	require.Empty(t, saved)
+	require.Equal(t, uint(100), job.progress)
+	require.Contains(t, job.logs[len(job.logs)-1], "regenerated 3 files")
+	var count int64
+	require.NoError(t, testDB.Model(&RegenerateCheckpoint{}).Count(&count).Error)
+	require.Zero(t, count)
+
+	// other arguments have their own checkpoint
+	saved = nil
+	other := &RegenerateJobArgs{Force: true}
+	require.NotEqual(t, args.checkpointKey(), other.checkpointKey())
+	require.NoError(t, b.regenerate(context.Background(), &testJob{args: other}, configured))
+	require.Equal(t, ids, saved)
+}
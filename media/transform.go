@@ -0,0 +1,84 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/qor5/x/v3/oss"
+
+	"github.com/qor5/admin/v3/media/base"
+)
+
+var transformContentTypes = map[string]string{
+	".webp": "image/webp",
+	".avif": "image/avif",
+}
+
+// TransformHandler serves image variants generated on request from signed URLs,
+// results are cached into the storage so each variant is only generated once.
+// Mount it on base.TransformPathPrefix and set base.TransformSecret.
+func TransformHandler(storage oss.StorageInterface) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		p, opts, err := base.VerifyTransformURL(r.URL)
+		if err != nil {
+			if errors.Is(err, base.ErrTransformInvalidSignature) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx := r.Context()
+		cachePath := base.TransformCachePath(p, opts)
+		var body []byte
+		if cached, err := storage.GetStream(ctx, cachePath); err == nil {
+			body, err = io.ReadAll(cached)
+			cached.Close()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		} else {
+			src, err := storage.GetStream(ctx, p)
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+			body, err = base.TransformImage(p, src, opts)
+			src.Close()
+			if err != nil {
+				if errors.Is(err, base.ErrTransformUnsupported) {
+					http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+					return
+				}
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if _, err = storage.Put(ctx, cachePath, bytes.NewReader(body)); err != nil {
+				log.Printf("media transform: cache %s error: %v\n", cachePath, err)
+			}
+		}
+
+		ext := path.Ext(cachePath)
+		contentType := transformContentTypes[ext]
+		if contentType == "" {
+			contentType = mime.TypeByExtension(ext)
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		if r.Method == http.MethodHead {
+			return
+		}
+		_, _ = w.Write(body)
+	})
+}
@@ -0,0 +1,474 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/i18n"
+	. "github.com/qor5/x/v3/ui/vuetify"
+	vx "github.com/qor5/x/v3/ui/vuetifyx"
+	h "github.com/theplant/htmlgo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"github.com/qor5/admin/v3/media/media_library"
+	"github.com/qor5/admin/v3/presets"
+)
+
+const (
+	usageCallbackName = "media:track_usage"
+	usageBatchSize    = 100
+	usageSourcesKey   = "media:usage_sources"
+)
+
+// MediaUsage is a row of the reference index, it records a MediaBox field
+// of a record that points to a media library file.
+type MediaUsage struct {
+	ID             uint   `gorm:"primarykey"`
+	MediaLibraryID uint   `gorm:"index"`
+	SourceTable    string `gorm:"index:idx_media_usage_source"`
+	SourceID       string `gorm:"index:idx_media_usage_source"`
+	SourceModel    string
+	Field          string
+	UpdatedAt      time.Time
+}
+
+func (*MediaUsage) TableName() string {
+	return "media_library_usages"
+}
+
+var (
+	mediaBoxType      = reflect.TypeOf(media_library.MediaBox{})
+	timeType          = reflect.TypeOf(time.Time{})
+	typeHasMediaBoxes sync.Map
+)
+
+// hasMediaBox reports if the type contains a MediaBox, directly or in nested structs and slices
+func hasMediaBox(t reflect.Type) bool {
+	if v, ok := typeHasMediaBoxes.Load(t); ok {
+		return v.(bool)
+	}
+	// store false first so recursive types end
+	typeHasMediaBoxes.Store(t, false)
+	r := false
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		r = hasMediaBox(t.Elem())
+	case reflect.Struct:
+		if t == mediaBoxType {
+			r = true
+			break
+		}
+		if t == timeType {
+			break
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.IsExported() && hasMediaBox(f.Type) {
+				r = true
+				break
+			}
+		}
+	}
+	typeHasMediaBoxes.Store(t, r)
+	return r
+}
+
+type mediaReference struct {
+	MediaLibraryID uint
+	Field          string
+}
+
+// collectMediaReferences returns the media library files the MediaBoxes inside v point to,
+// slice indexes are left out of the field path.
+func collectMediaReferences(v reflect.Value, field string, refs map[mediaReference]bool) {
+	if !v.IsValid() || !hasMediaBox(v.Type()) {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			collectMediaReferences(v.Elem(), field, refs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectMediaReferences(v.Index(i), field, refs)
+		}
+	case reflect.Struct:
+		if v.Type() == mediaBoxType {
+			mb := v.Interface().(media_library.MediaBox)
+			if id, err := strconv.ParseUint(mb.ID.String(), 10, 64); err == nil && id > 0 {
+				refs[mediaReference{MediaLibraryID: uint(id), Field: field}] = true
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name := f.Name
+			if field != "" && !f.Anonymous {
+				name = field + "." + f.Name
+			} else if f.Anonymous {
+				name = field
+			}
+			collectMediaReferences(v.Field(i), name, refs)
+		}
+	}
+}
+
+func usageSourceID(db *gorm.DB, s *schema.Schema, rv reflect.Value) (string, bool) {
+	var (
+		segs []string
+		ok   = true
+	)
+	for _, f := range s.PrimaryFields {
+		value, zero := f.ValueOf(db.Statement.Context, rv)
+		if zero {
+			ok = false
+		}
+		segs = append(segs, fmt.Sprint(value))
+	}
+	return strings.Join(segs, "_"), ok && len(segs) > 0
+}
+
+func statementRecords(db *gorm.DB) (records []reflect.Value) {
+	rv := reflect.Indirect(db.Statement.ReflectValue)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			records = append(records, reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct:
+		records = append(records, rv)
+	}
+	return
+}
+
+// mediaBoxFields returns the fields of the schema containing MediaBoxes
+func mediaBoxFields(s *schema.Schema) (fields []*schema.Field) {
+	for _, f := range s.Fields {
+		if f.DBName != "" && hasMediaBox(f.FieldType) {
+			fields = append(fields, f)
+		}
+	}
+	return
+}
+
+// updatedMediaBoxFields returns the fields containing MediaBoxes written by the update, partial
+// updates such as Update("title", v) or Updates with a struct of other fields keep their usages.
+func updatedMediaBoxFields(db *gorm.DB) (fields []*schema.Field) {
+	stmt := db.Statement
+	selects, restricted := stmt.SelectAndOmitColumns(false, true)
+	dest := reflect.Indirect(reflect.ValueOf(stmt.Dest))
+	for _, f := range mediaBoxFields(stmt.Schema) {
+		selected, ok := selects[f.DBName]
+		switch {
+		case ok:
+			if !selected {
+				continue
+			}
+		case restricted:
+			continue
+		case dest.Kind() == reflect.Map && dest.Type().Key().Kind() == reflect.String:
+			if !dest.MapIndex(reflect.ValueOf(f.DBName)).IsValid() && !dest.MapIndex(reflect.ValueOf(f.Name)).IsValid() {
+				continue
+			}
+		case dest.Kind() == reflect.Struct && dest.Type() == stmt.Schema.ModelType:
+			if _, zero := f.ValueOf(stmt.Context, dest); zero {
+				continue
+			}
+		default:
+			continue
+		}
+		fields = append(fields, f)
+	}
+	return
+}
+
+// saveMediaUsages replaces the usages of the fields of the record
+func saveMediaUsages(tx *gorm.DB, s *schema.Schema, sourceID string, rv reflect.Value, fields []*schema.Field) (err error) {
+	var (
+		refs   = map[mediaReference]bool{}
+		conds  []string
+		values []interface{}
+	)
+	for _, f := range fields {
+		collectMediaReferences(f.ReflectValueOf(tx.Statement.Context, rv), f.Name, refs)
+		conds = append(conds, "field = ? OR field LIKE ?")
+		values = append(values, f.Name, f.Name+".%")
+	}
+	if err = tx.Where("source_table = ? AND source_id = ?", s.Table, sourceID).
+		Where(strings.Join(conds, " OR "), values...).
+		Delete(&MediaUsage{}).Error; err != nil {
+		return
+	}
+	if len(refs) == 0 {
+		return
+	}
+	var usages []*MediaUsage
+	for ref := range refs {
+		usages = append(usages, &MediaUsage{
+			MediaLibraryID: ref.MediaLibraryID,
+			SourceTable:    s.Table,
+			SourceID:       sourceID,
+			SourceModel:    s.Name,
+			Field:          ref.Field,
+		})
+	}
+	return tx.Create(&usages).Error
+}
+
+func tracksMediaUsages(db *gorm.DB) bool {
+	return db.Error == nil && db.Statement.Schema != nil && hasMediaBox(db.Statement.Schema.ModelType)
+}
+
+func saveStatementMediaUsages(db *gorm.DB, fields []*schema.Field) {
+	if len(fields) == 0 {
+		return
+	}
+	tx := db.Session(&gorm.Session{NewDB: true})
+	for _, rv := range statementRecords(db) {
+		sourceID, ok := usageSourceID(db, db.Statement.Schema, rv)
+		if !ok {
+			continue
+		}
+		if err := saveMediaUsages(tx, db.Statement.Schema, sourceID, rv, fields); err != nil {
+			db.AddError(err)
+			return
+		}
+	}
+}
+
+func trackCreatedMediaUsages(db *gorm.DB) {
+	if tracksMediaUsages(db) {
+		saveStatementMediaUsages(db, mediaBoxFields(db.Statement.Schema))
+	}
+}
+
+func trackUpdatedMediaUsages(db *gorm.DB) {
+	if tracksMediaUsages(db) {
+		saveStatementMediaUsages(db, updatedMediaBoxFields(db))
+	}
+}
+
+// collectDeletedUsageSources finds the records a delete removes before they are gone, the statement
+// may hold only conditions and no loaded records, like the deletes of the presets.
+func collectDeletedUsageSources(db *gorm.DB) {
+	if !tracksMediaUsages(db) {
+		return
+	}
+	var (
+		s            = db.Statement.Schema
+		records      = statementRecords(db)
+		sourceIDs    []string
+		byConditions = len(records) == 0
+	)
+	for _, rv := range records {
+		if sourceID, ok := usageSourceID(db, s, rv); ok {
+			sourceIDs = append(sourceIDs, sourceID)
+		} else {
+			byConditions = true
+		}
+	}
+	where, ok := db.Statement.Clauses["WHERE"].Expression.(clause.Where)
+	if byConditions && ok && len(where.Exprs) > 0 {
+		var columns []string
+		for _, f := range s.PrimaryFields {
+			columns = append(columns, f.DBName)
+		}
+		q := db.Session(&gorm.Session{NewDB: true}).Model(reflect.New(s.ModelType).Interface())
+		if db.Statement.Unscoped {
+			q = q.Unscoped()
+		}
+		found := reflect.New(reflect.SliceOf(reflect.PointerTo(s.ModelType)))
+		if err := q.Select(columns).Clauses(clause.Where{Exprs: where.Exprs}).Find(found.Interface()).Error; err != nil {
+			db.AddError(err)
+			return
+		}
+		for i := 0; i < found.Elem().Len(); i++ {
+			if sourceID, ok := usageSourceID(db, s, found.Elem().Index(i).Elem()); ok {
+				sourceIDs = append(sourceIDs, sourceID)
+			}
+		}
+	}
+	db.InstanceSet(usageSourcesKey, sourceIDs)
+}
+
+func untrackMediaUsages(db *gorm.DB) {
+	if !tracksMediaUsages(db) {
+		return
+	}
+	v, _ := db.InstanceGet(usageSourcesKey)
+	sourceIDs, _ := v.([]string)
+	if len(sourceIDs) == 0 {
+		return
+	}
+	if err := db.Session(&gorm.Session{NewDB: true}).
+		Where("source_table = ? AND source_id IN ?", db.Statement.Schema.Table, sourceIDs).
+		Delete(&MediaUsage{}).Error; err != nil {
+		db.AddError(err)
+	}
+}
+
+// TrackUsage keeps an index of the records whose MediaBox fields point to
+// media library files, it is refreshed whenever a record is saved or deleted.
+// The file menu shows where a file is used, deleting a file in use asks for
+// a confirmation, and the Unused filter lists the files nothing refers to.
+// Call RebuildUsages once to index the records saved before.
+func (b *Builder) TrackUsage(v bool) *Builder {
+	if v && !b.usageTracking {
+		cb := b.db.Callback()
+		for _, err := range []error{
+			cb.Create().After("gorm:create").Register(usageCallbackName, trackCreatedMediaUsages),
+			cb.Update().After("gorm:update").Register(usageCallbackName, trackUpdatedMediaUsages),
+			cb.Delete().Before("gorm:delete").Register(usageCallbackName+"_sources", collectDeletedUsageSources),
+			cb.Delete().After("gorm:delete").Register(usageCallbackName, untrackMediaUsages),
+		} {
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+	b.usageTracking = v
+	return b
+}
+
+// PreventDeletingUsedFiles blocks deleting files that are still used instead of asking for a confirmation
+func (b *Builder) PreventDeletingUsedFiles(v bool) *Builder {
+	b.preventDeletingUsedFiles = v
+	return b
+}
+
+// RebuildUsages indexes every record of the models, replacing their rows in the index
+func (b *Builder) RebuildUsages(models ...interface{}) error {
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: b.db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		s := stmt.Schema
+		if !hasMediaBox(s.ModelType) {
+			continue
+		}
+		if err := b.db.Where("source_table = ?", s.Table).Delete(&MediaUsage{}).Error; err != nil {
+			return err
+		}
+		fields := mediaBoxFields(s)
+		records := reflect.New(reflect.SliceOf(reflect.PointerTo(s.ModelType))).Interface()
+		if err := b.db.Model(model).FindInBatches(records, usageBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, rv := range statementRecords(tx) {
+				sourceID, ok := usageSourceID(tx, s, rv)
+				if !ok {
+					continue
+				}
+				if err := saveMediaUsages(b.db, s, sourceID, rv, fields); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Builder) mediaUsages(ids ...uint) (usages []*MediaUsage, err error) {
+	err = b.db.Where("media_library_id IN ?", ids).
+		Order("source_model, source_id, field").
+		Find(&usages).Error
+	return
+}
+
+// unusedScope limits the query to the files nothing refers to, folders are kept so they could still be browsed
+func (b *Builder) unusedScope(db *gorm.DB, keepFolders bool) *gorm.DB {
+	used := b.db.Model(&MediaUsage{}).Select("media_library_id")
+	if keepFolders {
+		return db.Where("media_libraries.folder = true OR media_libraries.id NOT IN (?)", used)
+	}
+	return db.Where("media_libraries.id NOT IN (?)", used)
+}
+
+func mediaUsageList(usages []*MediaUsage) h.HTMLComponent {
+	sort.SliceStable(usages, func(i, j int) bool {
+		return usages[i].MediaLibraryID < usages[j].MediaLibraryID
+	})
+	list := VList().Density(DensityCompact).MaxHeight(320).Class("overflow-y-auto")
+	for _, u := range usages {
+		list.AppendChildren(
+			VListItem(
+				VListItemTitle(h.Text(fmt.Sprintf("%s #%s", u.SourceModel, u.SourceID))),
+				VListItemSubtitle(h.Text(u.Field)),
+			).PrependIcon("mdi-link-variant"),
+		)
+	}
+	return list
+}
+
+func usedInDialog(mb *Builder) web.EventFunc {
+	return func(ctx *web.EventContext) (r web.EventResponse, err error) {
+		obj, ok := wrapFirst(mb, ctx, &r)
+		if !ok {
+			return
+		}
+		var (
+			pMsgr  = i18n.MustGetModuleMessages(ctx.R, presets.CoreI18nModuleKey, Messages_en_US).(*presets.Messages)
+			msgr   = i18n.MustGetModuleMessages(ctx.R, I18nMediaLibraryKey, Messages_en_US).(*Messages)
+			usages []*MediaUsage
+		)
+		if usages, err = mb.mediaUsages(obj.ID); err != nil {
+			return
+		}
+		var body h.HTMLComponent = mediaUsageList(usages)
+		if len(usages) == 0 {
+			body = h.Span(msgr.NotUsedAnywhere)
+		}
+		r.UpdatePortals = append(r.UpdatePortals, &web.PortalUpdate{
+			Name: usedInDialogPortalName,
+			Body: web.Scope(
+				vx.VXDialog(body).
+					Attr("v-model", "dialogLocals.show").
+					Title(msgr.UsedIn).
+					Width(480).
+					HideCancel(true).
+					OkText(pMsgr.OK).
+					Attr("@click:ok", "dialogLocals.show = false"),
+			).VSlot("{locals:dialogLocals}").Init("{show:true}"),
+		})
+		return
+	}
+}
+
+var errFilesInUse = errors.New("files in use")
+
+// checkFilesInUse returns the usages of the files, the error is errFilesInUse when deleting them is prevented
+func (b *Builder) checkFilesInUse(objs []media_library.MediaLibrary) (usages []*MediaUsage, err error) {
+	if !b.usageTracking {
+		return
+	}
+	var ids []uint
+	for _, obj := range objs {
+		if !obj.Folder {
+			ids = append(ids, obj.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	if usages, err = b.mediaUsages(ids...); err != nil {
+		return
+	}
+	if len(usages) > 0 && b.preventDeletingUsedFiles {
+		err = errFilesInUse
+	}
+	return
+}
@@ -0,0 +1,112 @@
+package media
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/qor5/admin/v3/media/media_library"
+	"github.com/qor5/admin/v3/presets"
+	"github.com/qor5/admin/v3/presets/gorm2op"
+)
+
+type usageTestPost struct {
+	ID    uint
+	Title string
+	Hero  media_library.MediaBox `sql:"type:text;"`
+	Body  usageTestBody          `gorm:"serializer:json"`
+}
+
+type usageTestBody struct {
+	Images []media_library.MediaBox
+}
+
+func usageRows(t *testing.T, fileID uint) (usages []*MediaUsage) {
+	t.Helper()
+	require.NoError(t, testDB.Where("media_library_id = ?", fileID).Order("field").Find(&usages).Error)
+	return
+}
+
+func TestTrackUsage(t *testing.T) {
+	require.NoError(t, testDB.AutoMigrate(&usageTestPost{}))
+	t.Cleanup(func() {
+		if err := testDB.Migrator().DropTable(&usageTestPost{}); err != nil {
+			t.Errorf("drop posts table: %v", err)
+		}
+	})
+	require.NoError(t, testDB.Exec("DELETE FROM media_library_usages").Error)
+	require.NoError(t, testDB.Exec("DELETE FROM media_libraries").Error)
+	hero := mkRow(t, 1, false, 0, "hero.png")
+	inline := mkRow(t, 1, false, 0, "inline.png")
+
+	pb := presets.New().DataOperator(gorm2op.DataOperator(testDB))
+	b := New(testDB).TrackUsage(true).PreventDeletingUsedFiles(true)
+	require.NoError(t, b.Install(pb))
+
+	box := func(m *media_library.MediaLibrary) media_library.MediaBox {
+		return media_library.MediaBox{ID: json.Number(fmt.Sprint(m.ID)), Url: "/system/media_libraries/file.png"}
+	}
+	post := &usageTestPost{
+		Title: "post",
+		Hero:  box(hero),
+		Body:  usageTestBody{Images: []media_library.MediaBox{box(inline), box(inline)}},
+	}
+	require.NoError(t, testDB.Create(post).Error)
+
+	usages := usageRows(t, hero.ID)
+	require.Len(t, usages, 1, "saving a record with a MediaBox records the usage")
+	require.Equal(t, "usageTestPost", usages[0].SourceModel)
+	require.Equal(t, fmt.Sprint(post.ID), usages[0].SourceID)
+	require.Equal(t, "Hero", usages[0].Field)
+	usages = usageRows(t, inline.ID)
+	require.Len(t, usages, 1, "the same file in a slice is recorded once per field")
+	require.Equal(t, "Body.Images", usages[0].Field)
+
+	deleteFile := func(m *media_library.MediaLibrary) {
+		t.Helper()
+		_, err := doDelete(b)(scopeEventContext(t, pb, 1, url.Values{ParamMediaIDS: []string{fmt.Sprint(m.ID)}}))
+		require.NoError(t, err)
+	}
+	deleteFile(hero)
+	require.NotZero(t, reload(t, hero.ID).ID, "a file in use must not be deleted")
+
+	require.NoError(t, testDB.Model(post).Update("title", "renamed").Error)
+	require.Len(t, usageRows(t, hero.ID), 1, "partial updates keep the usages")
+	require.NoError(t, testDB.Model(&usageTestPost{ID: post.ID}).Updates(usageTestPost{Title: "updated"}).Error)
+	require.Len(t, usageRows(t, hero.ID), 1, "the updates of other fields keep the usages")
+	require.Len(t, usageRows(t, inline.ID), 1)
+
+	post.Hero = media_library.MediaBox{}
+	require.NoError(t, testDB.Save(post).Error)
+	require.Empty(t, usageRows(t, hero.ID), "clearing the MediaBox removes the usage")
+	require.Len(t, usageRows(t, inline.ID), 1)
+
+	deleteFile(hero)
+	var count int64
+	require.NoError(t, testDB.Model(&media_library.MediaLibrary{}).Where("id = ?", hero.ID).Count(&count).Error)
+	require.Zero(t, count, "a file no longer used is deleted")
+
+	require.NoError(t, testDB.Delete(post).Error)
+	require.Empty(t, usageRows(t, inline.ID), "deleting the record removes its usages")
+
+	// the records saved before tracking are indexed by RebuildUsages
+	require.NoError(t, testDB.Exec("INSERT INTO usage_test_posts (id, title, hero, body) VALUES (?, ?, ?, ?)",
+		post.ID+1, "raw", fmt.Sprintf(`{"ID":"%d"}`, inline.ID), `{}`).Error)
+	require.Empty(t, usageRows(t, inline.ID))
+	require.NoError(t, b.RebuildUsages(&usageTestPost{}))
+	require.Len(t, usageRows(t, inline.ID), 1)
+
+	// the deletes by conditions remove the usages of the deleted records
+	mb := pb.Model(&usageTestPost{})
+	require.NoError(t, mb.Editing().Deleter(mb.NewModel(), fmt.Sprint(post.ID+1), scopeEventContext(t, pb, 1, nil)))
+	require.Empty(t, usageRows(t, inline.ID), "deleting through the presets removes the usages")
+
+	other := &usageTestPost{Title: "other", Hero: box(inline)}
+	require.NoError(t, testDB.Create(other).Error)
+	require.Len(t, usageRows(t, inline.ID), 1)
+	require.NoError(t, testDB.Where("title = ?", "other").Delete(&usageTestPost{}).Error)
+	require.Empty(t, usageRows(t, inline.ID), "deleting by conditions removes the usages")
+}
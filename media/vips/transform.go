@@ -0,0 +1,70 @@
+package vips
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/theplant/bimg"
+
+	"github.com/qor5/admin/v3/media/base"
+)
+
+// bimgImageTransformer generates image variants with libvips, supports webp output
+type bimgImageTransformer struct{}
+
+var transformImageTypes = map[string]bimg.ImageType{
+	base.TransformFormatJPEG: bimg.JPEG,
+	base.TransformFormatPNG:  bimg.PNG,
+	base.TransformFormatGIF:  bimg.GIF,
+	base.TransformFormatWebp: bimg.WEBP,
+}
+
+func (bimgImageTransformer) CouldTransform(name string, opts base.TransformOptions) bool {
+	if !base.IsImageFormat(name) {
+		return false
+	}
+	if opts.Format == "" {
+		return true
+	}
+	t, ok := transformImageTypes[opts.Format]
+	return ok && bimg.IsTypeSupportedSave(t)
+}
+
+func (bimgImageTransformer) Transform(name string, file io.Reader, opts base.TransformOptions) ([]byte, error) {
+	buffer := new(bytes.Buffer)
+	if _, err := io.Copy(buffer, file); err != nil {
+		return nil, err
+	}
+	img := bimg.NewImage(buffer.Bytes())
+	if c := opts.Crop; c != nil {
+		cropOptions := bimg.Options{
+			Quality:    100, // Don't compress twice
+			Top:        c.Y,
+			Left:       c.X,
+			AreaWidth:  c.Width,
+			AreaHeight: c.Height,
+		}
+		if cropOptions.Top == 0 && cropOptions.Left == 0 {
+			cropOptions.Top = -1
+		}
+		if _, err := img.Process(cropOptions); err != nil {
+			return nil, err
+		}
+	}
+	quality := opts.Quality
+	if quality == 0 {
+		quality = getQualityByImageType(name)
+	}
+	bimgOption := bimg.Options{
+		Width:       opts.Width,
+		Height:      opts.Height,
+		Crop:        opts.Fit == base.TransformFitCover,
+		Quality:     quality,
+		Compression: PNGCompression,
+		Palette:     true,
+	}
+	if opts.Format != "" {
+		bimgOption.Type = transformImageTypes[opts.Format]
+	}
+	return img.Process(bimgOption)
+}
@@ -265,4 +265,5 @@ func UseVips(cfg Config) {
 	bimg.VipsCacheSetMax(0)
 	bimg.VipsCacheSetMaxMem(0)
 	base.RegisterMediaHandler("image_handler", bimgImageHandler{})
+	base.RegisterImageTransformer("image_transformer", bimgImageTransformer{})
 }
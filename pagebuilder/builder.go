@@ -126,6 +126,13 @@ type Builder struct {
 	templateInstall                presets.ModelInstallFunc
 	pageInstall                    presets.ModelInstallFunc
 	categoryInstall                presets.ModelInstallFunc
+	menuInstall                    presets.ModelInstallFunc
+	menuEnabled                    bool
+	menuPositions                  []string
+	menuRenderFunc                 MenuRenderFunc
+	linkCheckEnabled               bool
+	collaborationEnabled           bool
+	presenceTimeout                time.Duration
 	devices                        []Device
 	fields                         []string
 	editorActivityProcessor        func(ctx *web.EventContext, input *EditorLogInput) *EditorLogInput
@@ -159,10 +166,14 @@ func newBuilder(prefix string, db *gorm.DB, b *presets.Builder) *Builder {
 		pageEnabled:       true,
 		previewContainer:  true,
 		pb:                b,
+		menuPositions:     []string{MenuPositionHeader, MenuPositionFooter},
+		presenceTimeout:   defaultPresenceTimeout,
 	}
 	r.templateInstall = r.defaultTemplateInstall
 	r.categoryInstall = r.defaultCategoryInstall
 	r.pageInstall = r.defaultPageInstall
+	r.menuInstall = r.defaultMenuInstall
+	r.menuRenderFunc = defaultMenuRenderFunc
 	r.pageLayoutFunc = defaultPageLayoutFunc
 	return r
 }
@@ -226,6 +237,9 @@ func AutoMigrate(db *gorm.DB) (err error) {
 		&Container{},
 		&Category{},
 		&DemoContainer{},
+		&Menu{},
+		&BrokenLink{},
+		&EditorPresence{},
 	); err != nil {
 		return
 	}
@@ -420,6 +434,15 @@ func (b *Builder) Install(pb *presets.Builder) (err error) {
 		if err = b.categoryInstall(pb, categoryM); err != nil {
 			return
 		}
+		if b.menuEnabled {
+			menuM := pb.Model(&Menu{}).URIName("page_menus").Label("Menus")
+			if err = b.menuInstall(pb, menuM); err != nil {
+				return
+			}
+		}
+		if b.linkCheckEnabled {
+			b.installBrokenLinks(pb)
+		}
 	}
 	if b.templateEnabled {
 		var pm *presets.ModelBuilder
@@ -494,6 +517,9 @@ func (b *Builder) configTemplateAndPage(pb *presets.Builder, r *ModelBuilder) {
 	b.configPublish(r)
 	b.useAllPlugin(pm, r.name)
 	b.seoDisableEditOnline(pm)
+	if b.l10n != nil {
+		b.l10n.ContentExtractors(pm.NewModel(), r)
+	}
 	// dp.TabsPanels()
 }
 
@@ -582,6 +608,7 @@ func (b *Builder) preparePlugins() {
 				return ""
 			},
 		)
+		seoBuilder.PageHTMLFuncs(b.pageHTML)
 	}
 
 	if b.mediaBuilder == nil {
@@ -589,6 +616,19 @@ func (b *Builder) preparePlugins() {
 	}
 }
 
+// pageHTML renders obj by the page model of its type for the seo audit
+func (b *Builder) pageHTML(ctx context.Context, obj interface{}) (string, error) {
+	for _, m := range b.models {
+		if m.isTemplate || m.preview == nil {
+			continue
+		}
+		if reflect.TypeOf(m.mb.NewModel()) == reflect.TypeOf(obj) {
+			return m.PreviewHTML(ctx, obj), nil
+		}
+	}
+	return "", nil
+}
+
 func (b *Builder) configDetailLayoutFunc(
 	pb *presets.Builder,
 	pm *presets.ModelBuilder,
@@ -1088,12 +1128,21 @@ func (b *ContainerBuilder) Install() {
 			if err = db.Transaction(func(tx *gorm.DB) (dbErr error) {
 				ctx.WithContextValue(gorm2op.CtxKeyDB{}, tx)
 				defer ctx.WithContextValue(gorm2op.CtxKeyDB{}, nil)
+				if dbErr = b.checkModelUpdatedAt(tx, id, ctx); dbErr != nil {
+					return
+				}
 				if dbErr = in(obj, id, ctx); dbErr != nil {
 					return
 				}
 				if dbErr = b.builder.updateAllContainersUpdatedTimeFromModel(tx, id); dbErr != nil {
 					return
 				}
+				if dbErr = b.builder.touchLockedTemplateContainers(tx, b.name, id); dbErr != nil {
+					return
+				}
+				if dbErr = b.touchModelUpdatedAt(tx, id, ctx); dbErr != nil {
+					return
+				}
 
 				return
 			}); err != nil {
@@ -1142,6 +1191,7 @@ func (b *ContainerBuilder) Install() {
 			addRowBtnID = ctx.Param(presets.AddRowBtnKey(fromKey))
 		)
 		return h.Components(
+			b.concurrencyComponent(obj, ctx),
 			h.Div().Style("display:none").Attr("v-on-mounted", fmt.Sprintf(`({window}) => {
 				if (!!locals.__pageBuilderRightContentKeepScroll) {
 					locals.__pageBuilderRightContentKeepScroll();
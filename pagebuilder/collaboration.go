@@ -0,0 +1,309 @@
+package pagebuilder
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/i18n"
+	"github.com/qor5/x/v3/login"
+	. "github.com/qor5/x/v3/ui/vuetify"
+	"github.com/sunfmin/reflectutils"
+	h "github.com/theplant/htmlgo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/qor5/admin/v3/presets"
+	"github.com/qor5/admin/v3/presets/actions"
+)
+
+const (
+	editorPresencePortal = "editorPresencePortal"
+
+	defaultPresenceTimeout = 30 * time.Second
+)
+
+type ctxKeyContainerConflict struct{}
+
+// EditorPresence records that a user has a page open in the editor,
+// ContainerDataID is the container the user is currently editing.
+type EditorPresence struct {
+	ID              uint   `gorm:"primarykey"`
+	PageModelName   string `gorm:"uniqueIndex:uidx_page_builder_editor_presences_page_user"`
+	PageSlug        string `gorm:"uniqueIndex:uidx_page_builder_editor_presences_page_user"`
+	UserID          string `gorm:"uniqueIndex:uidx_page_builder_editor_presences_page_user"`
+	UserName        string
+	ContainerDataID string    `gorm:"index"`
+	LastSeenAt      time.Time `gorm:"index"`
+}
+
+func (*EditorPresence) TableName() string {
+	return "page_builder_editor_presences"
+}
+
+// CollaborationEnabled shows who else is editing the page, soft locks the
+// containers being edited and rejects saves of containers changed by others
+// since the edit drawer was opened.
+func (b *Builder) CollaborationEnabled(v bool) (r *Builder) {
+	b.collaborationEnabled = v
+	return b
+}
+
+// PresenceTimeout is how long an editor is considered present after the last heartbeat.
+func (b *Builder) PresenceTimeout(v time.Duration) (r *Builder) {
+	b.presenceTimeout = v
+	return b
+}
+
+func currentEditor(ctx *web.EventContext) (id, name string) {
+	user := login.GetCurrentUser(ctx.R)
+	if user == nil {
+		return
+	}
+	id = presets.ObjectID(user)
+	name = id
+	if v, err := reflectutils.Get(user, "Name"); err == nil && fmt.Sprint(v) != "" {
+		name = fmt.Sprint(v)
+	}
+	return
+}
+
+func (b *Builder) touchPresence(pageModelName, pageSlug, containerDataID string, ctx *web.EventContext) (userID string, err error) {
+	userID, userName := currentEditor(ctx)
+	if userID == "" {
+		return
+	}
+	now := time.Now()
+	var presence EditorPresence
+	if err = b.db.Where(EditorPresence{PageModelName: pageModelName, PageSlug: pageSlug, UserID: userID}).
+		Assign(map[string]interface{}{
+			"user_name":         userName,
+			"container_data_id": containerDataID,
+			"last_seen_at":      now,
+		}).
+		FirstOrCreate(&presence).Error; err != nil {
+		return
+	}
+	err = b.db.Where("last_seen_at < ?", now.Add(-time.Hour)).Delete(&EditorPresence{}).Error
+	return
+}
+
+func (b *Builder) activeEditors(wh *gorm.DB, exceptUserID string) (editors []*EditorPresence, err error) {
+	err = wh.Where("user_id <> ? AND last_seen_at > ?", exceptUserID, time.Now().Add(-b.presenceTimeout)).
+		Order("last_seen_at DESC").
+		Find(&editors).Error
+	return
+}
+
+func (b *Builder) editorName(userID string) string {
+	var presence EditorPresence
+	if b.db.Where("user_id = ?", userID).Order("last_seen_at DESC").First(&presence).Error != nil {
+		return userID
+	}
+	return presence.UserName
+}
+
+func (b *Builder) presenceComponent() h.HTMLComponent {
+	interval := b.presenceTimeout.Milliseconds() / 3
+	return web.Scope(
+		web.Portal().Name(editorPresencePortal).
+			Loader(web.Plaid().EventFunc(PresenceHeartbeatEvent).MergeQuery(true)).
+			AutoReloadInterval("locals.presenceInterval"),
+	).VSlot("{ locals }").Init(fmt.Sprintf("{presenceInterval: %d}", interval))
+}
+
+func (b *Builder) presenceAvatars(ctx *web.EventContext, editors []*EditorPresence) h.HTMLComponent {
+	msgr := i18n.MustGetModuleMessages(ctx.R, I18nPageBuilderKey, Messages_en_US).(*Messages)
+	var avatars []h.HTMLComponent
+	for _, e := range editors {
+		tip := e.UserName
+		if e.ContainerDataID != "" {
+			tip = msgr.EditingContainer(e.UserName, b.containerDisplayName(e.ContainerDataID))
+		}
+		var avatarText string
+		if r := []rune(e.UserName); len(r) > 0 {
+			avatarText = strings.ToUpper(string(r[0]))
+		}
+		avatars = append(avatars, VTooltip(
+			web.Slot(
+				VAvatar().Class("text-overline font-weight-medium text-primary bg-primary-lighten-2 border").
+					Size(SizeSmall).Text(avatarText).Attr("v-bind", "tooltip"),
+			).Name("activator").Scope(`{props:tooltip}`),
+		).Location(LocationBottom).Text(tip))
+	}
+	return h.Div(avatars...).Class("d-flex align-center ga-1")
+}
+
+func (b *ModelBuilder) presenceHeartbeat(ctx *web.EventContext) (r web.EventResponse, err error) {
+	var (
+		userID   string
+		editors  []*EditorPresence
+		pageSlug = ctx.Param(presets.ParamID)
+	)
+	if userID, err = b.builder.touchPresence(b.name, pageSlug, ctx.Param(paramContainerDataID), ctx); err != nil {
+		return
+	}
+	if editors, err = b.builder.activeEditors(b.db.Where("page_model_name = ? AND page_slug = ?", b.name, pageSlug), userID); err != nil {
+		return
+	}
+	r.Body = b.builder.presenceAvatars(ctx, editors)
+	return
+}
+
+// lockedContainerEditors returns the other users editing the container,
+// the returned editors is empty if the user chooses to edit it anyway.
+func (b *ModelBuilder) lockedContainerEditors(ctx *web.EventContext, containerDataID string) (editors []*EditorPresence, err error) {
+	if !b.builder.collaborationEnabled {
+		return
+	}
+	userID, err := b.builder.touchPresence(b.name, ctx.Param(presets.ParamID), containerDataID, ctx)
+	if err != nil || ctx.Param(paramForceEdit) == "true" {
+		return
+	}
+	return b.builder.activeEditors(b.db.Where("container_data_id = ?", containerDataID), userID)
+}
+
+func (b *Builder) lockedContainerEdit(ctx *web.EventContext, containerDataID string, editors []*EditorPresence) h.HTMLComponent {
+	msgr := i18n.MustGetModuleMessages(ctx.R, I18nPageBuilderKey, Messages_en_US).(*Messages)
+	var names []string
+	for _, e := range editors {
+		names = append(names, e.UserName)
+	}
+	return VLayout(
+		VAppBar(
+			VToolbarTitle("").Children(h.Text(b.containerDisplayName(containerDataID))),
+		).Elevation(0),
+		VMain(
+			VSheet(
+				VAlert(h.Text(msgr.ContainerLockedBy(strings.Join(names, ", ")))).
+					Type(TypeWarning).Variant(VariantTonal).Density(DensityCompact).Class("mb-4"),
+				VBtn(msgr.EditAnyway).Color(ColorPrimary).Variant(VariantElevated).
+					Attr("@click", web.Plaid().
+						EventFunc(EditContainerEvent).
+						MergeQuery(true).
+						Query(paramContainerDataID, containerDataID).
+						Query(paramForceEdit, true).
+						Go()),
+			).Class("pa-4"),
+		),
+	)
+}
+
+func modelUpdatedAtToken(t time.Time) string {
+	return fmt.Sprint(t.UnixMicro())
+}
+
+func (b *Builder) lastModifiedContainer(db *gorm.DB, modelName string, modelID interface{}) (con *Container, err error) {
+	con = &Container{}
+	err = db.Where("model_id = ? AND model_name = ?", modelID, modelName).Order("model_updated_at DESC").First(con).Error
+	return
+}
+
+// checkModelUpdatedAt rejects the save when the container has been changed
+// by others after the editor loaded it.
+func (b *ContainerBuilder) checkModelUpdatedAt(tx *gorm.DB, id string, ctx *web.EventContext) error {
+	token := ctx.Param(paramModelUpdatedAt)
+	if !b.builder.collaborationEnabled || id == "" || token == "" {
+		return nil
+	}
+	seen, err := strconv.ParseInt(token, 10, 64)
+	if err != nil {
+		return nil
+	}
+	// the row stays locked until the save commits, a concurrent save waits and sees the new ModelUpdatedAt
+	con, err := b.builder.lastModifiedContainer(tx.Clauses(clause.Locking{Strength: "UPDATE"}), b.name, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if con.ModelUpdatedAt.UnixMicro() <= seen {
+		return nil
+	}
+	if userID, _ := currentEditor(ctx); userID != "" && userID == con.ModelUpdatedBy {
+		return nil
+	}
+	ctx.WithContextValue(ctxKeyContainerConflict{}, con)
+	msgr := i18n.MustGetModuleMessages(ctx.R, I18nPageBuilderKey, Messages_en_US).(*Messages)
+	vErr := &web.ValidationErrors{}
+	vErr.GlobalError(msgr.ContainerChangedByOthers(b.builder.editorName(con.ModelUpdatedBy), con.ModelUpdatedAt.Local().Format("2006-01-02 15:04:05")))
+	return vErr
+}
+
+func (b *ContainerBuilder) touchModelUpdatedAt(tx *gorm.DB, id string, ctx *web.EventContext) error {
+	if !b.builder.collaborationEnabled || id == "" || ctx.Param(paramDemoContainer) == "true" {
+		return nil
+	}
+	columns := map[string]interface{}{"model_updated_at": time.Now()}
+	if userID, _ := currentEditor(ctx); userID != "" {
+		columns["model_updated_by"] = userID
+	}
+	return tx.Model(&Container{}).Where("model_id = ? AND model_name = ?", id, b.name).UpdateColumns(columns).Error
+}
+
+// concurrencyComponent keeps the ModelUpdatedAt the editor has seen in vars.__pageBuilderModelUpdatedAt,
+// and renders the merge prompt when the save is rejected.
+func (b *ContainerBuilder) concurrencyComponent(obj interface{}, ctx *web.EventContext) h.HTMLComponent {
+	if !b.builder.collaborationEnabled {
+		return nil
+	}
+	if v := ctx.ContextValue(ctxKeyContainerConflict{}); v != nil {
+		var (
+			con  = v.(*Container)
+			msgr = i18n.MustGetModuleMessages(ctx.R, I18nPageBuilderKey, Messages_en_US).(*Messages)
+		)
+		return VAlert(
+			h.Div(h.Text(msgr.ContainerMergePrompt)).Class("mb-2"),
+			h.Div(
+				VBtn(msgr.LoadLatestVersion).Size(SizeSmall).Variant(VariantTonal).Class("mr-2").
+					Attr("@click", web.Plaid().
+						EventFunc(EditContainerEvent).
+						MergeQuery(true).
+						Query(paramForceEdit, true).
+						Go()),
+				VBtn(msgr.KeepMyChanges).Size(SizeSmall).Color(ColorWarning).Variant(VariantElevated).
+					Attr("@click", fmt.Sprintf(`vars.__pageBuilderModelConflict=false;vars.__pageBuilderModelUpdatedAt=%q;%s`,
+						modelUpdatedAtToken(con.ModelUpdatedAt),
+						web.Plaid().
+							EventFunc(UpdateContainerEvent).
+							Query(paramContainerUri, b.mb.Info().ListingHref()).
+							Query(paramContainerID, presets.ObjectID(obj)).
+							MergeQuery(true).
+							Go())),
+			),
+		).Type(TypeWarning).Variant(VariantTonal).Density(DensityCompact).Class("mb-4").
+			Attr("v-on-mounted", `()=>{vars.__pageBuilderModelConflict=true}`)
+	}
+	if ctx.Param(web.EventFuncIDName) != actions.Edit {
+		return nil
+	}
+	var token string
+	if con, err := b.builder.lastModifiedContainer(b.builder.db, b.name, reflectutils.MustGet(obj, "ID")); err == nil {
+		token = modelUpdatedAtToken(con.ModelUpdatedAt)
+	}
+	return h.Div().Style("display:none").Attr("v-on-mounted", fmt.Sprintf(`()=>{vars.__pageBuilderModelConflict=false;vars.__pageBuilderModelUpdatedAt=%q}`, token))
+}
+
+// refreshModelUpdatedAtScript advances the seen ModelUpdatedAt after the editor saved the container.
+func (b *ModelBuilder) refreshModelUpdatedAtScript(containerDataID string) string {
+	if !b.builder.collaborationEnabled {
+		return ""
+	}
+	segs := strings.SplitN(containerDataID, "_", 4)
+	if len(segs) < 3 {
+		return ""
+	}
+	var con Container
+	if b.db.Where("id = ?", segs[2]).First(&con).Error != nil {
+		return ""
+	}
+	last, err := b.builder.lastModifiedContainer(b.db, con.ModelName, con.ModelID)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf(`if(!vars.__pageBuilderModelConflict){vars.__pageBuilderModelUpdatedAt=%q}`, modelUpdatedAtToken(last.ModelUpdatedAt))
+}
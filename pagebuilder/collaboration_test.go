@@ -0,0 +1,96 @@
+package pagebuilder
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/login"
+)
+
+type collaborationTestUser struct {
+	ID   uint
+	Name string
+}
+
+func collaborationEventContext(userID uint, params url.Values) *web.EventContext {
+	req := httptest.NewRequest(http.MethodPost, "/?"+params.Encode(), http.NoBody)
+	if userID != 0 {
+		req = req.WithContext(context.WithValue(req.Context(), login.UserKey, &collaborationTestUser{ID: userID, Name: "editor"}))
+	}
+	return &web.EventContext{R: req, W: httptest.NewRecorder()}
+}
+
+func TestCheckModelUpdatedAt(t *testing.T) {
+	if err := TestDB.AutoMigrate(&Container{}, &EditorPresence{}); err != nil {
+		t.Fatal(err)
+	}
+	TestDB.Exec("DELETE FROM page_builder_containers")
+	updatedAt := time.Date(2024, 12, 24, 10, 0, 0, 0, time.UTC)
+	if err := TestDB.Create(&Container{ModelName: "Heading", ModelID: 1, ModelUpdatedAt: updatedAt, ModelUpdatedBy: "2"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	b := &ContainerBuilder{name: "Heading", builder: &Builder{db: TestDB, collaborationEnabled: true}}
+
+	for _, c := range []struct {
+		name     string
+		userID   uint
+		seen     string
+		rejected bool
+	}{
+		{name: "seen the latest", userID: 1, seen: modelUpdatedAtToken(updatedAt)},
+		{name: "seen an old one", userID: 1, seen: modelUpdatedAtToken(updatedAt.Add(-time.Second)), rejected: true},
+		{name: "changed by the same editor", userID: 2, seen: modelUpdatedAtToken(updatedAt.Add(-time.Second))},
+		{name: "no token", userID: 1},
+	} {
+		ctx := collaborationEventContext(c.userID, url.Values{paramModelUpdatedAt: []string{c.seen}})
+		err := b.checkModelUpdatedAt(TestDB, "1", ctx)
+		var vErr *web.ValidationErrors
+		if got := errors.As(err, &vErr); got != c.rejected {
+			t.Fatalf("%s: expect rejected %v, got %v", c.name, c.rejected, err)
+		}
+		if c.rejected && ctx.ContextValue(ctxKeyContainerConflict{}) == nil {
+			t.Fatalf("%s: expect the conflicting container in the context", c.name)
+		}
+	}
+
+	b.builder.collaborationEnabled = false
+	ctx := collaborationEventContext(1, url.Values{paramModelUpdatedAt: []string{modelUpdatedAtToken(updatedAt.Add(-time.Second))}})
+	if err := b.checkModelUpdatedAt(TestDB, "1", ctx); err != nil {
+		t.Fatalf("expect no check without collaboration, got %v", err)
+	}
+}
+
+func TestActiveEditors(t *testing.T) {
+	if err := TestDB.AutoMigrate(&EditorPresence{}); err != nil {
+		t.Fatal(err)
+	}
+	TestDB.Exec("DELETE FROM page_builder_editor_presences")
+	b := &Builder{db: TestDB, presenceTimeout: defaultPresenceTimeout}
+	now := time.Now()
+	for _, p := range []*EditorPresence{
+		{PageModelName: "pages", PageSlug: "1_v1", UserID: "1", UserName: "me", ContainerDataID: "Heading_1_1", LastSeenAt: now},
+		{PageModelName: "pages", PageSlug: "1_v1", UserID: "2", UserName: "other", ContainerDataID: "Heading_1_1", LastSeenAt: now},
+		{PageModelName: "pages", PageSlug: "1_v1", UserID: "3", UserName: "gone", ContainerDataID: "Heading_1_1", LastSeenAt: now.Add(-time.Hour)},
+	} {
+		if err := TestDB.Create(p).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	editors, err := b.activeEditors(TestDB.Where("container_data_id = ?", "Heading_1_1"), "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(editors) != 1 || editors[0].UserID != "2" {
+		t.Fatalf("expect only the other present editor, got %+v", editors)
+	}
+	if name := b.editorName("2"); name != "other" {
+		t.Fatalf("expect the editor name, got %q", name)
+	}
+}
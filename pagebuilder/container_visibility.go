@@ -0,0 +1,245 @@
+package pagebuilder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/i18n"
+	v "github.com/qor5/x/v3/ui/vuetify"
+	vx "github.com/qor5/x/v3/ui/vuetifyx"
+	"gorm.io/gorm"
+
+	"github.com/qor5/admin/v3/activity"
+	"github.com/qor5/admin/v3/l10n"
+	"github.com/qor5/admin/v3/presets"
+	"github.com/qor5/admin/v3/publish"
+)
+
+const (
+	containerVisibilityJobName = "page-builder-container-visibility"
+	fieldVisibleFrom           = "VisibleFrom"
+	fieldVisibleTo             = "VisibleTo"
+	timeFormatVisibility       = "2006-01-02 15:04"
+)
+
+func containerVisibilitySchedule(c *Container) string {
+	if c.VisibleFrom == nil && c.VisibleTo == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s ~ %s", publish.ScheduleTimeString(c.VisibleFrom), publish.ScheduleTimeString(c.VisibleTo))
+}
+
+func parseVisibilityTime(val string) (*time.Time, error) {
+	if val == "" {
+		return nil, nil
+	}
+	t, err := time.ParseInLocation(timeFormatVisibility, val, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (b *ModelBuilder) containerVisibilityDialog(ctx *web.EventContext) (r web.EventResponse, err error) {
+	var (
+		container   Container
+		paramID     = ctx.R.FormValue(paramContainerID)
+		cs          = container.PrimaryColumnValuesBySlug(paramID)
+		containerID = cs[presets.ParamID]
+		locale      = cs[l10n.SlugLocaleCode]
+		msgr        = i18n.MustGetModuleMessages(ctx.R, I18nPageBuilderKey, Messages_en_US).(*Messages)
+		pMsgr       = presets.MustGetMessages(ctx.R)
+	)
+	if err = b.db.Where("id = ? AND locale_code = ?", containerID, locale).First(&container).Error; err != nil {
+		return
+	}
+
+	r.UpdatePortals = append(r.UpdatePortals, &web.PortalUpdate{
+		Name: dialogPortalName,
+		Body: web.Scope(
+			vx.VXDialog(
+				v.VRow().Class("justify-center").Children(
+					v.VCol().Children(
+						vx.VXDatepicker().Type("datetimepicker").
+							Format("YYYY-MM-DD HH:mm").
+							Clearable(true).
+							Attr(web.VField(fieldVisibleFrom, publish.ScheduleTimeString(container.VisibleFrom))...).
+							Label(msgr.VisibleFrom),
+					),
+					v.VCol().Children(
+						vx.VXDatepicker().Type("datetimepicker").
+							Format("YYYY-MM-DD HH:mm").
+							Clearable(true).
+							Attr(web.VField(fieldVisibleTo, publish.ScheduleTimeString(container.VisibleTo))...).
+							Label(msgr.VisibleTo),
+					),
+				),
+			).Attr("v-model", "locals.visibilityDialog").
+				Title(msgr.VisibilitySchedule).
+				ContentHeight(108).
+				CancelText(pMsgr.Cancel).
+				OkText(pMsgr.Update).
+				Attr("@click:ok", web.Plaid().
+					EventFunc(UpdateContainerVisibilityEvent).
+					MergeQuery(true).
+					Query(paramContainerID, paramID).
+					Query(paramStatus, ctx.Param(paramStatus)).
+					Go()).
+				MaxWidth(480),
+		).VSlot("{ locals }").Init("{visibilityDialog: true}"),
+	})
+	return
+}
+
+func (b *ModelBuilder) updateContainerVisibility(ctx *web.EventContext) (r web.EventResponse, err error) {
+	var (
+		container   Container
+		paramID     = ctx.R.FormValue(paramContainerID)
+		cs          = container.PrimaryColumnValuesBySlug(paramID)
+		containerID = cs[presets.ParamID]
+		locale      = cs[l10n.SlugLocaleCode]
+		msgr        = i18n.MustGetModuleMessages(ctx.R, I18nPageBuilderKey, Messages_en_US).(*Messages)
+		obj         interface{}
+		from, to    *time.Time
+	)
+	defer func() {
+		if err != nil {
+			presets.ShowMessage(&r, err.Error(), "error")
+			err = nil
+		}
+	}()
+	if from, err = parseVisibilityTime(ctx.R.FormValue(fieldVisibleFrom)); err != nil {
+		return
+	}
+	if to, err = parseVisibilityTime(ctx.R.FormValue(fieldVisibleTo)); err != nil {
+		return
+	}
+	if from != nil && to != nil && !to.After(*from) {
+		err = errors.New(msgr.VisibleToShouldLaterThanVisibleFrom)
+		return
+	}
+	if obj, err = b.getObjFromSlug(ctx); err != nil {
+		return
+	}
+	if err = b.db.Where("id = ? AND locale_code = ?", containerID, locale).First(&container).Error; err != nil {
+		return
+	}
+	diffs := []activity.Diff{
+		{Field: fmt.Sprintf("[%s %v].VisibleFrom", container.DisplayName, container.ModelID), Old: publish.ScheduleTimeString(container.VisibleFrom), New: publish.ScheduleTimeString(from)},
+		{Field: fmt.Sprintf("[%s %v].VisibleTo", container.DisplayName, container.ModelID), Old: publish.ScheduleTimeString(container.VisibleTo), New: publish.ScheduleTimeString(to)},
+	}
+	container.VisibleFrom = from
+	container.VisibleTo = to
+	// boundaries that already passed are picked up when the page is published,
+	// so only the ones in the future need to trigger a republish.
+	now := b.db.NowFunc()
+	if err = b.db.Model(&Container{}).Where("id = ? AND locale_code = ?", containerID, locale).
+		Updates(map[string]interface{}{"visible_from": from, "visible_to": to, "visibility_synced_at": &now}).Error; err != nil {
+		return
+	}
+	if b.builder.ab != nil {
+		detail := &EditorLogInput{
+			Action:     activity.ActionEdit,
+			PageObject: obj,
+			Container:  container,
+			Detail:     diffs,
+		}
+		if b.builder.editorActivityProcessor != nil {
+			detail = b.builder.editorActivityProcessor(ctx, detail)
+		}
+		if mb, ok := b.builder.ab.GetModelBuilder(b.mb); ok && detail != nil {
+			mb.Log(ctx.R.Context(), detail.Action, detail.PageObject, detail.Detail)
+		}
+	}
+
+	web.AppendRunScripts(&r,
+		"locals.visibilityDialog=false",
+		web.Plaid().
+			EventFunc(ReloadRenderPageOrTemplateBodyEvent).
+			MergeQuery(true).
+			Go(),
+		web.Plaid().
+			EventFunc(ShowSortedContainerDrawerEvent).
+			MergeQuery(true).
+			Query(paramStatus, ctx.Param(paramStatus)).
+			Go(),
+	)
+	return
+}
+
+type visibilityChangedPage struct {
+	PageModelName string
+	PageID        uint
+	PageVersion   string
+	LocaleCode    string
+}
+
+// RepublishContainerVisibilityChanges republishes the online pages that have a container
+// whose visibility window started or ended since the last run, so static pages stay in sync.
+func (b *Builder) RepublishContainerVisibilityChanges(ctx context.Context) (err error) {
+	if b.publisher == nil {
+		return
+	}
+	now := b.db.NowFunc()
+	boundaryPassed := b.db.
+		Where("visible_from <= ? AND (visibility_synced_at IS NULL OR visibility_synced_at < visible_from)", now).
+		Or("visible_to <= ? AND (visibility_synced_at IS NULL OR visibility_synced_at < visible_to)", now)
+
+	var pages []*visibilityChangedPage
+	if err = b.db.Model(&Container{}).
+		Select("DISTINCT page_model_name, page_id, page_version, locale_code").
+		Where(boundaryPassed).
+		Scan(&pages).Error; err != nil {
+		return
+	}
+
+	ctx = b.publisher.WithContextValues(ctx)
+	for _, p := range pages {
+		if err = b.republishVisibilityChangedPage(ctx, p); err != nil {
+			return
+		}
+		if err = b.db.Model(&Container{}).
+			Where("page_model_name = ? AND page_id = ? AND page_version = ? AND locale_code = ?", p.PageModelName, p.PageID, p.PageVersion, p.LocaleCode).
+			Where(boundaryPassed).
+			UpdateColumn("visibility_synced_at", now).Error; err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (b *Builder) republishVisibilityChangedPage(ctx context.Context, p *visibilityChangedPage) (err error) {
+	m := b.getModelBuilderByName(p.PageModelName)
+	if m == nil || m.isTemplate {
+		return
+	}
+	page := m.mb.NewModel()
+	db := b.db.Where("id = ? AND version = ?", p.PageID, p.PageVersion)
+	if p.LocaleCode != "" {
+		db = db.Where("locale_code = ?", p.LocaleCode)
+	}
+	if err = db.First(page).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return
+	}
+	if st, ok := page.(publish.StatusInterface); !ok || st.EmbedStatus().Status != publish.StatusOnline {
+		return
+	}
+	return b.publisher.Publish(ctx, page)
+}
+
+// RunContainerVisibilityScheduler starts a background job that republishes pages
+// every minute when a container visibility window boundary passes.
+func (b *Builder) RunContainerVisibilityScheduler(ctx context.Context) {
+	go publish.RunJob(containerVisibilityJobName, time.Minute, time.Minute*5, func() {
+		if err := b.RepublishContainerVisibilityChanges(ctx); err != nil {
+			log.Printf("container visibility scheduler error: %v\n", err)
+		}
+	})
+}
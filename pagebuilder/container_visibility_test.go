@@ -0,0 +1,30 @@
+package pagebuilder
+
+import (
+	"testing"
+	"time"
+)
+
+func TestContainerIsVisibleAt(t *testing.T) {
+	now := time.Date(2024, 12, 24, 10, 0, 0, 0, time.UTC)
+	before := now.Add(-time.Hour)
+	after := now.Add(time.Hour)
+	for _, c := range []struct {
+		name      string
+		container Container
+		expect    bool
+	}{
+		{name: "no window", container: Container{}, expect: true},
+		{name: "hidden", container: Container{Hidden: true}, expect: false},
+		{name: "started", container: Container{VisibleFrom: &before}, expect: true},
+		{name: "not started", container: Container{VisibleFrom: &after}, expect: false},
+		{name: "not ended", container: Container{VisibleTo: &after}, expect: true},
+		{name: "ended", container: Container{VisibleTo: &before}, expect: false},
+		{name: "ends now", container: Container{VisibleTo: &now}, expect: false},
+		{name: "inside window", container: Container{VisibleFrom: &before, VisibleTo: &after}, expect: true},
+	} {
+		if got := c.container.IsVisibleAt(now); got != c.expect {
+			t.Fatalf("%s: expect %v, got %v", c.name, c.expect, got)
+		}
+	}
+}
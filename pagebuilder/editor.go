@@ -36,6 +36,12 @@ const (
 	EditContainerEvent                  = "page_builder_EditContainerEvent"
 	UpdateContainerEvent                = "page_builder_UpdateContainerEvent"
 	ReloadAddContainersListEvent        = "page_builder_ReloadAddContainersEvent"
+	PresenceHeartbeatEvent              = "page_builder_PresenceHeartbeatEvent"
+	ContainerVisibilityDialogEvent      = "page_builder_ContainerVisibilityDialogEvent"
+	UpdateContainerVisibilityEvent      = "page_builder_UpdateContainerVisibilityEvent"
+	ToggleContainerLockEvent            = "page_builder_ToggleContainerLockEvent"
+	SyncPageTemplateEvent               = "page_builder_SyncPageTemplateEvent"
+	SyncTemplatePagesEvent              = "page_builder_SyncTemplatePagesEvent"
 
 	ParamContainerCreate = "paramContainerCreate"
 
@@ -56,6 +62,8 @@ const (
 	paramDevice          = "device"
 	paramDisplayName     = "DisplayName"
 	paramDemoContainer   = "demoContainer"
+	paramModelUpdatedAt  = "modelUpdatedAt"
+	paramForceEdit       = "forceEdit"
 
 	DevicePhone    = "phone"
 	DeviceTablet   = "tablet"
@@ -211,6 +219,7 @@ func (b *Builder) editorBody(ctx *web.EventContext, m *ModelBuilder) (body h.HTM
 			VAppBarTitle().Text(title),
 		).Class("d-inline-flex align-center"),
 		h.Div(deviceToggle).Class("text-center d-flex justify-space-between mx-6"),
+		h.If(b.collaborationEnabled, b.presenceComponent()),
 		versionComponent,
 		publish.NewListenerModelsDeleted(m.mb, ctx.Param(presets.ParamID)),
 		newListenerVersionSelected(ctx, m.mb, m.editorURL(), ctx.Param(presets.ParamID)),
@@ -483,6 +492,9 @@ type ContainerSorterItem struct {
 	Shared          bool   `json:"shared"`
 	Hidden          bool   `json:"hidden"`
 	VisibilityIcon  string `json:"visibility_icon"`
+	Schedule        string `json:"schedule"`
+	Locked          bool   `json:"locked"`
+	Inherited       bool   `json:"inherited"`
 	ParamID         string `json:"param_id"`
 	Locale          string `json:"locale"`
 }
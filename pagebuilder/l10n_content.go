@@ -0,0 +1,128 @@
+package pagebuilder
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sunfmin/reflectutils"
+	"gorm.io/gorm"
+
+	"github.com/qor5/admin/v3/l10n"
+	"github.com/qor5/admin/v3/presets"
+	"github.com/qor5/admin/v3/presets/gorm2op"
+)
+
+const containerSegmentPrefix = "containers."
+
+// contextDB returns the transaction in ctx, such as the one of an import of l10n, or the db of the builder
+func (b *ModelBuilder) contextDB(ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(gorm2op.CtxKeyDB{}).(*gorm.DB); ok && tx != nil {
+		return tx
+	}
+	return b.db.WithContext(ctx)
+}
+
+// pageContainers returns the containers owned by the page, shared containers and the ones inherited from
+// a locked template region are left out, they are localized with their own records.
+func (b *ModelBuilder) pageContainers(ctx context.Context, obj interface{}) (cons []*Container, err error) {
+	slug, ok := obj.(presets.SlugEncoder)
+	if !ok {
+		return
+	}
+	pageID, pageVersion, locale := b.primaryColumnValuesBySlug(slug.PrimarySlug())
+	err = b.contextDB(ctx).Order("display_order ASC").
+		Where("page_id = ? AND page_version = ? AND locale_code = ? AND page_model_name = ? AND shared = ? AND template_container_id = 0",
+			pageID, pageVersion, locale, b.name, false).
+		Find(&cons).Error
+	return
+}
+
+func containerSegmentKey(c *Container, field string) string {
+	return fmt.Sprintf("%s%d.%s", containerSegmentPrefix, c.ID, field)
+}
+
+func parseContainerSegmentKey(key string) (id uint, field string, ok bool) {
+	rest, ok := strings.CutPrefix(key, containerSegmentPrefix)
+	if !ok {
+		return
+	}
+	idStr, field, ok := strings.Cut(rest, ".")
+	if !ok {
+		return
+	}
+	v, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return uint(v), field, true
+}
+
+// ExtractContent implements l10n.ContentExtractor, it extracts the translatable fields of the page containers
+func (b *ModelBuilder) ExtractContent(ctx context.Context, obj interface{}) (r []*l10n.ContentSegment, err error) {
+	cons, err := b.pageContainers(ctx, obj)
+	if err != nil {
+		return
+	}
+	for _, c := range cons {
+		model := b.builder.ContainerByName(c.ModelName).NewModel()
+		if err = b.contextDB(ctx).First(model, "id = ?", c.ModelID).Error; err != nil {
+			return
+		}
+		for _, field := range b.builder.l10n.GetTranslatableFields(model) {
+			value, gErr := reflectutils.Get(model, field)
+			if gErr != nil {
+				return nil, gErr
+			}
+			if text, ok := value.(string); ok && strings.TrimSpace(text) != "" {
+				r = append(r, &l10n.ContentSegment{
+					Key:    containerSegmentKey(c, field),
+					Note:   c.DisplayName,
+					Source: text,
+				})
+			}
+		}
+	}
+	return
+}
+
+// ApplyContent implements l10n.ContentExtractor, it sets the translations to the containers of the localized page
+func (b *ModelBuilder) ApplyContent(ctx context.Context, obj interface{}, segments []*l10n.ContentSegment) (err error) {
+	cons, err := b.pageContainers(ctx, obj)
+	if err != nil {
+		return
+	}
+	containers := make(map[uint]*Container, len(cons))
+	for _, c := range cons {
+		containers[c.ID] = c
+	}
+	models := make(map[uint]interface{})
+	for _, s := range segments {
+		id, field, ok := parseContainerSegmentKey(s.Key)
+		if !ok {
+			return fmt.Errorf("invalid container segment %s", s.Key)
+		}
+		c, ok := containers[id]
+		if !ok {
+			return fmt.Errorf("container %d is not localized in the page", id)
+		}
+		model, ok := models[id]
+		if !ok {
+			model = b.builder.ContainerByName(c.ModelName).NewModel()
+			if err = b.contextDB(ctx).First(model, "id = ?", c.ModelID).Error; err != nil {
+				return
+			}
+			models[id] = model
+		}
+		if err = reflectutils.Set(model, field, s.Target); err != nil {
+			return
+		}
+	}
+	for _, model := range models {
+		if err = b.contextDB(ctx).Save(model).Error; err != nil {
+			return
+		}
+	}
+	return
+}
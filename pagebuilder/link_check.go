@@ -0,0 +1,438 @@
+package pagebuilder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/i18n"
+	"github.com/sunfmin/reflectutils"
+	h "github.com/theplant/htmlgo"
+	"golang.org/x/net/html"
+	"gorm.io/gorm"
+
+	"github.com/qor5/admin/v3/media/media_library"
+	mediaoss "github.com/qor5/admin/v3/media/oss"
+	"github.com/qor5/admin/v3/presets"
+	"github.com/qor5/admin/v3/publish"
+	"github.com/qor5/admin/v3/redirection"
+	"github.com/qor5/admin/v3/worker"
+)
+
+const (
+	LinkCheckJobName = "PageBuilderLinkCheck"
+
+	BrokenLinkKindLink  = "link"
+	BrokenLinkKindAsset = "asset"
+
+	BrokenLinkReasonInvalidURL    = "invalid_url"
+	BrokenLinkReasonPageNotFound  = "page_not_found"
+	BrokenLinkReasonRedirectLoop  = "redirect_loop"
+	BrokenLinkReasonMediaNotFound = "media_not_found"
+
+	linkCheckMaxRedirects = 10
+)
+
+var mediaLibraryPathRegexp = regexp.MustCompile(`^/system/media_libraries/(\d+)/`)
+
+// BrokenLink is a row of the report produced by the link check job,
+// one for every broken link or missing asset found in an online page.
+type BrokenLink struct {
+	gorm.Model
+	PageModelName   string `gorm:"index"`
+	PageSlug        string
+	PageTitle       string
+	ContainerDataID string
+	ContainerName   string
+	Kind            string
+	URL             string
+	Reason          string
+}
+
+func (*BrokenLink) TableName() string {
+	return "page_builder_broken_links"
+}
+
+type LinkCheckJobArgs struct {
+	// Hosts are the site domains, links to them are checked like relative links. One per line.
+	Hosts string
+	// IgnorePaths are path prefixes that are never reported. One per line.
+	IgnorePaths string
+}
+
+type pageLink struct {
+	ContainerDataID string
+	Kind            string
+	URL             string
+}
+
+// extractPageLinks returns the links and assets inside the page containers,
+// anything in the page layout outside a container is not reported.
+func extractPageLinks(content string) (links []*pageLink, err error) {
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return
+	}
+	var walk func(n *html.Node, containerDataID string)
+	walk = func(n *html.Node, containerDataID string) {
+		if n.Type == html.ElementNode {
+			for _, attr := range n.Attr {
+				if attr.Key == "data-container-id" && attr.Val != "" {
+					containerDataID = attr.Val
+				}
+			}
+			if containerDataID != "" {
+				for _, attr := range n.Attr {
+					switch attr.Key {
+					case "href":
+						links = append(links, &pageLink{ContainerDataID: containerDataID, Kind: BrokenLinkKindLink, URL: attr.Val})
+					case "src", "poster":
+						links = append(links, &pageLink{ContainerDataID: containerDataID, Kind: BrokenLinkKindAsset, URL: attr.Val})
+					case "srcset":
+						for _, candidate := range strings.Split(attr.Val, ",") {
+							if fields := strings.Fields(candidate); len(fields) > 0 {
+								links = append(links, &pageLink{ContainerDataID: containerDataID, Kind: BrokenLinkKindAsset, URL: fields[0]})
+							}
+						}
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, containerDataID)
+		}
+	}
+	walk(doc, "")
+	return
+}
+
+func normalizeLinkPath(p string) string {
+	p = path.Clean("/" + p)
+	p = strings.TrimSuffix(p, "/index.html")
+	if p == "" {
+		return "/"
+	}
+	return p
+}
+
+func splitLines(s string) (r []string) {
+	for _, v := range strings.FieldsFunc(s, func(c rune) bool { return c == '\n' || c == ',' }) {
+		if v = strings.TrimSpace(v); v != "" {
+			r = append(r, v)
+		}
+	}
+	return
+}
+
+type linkChecker struct {
+	hosts       map[string]bool
+	pages       map[string]bool
+	redirects   map[string]string
+	ignorePaths []string
+	mediaExists func(id uint) (bool, error)
+}
+
+func (c *linkChecker) isInternal(u *url.URL) bool {
+	return u.Host == "" || c.hosts[strings.ToLower(u.Hostname())]
+}
+
+// check returns the reason why rawURL is broken, or empty if it is fine or can not be checked offline.
+func (c *linkChecker) check(kind, rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return BrokenLinkReasonInvalidURL, nil
+	}
+	if u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" {
+		return "", nil
+	}
+	if u.Path == "" || !c.isInternal(u) {
+		return "", nil
+	}
+	if u.Host == "" && !strings.HasPrefix(u.Path, "/") {
+		return "", nil
+	}
+	for _, prefix := range c.ignorePaths {
+		if strings.HasPrefix(u.Path, prefix) {
+			return "", nil
+		}
+	}
+	if m := mediaLibraryPathRegexp.FindStringSubmatch(u.Path); m != nil {
+		id, _ := strconv.ParseUint(m[1], 10, 64)
+		if c.mediaExists == nil {
+			return "", nil
+		}
+		exists, err := c.mediaExists(uint(id))
+		if err != nil || exists {
+			return "", err
+		}
+		return BrokenLinkReasonMediaNotFound, nil
+	}
+	if kind != BrokenLinkKindLink {
+		return "", nil
+	}
+	p := u.Path
+	for range linkCheckMaxRedirects {
+		p = normalizeLinkPath(p)
+		if c.pages[p] {
+			return "", nil
+		}
+		target, ok := c.redirects[p]
+		if !ok {
+			return BrokenLinkReasonPageNotFound, nil
+		}
+		t, err := url.Parse(target)
+		if err != nil {
+			return BrokenLinkReasonInvalidURL, nil
+		}
+		if !c.isInternal(t) {
+			return "", nil
+		}
+		p = t.Path
+	}
+	return BrokenLinkReasonRedirectLoop, nil
+}
+
+func (b *Builder) newLinkChecker(ctx context.Context, args *LinkCheckJobArgs) (c *linkChecker, err error) {
+	c = &linkChecker{
+		hosts:     map[string]bool{},
+		pages:     map[string]bool{},
+		redirects: map[string]string{},
+		mediaExists: func(id uint) (bool, error) {
+			var count int64
+			err := b.db.Model(&media_library.MediaLibrary{}).Where("id = ?", id).Count(&count).Error
+			return count > 0, err
+		},
+	}
+	endpoints := []string{mediaoss.Storage.GetEndpoint(ctx)}
+	if b.publisher != nil {
+		if endpoint, fErr := b.publisher.FullUrl(ctx, "/"); fErr == nil {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	for _, endpoint := range endpoints {
+		if !strings.Contains(endpoint, "//") {
+			endpoint = "//" + endpoint
+		}
+		if u, pErr := url.Parse(endpoint); pErr == nil && u.Host != "" {
+			c.hosts[strings.ToLower(u.Hostname())] = true
+		}
+	}
+	if args != nil {
+		for _, host := range splitLines(args.Hosts) {
+			c.hosts[strings.ToLower(host)] = true
+		}
+		c.ignorePaths = splitLines(args.IgnorePaths)
+	}
+
+	if b.db.Migrator().HasTable(&redirection.Redirection{}) {
+		var redirections []*redirection.Redirection
+		if err = b.db.Where("target <> ''").Order("id ASC").Find(&redirections).Error; err != nil {
+			return
+		}
+		for _, r := range redirections {
+			// the pattern redirections have no single source to look up
+			if r.MatchType != "" && r.MatchType != redirection.MatchExact {
+				continue
+			}
+			c.redirects[normalizeLinkPath(r.Source)] = r.Target
+		}
+	}
+	return
+}
+
+func (b *Builder) containerDisplayName(containerDataID string) string {
+	// containerDataID is formatted as {kind}_{modelID}_{containerID}[_{localeCode}]
+	segs := strings.SplitN(containerDataID, "_", 4)
+	if len(segs) < 3 {
+		return ""
+	}
+	var con Container
+	wh := b.db.Where("id = ?", segs[2])
+	if len(segs) == 4 {
+		wh = wh.Where("locale_code = ?", segs[3])
+	}
+	if wh.First(&con).Error != nil {
+		return ""
+	}
+	return con.DisplayName
+}
+
+// LinkCheckJob registers the job that crawls the online pages and records
+// broken internal links and missing media into the BrokenLink report.
+// It works offline, only internal links and media library files are checked.
+func (b *Builder) LinkCheckJob(w *worker.Builder) *worker.JobBuilder {
+	b.linkCheckEnabled = true
+	return w.NewJob(LinkCheckJobName).
+		Resource(&LinkCheckJobArgs{}).
+		Handler(b.checkLinks)
+}
+
+func (b *Builder) checkLinks(ctx context.Context, job worker.QorJobInterface) (err error) {
+	jobInfo, err := job.GetJobInfo()
+	if err != nil {
+		return
+	}
+	args, _ := jobInfo.Argument.(*LinkCheckJobArgs)
+	checker, err := b.newLinkChecker(ctx, args)
+	if err != nil {
+		return
+	}
+
+	type onlinePage struct {
+		model  *ModelBuilder
+		record interface{}
+	}
+	var pages []onlinePage
+	for _, m := range b.models {
+		if m.isTemplate {
+			continue
+		}
+		if _, ok := m.mb.NewModel().(publish.StatusInterface); !ok {
+			continue
+		}
+		records := m.mb.NewModelSlice()
+		if err = b.db.Where("status = ?", publish.StatusOnline).Find(records).Error; err != nil {
+			return
+		}
+		reflectutils.ForEach(records, func(record interface{}) {
+			if onlineUrl := record.(publish.StatusInterface).EmbedStatus().OnlineUrl; onlineUrl != "" {
+				checker.pages[normalizeLinkPath(onlineUrl)] = true
+			}
+			pages = append(pages, onlinePage{model: m, record: record})
+		})
+	}
+
+	var (
+		results        []*BrokenLink
+		containerNames = map[string]string{}
+	)
+	for i, p := range pages {
+		slug := p.record.(presets.SlugEncoder).PrimarySlug()
+		links, pErr := extractPageLinks(p.model.PreviewHTML(ctx, p.record))
+		if pErr != nil {
+			job.AddLogf("%s %s: %v", p.model.name, slug, pErr)
+			continue
+		}
+		title := slug
+		if v, gErr := reflectutils.Get(p.record, "Title"); gErr == nil && fmt.Sprint(v) != "" {
+			title = fmt.Sprint(v)
+		}
+		for _, link := range links {
+			var reason string
+			if reason, err = checker.check(link.Kind, link.URL); err != nil {
+				return
+			}
+			if reason == "" {
+				continue
+			}
+			name, ok := containerNames[link.ContainerDataID]
+			if !ok {
+				name = b.containerDisplayName(link.ContainerDataID)
+				containerNames[link.ContainerDataID] = name
+			}
+			results = append(results, &BrokenLink{
+				PageModelName:   p.model.name,
+				PageSlug:        slug,
+				PageTitle:       title,
+				ContainerDataID: link.ContainerDataID,
+				ContainerName:   name,
+				Kind:            link.Kind,
+				URL:             link.URL,
+				Reason:          reason,
+			})
+		}
+		if err = job.SetProgress(uint((i + 1) * 100 / len(pages))); err != nil {
+			return
+		}
+	}
+
+	err = b.db.Transaction(func(tx *gorm.DB) (dbErr error) {
+		if dbErr = tx.Unscoped().Where("1 = 1").Delete(&BrokenLink{}).Error; dbErr != nil {
+			return
+		}
+		if len(results) == 0 {
+			return
+		}
+		return tx.CreateInBatches(results, 100).Error
+	})
+	if err != nil {
+		return
+	}
+	return errors.Join(
+		job.AddLogf("checked %d pages, found %d broken links", len(pages), len(results)),
+		job.SetProgress(100),
+	)
+}
+
+func (b *Builder) brokenLinkEditorURL(obj *BrokenLink) string {
+	for _, m := range b.models {
+		if m.name == obj.PageModelName {
+			return m.editorURLWithSlug(obj.PageSlug)
+		}
+	}
+	return ""
+}
+
+func (b *Builder) installBrokenLinks(pb *presets.Builder) {
+	pm := pb.Model(&BrokenLink{}).URIName("page_broken_links").Label("Broken Links").MenuIcon("mdi-link-off")
+	pm.LabelName(func(evCtx *web.EventContext, singular bool) string {
+		msgr := i18n.MustGetModuleMessages(evCtx.R, I18nPageBuilderKey, Messages_en_US).(*Messages)
+		if singular {
+			return msgr.ModelLabelBrokenLink
+		}
+		return msgr.ModelLabelBrokenLinks
+	})
+
+	lb := pm.Listing("PageTitle", "ContainerName", "Kind", "URL", "Reason", "UpdatedAt").SearchColumns("page_title", "url")
+	lb.WrapColumns(presets.CustomizeColumnLabel(func(evCtx *web.EventContext) (map[string]string, error) {
+		msgr := i18n.MustGetModuleMessages(evCtx.R, I18nPageBuilderKey, Messages_en_US).(*Messages)
+		return map[string]string{
+			"PageTitle":     msgr.ModelLabelPage,
+			"ContainerName": msgr.BrokenLinkContainer,
+			"Kind":          msgr.BrokenLinkKind,
+			"URL":           msgr.BrokenLinkURL,
+			"Reason":        msgr.BrokenLinkReason,
+			"UpdatedAt":     msgr.BrokenLinkCheckedAt,
+		}, nil
+	}))
+	lb.NewButtonFunc(func(ctx *web.EventContext) h.HTMLComponent { return nil })
+	lb.RowMenu().Empty()
+	lb.Field("Kind").ComponentFunc(func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) h.HTMLComponent {
+		msgr := i18n.MustGetModuleMessages(ctx.R, I18nPageBuilderKey, Messages_en_US).(*Messages)
+		labels := map[string]string{
+			BrokenLinkKindLink:  msgr.BrokenLinkKindLink,
+			BrokenLinkKindAsset: msgr.BrokenLinkKindAsset,
+		}
+		return h.Td(h.Text(labels[obj.(*BrokenLink).Kind]))
+	})
+	lb.Field("Reason").ComponentFunc(func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) h.HTMLComponent {
+		msgr := i18n.MustGetModuleMessages(ctx.R, I18nPageBuilderKey, Messages_en_US).(*Messages)
+		labels := map[string]string{
+			BrokenLinkReasonInvalidURL:    msgr.BrokenLinkReasonInvalidURL,
+			BrokenLinkReasonPageNotFound:  msgr.BrokenLinkReasonPageNotFound,
+			BrokenLinkReasonRedirectLoop:  msgr.BrokenLinkReasonRedirectLoop,
+			BrokenLinkReasonMediaNotFound: msgr.BrokenLinkReasonMediaNotFound,
+		}
+		return h.Td(h.Text(labels[obj.(*BrokenLink).Reason]))
+	})
+	lb.Field("UpdatedAt").ComponentFunc(func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) h.HTMLComponent {
+		return h.Td(h.Text(obj.(*BrokenLink).UpdatedAt.Format("2006-01-02 15:04:05")))
+	})
+	lb.CellWrapperFunc(func(cell h.MutableAttrHTMLComponent, id string, obj interface{}, dataTableID string) h.HTMLComponent {
+		link := obj.(*BrokenLink)
+		if editorURL := b.brokenLinkEditorURL(link); editorURL != "" {
+			cell.SetAttr("@click", web.Plaid().URL(editorURL).Query(paramContainerDataID, link.ContainerDataID).PushState(true).Go())
+		}
+		return cell
+	})
+}
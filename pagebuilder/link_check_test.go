@@ -0,0 +1,81 @@
+package pagebuilder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExtractPageLinks(t *testing.T) {
+	links, err := extractPageLinks(`<html><head><link href="/assets/main.css"></head><body>
+<div data-container-id="headers_1_1_International"><a href="/en/about">About</a><img src="/system/media_libraries/2/file.jpg" srcset="/a.jpg 1x, /b.jpg 2x"></div>
+<footer><a href="/outside"></a></footer>
+</body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []*pageLink{
+		{ContainerDataID: "headers_1_1_International", Kind: BrokenLinkKindLink, URL: "/en/about"},
+		{ContainerDataID: "headers_1_1_International", Kind: BrokenLinkKindAsset, URL: "/system/media_libraries/2/file.jpg"},
+		{ContainerDataID: "headers_1_1_International", Kind: BrokenLinkKindAsset, URL: "/a.jpg"},
+		{ContainerDataID: "headers_1_1_International", Kind: BrokenLinkKindAsset, URL: "/b.jpg"},
+	}
+	if diff := cmp.Diff(expect, links); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestLinkCheckerCheck(t *testing.T) {
+	c := &linkChecker{
+		hosts: map[string]bool{"www.example.com": true},
+		pages: map[string]bool{"/en/about": true, "/": true},
+		redirects: map[string]string{
+			"/old":   "/en/about/index.html",
+			"/gone":  "/missing",
+			"/loop":  "/loop2",
+			"/loop2": "/loop",
+			"/away":  "https://other.com/",
+		},
+		ignorePaths: []string{"/assets/"},
+		mediaExists: func(id uint) (bool, error) { return id == 1, nil },
+	}
+	for _, cs := range []struct {
+		name   string
+		kind   string
+		url    string
+		expect string
+	}{
+		{name: "online page", kind: BrokenLinkKindLink, url: "/en/about/"},
+		{name: "online page index", kind: BrokenLinkKindLink, url: "/en/about/index.html?a=b#top"},
+		{name: "site host", kind: BrokenLinkKindLink, url: "https://www.example.com/en/about"},
+		{name: "root", kind: BrokenLinkKindLink, url: "/"},
+		{name: "external", kind: BrokenLinkKindLink, url: "https://other.com/nothing"},
+		{name: "mailto", kind: BrokenLinkKindLink, url: "mailto:a@example.com"},
+		{name: "anchor", kind: BrokenLinkKindLink, url: "#top"},
+		{name: "ignored", kind: BrokenLinkKindLink, url: "/assets/a.css"},
+		{name: "missing page", kind: BrokenLinkKindLink, url: "/en/contact", expect: BrokenLinkReasonPageNotFound},
+		{name: "redirected", kind: BrokenLinkKindLink, url: "/old"},
+		{name: "redirected away", kind: BrokenLinkKindLink, url: "/away"},
+		{name: "redirected to missing", kind: BrokenLinkKindLink, url: "/gone", expect: BrokenLinkReasonPageNotFound},
+		{name: "redirect loop", kind: BrokenLinkKindLink, url: "/loop", expect: BrokenLinkReasonRedirectLoop},
+		{name: "media", kind: BrokenLinkKindAsset, url: "//www.example.com/system/media_libraries/1/file.jpg"},
+		{name: "missing media", kind: BrokenLinkKindAsset, url: "/system/media_libraries/2/file.jpg", expect: BrokenLinkReasonMediaNotFound},
+		{name: "missing media link", kind: BrokenLinkKindLink, url: "/system/media_libraries/3/file.pdf", expect: BrokenLinkReasonMediaNotFound},
+		{name: "other asset", kind: BrokenLinkKindAsset, url: "/images/logo.png"},
+	} {
+		reason, err := c.check(cs.kind, cs.url)
+		if err != nil {
+			t.Fatalf("%s: %v", cs.name, err)
+		}
+		if diff := cmp.Diff(cs.expect, reason); diff != "" {
+			t.Fatalf("%s: %s\n", cs.name, diff)
+		}
+	}
+
+	dbErr := errors.New("connection refused")
+	c.mediaExists = func(uint) (bool, error) { return false, dbErr }
+	if _, err := c.check(BrokenLinkKindAsset, "/system/media_libraries/2/file.jpg"); !errors.Is(err, dbErr) {
+		t.Fatalf("expect the lookup error, got %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package pagebuilder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+
+	"github.com/qor5/x/v3/oss"
+	h "github.com/theplant/htmlgo"
+	"gorm.io/gorm"
+
+	"github.com/qor5/admin/v3/publish"
+	"github.com/qor5/admin/v3/utils"
+)
+
+type ctxKeyFallbackCanonical struct{}
+
+// PageByPath returns the online page published at the path in the locale, the page path excludes the locale path.
+// The page in the nearest fallback locale is returned when the locale has no page at the path.
+func (b *Builder) PageByPath(ctx context.Context, localeCode, pagePath string) (page *Page, err error) {
+	db := b.db.WithContext(ctx)
+	target := generatePublishUrl("", pagePath, "")
+
+	var (
+		chain = []string{localeCode}
+		q     = queryLocaleCodeCategoryPathSlugSQL + " AND pages.status = ?"
+		args  = []interface{}{publish.StatusOnline}
+		infos []pagePathInfo
+	)
+	if b.l10n != nil {
+		chain = b.l10n.GetLocaleChain(localeCode)
+		q += " AND pages.locale_code IN ?"
+		args = append(args, chain)
+	}
+	if err = db.Raw(q, args...).Scan(&infos).Error; err != nil {
+		return
+	}
+	for _, code := range chain {
+		i := slices.IndexFunc(infos, func(info pagePathInfo) bool {
+			return (b.l10n == nil || info.LocaleCode == code) && generatePublishUrl("", info.CategoryPath, info.Slug) == target
+		})
+		if i < 0 {
+			continue
+		}
+		page = &Page{}
+		err = withLocale(b, db.Where("id = ? AND version = ?", infos[i].ID, infos[i].Version), infos[i].LocaleCode).
+			First(page).Error
+		return
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// servingPage returns the online version of the page serving the locale, which is the page in the locale
+// or the page in its nearest fallback locale. with is the page being published, the version in locale except
+// is the one being unpublished.
+func (b *ModelBuilder) servingPage(db *gorm.DB, pageID uint, localeCode string, with *Page, except string) (page *Page, err error) {
+	for _, code := range b.builder.l10n.GetLocaleChain(localeCode) {
+		if with != nil && with.LocaleCode == code {
+			return with, nil
+		}
+		if code == except {
+			continue
+		}
+		page = &Page{}
+		err = db.Where("id = ? AND locale_code = ? AND status = ?", pageID, code, publish.StatusOnline).First(page).Error
+		if err == nil {
+			return
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// fallbackPublishUrl is where the page is published for the locale falling back to its locale
+func (b *ModelBuilder) fallbackPublishUrl(db *gorm.DB, page *Page, localeCode string) (string, error) {
+	category, err := page.GetCategory(db)
+	if err != nil {
+		return "", err
+	}
+	return page.getPublishUrl(b.builder.l10n.GetLocalePath(localeCode), category.Path), nil
+}
+
+// fallbackHTML renders the page for the locales falling back to its locale, the canonical link
+// points to the page in its own locale.
+func (b *ModelBuilder) fallbackHTML(ctx context.Context, db *gorm.DB, storage oss.StorageInterface, page *Page) (string, error) {
+	publishUrl, err := b.fallbackPublishUrl(db, page, page.LocaleCode)
+	if err != nil {
+		return "", err
+	}
+	canonical := strings.TrimSuffix(storage.GetEndpoint(ctx), "/") + page.getAccessUrl(publishUrl)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", fmt.Sprintf("/?id=%s", page.PrimarySlug()), http.NoBody)
+	req = req.WithContext(context.WithValue(ctx, ctxKeyFallbackCanonical{}, canonical))
+	b.preview.ServeHTTP(w, req)
+	return w.Body.String(), nil
+}
+
+func fallbackCanonicalLink(ctx context.Context) h.HTMLComponent {
+	canonical, ok := ctx.Value(ctxKeyFallbackCanonical{}).(string)
+	if !ok {
+		return nil
+	}
+	return h.Link(canonical).Rel("canonical")
+}
+
+// fallbackPublishActions keeps the pages published for the locales falling back to the locale of p,
+// p is being published, or unpublished if unpublishing is true.
+func (b *ModelBuilder) fallbackPublishActions(ctx context.Context, db *gorm.DB, storage oss.StorageInterface, p *Page, unpublishing bool) (actions []*publish.PublishAction, err error) {
+	for _, code := range append([]string{p.LocaleCode}, b.builder.l10n.GetFallingBackLocales(p.LocaleCode)...) {
+		var before, after *Page
+		if before, err = b.servingPage(db, p.ID, code, nil, ""); err != nil {
+			return
+		}
+		if unpublishing {
+			if before == nil || before.LocaleCode != p.LocaleCode {
+				continue
+			}
+			after, err = b.servingPage(db, p.ID, code, nil, p.LocaleCode)
+		} else {
+			after, err = b.servingPage(db, p.ID, code, p, "")
+		}
+		if err != nil {
+			return
+		}
+
+		var beforeUrl, afterUrl string
+		if before != nil && (before.LocaleCode != code || unpublishing) {
+			if beforeUrl, err = b.fallbackPublishUrl(db, before, code); err != nil {
+				return
+			}
+		}
+		if after != nil {
+			if afterUrl, err = b.fallbackPublishUrl(db, after, code); err != nil {
+				return
+			}
+		}
+		// the page in the locale itself is published and unpublished by the default actions
+		if beforeUrl != "" && beforeUrl != afterUrl && (code != p.LocaleCode || !unpublishing) {
+			actions = append(actions, &publish.PublishAction{Url: beforeUrl, IsDelete: true})
+		}
+		if after == nil || after.LocaleCode == code || (!unpublishing && after != p) {
+			continue
+		}
+		var content string
+		if content, err = b.fallbackHTML(ctx, db, storage, after); err != nil {
+			return
+		}
+		actions = append(actions, &publish.PublishAction{Url: afterUrl, Content: content})
+	}
+	return
+}
+
+// WrapPublishActions publishes the page for the locales falling back to its locale too,
+// unless they have the page in a nearer locale.
+func (p *Page) WrapPublishActions(in publish.PublishActionsFunc) publish.PublishActionsFunc {
+	return func(ctx context.Context, db *gorm.DB, storage oss.StorageInterface, obj any) (actions []*publish.PublishAction, err error) {
+		if actions, err = in(ctx, db, storage, obj); err != nil {
+			return
+		}
+		b, ok := ctx.Value(utils.GetObjectName(p)).(*ModelBuilder)
+		if !ok || b.builder.l10n == nil || b.preview == nil {
+			return
+		}
+		fallbackActions, err := b.fallbackPublishActions(ctx, db, storage, p, false)
+		return append(actions, fallbackActions...), err
+	}
+}
+
+// WrapUnPublishActions replaces the page with the one in the next fallback locale
+// for the locales it is published for.
+func (p *Page) WrapUnPublishActions(in publish.PublishActionsFunc) publish.PublishActionsFunc {
+	return func(ctx context.Context, db *gorm.DB, storage oss.StorageInterface, obj any) (actions []*publish.PublishAction, err error) {
+		if actions, err = in(ctx, db, storage, obj); err != nil {
+			return
+		}
+		b, ok := ctx.Value(utils.GetObjectName(p)).(*ModelBuilder)
+		if !ok || b.builder.l10n == nil || b.preview == nil {
+			return
+		}
+		fallbackActions, err := b.fallbackPublishActions(ctx, db, storage, p, true)
+		return append(actions, fallbackActions...), err
+	}
+}
@@ -0,0 +1,183 @@
+package pagebuilder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qor5/x/v3/oss/filesystem"
+	"gorm.io/gorm"
+
+	"github.com/qor5/admin/v3/l10n"
+	"github.com/qor5/admin/v3/publish"
+)
+
+// fallbackTestModelBuilder is the page model builder of the fr-CA -> fr -> en chain,
+// its preview renders the page slug and the canonical link.
+func fallbackTestModelBuilder(t *testing.T) *ModelBuilder {
+	t.Helper()
+	if err := TestDB.AutoMigrate(&Page{}, &Category{}); err != nil {
+		t.Fatal(err)
+	}
+	TestDB.Exec("DELETE FROM page_builder_pages")
+	lb := l10n.New(TestDB).
+		RegisterLocales("en", "en", "English", "").
+		RegisterLocales("fr", "fr", "French", "", "en").
+		RegisterLocales("fr-CA", "fr-ca", "French (Canada)", "", "fr")
+	return &ModelBuilder{
+		name:    "pages",
+		db:      TestDB,
+		builder: &Builder{db: TestDB, l10n: lb},
+		preview: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s %v", r.FormValue("id"), r.Context().Value(ctxKeyFallbackCanonical{}))
+		}),
+	}
+}
+
+func createFallbackTestPage(t *testing.T, locale, slug, status string) *Page {
+	t.Helper()
+	p := &Page{Slug: slug, Status: publish.Status{Status: status}, Version: publish.Version{Version: "v1"}, Locale: l10n.Locale{LocaleCode: locale}}
+	p.ID = 1
+	if err := TestDB.Create(p).Error; err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func setFallbackTestPageStatus(t *testing.T, p *Page, status string) {
+	t.Helper()
+	p.Status.Status = status
+	if err := TestDB.Model(&Page{}).Where("id = ? AND locale_code = ?", p.ID, p.LocaleCode).Update("status", status).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPageByPathFallsBack(t *testing.T) {
+	mb := fallbackTestModelBuilder(t)
+	b := mb.builder
+	pageLocale := func(locale, pagePath string) string {
+		t.Helper()
+		page, err := b.PageByPath(context.Background(), locale, pagePath)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ""
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return page.LocaleCode
+	}
+
+	createFallbackTestPage(t, "en", "/about", publish.StatusOnline)
+	createFallbackTestPage(t, "fr-CA", "/about", publish.StatusDraft)
+	fr := createFallbackTestPage(t, "fr", "/about", publish.StatusDraft)
+	for _, c := range []struct{ locale, path, expect string }{
+		{locale: "fr-CA", path: "/about", expect: "en"},
+		{locale: "fr", path: "/about/", expect: "en"},
+		{locale: "en", path: "/about", expect: "en"},
+		{locale: "fr-CA", path: "/contact"},
+	} {
+		if got := pageLocale(c.locale, c.path); got != c.expect {
+			t.Errorf("%s %s: expect the page in %q, got %q", c.locale, c.path, c.expect, got)
+		}
+	}
+
+	setFallbackTestPageStatus(t, fr, publish.StatusOnline)
+	if got := pageLocale("fr-CA", "/about"); got != "fr" {
+		t.Errorf("expect the page in the nearest fallback fr, got %q", got)
+	}
+	if got := pageLocale("en", "/about"); got != "en" {
+		t.Errorf("expect the fallbacks of other locales ignored, got %q", got)
+	}
+}
+
+func TestServingPage(t *testing.T) {
+	mb := fallbackTestModelBuilder(t)
+	createFallbackTestPage(t, "en", "/about", publish.StatusOnline)
+	fr := createFallbackTestPage(t, "fr", "/about", publish.StatusOnline)
+	frCA := createFallbackTestPage(t, "fr-CA", "/about", publish.StatusDraft)
+
+	for _, c := range []struct {
+		name   string
+		with   *Page
+		except string
+		expect string
+	}{
+		{name: "nearest online", expect: "fr"},
+		{name: "published", with: frCA, expect: "fr-CA"},
+		{name: "unpublished", except: "fr", expect: "en"},
+	} {
+		page, err := mb.servingPage(TestDB, 1, "fr-CA", c.with, c.except)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page == nil || page.LocaleCode != c.expect {
+			t.Errorf("%s: expect the page in %s, got %v", c.name, c.expect, page)
+		}
+	}
+
+	setFallbackTestPageStatus(t, fr, publish.StatusOffline)
+	page, err := mb.servingPage(TestDB, 1, "fr", nil, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page != nil {
+		t.Errorf("expect no page serving fr, got %s", page.LocaleCode)
+	}
+}
+
+func TestFallbackPublishActions(t *testing.T) {
+	mb := fallbackTestModelBuilder(t)
+	storage := filesystem.New(t.TempDir())
+	actions := func(p *Page, unpublishing bool) []*publish.PublishAction {
+		t.Helper()
+		r, err := mb.fallbackPublishActions(context.Background(), TestDB, storage, p, unpublishing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	expect := func(name string, want, got []*publish.PublishAction) {
+		t.Helper()
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("%s: %s", name, diff)
+		}
+	}
+
+	// the first published page serves the locales falling back to it
+	en := createFallbackTestPage(t, "en", "/about", publish.StatusDraft)
+	expect("publish en", []*publish.PublishAction{
+		{Url: "/fr/about/index.html", Content: "1_v1_en /en/about"},
+		{Url: "/fr-ca/about/index.html", Content: "1_v1_en /en/about"},
+	}, actions(en, false))
+	setFallbackTestPageStatus(t, en, publish.StatusOnline)
+
+	// the page in a nearer locale replaces it, moved to its own slug
+	fr := createFallbackTestPage(t, "fr", "/a-propos", publish.StatusDraft)
+	expect("publish fr", []*publish.PublishAction{
+		{Url: "/fr/about/index.html", IsDelete: true},
+		{Url: "/fr-ca/about/index.html", IsDelete: true},
+		{Url: "/fr-ca/a-propos/index.html", Content: "1_v1_fr /fr/a-propos"},
+	}, actions(fr, false))
+	setFallbackTestPageStatus(t, fr, publish.StatusOnline)
+
+	// a locale with its own online page is left untouched
+	frCA := createFallbackTestPage(t, "fr-CA", "/a-propos", publish.StatusOnline)
+	expect("publish fr again", nil, actions(fr, false))
+
+	// unpublishing gives the locales back to the next fallback
+	setFallbackTestPageStatus(t, frCA, publish.StatusOffline)
+	expect("unpublish fr", []*publish.PublishAction{
+		{Url: "/fr/about/index.html", Content: "1_v1_en /en/about"},
+		{Url: "/fr-ca/a-propos/index.html", IsDelete: true},
+		{Url: "/fr-ca/about/index.html", Content: "1_v1_en /en/about"},
+	}, actions(fr, true))
+	setFallbackTestPageStatus(t, fr, publish.StatusOffline)
+
+	expect("unpublish en", []*publish.PublishAction{
+		{Url: "/fr/about/index.html", IsDelete: true},
+		{Url: "/fr-ca/about/index.html", IsDelete: true},
+	}, actions(en, true))
+}
@@ -0,0 +1,476 @@
+package pagebuilder
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/i18n"
+	"github.com/qor5/x/v3/oss"
+	"github.com/sunfmin/reflectutils"
+	h "github.com/theplant/htmlgo"
+	"gorm.io/gorm"
+
+	"github.com/qor5/admin/v3/l10n"
+	"github.com/qor5/admin/v3/presets"
+	"github.com/qor5/admin/v3/publish"
+)
+
+const (
+	MenuPositionHeader = "header"
+	MenuPositionFooter = "footer"
+
+	MenuItemTypePage     = "page"
+	MenuItemTypeCategory = "category"
+	MenuItemTypeLink     = "link"
+)
+
+type Menu struct {
+	gorm.Model
+	Name     string
+	Position string
+	Items    MenuItems `sql:"type:text;"`
+
+	publish.Status
+	publish.Version
+	l10n.Locale
+}
+
+func (m *Menu) PrimarySlug() string {
+	return primarySlug(m)
+}
+
+func (m *Menu) PrimaryColumnValuesBySlug(slug string) map[string]string {
+	return primaryColumnValuesBySlug(slug)
+}
+
+func (*Menu) TableName() string {
+	return "page_builder_menus"
+}
+
+// Menus are not published to a file of their own, they are rendered into the
+// pages that use them, see Builder.republishOnlinePages.
+func (m *Menu) GetPublishActions(_ context.Context, _ *gorm.DB, _ oss.StorageInterface) (actions []*publish.PublishAction, err error) {
+	return
+}
+
+func (m *Menu) GetUnPublishActions(_ context.Context, _ *gorm.DB, _ oss.StorageInterface) (actions []*publish.PublishAction, err error) {
+	return
+}
+
+// MenuItem is one entry of a menu, the tree is described by Depth:
+// an item is a child of the closest preceding item with a smaller Depth.
+type MenuItem struct {
+	Type         string
+	Label        string
+	PageID       uint
+	CategoryID   uint
+	URL          string
+	Depth        int
+	OpenInNewTab bool
+}
+
+type MenuItems []*MenuItem
+
+func (this MenuItems) Value() (driver.Value, error) {
+	return json.Marshal(this)
+}
+
+func (this *MenuItems) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), this)
+	case []byte:
+		return json.Unmarshal(v, this)
+	case nil:
+		return nil
+	default:
+		return errors.New("not supported")
+	}
+}
+
+type MenuNode struct {
+	Item     *MenuItem
+	Label    string
+	URL      string
+	Children []*MenuNode
+}
+
+type MenuRenderFunc func(menu *Menu, nodes []*MenuNode, input *PageLayoutInput, ctx *web.EventContext) h.HTMLComponent
+
+// buildMenuTree turns the flat, ordered items into a tree, depths that skip a level
+// are attached to the deepest available parent.
+func buildMenuTree(items []*MenuItem) (roots []*MenuNode) {
+	var stack []*MenuNode
+	for _, item := range items {
+		node := &MenuNode{Item: item, Label: item.Label, URL: item.URL}
+		depth := item.Depth
+		if depth < 0 {
+			depth = 0
+		}
+		if depth > len(stack) {
+			depth = len(stack)
+		}
+		stack = stack[:depth]
+		if depth == 0 {
+			roots = append(roots, node)
+		} else {
+			parent := stack[depth-1]
+			parent.Children = append(parent.Children, node)
+		}
+		stack = append(stack, node)
+	}
+	return
+}
+
+// MenuItemsFromCategories derives menu items from the categories tree,
+// cats should be ordered by path.
+func MenuItemsFromCategories(cats []*Category) (items MenuItems) {
+	fillCategoryIndentLevels(cats)
+	for _, cat := range cats {
+		items = append(items, &MenuItem{
+			Type:       MenuItemTypeCategory,
+			Label:      cat.Name,
+			CategoryID: cat.ID,
+			Depth:      cat.IndentLevel,
+		})
+	}
+	return
+}
+
+func defaultMenuRenderFunc(menu *Menu, nodes []*MenuNode, _ *PageLayoutInput, _ *web.EventContext) h.HTMLComponent {
+	return h.Nav(menuNodesList(nodes)).Class("page-builder-menu", fmt.Sprintf("page-builder-menu-%s", menu.Position))
+}
+
+func menuNodesList(nodes []*MenuNode) h.HTMLComponent {
+	ul := h.Ul()
+	for _, node := range nodes {
+		a := h.A(h.Text(node.Label)).Href(node.URL)
+		if node.Item.OpenInNewTab {
+			a.Attr("target", "_blank").Attr("rel", "noopener")
+		}
+		li := h.Li(a)
+		if len(node.Children) > 0 {
+			li.AppendChildren(menuNodesList(node.Children))
+		}
+		ul.AppendChildren(li)
+	}
+	return ul
+}
+
+func (b *Builder) MenuEnabled(v bool) (r *Builder) {
+	b.menuEnabled = v
+	return b
+}
+
+func (b *Builder) MenuPositions(vs ...string) (r *Builder) {
+	b.menuPositions = vs
+	return b
+}
+
+func (b *Builder) MenuRender(v MenuRenderFunc) (r *Builder) {
+	b.menuRenderFunc = v
+	return b
+}
+
+func (b *Builder) WrapMenuInstall(w func(presets.ModelInstallFunc) presets.ModelInstallFunc) (r *Builder) {
+	b.menuInstall = w(b.menuInstall)
+	return b
+}
+
+// onlineMenu returns the online menu of the position, nil if there is none.
+func (b *Builder) onlineMenu(db *gorm.DB, position, locale string) (menu *Menu, err error) {
+	menu = &Menu{}
+	err = withLocale(b, db.Where("position = ? AND status = ?", position, publish.StatusOnline), locale).
+		Order("updated_at DESC").
+		First(menu).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return
+}
+
+// resolveMenuNodes fills the urls and the default labels of the nodes, the pages and the categories
+// of the whole tree are loaded with one query each.
+func (b *Builder) resolveMenuNodes(db *gorm.DB, nodes []*MenuNode, locale string) (r []*MenuNode, err error) {
+	var (
+		pageIDs     []uint
+		categoryIDs []uint
+		collect     func(nodes []*MenuNode)
+	)
+	collect = func(nodes []*MenuNode) {
+		for _, node := range nodes {
+			switch node.Item.Type {
+			case MenuItemTypePage:
+				pageIDs = append(pageIDs, node.Item.PageID)
+			case MenuItemTypeCategory:
+				categoryIDs = append(categoryIDs, node.Item.CategoryID)
+			}
+			collect(node.Children)
+		}
+	}
+	collect(nodes)
+
+	pages := make(map[uint]*Page)
+	if len(pageIDs) > 0 {
+		var ps []*Page
+		if err = withLocale(b, db.Where("id IN ? AND status = ?", pageIDs, publish.StatusOnline), locale).
+			Find(&ps).Error; err != nil {
+			return
+		}
+		for _, p := range ps {
+			pages[p.ID] = p
+		}
+	}
+	categories := make(map[uint]*Category)
+	if len(categoryIDs) > 0 {
+		var cs []*Category
+		if err = withLocale(b, db.Where("id IN ?", categoryIDs), locale).Find(&cs).Error; err != nil {
+			return
+		}
+		for _, c := range cs {
+			categories[c.ID] = c
+		}
+	}
+	return resolveMenuTree(nodes, pages, categories, b.l10n.GetLocalePath(locale)), nil
+}
+
+func resolveMenuTree(nodes []*MenuNode, pages map[uint]*Page, categories map[uint]*Category, localePath string) (r []*MenuNode) {
+	for _, node := range nodes {
+		switch node.Item.Type {
+		case MenuItemTypePage:
+			p, ok := pages[node.Item.PageID]
+			if !ok {
+				// the page is not online, its entry is skipped together with the children
+				continue
+			}
+			node.URL = p.getAccessUrl(p.OnlineUrl)
+			if node.Label == "" {
+				node.Label = p.Title
+			}
+		case MenuItemTypeCategory:
+			c, ok := categories[node.Item.CategoryID]
+			if !ok {
+				continue
+			}
+			node.URL = path.Join("/", localePath, c.Path)
+			if node.Label == "" {
+				node.Label = c.Name
+			}
+		}
+		node.Children = resolveMenuTree(node.Children, pages, categories, localePath)
+		r = append(r, node)
+	}
+	return
+}
+
+func (b *Builder) renderMenu(ctx *web.EventContext, position string, input *PageLayoutInput) h.HTMLComponent {
+	menu, err := b.onlineMenu(b.db, position, input.LocaleCode)
+	if err != nil || menu == nil {
+		return nil
+	}
+	nodes, err := b.resolveMenuNodes(b.db, buildMenuTree(menu.Items), input.LocaleCode)
+	if err != nil {
+		return nil
+	}
+	return b.menuRenderFunc(menu, nodes, input, ctx)
+}
+
+// fillMenus renders the header and footer menus into the layout input
+// unless the layout func has been given its own.
+func (b *Builder) fillMenus(ctx *web.EventContext, input *PageLayoutInput) {
+	if !b.menuEnabled {
+		return
+	}
+	if input.Header == nil {
+		if comp := b.renderMenu(ctx, MenuPositionHeader, input); comp != nil {
+			input.Header = comp
+		}
+	}
+	if input.Footer == nil {
+		if comp := b.renderMenu(ctx, MenuPositionFooter, input); comp != nil {
+			input.Footer = comp
+		}
+	}
+}
+
+// republishOnlinePages republishes the online pages of the locale so they pick up the published or unpublished menu.
+func (b *Builder) republishOnlinePages(ctx context.Context, locale string) (err error) {
+	for _, m := range b.models {
+		if m.isTemplate {
+			continue
+		}
+		if _, ok := m.mb.NewModel().(publish.StatusInterface); !ok {
+			continue
+		}
+		records := m.mb.NewModelSlice()
+		if err = withLocale(b, b.db.Where("status = ?", publish.StatusOnline), locale).Find(records).Error; err != nil {
+			return
+		}
+		var errs []error
+		reflectutils.ForEach(records, func(record interface{}) {
+			if pErr := b.publisher.Publish(ctx, record); pErr != nil {
+				errs = append(errs, pErr)
+			}
+		})
+		if err = errors.Join(errs...); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (b *Builder) configMenuPublish() {
+	if b.publisher == nil {
+		return
+	}
+	menuType := reflect.TypeOf(&Menu{})
+	b.publisher.WrapPublish(func(in publish.PublishFunc) publish.PublishFunc {
+		return func(ctx context.Context, record any) (err error) {
+			if err = in(ctx, record); err != nil {
+				return
+			}
+			if reflect.TypeOf(record) != menuType {
+				return
+			}
+			return b.republishOnlinePages(ctx, record.(*Menu).LocaleCode)
+		}
+	})
+	// the items of the unpublished menu are removed from the online pages too
+	b.publisher.WrapUnPublish(func(in publish.UnPublishFunc) publish.UnPublishFunc {
+		return func(ctx context.Context, record any) (err error) {
+			if err = in(ctx, record); err != nil {
+				return
+			}
+			if reflect.TypeOf(record) != menuType {
+				return
+			}
+			return b.republishOnlinePages(ctx, record.(*Menu).LocaleCode)
+		}
+	})
+}
+
+func (b *Builder) defaultMenuInstall(pb *presets.Builder, pm *presets.ModelBuilder) (err error) {
+	db := b.db
+
+	pm.LabelName(func(evCtx *web.EventContext, singular bool) string {
+		msgr := i18n.MustGetModuleMessages(evCtx.R, I18nPageBuilderKey, Messages_en_US).(*Messages)
+		if singular {
+			return msgr.ModelLabelMenu
+		}
+		return msgr.ModelLabelMenus
+	})
+
+	listingFields := []string{"ID", "Name", "Position"}
+	if b.publisher != nil {
+		listingFields = append(listingFields, publish.ListingFieldLive)
+	}
+	lb := pm.Listing(listingFields...).SearchColumns("name")
+	lb.WrapColumns(presets.CustomizeColumnLabel(func(evCtx *web.EventContext) (map[string]string, error) {
+		msgr := i18n.MustGetModuleMessages(evCtx.R, I18nPageBuilderKey, Messages_en_US).(*Messages)
+		return map[string]string{
+			"ID":       msgr.ListHeaderID,
+			"Name":     msgr.ListHeaderName,
+			"Position": msgr.MenuPosition,
+		}, nil
+	}))
+
+	itemFb := pb.NewFieldsBuilder(presets.WRITE).Model(&MenuItem{}).Only("Type", "Label", "PageID", "CategoryID", "URL", "Depth", "OpenInNewTab")
+	itemFb.Field("Type").ComponentFunc(func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) h.HTMLComponent {
+		msgr := i18n.MustGetModuleMessages(ctx.R, I18nPageBuilderKey, Messages_en_US).(*Messages)
+		return presets.SelectField(obj, field, ctx).
+			Label(msgr.MenuItemType).
+			Items([]map[string]string{
+				{"title": msgr.MenuItemTypePage, "value": MenuItemTypePage},
+				{"title": msgr.MenuItemTypeCategory, "value": MenuItemTypeCategory},
+				{"title": msgr.MenuItemTypeLink, "value": MenuItemTypeLink},
+			})
+	})
+	itemFb.Field("PageID").ComponentFunc(func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) h.HTMLComponent {
+		var (
+			pages     []*Page
+			locale, _ = l10n.IsLocalizableFromContext(ctx.R.Context())
+			msgr      = i18n.MustGetModuleMessages(ctx.R, I18nPageBuilderKey, Messages_en_US).(*Messages)
+		)
+		if innerErr := withLocale(b, db.Model(&Page{}), locale).Order("id ASC, version DESC").Find(&pages).Error; innerErr != nil {
+			panic(innerErr)
+		}
+		return presets.SelectField(obj, field, ctx).
+			Label(msgr.ModelLabelPage).
+			Clearable(true).
+			Items(uniquePages(pages)).ItemTitle("Title").ItemValue("ID")
+	})
+	itemFb.Field("CategoryID").ComponentFunc(func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) h.HTMLComponent {
+		var (
+			categories []*Category
+			locale, _  = l10n.IsLocalizableFromContext(ctx.R.Context())
+			msgr       = i18n.MustGetModuleMessages(ctx.R, I18nPageBuilderKey, Messages_en_US).(*Messages)
+		)
+		if innerErr := withLocale(b, db.Model(&Category{}), locale).Order("path ASC").Find(&categories).Error; innerErr != nil {
+			panic(innerErr)
+		}
+		return presets.SelectField(obj, field, ctx).
+			Label(msgr.Category).
+			Clearable(true).
+			Items(categories).ItemTitle("Path").ItemValue("ID")
+	})
+
+	positionComponent := func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) h.HTMLComponent {
+		return presets.SelectField(obj, field, ctx).Items(b.menuPositions)
+	}
+	validator := func(obj interface{}, ctx *web.EventContext) (err web.ValidationErrors) {
+		m := obj.(*Menu)
+		msgr := i18n.MustGetModuleMessages(ctx.R, I18nPageBuilderKey, Messages_en_US).(*Messages)
+		if m.Name == "" {
+			err.FieldError("Name", msgr.InvalidNameMsg)
+		}
+		for i, item := range m.Items {
+			if item.Type == MenuItemTypeLink && item.URL == "" {
+				err.FieldError(fmt.Sprintf("Items[%d].URL", i), msgr.InvalidMenuItemURLMsg)
+			}
+		}
+		return
+	}
+
+	eb := pm.Editing("Name", "Position", "Items")
+	eb.Field("Position").ComponentFunc(positionComponent)
+	eb.Field("Items").Nested(itemFb, &presets.DisplayFieldInSorter{Field: "Label"})
+	eb.ValidateFunc(validator)
+
+	if b.publisher != nil {
+		dp := pm.Detailing(publish.VersionsPublishBar, "Menu").Drawer(true)
+		section := presets.NewSectionBuilder(pm, "Menu").
+			Editing("Name", "Position", "Items").
+			WrapValidator(func(presets.ValidateFunc) presets.ValidateFunc {
+				return validator
+			})
+		section.EditingField("Position").ComponentFunc(positionComponent)
+		section.EditingField("Items").Nested(itemFb, &presets.DisplayFieldInSorter{Field: "Label"})
+		dp.Section(section)
+		pm.Use(b.publisher)
+		b.configMenuPublish()
+	}
+	if b.ab != nil {
+		pm.Use(b.ab)
+	}
+	if b.l10n != nil {
+		pm.Use(b.l10n)
+	}
+	return
+}
+
+// uniquePages keeps the first version of each page, pages should be ordered by id.
+func uniquePages(pages []*Page) (r []*Page) {
+	for _, p := range pages {
+		if len(r) > 0 && r[len(r)-1].ID == p.ID {
+			continue
+		}
+		r = append(r, p)
+	}
+	return
+}
@@ -0,0 +1,111 @@
+package pagebuilder
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gorm.io/gorm"
+
+	"github.com/qor5/admin/v3/publish"
+)
+
+func TestBuildMenuTree(t *testing.T) {
+	type node struct {
+		Label    string
+		Children []node
+	}
+	var simplify func(nodes []*MenuNode) []node
+	simplify = func(nodes []*MenuNode) (r []node) {
+		for _, n := range nodes {
+			r = append(r, node{Label: n.Label, Children: simplify(n.Children)})
+		}
+		return
+	}
+
+	for _, c := range []struct {
+		name   string
+		items  []*MenuItem
+		expect []node
+	}{
+		{
+			name:  "flat",
+			items: []*MenuItem{{Label: "a"}, {Label: "b"}},
+			expect: []node{
+				{Label: "a"},
+				{Label: "b"},
+			},
+		},
+		{
+			name: "nested",
+			items: []*MenuItem{
+				{Label: "a"},
+				{Label: "a1", Depth: 1},
+				{Label: "a11", Depth: 2},
+				{Label: "a2", Depth: 1},
+				{Label: "b"},
+			},
+			expect: []node{
+				{Label: "a", Children: []node{
+					{Label: "a1", Children: []node{{Label: "a11"}}},
+					{Label: "a2"},
+				}},
+				{Label: "b"},
+			},
+		},
+		{
+			name: "skipped level",
+			items: []*MenuItem{
+				{Label: "a", Depth: 2},
+				{Label: "a1", Depth: 3},
+			},
+			expect: []node{
+				{Label: "a", Children: []node{{Label: "a1"}}},
+			},
+		},
+	} {
+		if diff := cmp.Diff(c.expect, simplify(buildMenuTree(c.items))); diff != "" {
+			t.Fatalf("%s: %s\n", c.name, diff)
+		}
+	}
+}
+
+func TestMenuItemsFromCategories(t *testing.T) {
+	items := MenuItemsFromCategories([]*Category{
+		{Name: "A", Path: "/a"},
+		{Name: "B", Path: "/a/b"},
+		{Name: "C", Path: "/c"},
+	})
+	var depths []int
+	for _, item := range items {
+		depths = append(depths, item.Depth)
+	}
+	if diff := cmp.Diff([]int{0, 1, 0}, depths); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestResolveMenuTree(t *testing.T) {
+	nodes := buildMenuTree([]*MenuItem{
+		{Type: MenuItemTypePage, PageID: 1},
+		{Type: MenuItemTypeCategory, CategoryID: 2, Depth: 1},
+		{Type: MenuItemTypePage, PageID: 3},
+		{Type: MenuItemTypeLink, Label: "Shop", URL: "https://shop.example.com", Depth: 1},
+		{Type: MenuItemTypeLink, Label: "Blog", URL: "/blog"},
+	})
+	pages := map[uint]*Page{1: {Model: gorm.Model{ID: 1}, Title: "About", Status: publish.Status{OnlineUrl: "/about/index.html"}}}
+	categories := map[uint]*Category{2: {Model: gorm.Model{ID: 2}, Name: "Team", Path: "/team"}}
+
+	var urls []string
+	var walk func(nodes []*MenuNode)
+	walk = func(nodes []*MenuNode) {
+		for _, n := range nodes {
+			urls = append(urls, n.Label+" "+n.URL)
+			walk(n.Children)
+		}
+	}
+	walk(resolveMenuTree(nodes, pages, categories, "/japan"))
+	// the offline page 3 is skipped together with its children
+	if diff := cmp.Diff([]string{"About /about", "Team /japan/team", "Blog /blog"}, urls); diff != "" {
+		t.Fatal(diff)
+	}
+}
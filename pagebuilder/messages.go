@@ -96,14 +96,54 @@ type Messages struct {
 	Name                       string
 	Description                string
 
-	CategoryDeleteConfirmationText     string
-	TheResourceCanNotBeModified        string
-	MarkAsShared                       string
-	Copy                               string
-	SharedContainerHasBeenUpdated      string
-	TemplateFixedAreaMessage           string
-	SharedContainerModificationWarning string
-	Success                            string
+	CategoryDeleteConfirmationText      string
+	TheResourceCanNotBeModified         string
+	MarkAsShared                        string
+	Copy                                string
+	SharedContainerHasBeenUpdated       string
+	TemplateFixedAreaMessage            string
+	SharedContainerModificationWarning  string
+	Success                             string
+	ModelLabelMenus                     string
+	ModelLabelMenu                      string
+	MenuPosition                        string
+	MenuItemType                        string
+	MenuItemTypePage                    string
+	MenuItemTypeCategory                string
+	MenuItemTypeLink                    string
+	InvalidMenuItemURLMsg               string
+	ModelLabelBrokenLinks               string
+	ModelLabelBrokenLink                string
+	BrokenLinkContainer                 string
+	BrokenLinkKind                      string
+	BrokenLinkKindLink                  string
+	BrokenLinkKindAsset                 string
+	BrokenLinkURL                       string
+	BrokenLinkReason                    string
+	BrokenLinkCheckedAt                 string
+	BrokenLinkReasonInvalidURL          string
+	BrokenLinkReasonPageNotFound        string
+	BrokenLinkReasonRedirectLoop        string
+	BrokenLinkReasonMediaNotFound       string
+	EditingContainer                    func(user, container string) string
+	ContainerLockedBy                   func(users string) string
+	EditAnyway                          string
+	ContainerChangedByOthers            func(user, at string) string
+	ContainerMergePrompt                string
+	LoadLatestVersion                   string
+	KeepMyChanges                       string
+	VisibilitySchedule                  string
+	VisibleFrom                         string
+	VisibleTo                           string
+	VisibleToShouldLaterThanVisibleFrom string
+	Lock                                string
+	Unlock                              string
+	InheritedContainerReadonly          string
+	PageOutdatedWithTemplate            string
+	SyncWithTemplate                    string
+	TemplatePagesOutdated               func(count int) string
+	SyncPages                           string
+	TemplatePagesSynced                 func(count int) string
 }
 
 var Messages_en_US = &Messages{
@@ -203,6 +243,56 @@ var Messages_en_US = &Messages{
 	TemplateFixedAreaMessage:           "This container is fixed and cannot be updated",
 	SharedContainerModificationWarning: "This is a shared container. Any modifications you make will apply to all pages that use it",
 	Success:                            "Success",
+	ModelLabelMenus:                    "Menus",
+	ModelLabelMenu:                     "Menu",
+	MenuPosition:                       "Position",
+	MenuItemType:                       "Type",
+	MenuItemTypePage:                   "Page",
+	MenuItemTypeCategory:               "Category",
+	MenuItemTypeLink:                   "External Link",
+	InvalidMenuItemURLMsg:              "URL is required for external links",
+	ModelLabelBrokenLinks:              "Broken Links",
+	ModelLabelBrokenLink:               "Broken Link",
+	BrokenLinkContainer:                "Container",
+	BrokenLinkKind:                     "Kind",
+	BrokenLinkKindLink:                 "Link",
+	BrokenLinkKindAsset:                "Asset",
+	BrokenLinkURL:                      "URL",
+	BrokenLinkReason:                   "Reason",
+	BrokenLinkCheckedAt:                "Checked At",
+	BrokenLinkReasonInvalidURL:         "Invalid URL",
+	BrokenLinkReasonPageNotFound:       "Page not found",
+	BrokenLinkReasonRedirectLoop:       "Too many redirects",
+	BrokenLinkReasonMediaNotFound:      "Media file not found",
+	EditingContainer: func(user, container string) string {
+		return fmt.Sprintf("%v is editing %v", user, container)
+	},
+	ContainerLockedBy: func(users string) string {
+		return fmt.Sprintf("%v is currently editing this container. Your changes may conflict with theirs.", users)
+	},
+	EditAnyway: "Edit Anyway",
+	ContainerChangedByOthers: func(user, at string) string {
+		return fmt.Sprintf("This container was changed by %v at %v after you opened it.", user, at)
+	},
+	ContainerMergePrompt:                "Load the latest version to discard your changes, or keep your changes to overwrite theirs.",
+	LoadLatestVersion:                   "Load Latest",
+	KeepMyChanges:                       "Keep My Changes",
+	VisibilitySchedule:                  "Visibility Schedule",
+	VisibleFrom:                         "Visible From",
+	VisibleTo:                           "Visible To",
+	VisibleToShouldLaterThanVisibleFrom: "Visible To should be later than Visible From",
+	Lock:                                "Lock",
+	Unlock:                              "Unlock",
+	InheritedContainerReadonly:          "This container is inherited from the template and can only be edited in the template.",
+	PageOutdatedWithTemplate:            "This page is out of date with its template.",
+	SyncWithTemplate:                    "Sync With Template",
+	TemplatePagesOutdated: func(count int) string {
+		return fmt.Sprintf("%d pages are out of date with this template.", count)
+	},
+	SyncPages: "Sync Pages",
+	TemplatePagesSynced: func(count int) string {
+		return fmt.Sprintf("%d pages synced.", count)
+	},
 }
 
 var Messages_zh_CN = &Messages{
@@ -303,6 +393,56 @@ var Messages_zh_CN = &Messages{
 	TemplateFixedAreaMessage:           "此区域由模板固定，无法编辑。",
 	SharedContainerModificationWarning: "这是一个共享容器。您所做的任何修改都将应用于使用它的所有页面",
 	Success:                            "成功",
+	ModelLabelMenus:                    "菜单",
+	ModelLabelMenu:                     "菜单",
+	MenuPosition:                       "位置",
+	MenuItemType:                       "类型",
+	MenuItemTypePage:                   "页面",
+	MenuItemTypeCategory:               "目录",
+	MenuItemTypeLink:                   "外部链接",
+	InvalidMenuItemURLMsg:              "外部链接必须填写URL",
+	ModelLabelBrokenLinks:              "失效链接",
+	ModelLabelBrokenLink:               "失效链接",
+	BrokenLinkContainer:                "组件",
+	BrokenLinkKind:                     "类型",
+	BrokenLinkKindLink:                 "链接",
+	BrokenLinkKindAsset:                "资源",
+	BrokenLinkURL:                      "URL",
+	BrokenLinkReason:                   "原因",
+	BrokenLinkCheckedAt:                "检查时间",
+	BrokenLinkReasonInvalidURL:         "无效的URL",
+	BrokenLinkReasonPageNotFound:       "页面不存在",
+	BrokenLinkReasonRedirectLoop:       "重定向次数过多",
+	BrokenLinkReasonMediaNotFound:      "媒体文件不存在",
+	EditingContainer: func(user, container string) string {
+		return fmt.Sprintf("%v 正在编辑 %v", user, container)
+	},
+	ContainerLockedBy: func(users string) string {
+		return fmt.Sprintf("%v 正在编辑此组件，您的修改可能会与其冲突。", users)
+	},
+	EditAnyway: "仍然编辑",
+	ContainerChangedByOthers: func(user, at string) string {
+		return fmt.Sprintf("您打开此组件后，%v 于 %v 修改了它。", user, at)
+	},
+	ContainerMergePrompt:                "加载最新版本将放弃您的修改，保留您的修改将覆盖对方的修改。",
+	LoadLatestVersion:                   "加载最新版本",
+	KeepMyChanges:                       "保留我的修改",
+	VisibilitySchedule:                  "显示时间",
+	VisibleFrom:                         "开始显示时间",
+	VisibleTo:                           "结束显示时间",
+	VisibleToShouldLaterThanVisibleFrom: "结束显示时间必须晚于开始显示时间",
+	Lock:                                "锁定",
+	Unlock:                              "解锁",
+	InheritedContainerReadonly:          "该组件继承自模板，只能在模板中编辑。",
+	PageOutdatedWithTemplate:            "该页面与其模板不一致。",
+	SyncWithTemplate:                    "与模板同步",
+	TemplatePagesOutdated: func(count int) string {
+		return fmt.Sprintf("%d 个页面与该模板不一致。", count)
+	},
+	SyncPages: "同步页面",
+	TemplatePagesSynced: func(count int) string {
+		return fmt.Sprintf("已同步 %d 个页面。", count)
+	},
 }
 
 var Messages_ja_JP = &Messages{
@@ -401,6 +541,56 @@ var Messages_ja_JP = &Messages{
 	TemplateFixedAreaMessage:           "この領域はテンプレートによって固定されており、編集できません。",
 	SharedContainerModificationWarning: "これは共有コンテナです。行った変更は、それを使用するすべてのページに適用されます",
 	Success:                            "成功",
+	ModelLabelMenus:                    "メニュー",
+	ModelLabelMenu:                     "メニュー",
+	MenuPosition:                       "位置",
+	MenuItemType:                       "タイプ",
+	MenuItemTypePage:                   "ページ",
+	MenuItemTypeCategory:               "カテゴリー",
+	MenuItemTypeLink:                   "外部リンク",
+	InvalidMenuItemURLMsg:              "外部リンクにはURLが必要です",
+	ModelLabelBrokenLinks:              "リンク切れ",
+	ModelLabelBrokenLink:               "リンク切れ",
+	BrokenLinkContainer:                "コンテナ",
+	BrokenLinkKind:                     "種類",
+	BrokenLinkKindLink:                 "リンク",
+	BrokenLinkKindAsset:                "アセット",
+	BrokenLinkURL:                      "URL",
+	BrokenLinkReason:                   "理由",
+	BrokenLinkCheckedAt:                "チェック日時",
+	BrokenLinkReasonInvalidURL:         "無効なURL",
+	BrokenLinkReasonPageNotFound:       "ページが見つかりません",
+	BrokenLinkReasonRedirectLoop:       "リダイレクトが多すぎます",
+	BrokenLinkReasonMediaNotFound:      "メディアファイルが見つかりません",
+	EditingContainer: func(user, container string) string {
+		return fmt.Sprintf("%v が %v を編集中です", user, container)
+	},
+	ContainerLockedBy: func(users string) string {
+		return fmt.Sprintf("%v がこのコンテナを編集中です。変更が競合する可能性があります。", users)
+	},
+	EditAnyway: "このまま編集",
+	ContainerChangedByOthers: func(user, at string) string {
+		return fmt.Sprintf("このコンテナを開いた後、%v が %v に変更しました。", user, at)
+	},
+	ContainerMergePrompt:                "最新版を読み込むと変更は破棄されます。変更を保持すると相手の変更を上書きします。",
+	LoadLatestVersion:                   "最新版を読み込む",
+	KeepMyChanges:                       "変更を保持",
+	VisibilitySchedule:                  "表示スケジュール",
+	VisibleFrom:                         "表示開始日時",
+	VisibleTo:                           "表示終了日時",
+	VisibleToShouldLaterThanVisibleFrom: "表示終了日時は表示開始日時より後である必要があります",
+	Lock:                                "ロック",
+	Unlock:                              "ロック解除",
+	InheritedContainerReadonly:          "このコンテナはテンプレートから継承されているため、テンプレートでのみ編集できます。",
+	PageOutdatedWithTemplate:            "このページはテンプレートと一致していません。",
+	SyncWithTemplate:                    "テンプレートと同期",
+	TemplatePagesOutdated: func(count int) string {
+		return fmt.Sprintf("%d 件のページがこのテンプレートと一致していません。", count)
+	},
+	SyncPages: "ページを同期",
+	TemplatePagesSynced: func(count int) string {
+		return fmt.Sprintf("%d 件のページを同期しました。", count)
+	},
 }
 
 type ModelsI18nModulePage struct {
@@ -13,6 +13,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/qor5/web/v3"
 	"github.com/qor5/x/v3/i18n"
@@ -342,6 +343,9 @@ func (b *ModelBuilder) renderScrollIframe(comps []h.HTMLComponent, ctx *web.Even
 		seoTags = b.builder.seoBuilder.Render(obj, ctx.R)
 		canonicalLink = b.builder.seoBuilder.RenderCanonical(obj, ctx.R)
 	}
+	if link := fallbackCanonicalLink(ctx.R.Context()); link != nil {
+		canonicalLink = link
+	}
 	input := &PageLayoutInput{
 		LocaleCode:    locale,
 		IsEditor:      isEditor,
@@ -486,6 +490,7 @@ func (b *ModelBuilder) renderScrollIframe(comps []h.HTMLComponent, ctx *web.Even
 			input.Hreflang = pl.Hreflang
 		}
 	}
+	b.builder.fillMenus(ctx, input)
 
 	if isIframe {
 		// use newCtx to avoid inserting page head to head outside of iframe
@@ -601,6 +606,9 @@ func (b *ModelBuilder) renderContainers(ctx *web.EventContext, obj interface{},
 		if ec.container.Hidden {
 			continue
 		}
+		if !isEditor && !ec.container.IsVisibleAt(time.Now()) {
+			continue
+		}
 		containerObj := ec.builder.NewModel()
 		err = b.db.FirstOrCreate(containerObj, "id = ?", ec.container.ModelID).Error
 		if err != nil {
@@ -754,6 +762,11 @@ func (b *ModelBuilder) copyContainersToAnotherPage(db *gorm.DB, pageID int, page
 		return
 	}
 	buildeContainer := b.getContainerBuilders()
+	fromTemplate := false
+	if m := b.builder.getModelBuilderByName(fromModelName); m != nil && m.isTemplate && fromModelName != toModelName {
+		fromTemplate = true
+	}
+	now := db.NowFunc()
 	for _, c := range cons {
 		if !slices.ContainsFunc(buildeContainer, func(builder *ContainerBuilder) bool {
 			return c.ModelName == builder.name
@@ -761,7 +774,11 @@ func (b *ModelBuilder) copyContainersToAnotherPage(db *gorm.DB, pageID int, page
 			continue
 		}
 		newModelID := c.ModelID
-		if !c.Shared {
+		templateContainerID, templateSyncedAt := c.TemplateContainerID, c.TemplateSyncedAt
+		if fromTemplate && c.Locked {
+			templateContainerID, templateSyncedAt = c.ID, &now
+		}
+		if !c.Shared && templateContainerID == 0 {
 			model := b.builder.ContainerByName(c.ModelName).NewModel()
 			if err = db.First(model, "id = ?", c.ModelID).Error; err != nil {
 				return
@@ -784,6 +801,12 @@ func (b *ModelBuilder) copyContainersToAnotherPage(db *gorm.DB, pageID int, page
 			ModelID:       newModelID,
 			DisplayOrder:  c.DisplayOrder,
 			Shared:        c.Shared,
+			VisibleFrom:   c.VisibleFrom,
+			VisibleTo:     c.VisibleTo,
+			Locked:        c.Locked && templateContainerID == 0,
+
+			TemplateContainerID: templateContainerID,
+			TemplateSyncedAt:    templateSyncedAt,
 			Locale: l10n.Locale{
 				LocaleCode: toPageLocale,
 			},
@@ -803,10 +826,28 @@ func (b *ModelBuilder) localizeContainersToAnotherPage(db *gorm.DB, pageID int,
 		return
 	}
 
+	now := db.NowFunc()
 	for _, c := range cons {
-		var newModelID uint
-		newDisplayName := c.DisplayName
-		if !c.Shared {
+		var (
+			newModelID          uint
+			newDisplayName      = c.DisplayName
+			templateContainerID uint
+			templateSyncedAt    *time.Time
+		)
+		if c.TemplateContainerID != 0 {
+			var tplCon Container
+			if err = db.Where("id = ? AND locale_code = ? AND locked = true", c.TemplateContainerID, toPageLocale).
+				First(&tplCon).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return
+			}
+			err = nil
+			if tplCon.ID != 0 {
+				newModelID = tplCon.ModelID
+				newDisplayName = tplCon.DisplayName
+				templateContainerID, templateSyncedAt = tplCon.ID, &now
+			}
+		}
+		if templateContainerID == 0 && !c.Shared {
 			model := b.builder.ContainerByName(c.ModelName).NewModel()
 			if err = db.First(model, "id = ?", c.ModelID).Error; err != nil {
 				return
@@ -818,7 +859,7 @@ func (b *ModelBuilder) localizeContainersToAnotherPage(db *gorm.DB, pageID int,
 				return
 			}
 			newModelID = reflectutils.MustGet(model, "ID").(uint)
-		} else {
+		} else if templateContainerID == 0 {
 			var count int64
 			var sharedCon Container
 			if err = db.Where("model_name = ? AND localize_from_model_id = ? AND locale_code = ? AND shared = ? and page_model_name = ? ",
@@ -858,6 +899,11 @@ func (b *ModelBuilder) localizeContainersToAnotherPage(db *gorm.DB, pageID int,
 		newCon.ModelID = newModelID
 		newCon.DisplayOrder = c.DisplayOrder
 		newCon.Shared = c.Shared
+		newCon.VisibleFrom = c.VisibleFrom
+		newCon.VisibleTo = c.VisibleTo
+		newCon.Locked = c.Locked
+		newCon.TemplateContainerID = templateContainerID
+		newCon.TemplateSyncedAt = templateSyncedAt
 		newCon.LocaleCode = toPageLocale
 		newCon.LocalizeFromModelID = c.ModelID
 		newCon.PageModelName = b.name
@@ -911,7 +957,7 @@ func (b *ModelBuilder) configDuplicate(mb *presets.ModelBuilder) {
 				parentVersion = p.EmbedVersion().ParentVersion
 				version = p.EmbedVersion().Version
 			}
-			err = b.db.Transaction(func(tx *gorm.DB) (inerr error) {
+			err = b.contextDB(ctx.R.Context()).Transaction(func(tx *gorm.DB) (inerr error) {
 				if strings.Contains(ctx.R.RequestURI, publish.EventDuplicateVersion) {
 					if inerr = b.copyContainersToNewPageVersion(tx, pageID, localeCode, parentVersion, version, b.name, b.name); inerr != nil {
 						return
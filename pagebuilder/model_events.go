@@ -54,6 +54,12 @@ func (b *ModelBuilder) registerCustomFuncs() {
 	b.editor.RegisterEventFunc(EditContainerEvent, b.eventMiddleware(b.editContainer))
 	b.editor.RegisterEventFunc(UpdateContainerEvent, b.eventMiddleware(b.updateContainer))
 	b.editor.RegisterEventFunc(ReloadAddContainersListEvent, b.eventMiddleware(b.reloadAddContainersList))
+	b.editor.RegisterEventFunc(PresenceHeartbeatEvent, b.eventMiddleware(b.presenceHeartbeat))
+	b.editor.RegisterEventFunc(ContainerVisibilityDialogEvent, b.eventMiddleware(b.containerVisibilityDialog))
+	b.editor.RegisterEventFunc(UpdateContainerVisibilityEvent, b.eventMiddleware(b.updateContainerVisibility))
+	b.editor.RegisterEventFunc(ToggleContainerLockEvent, b.eventMiddleware(b.toggleContainerLock))
+	b.editor.RegisterEventFunc(SyncPageTemplateEvent, b.eventMiddleware(b.syncPageTemplate))
+	b.editor.RegisterEventFunc(SyncTemplatePagesEvent, b.eventMiddleware(b.syncTemplatePages))
 
 	preview := web.Page(b.previewContent)
 	preview.Wrap(func(in web.PageFunc) web.PageFunc {
@@ -158,6 +164,11 @@ func (b *ModelBuilder) renderContainersSortedList(ctx *web.EventContext) (r h.HT
 		return
 	}
 
+	templateStatus, err := b.templateStatusComponent(ctx, isReadonly)
+	if err != nil {
+		return
+	}
+
 	var sorterData ContainerSorter
 	sorterData.Items = []ContainerSorterItem{}
 
@@ -177,6 +188,9 @@ func (b *ModelBuilder) renderContainersSortedList(ctx *web.EventContext) (r h.HT
 				URL:             b.builder.ContainerByName(c.ModelName).mb.Info().ListingHref(),
 				Shared:          c.Shared,
 				VisibilityIcon:  vicon,
+				Schedule:        containerVisibilitySchedule(c),
+				Locked:          c.Locked,
+				Inherited:       c.TemplateContainerID != 0,
 				ParamID:         c.PrimarySlug(),
 				Locale:          locale,
 				Hidden:          c.Hidden,
@@ -205,6 +219,8 @@ func (b *ModelBuilder) renderContainersSortedList(ctx *web.EventContext) (r h.HT
 	// container functions
 	containerOperations := h.Div(
 		VChip().Text(msgr.Shared).Color(ColorPrimary).Size(SizeXSmall).Attr("v-if", "element.shared"),
+		VIcon("mdi-clock-outline").Size(SizeSmall).Class("mx-1").Attr("v-if", "element.schedule", ":title", "element.schedule"),
+		VIcon("mdi-lock").Size(SizeSmall).Class("mx-1").Attr("v-if", "element.locked || element.inherited"),
 		VMenu(
 			web.Slot(
 				VBtn("").Children(
@@ -222,6 +238,23 @@ func (b *ModelBuilder) renderContainersSortedList(ctx *web.EventContext) (r h.HT
 						Query(paramStatus, status).
 						Go(),
 				),
+				VListItem(h.Text(msgr.VisibilitySchedule)).PrependIcon("mdi-clock-outline").Attr("@click",
+					web.Plaid().
+						EventFunc(ContainerVisibilityDialogEvent).
+						Query(paramContainerID, web.Var("element.param_id")).
+						Query(paramStatus, status).
+						Go(),
+				),
+				h.If(b.isTemplate,
+					VListItem(h.Text(fmt.Sprintf("{{element.locked?%q:%q}}", msgr.Unlock, msgr.Lock))).
+						Attr(":prepend-icon", `element.locked?"mdi-lock-open-variant":"mdi-lock"`).Attr("@click",
+						web.Plaid().
+							EventFunc(ToggleContainerLockEvent).
+							Query(paramContainerID, web.Var("element.param_id")).
+							Query(paramStatus, status).
+							Go(),
+					),
+				),
 				VListItem(h.Text(msgr.Copy)).PrependIcon("mdi-content-copy").Attr("@click",
 					web.Plaid().
 						EventFunc(ReplicateContainerEvent).
@@ -247,7 +280,7 @@ func (b *ModelBuilder) renderContainersSortedList(ctx *web.EventContext) (r h.HT
 				),
 			),
 		),
-	).Attr("v-show", "!element.editShow")
+	).Attr("v-show", "!element.editShow && !element.inherited")
 	r = web.Scope(
 		VSheet(
 			VList(
@@ -335,6 +368,9 @@ func (b *ModelBuilder) renderContainersSortedList(ctx *web.EventContext) (r h.HT
 			Attr(":disabled", "vars.__pageBuilderAddContainerBtnDisabled").
 			Attr("@click", appendVirtualElement()+web.Plaid().PushState(true).ClearMergeQuery([]string{paramContainerID}).RunPushState()+";vars.containerPreview=false;vars.overlay=true;vars.overlayEl.refs.overlay.showByElement($event)"),
 	).Init(h.JSONString(sorterData)).VSlot("{ locals:sortLocals,form }")
+	if templateStatus != nil {
+		r = h.Components(templateStatus, r)
+	}
 	return
 }
 
@@ -550,6 +586,10 @@ func (b *ModelBuilder) deleteContainer(ctx *web.EventContext) (r web.EventRespon
 		if dbErr = tx.Where("id = ? AND locale_code = ?", containerID, locale).First(&container).Error; dbErr != nil {
 			return
 		}
+		if container.TemplateContainerID != 0 {
+			msgr := i18n.MustGetModuleMessages(ctx.R, I18nPageBuilderKey, Messages_en_US).(*Messages)
+			return errors.New(msgr.InheritedContainerReadonly)
+		}
 		if dbErr = tx.Delete(&Container{}, "id = ? AND locale_code = ?", containerID, locale).Error; err != nil {
 			return
 		}
@@ -985,6 +1025,28 @@ func (b *ModelBuilder) editContainer(ctx *web.EventContext) (r web.EventResponse
 		})
 		return
 	}
+	editors, err := b.lockedContainerEditors(ctx, ctx.Param(paramContainerDataID))
+	if err != nil {
+		return
+	}
+	if len(editors) > 0 {
+		r.UpdatePortals = append(r.UpdatePortals, &web.PortalUpdate{
+			Name: pageBuilderRightContentPortal,
+			Body: b.builder.lockedContainerEdit(ctx, ctx.Param(paramContainerDataID), editors),
+		})
+		return
+	}
+	inherited, err := b.inheritedContainer(ctx.Param(paramContainerDataID))
+	if err != nil {
+		return
+	}
+	if inherited != nil {
+		r.UpdatePortals = append(r.UpdatePortals, &web.PortalUpdate{
+			Name: pageBuilderRightContentPortal,
+			Body: b.builder.inheritedContainerEdit(ctx, ctx.Param(paramContainerDataID)),
+		})
+		return
+	}
 	r.RunScript = web.Plaid().
 		URL("/"+strings.TrimLeft(path.Join(b.builder.pb.GetURIPrefix(), data[0]), "/")).
 		EventFunc(actions.Edit).
@@ -1001,10 +1063,14 @@ func (b *ModelBuilder) updateContainer(ctx *web.EventContext) (r web.EventRespon
 		containerUri = ctx.Param(paramContainerUri)
 		containerID  = ctx.Param(paramContainerID)
 	)
-	r.RunScript = web.Plaid().URL(containerUri).
+	update := web.Plaid().URL(containerUri).
 		EventFunc(actions.Update).
 		Query(presets.ParamID, containerID).
-		Query(presets.ParamPortalName, pageBuilderRightContentPortal).
+		Query(presets.ParamPortalName, pageBuilderRightContentPortal)
+	if b.builder.collaborationEnabled {
+		update.Query(paramModelUpdatedAt, web.Var("vars.__pageBuilderModelUpdatedAt"))
+	}
+	r.RunScript = update.
 		ThenScript(
 			web.Plaid().EventFunc(ReloadRenderPageOrTemplateBodyEvent).
 				Query(paramStatus, ctx.Param(paramStatus)).MergeQuery(true).
@@ -1039,6 +1105,9 @@ func (b *ModelBuilder) reloadRenderPageOrTemplateBody(ctx *web.EventContext) (r
 			},
 		),
 	)
+	if ctx.Param(paramIsUpdate) == "true" {
+		web.AppendRunScripts(&r, b.refreshModelUpdatedAtScript(ctx.Param(paramContainerDataID)))
+	}
 	return
 }
 
@@ -177,6 +177,30 @@ type Container struct {
 
 	ModelUpdatedAt time.Time
 	ModelUpdatedBy string
+
+	VisibleFrom        *time.Time `gorm:"index"`
+	VisibleTo          *time.Time `gorm:"index"`
+	VisibilitySyncedAt *time.Time
+
+	// Locked marks a template container as a locked region, pages created from the template inherit it by reference.
+	Locked bool
+	// TemplateContainerID is the locked template container that a page container is inherited from.
+	TemplateContainerID uint `gorm:"index"`
+	TemplateSyncedAt    *time.Time
+}
+
+// IsVisibleAt reports whether the container is not hidden and t falls inside its visibility window.
+func (c *Container) IsVisibleAt(t time.Time) bool {
+	if c.Hidden {
+		return false
+	}
+	if c.VisibleFrom != nil && t.Before(*c.VisibleFrom) {
+		return false
+	}
+	if c.VisibleTo != nil && !t.Before(*c.VisibleTo) {
+		return false
+	}
+	return true
 }
 
 func (c *Container) PrimarySlug() string {
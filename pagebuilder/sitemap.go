@@ -0,0 +1,77 @@
+package pagebuilder
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"reflect"
+	"time"
+
+	"github.com/sunfmin/reflectutils"
+
+	"github.com/qor5/admin/v3/l10n"
+	"github.com/qor5/admin/v3/publish"
+	"github.com/qor5/admin/v3/seo"
+)
+
+const SitemapName = "pages"
+
+// SitemapProvider lists the online pages of the page models in the "pages" sitemap,
+// the locales of a page are linked as hreflang alternates.
+func (b *Builder) SitemapProvider() seo.SitemapProvider {
+	return &sitemapProvider{SitemapProvider: seo.SitemapProviderFunc(SitemapName, b.sitemapEntries), b: b}
+}
+
+type sitemapProvider struct {
+	seo.SitemapProvider
+	b *Builder
+}
+
+// SitemapListsRecord reports if the record is a page listed in the sitemap,
+// publishing containers, menus and templates does not change it
+func (p *sitemapProvider) SitemapListsRecord(record any) bool {
+	recordType := reflect.TypeOf(record)
+	for _, m := range p.b.models {
+		if m.isTemplate {
+			continue
+		}
+		if _, ok := m.mb.NewModel().(publish.StatusInterface); ok && reflect.TypeOf(m.mb.NewModel()) == recordType {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Builder) sitemapEntries(ctx context.Context) (entries []*seo.SitemapEntry, err error) {
+	for _, m := range b.models {
+		if m.isTemplate {
+			continue
+		}
+		if _, ok := m.mb.NewModel().(publish.StatusInterface); !ok {
+			continue
+		}
+		records := m.mb.NewModelSlice()
+		if err = b.db.WithContext(ctx).Where("status = ?", publish.StatusOnline).Find(records).Error; err != nil {
+			return
+		}
+		reflectutils.ForEach(records, func(record interface{}) {
+			onlineUrl := record.(publish.StatusInterface).EmbedStatus().OnlineUrl
+			if onlineUrl == "" {
+				return
+			}
+			entry := &seo.SitemapEntry{
+				Loc:    path.Dir(onlineUrl),
+				Group:  fmt.Sprintf("%s_%v", m.name, reflectutils.MustGet(record, "ID")),
+				Object: record,
+			}
+			if l, ok := record.(l10n.LocaleInterface); ok {
+				entry.Locale = l.EmbedLocale().LocaleCode
+			}
+			if v, gErr := reflectutils.Get(record, "UpdatedAt"); gErr == nil {
+				entry.LastMod, _ = v.(time.Time)
+			}
+			entries = append(entries, entry)
+		})
+	}
+	return
+}
@@ -0,0 +1,405 @@
+package pagebuilder
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/i18n"
+	. "github.com/qor5/x/v3/ui/vuetify"
+	h "github.com/theplant/htmlgo"
+	"gorm.io/gorm"
+
+	"github.com/qor5/admin/v3/l10n"
+	"github.com/qor5/admin/v3/presets"
+	"github.com/qor5/admin/v3/publish"
+)
+
+type derivedPage struct {
+	PageModelName string
+	PageID        uint
+	PageVersion   string
+	LocaleCode    string
+	Outdated      bool
+}
+
+func containerChangedAt(c *Container) time.Time {
+	if c.ModelUpdatedAt.After(c.UpdatedAt) {
+		return c.ModelUpdatedAt
+	}
+	return c.UpdatedAt
+}
+
+// templateOutdated reports whether the inherited containers of a page no longer match
+// the locked containers of its template.
+func templateOutdated(locked, inherited []*Container) bool {
+	if len(locked) != len(inherited) {
+		return true
+	}
+	byTemplate := make(map[uint]*Container, len(inherited))
+	for _, c := range inherited {
+		byTemplate[c.TemplateContainerID] = c
+	}
+	for _, lc := range locked {
+		c, ok := byTemplate[lc.ID]
+		if !ok || c.ModelID != lc.ModelID || c.ModelName != lc.ModelName || c.DisplayName != lc.DisplayName {
+			return true
+		}
+		if c.TemplateSyncedAt == nil || containerChangedAt(lc).After(*c.TemplateSyncedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Builder) templateModelName() string {
+	if b.templateBuilder == nil {
+		return ""
+	}
+	return b.templateBuilder.tm.name
+}
+
+func (b *Builder) lockedTemplateContainers(db *gorm.DB, templateID uint, locale string) (cons []*Container, err error) {
+	err = db.Order("display_order ASC").
+		Where("page_model_name = ? AND page_id = ? AND locale_code = ? AND locked = true", b.templateModelName(), templateID, locale).
+		Find(&cons).Error
+	return
+}
+
+func (b *Builder) inheritedContainers(db *gorm.DB, pageModelName string, pageID int, pageVersion, locale string) (cons []*Container, err error) {
+	err = db.Order("display_order ASC").
+		Where("page_model_name = ? AND page_id = ? AND page_version = ? AND locale_code = ? AND template_container_id <> 0", pageModelName, pageID, pageVersion, locale).
+		Find(&cons).Error
+	return
+}
+
+// pageTemplateID returns the template the page inherits its locked containers from, 0 if none.
+func (b *Builder) pageTemplateID(db *gorm.DB, inherited []*Container) (templateID uint, err error) {
+	if len(inherited) == 0 {
+		return
+	}
+	var tplCon Container
+	if err = db.Unscoped().Where("id = ? AND locale_code = ?", inherited[0].TemplateContainerID, inherited[0].LocaleCode).
+		First(&tplCon).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+		return
+	}
+	return tplCon.PageID, nil
+}
+
+func (b *ModelBuilder) isPageTemplateOutdated(pageID int, pageVersion, locale string) (outdated bool, err error) {
+	inherited, err := b.builder.inheritedContainers(b.db, b.name, pageID, pageVersion, locale)
+	if err != nil {
+		return
+	}
+	templateID, err := b.builder.pageTemplateID(b.db, inherited)
+	if err != nil || templateID == 0 {
+		return
+	}
+	locked, err := b.builder.lockedTemplateContainers(b.db, templateID, locale)
+	if err != nil {
+		return
+	}
+	return templateOutdated(locked, inherited), nil
+}
+
+// syncPageWithTemplate makes the inherited containers of the page match the locked containers of its template.
+func (b *Builder) syncPageWithTemplate(tx *gorm.DB, pageModelName string, pageID int, pageVersion, locale string) (err error) {
+	inherited, err := b.inheritedContainers(tx, pageModelName, pageID, pageVersion, locale)
+	if err != nil {
+		return
+	}
+	templateID, err := b.pageTemplateID(tx, inherited)
+	if err != nil || templateID == 0 {
+		return
+	}
+	locked, err := b.lockedTemplateContainers(tx, templateID, locale)
+	if err != nil {
+		return
+	}
+	var (
+		now        = tx.NowFunc()
+		byTemplate = make(map[uint]*Container, len(inherited))
+		lockedIDs  = make(map[uint]bool, len(locked))
+	)
+	for _, lc := range locked {
+		lockedIDs[lc.ID] = true
+	}
+	for _, c := range inherited {
+		if !lockedIDs[c.TemplateContainerID] {
+			if err = tx.Delete(&Container{}, "id = ? AND locale_code = ?", c.ID, c.LocaleCode).Error; err != nil {
+				return
+			}
+			continue
+		}
+		byTemplate[c.TemplateContainerID] = c
+	}
+	for _, lc := range locked {
+		if c, ok := byTemplate[lc.ID]; ok {
+			if err = tx.Model(&Container{}).Where("id = ? AND locale_code = ?", c.ID, c.LocaleCode).
+				UpdateColumns(map[string]interface{}{
+					"model_name":         lc.ModelName,
+					"model_id":           lc.ModelID,
+					"display_name":       lc.DisplayName,
+					"template_synced_at": now,
+				}).Error; err != nil {
+				return
+			}
+			continue
+		}
+		con := &Container{
+			PageID:              uint(pageID),
+			PageVersion:         pageVersion,
+			PageModelName:       pageModelName,
+			ModelName:           lc.ModelName,
+			ModelID:             lc.ModelID,
+			DisplayName:         lc.DisplayName,
+			DisplayOrder:        lc.DisplayOrder,
+			TemplateContainerID: lc.ID,
+			TemplateSyncedAt:    &now,
+		}
+		con.LocaleCode = locale
+		if err = tx.Create(con).Error; err != nil {
+			return
+		}
+	}
+	return
+}
+
+// derivedPages returns the draft and online pages created from the template.
+func (b *Builder) derivedPages(db *gorm.DB, templateID uint, locale string) (pages []*derivedPage, err error) {
+	var tplConIDs []uint
+	if err = db.Unscoped().Model(&Container{}).
+		Where("page_model_name = ? AND page_id = ? AND locale_code = ?", b.templateModelName(), templateID, locale).
+		Pluck("id", &tplConIDs).Error; err != nil || len(tplConIDs) == 0 {
+		return
+	}
+	if err = db.Model(&Container{}).
+		Select("DISTINCT page_model_name, page_id, page_version, locale_code").
+		Where("template_container_id IN ? AND locale_code = ?", tplConIDs, locale).
+		Scan(&pages).Error; err != nil {
+		return
+	}
+	locked, err := b.lockedTemplateContainers(db, templateID, locale)
+	if err != nil {
+		return
+	}
+	r := pages[:0]
+	for _, p := range pages {
+		m := b.getModelBuilderByName(p.PageModelName)
+		if m == nil || m.isTemplate {
+			continue
+		}
+		if status := b.pageStatus(db, m, p); status != publish.StatusDraft && status != publish.StatusOnline {
+			continue
+		}
+		var inherited []*Container
+		if inherited, err = b.inheritedContainers(db, p.PageModelName, int(p.PageID), p.PageVersion, p.LocaleCode); err != nil {
+			return
+		}
+		p.Outdated = templateOutdated(locked, inherited)
+		r = append(r, p)
+	}
+	return r, nil
+}
+
+func (b *Builder) loadDerivedPage(db *gorm.DB, m *ModelBuilder, p *derivedPage) (page interface{}, err error) {
+	page = m.mb.NewModel()
+	g := db.Where("id = ? AND version = ?", p.PageID, p.PageVersion)
+	if p.LocaleCode != "" {
+		g = g.Where("locale_code = ?", p.LocaleCode)
+	}
+	err = g.First(page).Error
+	return
+}
+
+func (b *Builder) pageStatus(db *gorm.DB, m *ModelBuilder, p *derivedPage) string {
+	page, err := b.loadDerivedPage(db, m, p)
+	if err != nil {
+		return ""
+	}
+	if st, ok := page.(publish.StatusInterface); ok {
+		return st.EmbedStatus().Status
+	}
+	return publish.StatusDraft
+}
+
+// SyncTemplatePages brings every out of date page created from the template back in line with it,
+// online pages are republished so the change reaches the published content.
+func (b *Builder) SyncTemplatePages(ctx context.Context, templateID uint, locale string) (count int, err error) {
+	pages, err := b.derivedPages(b.db, templateID, locale)
+	if err != nil {
+		return
+	}
+	for _, p := range pages {
+		if !p.Outdated {
+			continue
+		}
+		if err = b.db.Transaction(func(tx *gorm.DB) error {
+			return b.syncPageWithTemplate(tx, p.PageModelName, int(p.PageID), p.PageVersion, p.LocaleCode)
+		}); err != nil {
+			return
+		}
+		count++
+		if b.publisher == nil {
+			continue
+		}
+		m := b.getModelBuilderByName(p.PageModelName)
+		var page interface{}
+		if page, err = b.loadDerivedPage(b.db, m, p); err != nil {
+			return
+		}
+		if st, ok := page.(publish.StatusInterface); ok && st.EmbedStatus().Status == publish.StatusOnline {
+			if err = b.publisher.Publish(b.publisher.WithContextValues(ctx), page); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+func (b *Builder) touchLockedTemplateContainers(tx *gorm.DB, modelName, modelID string) error {
+	if b.templateBuilder == nil || modelID == "" {
+		return nil
+	}
+	return tx.Model(&Container{}).
+		Where("model_name = ? AND model_id = ? AND locked = true", modelName, modelID).
+		UpdateColumn("model_updated_at", time.Now()).Error
+}
+
+func (b *ModelBuilder) templateStatusComponent(ctx *web.EventContext, isReadonly bool) (r h.HTMLComponent, err error) {
+	var (
+		msgr                        = i18n.MustGetModuleMessages(ctx.R, I18nPageBuilderKey, Messages_en_US).(*Messages)
+		pageID, pageVersion, locale = b.getPrimaryColumnValuesBySlug(ctx)
+	)
+	if b.builder.templateBuilder == nil {
+		return
+	}
+	if b.isTemplate {
+		var pages []*derivedPage
+		if pages, err = b.builder.derivedPages(b.db, uint(pageID), locale); err != nil {
+			return
+		}
+		var outdated int
+		for _, p := range pages {
+			if p.Outdated {
+				outdated++
+			}
+		}
+		if outdated == 0 {
+			return
+		}
+		return VAlert(
+			h.Div(h.Text(msgr.TemplatePagesOutdated(outdated))),
+			VBtn(msgr.SyncPages).Size(SizeSmall).Color(ColorPrimary).Variant(VariantElevated).Class("mt-2").
+				Attr("@click", web.Plaid().EventFunc(SyncTemplatePagesEvent).MergeQuery(true).Go()),
+		).Type(TypeWarning).Variant(VariantTonal).Density(DensityCompact).Class("ma-4"), nil
+	}
+	outdated, err := b.isPageTemplateOutdated(pageID, pageVersion, locale)
+	if err != nil || !outdated {
+		return
+	}
+	return VAlert(
+		h.Div(h.Text(msgr.PageOutdatedWithTemplate)),
+		h.If(!isReadonly,
+			VBtn(msgr.SyncWithTemplate).Size(SizeSmall).Color(ColorPrimary).Variant(VariantElevated).Class("mt-2").
+				Attr("@click", web.Plaid().EventFunc(SyncPageTemplateEvent).MergeQuery(true).Query(paramStatus, ctx.Param(paramStatus)).Go()),
+		),
+	).Type(TypeWarning).Variant(VariantTonal).Density(DensityCompact).Class("ma-4"), nil
+}
+
+func (b *ModelBuilder) syncPageTemplate(ctx *web.EventContext) (r web.EventResponse, err error) {
+	pageID, pageVersion, locale := b.getPrimaryColumnValuesBySlug(ctx)
+	if err = b.db.Transaction(func(tx *gorm.DB) error {
+		return b.builder.syncPageWithTemplate(tx, b.name, pageID, pageVersion, locale)
+	}); err != nil {
+		return
+	}
+	web.AppendRunScripts(&r,
+		web.Plaid().EventFunc(ReloadRenderPageOrTemplateBodyEvent).MergeQuery(true).Go(),
+		web.Plaid().EventFunc(ShowSortedContainerDrawerEvent).MergeQuery(true).Query(paramStatus, ctx.Param(paramStatus)).Go(),
+	)
+	return
+}
+
+func (b *ModelBuilder) syncTemplatePages(ctx *web.EventContext) (r web.EventResponse, err error) {
+	var (
+		msgr              = i18n.MustGetModuleMessages(ctx.R, I18nPageBuilderKey, Messages_en_US).(*Messages)
+		templateID, _, lc = b.getPrimaryColumnValuesBySlug(ctx)
+	)
+	count, err := b.builder.SyncTemplatePages(ctx.R.Context(), uint(templateID), lc)
+	if err != nil {
+		return
+	}
+	presets.ShowMessage(&r, msgr.TemplatePagesSynced(count), "")
+	web.AppendRunScripts(&r,
+		web.Plaid().EventFunc(ShowSortedContainerDrawerEvent).MergeQuery(true).Query(paramStatus, ctx.Param(paramStatus)).Go(),
+	)
+	return
+}
+
+func (b *ModelBuilder) toggleContainerLock(ctx *web.EventContext) (r web.EventResponse, err error) {
+	var (
+		container Container
+		cs        = container.PrimaryColumnValuesBySlug(ctx.R.FormValue(paramContainerID))
+	)
+	if !b.isTemplate {
+		return
+	}
+	if err = b.db.Where("id = ? AND locale_code = ?", cs[presets.ParamID], cs[l10n.SlugLocaleCode]).First(&container).Error; err != nil {
+		return
+	}
+	if err = b.db.Model(&Container{}).Where("id = ? AND locale_code = ?", container.ID, container.LocaleCode).
+		Updates(map[string]interface{}{"locked": !container.Locked}).Error; err != nil {
+		return
+	}
+	web.AppendRunScripts(&r,
+		web.Plaid().EventFunc(ShowSortedContainerDrawerEvent).MergeQuery(true).Query(paramStatus, ctx.Param(paramStatus)).Go(),
+	)
+	return
+}
+
+// inheritedContainer returns the container identified by the container data id when it is inherited from a template.
+func (b *ModelBuilder) inheritedContainer(containerDataID string) (con *Container, err error) {
+	if b.isTemplate {
+		return
+	}
+	segs := strings.SplitN(containerDataID, "_", 4)
+	if len(segs) < 3 {
+		return
+	}
+	var locale string
+	if len(segs) == 4 {
+		locale = segs[3]
+	}
+	con = &Container{}
+	if err = b.db.Where("id = ? AND locale_code = ?", segs[2], locale).First(con).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+		return nil, err
+	}
+	if con.TemplateContainerID == 0 {
+		return nil, nil
+	}
+	return
+}
+
+func (b *Builder) inheritedContainerEdit(ctx *web.EventContext, containerDataID string) h.HTMLComponent {
+	msgr := i18n.MustGetModuleMessages(ctx.R, I18nPageBuilderKey, Messages_en_US).(*Messages)
+	return VLayout(
+		VAppBar(
+			VToolbarTitle("").Children(h.Text(b.containerDisplayName(containerDataID))),
+		).Elevation(0),
+		VMain(
+			VSheet(
+				VAlert(h.Text(msgr.InheritedContainerReadonly)).
+					Type(TypeInfo).Variant(VariantTonal).Density(DensityCompact).Icon("mdi-lock"),
+			).Class("pa-4"),
+		),
+	)
+}
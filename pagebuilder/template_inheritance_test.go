@@ -0,0 +1,37 @@
+package pagebuilder
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestTemplateOutdated(t *testing.T) {
+	synced := time.Date(2024, 12, 24, 10, 0, 0, 0, time.UTC)
+	before := synced.Add(-time.Hour)
+	after := synced.Add(time.Hour)
+	locked := func(id, modelID uint, updatedAt time.Time) *Container {
+		return &Container{Model: gorm.Model{ID: id, UpdatedAt: updatedAt}, ModelName: "Header", ModelID: modelID, Locked: true}
+	}
+	inherited := func(tplID, modelID uint) *Container {
+		return &Container{ModelName: "Header", ModelID: modelID, TemplateContainerID: tplID, TemplateSyncedAt: &synced}
+	}
+	for _, c := range []struct {
+		name      string
+		locked    []*Container
+		inherited []*Container
+		expect    bool
+	}{
+		{name: "in sync", locked: []*Container{locked(1, 10, before)}, inherited: []*Container{inherited(1, 10)}},
+		{name: "locked container added", locked: []*Container{locked(1, 10, before), locked(2, 20, before)}, inherited: []*Container{inherited(1, 10)}, expect: true},
+		{name: "locked container removed", locked: nil, inherited: []*Container{inherited(1, 10)}, expect: true},
+		{name: "locked container replaced", locked: []*Container{locked(2, 20, before)}, inherited: []*Container{inherited(1, 10)}, expect: true},
+		{name: "locked container changed", locked: []*Container{locked(1, 10, after)}, inherited: []*Container{inherited(1, 10)}, expect: true},
+		{name: "locked model changed", locked: []*Container{{Model: gorm.Model{ID: 1, UpdatedAt: before}, ModelName: "Header", ModelID: 10, ModelUpdatedAt: after}}, inherited: []*Container{inherited(1, 10)}, expect: true},
+	} {
+		if got := templateOutdated(c.locked, c.inherited); got != c.expect {
+			t.Fatalf("%s: expect %v, got %v", c.name, c.expect, got)
+		}
+	}
+}
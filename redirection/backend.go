@@ -0,0 +1,117 @@
+package redirection
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	s3x "github.com/qor5/x/v3/oss/s3"
+)
+
+// Backend applies the redirections to where the site is served
+type Backend interface {
+	// Redirect makes the requests to the source of the record redirect to its target,
+	// the record with an empty target removes the redirection of the source.
+	Redirect(ctx context.Context, record *Redirection) error
+	// TargetExists reports whether an internal redirect target resolves to an existing page
+	TargetExists(ctx context.Context, target string) bool
+}
+
+// committedRedirector is implemented by the backends serving the records saved in the database,
+// their Redirect checks the record in the transaction and redirectCommitted applies it after the commit.
+type committedRedirector interface {
+	redirectCommitted(record *Redirection)
+}
+
+// internalTarget returns the target as an absolute path unless it is an url
+func internalTarget(target string) string {
+	if strings.HasPrefix(target, "http") {
+		return target
+	}
+	r := path.Join("/", target)
+	// path.Join drops the trailing slash of a directory-form target; restore
+	// it so the redirect goes straight to the directory URL without an extra
+	// redirect hop.
+	if strings.HasSuffix(target, "/") && r != "/" {
+		r += "/"
+	}
+	return r
+}
+
+// ErrUnsupportedRedirection is returned by the backends not able to serve the redirection
+var ErrUnsupportedRedirection = errors.New("redirection is not supported by the backend")
+
+// S3Backend redirects with the WebsiteRedirectLocation of the objects in the bucket of the S3 static website,
+// the S3 website endpoint always responds 301 to them. It supports the exact redirections without dates only.
+type S3Backend struct {
+	client *s3x.Client
+}
+
+func NewS3Backend(client *s3x.Client) *S3Backend {
+	return &S3Backend{client: client}
+}
+
+// s3Supports reports whether the redirection can be served by the S3 static website
+func s3Supports(record *Redirection) bool {
+	return record.matchType() == MatchExact && record.StartAt == nil && record.ExpireAt == nil
+}
+
+func (b *S3Backend) Redirect(ctx context.Context, record *Redirection) (err error) {
+	// the object put at the source has replaced the redirect object
+	if record.Target == "" {
+		return nil
+	}
+	if !s3Supports(record) {
+		return ErrUnsupportedRedirection
+	}
+	var (
+		client = b.client
+		bucket = client.Config.Bucket
+		source = strings.TrimPrefix(record.Source, "/")
+		target = internalTarget(record.Target)
+	)
+	if b.objectExists(ctx, source) {
+		_, err = client.S3.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:                  aws.String(bucket),
+			CopySource:              aws.String(path.Join(bucket, record.Source)),
+			Key:                     aws.String(strings.TrimPrefix(record.Source, "/")),
+			WebsiteRedirectLocation: aws.String(target),
+		})
+	} else {
+		params := &s3.PutObjectInput{
+			Bucket:                  aws.String(client.Config.Bucket),
+			Key:                     aws.String(client.ToS3Key(source)),
+			ACL:                     types.ObjectCannedACL(client.Config.ACL),
+			Body:                    bytes.NewReader([]byte{}),
+			WebsiteRedirectLocation: aws.String(target),
+		}
+		if client.Config.CacheControl != "" {
+			params.CacheControl = aws.String(client.Config.CacheControl)
+		}
+		_, err = client.S3.PutObject(ctx, params)
+	}
+	return
+}
+
+// TargetExists reports whether an internal redirect target resolves to an
+// existing object. A directory-form target (trailing "/") resolves to its index
+// document, matching the behavior of the S3 website endpoint.
+func (b *S3Backend) TargetExists(ctx context.Context, target string) bool {
+	if strings.HasSuffix(target, "/") {
+		target += indexDocument
+	}
+	return b.objectExists(ctx, target)
+}
+
+func (b *S3Backend) objectExists(ctx context.Context, key string) bool {
+	_, err := b.client.S3.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(b.client.Config.Bucket),
+		Key:    aws.String(strings.TrimPrefix(key, "/")),
+	})
+	return err == nil
+}
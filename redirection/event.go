@@ -3,8 +3,11 @@ package redirection
 import (
 	"io"
 	"mime/multipart"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gocarina/gocsv"
 	"github.com/qor5/web/v3"
@@ -57,7 +60,7 @@ func (b *Builder) uploadFile(ctx *web.EventContext) (r web.EventResponse, err er
 	return
 }
 
-func (*Builder) checkRecords(r *web.EventResponse, msgr *Messages, records []Redirection) (passed bool) {
+func (b *Builder) checkRecords(r *web.EventResponse, msgr *Messages, records []Redirection) (passed bool) {
 	var (
 		existedSource = make(map[string][]string)
 		invalidFormat = make(map[string]string)
@@ -66,15 +69,36 @@ func (*Builder) checkRecords(r *web.EventResponse, msgr *Messages, records []Red
 	for index, item := range records {
 		row := strconv.Itoa(index + 1)
 		var messages []string
-		existedSource[item.Source] = append(existedSource[item.Source], strconv.Itoa(index+1))
-		if strings.HasPrefix(item.Source, "http") || !strings.HasPrefix(item.Source, "/") {
+		existedSource[item.matchType()+" "+item.Source] = append(existedSource[item.matchType()+" "+item.Source], strconv.Itoa(index+1))
+		switch {
+		case !slices.Contains(MatchTypes, item.matchType()):
+			messages = append(messages, msgr.MatchTypeInvalid(item.MatchType))
+		// the regular expressions are not required to start with "/", such as "^/news/(.*)$"
+		case strings.HasPrefix(item.Source, "http") || (item.matchType() != MatchRegex && !strings.HasPrefix(item.Source, "/")):
 			messages = append(messages, msgr.SourceInvalidFormat(item.Source))
+		default:
+			if _, err := compileRule(&item); err != nil {
+				messages = append(messages, msgr.SourceInvalidPattern(item.Source))
+			}
 		}
 		if strings.HasPrefix(item.Target, "http") {
-			urls[item.Target] = append(urls[item.Target], row)
+			// the targets with the captures of the source are different for every request
+			if !strings.Contains(item.Target, "$") {
+				urls[item.Target] = append(urls[item.Target], row)
+			}
 		} else if !strings.HasPrefix(item.Target, "/") {
 			messages = append(messages, msgr.TargetInvalidFormat(item.Target))
 		}
+		if item.StatusCode != 0 && !slices.Contains(StatusCodes, item.StatusCode) {
+			messages = append(messages, msgr.StatusCodeInvalid(item.StatusCode))
+		}
+		if item.StartAt != nil && item.ExpireAt != nil && !item.StartAt.Before(*item.ExpireAt) {
+			messages = append(messages, msgr.ExpireBeforeStart)
+		}
+		// the S3 static website redirects the objects to fixed targets only
+		if _, ok := b.backend.(*S3Backend); ok && !s3Supports(&item) {
+			messages = append(messages, msgr.UnsupportedByS3Backend)
+		}
 		if len(messages) > 0 {
 			invalidFormat[row] = strings.Join(messages, ",")
 		}
@@ -94,6 +118,23 @@ func (*Builder) checkRecords(r *web.EventResponse, msgr *Messages, records []Red
 		return
 	}
 
+	loops, chains, err := b.checkHops(records)
+	if err != nil {
+		web.AppendRunScripts(r, web.Emit(redirection_notify_error_msg, err.Error()))
+		return
+	}
+	if len(loops) > 0 || len(chains) > 0 {
+		var messages []string
+		if len(loops) > 0 {
+			messages = append(messages, msgr.RedirectLoop(loops))
+		}
+		if len(chains) > 0 {
+			messages = append(messages, msgr.ChainTooLong(chains, b.getMaxHops()))
+		}
+		web.AppendRunScripts(r, web.Emit(redirection_notify_error_msg, strings.Join(messages, "\n")))
+		return
+	}
+
 	// check all target urls is reachable
 	if len(urls) > 0 {
 		failedUrls := checkURLsBatch(urls)
@@ -110,6 +151,65 @@ func (*Builder) checkRecords(r *web.EventResponse, msgr *Messages, records []Red
 	return true
 }
 
+// checkHops follows the redirections from the sources of the saved and the uploaded records, the uploaded
+// ones replace the saved ones of the same sources. It returns the rows of the uploaded records in loops
+// and in the chains longer than the max hops, the dates of the records are ignored.
+func (b *Builder) checkHops(records []Redirection) (loops, chains []string, err error) {
+	var all []*Redirection
+	if b.db != nil {
+		if err = b.db.Order("id ASC").Find(&all).Error; err != nil {
+			return
+		}
+	}
+	rows := make(map[*Redirection]string)
+	for i := range records {
+		rows[&records[i]] = strconv.Itoa(i + 1)
+		all = append(all, &records[i])
+	}
+	rules, _ := newRuleSet(all)
+	rules.anyTime = true
+
+	var (
+		maxHops = b.getMaxHops()
+		inLoop  = make(map[string]bool)
+		inChain = make(map[string]bool)
+	)
+	for _, record := range all {
+		u, pErr := url.Parse(samplePath(record))
+		if pErr != nil || u.Path == "" {
+			continue
+		}
+		hops, loop := rules.trace(u, time.Time{}, maxHops)
+		if !loop && len(hops) <= maxHops {
+			continue
+		}
+		for _, m := range hops {
+			row, ok := rows[m.Redirection]
+			if !ok {
+				continue
+			}
+			if loop {
+				inLoop[row] = true
+			} else {
+				inChain[row] = true
+			}
+		}
+	}
+	return sortedRows(inLoop), sortedRows(inChain), nil
+}
+
+func sortedRows(vs map[string]bool) (rows []string) {
+	for row := range vs {
+		rows = append(rows, row)
+	}
+	slices.SortFunc(rows, func(a, b string) int {
+		x, _ := strconv.Atoi(a)
+		y, _ := strconv.Atoi(b)
+		return x - y
+	})
+	return
+}
+
 func (b *Builder) checkObjects(ctx *web.EventContext, r *web.EventResponse, msgr *Messages, records []Redirection) (passed bool) {
 	var (
 		errorRows         []string
@@ -118,6 +218,10 @@ func (b *Builder) checkObjects(ctx *web.EventContext, r *web.EventResponse, msgr
 	// check  target object is exist
 	for index, record := range records {
 		row := strconv.Itoa(index + 1)
+		// the targets of the pattern redirections depend on the requests
+		if record.matchType() != MatchExact {
+			continue
+		}
 		if !strings.HasPrefix(record.Target, "http") && !b.checkTargetExists(ctx.R.Context(), record.Target) {
 			errorRows = append(errorRows, row)
 		}
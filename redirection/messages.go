@@ -1,6 +1,7 @@
 package redirection
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/qor5/x/v3/i18n"
@@ -10,12 +11,29 @@ type Messages struct {
 	RepeatedSourceErrorTemplate    string
 	SourceInvalidFormatTemplate    string
 	TargetInvalidFormatTemplate    string
+	StatusCodeInvalidTemplate      string
+	MatchTypeInvalidTemplate       string
+	SourceInvalidPatternTemplate   string
+	ExpireBeforeStart              string
+	UnsupportedByS3Backend         string
+	RedirectLoopTemplate           string
+	ChainTooLongTemplate           string
 	TargetUnreachableErrorTemplate string
 	TargetObjectNotExistedTemplate string
 	NormalErrorTemplate            string
 	RedirectErrorTemplate          string
 	FileUploadFailed               string
 	ErrorTips                      string
+	TestURL                        string
+	TestURLPlaceholder             string
+	Test                           string
+	NoRedirectionMatched           string
+	TestLoopTips                   string
+	TestChainTooLongTemplate       string
+	TestSource                     string
+	TestMatchType                  string
+	TestLocation                   string
+	TestStatusCode                 string
 }
 
 const I18nRedirectionKey i18n.ModuleKey = "I18nRedirectionKey"
@@ -24,36 +42,87 @@ var Messages_en_US = &Messages{
 	RepeatedSourceErrorTemplate:    "Row {Rows}: Source Is Duplicated.",
 	SourceInvalidFormatTemplate:    "Source Invalid Format",
 	TargetInvalidFormatTemplate:    "Target Invalid Format",
+	StatusCodeInvalidTemplate:      "Status Code {Code} Is Not Supported",
+	MatchTypeInvalidTemplate:       "Match Type {Name} Is Not Supported",
+	SourceInvalidPatternTemplate:   "Source Invalid Pattern",
+	ExpireBeforeStart:              "Expire At Is Not After Start At",
+	UnsupportedByS3Backend:         "The S3 Backend Only Supports Exact Redirections Without Start At And Expire At",
+	RedirectLoopTemplate:           "Row {Rows}: Redirection Loop.",
+	ChainTooLongTemplate:           "Row {Rows}: Redirection Chain Is Longer Than {Hops} Hops.",
 	TargetUnreachableErrorTemplate: "Row {Rows}: Target Is Unreachable.",
 	TargetObjectNotExistedTemplate: "Row {Rows}: Target Object Not Existed",
 	NormalErrorTemplate:            "Row {Rows}:{Message}",
 	RedirectErrorTemplate:          "Row {Rows}: Redirection Failed.",
 	FileUploadFailed:               "File Upload Failed",
 	ErrorTips:                      "ErrorTips",
+	TestURL:                        "Test URL",
+	TestURLPlaceholder:             "Type a URL, such as /old/page.html?a=1",
+	Test:                           "Test",
+	NoRedirectionMatched:           "No redirection matches the URL.",
+	TestLoopTips:                   "The redirections loop.",
+	TestChainTooLongTemplate:       "The redirections are followed more than {Hops} times.",
+	TestSource:                     "Source",
+	TestMatchType:                  "Match Type",
+	TestLocation:                   "Location",
+	TestStatusCode:                 "Status Code",
 }
 
 var Messages_zh_CN = &Messages{
 	RepeatedSourceErrorTemplate:    "第{Rows}行：源数据重复。",
 	SourceInvalidFormatTemplate:    "源数据格式无效。",
 	TargetInvalidFormatTemplate:    "目标格式无效。",
+	StatusCodeInvalidTemplate:      "不支持状态码 {Code}。",
+	MatchTypeInvalidTemplate:       "不支持匹配类型 {Name}。",
+	SourceInvalidPatternTemplate:   "源数据的匹配模式无效。",
+	ExpireBeforeStart:              "过期时间必须晚于开始时间。",
+	UnsupportedByS3Backend:         "S3 后端仅支持没有开始时间和过期时间的精确匹配重定向。",
+	RedirectLoopTemplate:           "第{Rows}行：重定向循环。",
+	ChainTooLongTemplate:           "第{Rows}行：重定向链超过{Hops}跳。",
 	TargetUnreachableErrorTemplate: "第{Rows}行：目标无法访问。",
 	TargetObjectNotExistedTemplate: "第{Rows}行：目标对象不存在。",
 	NormalErrorTemplate:            "第{Rows}行：{Message}",
 	RedirectErrorTemplate:          "第{Rows}行：重定向失败。",
 	FileUploadFailed:               "文件上传失败。",
 	ErrorTips:                      "错误提示",
+	TestURL:                        "测试URL",
+	TestURLPlaceholder:             "输入URL，例如 /old/page.html?a=1",
+	Test:                           "测试",
+	NoRedirectionMatched:           "没有匹配该URL的重定向。",
+	TestLoopTips:                   "重定向出现循环。",
+	TestChainTooLongTemplate:       "重定向超过{Hops}次。",
+	TestSource:                     "源",
+	TestMatchType:                  "匹配类型",
+	TestLocation:                   "跳转地址",
+	TestStatusCode:                 "状态码",
 }
 
 var Messages_ja_JP = &Messages{
 	RepeatedSourceErrorTemplate:    "{Rows}行目: Source が重複しています。",
 	SourceInvalidFormatTemplate:    "Source のフォーマットが無効です。",
 	TargetInvalidFormatTemplate:    "Target のフォーマットが無効です。",
+	StatusCodeInvalidTemplate:      "ステータスコード {Code} はサポートされていません。",
+	MatchTypeInvalidTemplate:       "マッチタイプ {Name} はサポートされていません。",
+	SourceInvalidPatternTemplate:   "Source のパターンが無効です。",
+	ExpireBeforeStart:              "有効期限は開始日時より後にしてください。",
+	UnsupportedByS3Backend:         "S3 バックエンドは開始日時と有効期限のない完全一致のリダイレクトのみサポートしています。",
+	RedirectLoopTemplate:           "{Rows}行目: リダイレクトがループしています。",
+	ChainTooLongTemplate:           "{Rows}行目: リダイレクトチェーンが{Hops}ホップを超えています。",
 	TargetUnreachableErrorTemplate: "{Rows}行目: Target に到達できません。",
 	TargetObjectNotExistedTemplate: "ターゲットオブジェクトが存在しません。",
 	NormalErrorTemplate:            "{Rows}行目: {Message}",
 	RedirectErrorTemplate:          "{Rows}行目: リダイレクトに失敗しました。",
 	FileUploadFailed:               "ファイルのアップロードに失敗しました。",
 	ErrorTips:                      "エラーのヒント",
+	TestURL:                        "URLをテスト",
+	TestURLPlaceholder:             "URLを入力してください（例: /old/page.html?a=1）",
+	Test:                           "テスト",
+	NoRedirectionMatched:           "URLに一致するリダイレクトはありません。",
+	TestLoopTips:                   "リダイレクトがループしています。",
+	TestChainTooLongTemplate:       "リダイレクトが{Hops}回を超えています。",
+	TestSource:                     "Source",
+	TestMatchType:                  "マッチタイプ",
+	TestLocation:                   "リダイレクト先",
+	TestStatusCode:                 "ステータスコード",
 }
 
 func (msgr *Messages) RepeatedSource(vs map[string][]string) string {
@@ -94,6 +163,12 @@ func (msgr *Messages) TargetInvalidFormat(name string) string {
 	).Replace(msgr.TargetInvalidFormatTemplate)
 }
 
+func (msgr *Messages) StatusCodeInvalid(code int) string {
+	return strings.NewReplacer(
+		"{Code}", strconv.Itoa(code),
+	).Replace(msgr.StatusCodeInvalidTemplate)
+}
+
 func (msgr *Messages) InvalidFormat(vs map[string]string) string {
 	var messages []string
 	for rows, message := range vs {
@@ -109,3 +184,33 @@ func (msgr *Messages) TargetObjectNotExisted(vs []string) string {
 		"{Rows}", strings.Join(vs, ","),
 	).Replace(msgr.TargetObjectNotExistedTemplate)
 }
+
+func (msgr *Messages) MatchTypeInvalid(name string) string {
+	return strings.NewReplacer(
+		"{Name}", name,
+	).Replace(msgr.MatchTypeInvalidTemplate)
+}
+
+func (msgr *Messages) SourceInvalidPattern(name string) string {
+	return strings.NewReplacer(
+		"{Name}", name,
+	).Replace(msgr.SourceInvalidPatternTemplate)
+}
+
+func (msgr *Messages) RedirectLoop(vs []string) string {
+	return strings.NewReplacer(
+		"{Rows}", strings.Join(vs, ","),
+	).Replace(msgr.RedirectLoopTemplate)
+}
+
+func (msgr *Messages) ChainTooLong(vs []string, hops int) string {
+	return strings.NewReplacer(
+		"{Rows}", strings.Join(vs, ","), "{Hops}", strconv.Itoa(hops),
+	).Replace(msgr.ChainTooLongTemplate)
+}
+
+func (msgr *Messages) TestChainTooLong(hops int) string {
+	return strings.NewReplacer(
+		"{Hops}", strconv.Itoa(hops),
+	).Replace(msgr.TestChainTooLongTemplate)
+}
@@ -0,0 +1,138 @@
+package redirection
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MiddlewareBackend serves the redirections in the application with Middleware,
+// the redirections are cached in memory, the cache is updated when they are committed by the Builder
+// and reloaded every RefreshInterval for the ones saved by the other instances.
+type MiddlewareBackend struct {
+	db              *gorm.DB
+	targetExists    func(ctx context.Context, target string) bool
+	refreshInterval time.Duration
+
+	mu         sync.RWMutex
+	rules      *ruleSet
+	loadedAt   time.Time
+	refreshing atomic.Bool
+}
+
+func NewMiddlewareBackend(db *gorm.DB) *MiddlewareBackend {
+	return &MiddlewareBackend{db: db}
+}
+
+// TargetExistsFunc sets how the internal targets are checked, they are taken as existing if it is not set
+func (b *MiddlewareBackend) TargetExistsFunc(v func(ctx context.Context, target string) bool) (r *MiddlewareBackend) {
+	b.targetExists = v
+	return b
+}
+
+// RefreshInterval sets how often the cache is reloaded from the database, it is never reloaded if it is 0.
+// Set it when the redirections are saved by more than one instance of the application.
+func (b *MiddlewareBackend) RefreshInterval(v time.Duration) (r *MiddlewareBackend) {
+	b.refreshInterval = v
+	return b
+}
+
+// redirectKey makes the file and the directory form of the source the same key,
+// "/a/index.html" and "/a/" are both served by the index document.
+func redirectKey(source string) string {
+	return strings.TrimSuffix(source, indexDocument)
+}
+
+// Redirect checks the record only, the cache is updated by redirectCommitted after the record is committed
+func (b *MiddlewareBackend) Redirect(_ context.Context, record *Redirection) error {
+	if record.Target == "" || record.DeletedAt.Valid {
+		return nil
+	}
+	_, err := compileRule(record)
+	return err
+}
+
+func (b *MiddlewareBackend) redirectCommitted(record *Redirection) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// the cache is not loaded yet, the record is loaded with the others
+	if b.rules == nil {
+		return
+	}
+	rec := *record
+	if err := b.rules.set(&rec); err != nil {
+		log.Printf("redirection: skipped the redirection %d: %v", rec.ID, err)
+	}
+}
+
+func (b *MiddlewareBackend) TargetExists(ctx context.Context, target string) bool {
+	if b.targetExists == nil {
+		return true
+	}
+	return b.targetExists(ctx, target)
+}
+
+// Refresh reloads the redirections from the database, call it when they are changed by other processes.
+// The latest record of a source is served, the ones with empty targets remove the redirections.
+func (b *MiddlewareBackend) Refresh(ctx context.Context) error {
+	loadedAt := time.Now()
+	var records []*Redirection
+	if err := b.db.WithContext(ctx).Order("id ASC").Find(&records).Error; err != nil {
+		return err
+	}
+	rules, errs := newRuleSet(records)
+	for r, err := range errs {
+		log.Printf("redirection: skipped the redirection %d: %v", r.ID, err)
+	}
+	b.mu.Lock()
+	b.rules = rules
+	b.loadedAt = loadedAt
+	b.mu.Unlock()
+	return nil
+}
+
+// Match returns the redirection of the url at the time
+func (b *MiddlewareBackend) Match(ctx context.Context, u *url.URL, now time.Time) (*Match, error) {
+	b.mu.RLock()
+	loaded := b.rules != nil
+	expired := b.refreshInterval > 0 && time.Since(b.loadedAt) >= b.refreshInterval
+	b.mu.RUnlock()
+	if !loaded {
+		if err := b.Refresh(ctx); err != nil {
+			return nil, err
+		}
+	} else if expired && b.refreshing.CompareAndSwap(false, true) {
+		// the expired cache is served until it is reloaded
+		go func() {
+			defer b.refreshing.Store(false)
+			if err := b.Refresh(context.WithoutCancel(ctx)); err != nil {
+				log.Printf("redirection: failed to refresh redirections: %v", err)
+			}
+		}()
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.rules.match(u, now), nil
+}
+
+// Middleware responds the redirection of the request, the other requests are served by next
+func (b *MiddlewareBackend) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m, err := b.Match(r.Context(), r.URL, time.Now())
+		if err != nil {
+			log.Printf("redirection: failed to load redirections: %v", err)
+		}
+		if m == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		http.Redirect(w, r, m.Location, m.StatusCode)
+	})
+}
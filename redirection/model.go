@@ -1,17 +1,73 @@
 package redirection
 
 import (
+	"net/http"
+	"slices"
+	"time"
+
 	"gorm.io/gorm"
 )
 
+// StatusCodes are the status codes of the redirections
+var StatusCodes = []int{
+	http.StatusMovedPermanently,
+	http.StatusFound,
+	http.StatusTemporaryRedirect,
+	http.StatusPermanentRedirect,
+}
+
+const (
+	// MatchExact matches the path equal to the source
+	MatchExact = "exact"
+	// MatchPrefix matches the paths starting with the source at a path segment boundary,
+	// the rest of the path is appended to the target
+	MatchPrefix = "prefix"
+	// MatchWildcard matches the paths with the source, in which * matches any characters,
+	// the matched characters are $1, $2... in the target
+	MatchWildcard = "wildcard"
+	// MatchRegex matches the paths with the source as a regular expression,
+	// its captures are $1, $2... or ${name} in the target
+	MatchRegex = "regex"
+)
+
+var MatchTypes = []string{MatchExact, MatchPrefix, MatchWildcard, MatchRegex}
+
 type (
 	Redirection struct {
 		gorm.Model
 		Source string `csv:"source"`
 		Target string `csv:"target"`
+		// StatusCode is one of StatusCodes, 301 is used if it is empty
+		StatusCode int `csv:"status_code"`
+		// MatchType is one of MatchTypes, MatchExact is used if it is empty
+		MatchType string `csv:"match_type"`
+		// PreserveQuery appends the query string of the request to the target
+		PreserveQuery bool `csv:"preserve_query"`
+		// StartAt and ExpireAt limit when the redirection is served
+		StartAt  *time.Time `csv:"start_at,omitempty"`
+		ExpireAt *time.Time `csv:"expire_at,omitempty"`
 	}
 )
 
+func (r *Redirection) matchType() string {
+	if r.MatchType == "" {
+		return MatchExact
+	}
+	return r.MatchType
+}
+
+// Active reports whether the redirection is served at the time
+func (r *Redirection) Active(now time.Time) bool {
+	return (r.StartAt == nil || !now.Before(*r.StartAt)) && (r.ExpireAt == nil || now.Before(*r.ExpireAt))
+}
+
+func (r *Redirection) statusCode() int {
+	if slices.Contains(StatusCodes, r.StatusCode) {
+		return r.StatusCode
+	}
+	return http.StatusMovedPermanently
+}
+
 func (*Redirection) TableName() string {
 	return "redirections"
 }
@@ -1,8 +1,6 @@
 package redirection
 
 import (
-	"net/url"
-
 	"github.com/qor5/web/v3"
 	"github.com/qor5/x/v3/i18n"
 	"github.com/qor5/x/v3/oss"
@@ -22,8 +20,8 @@ const redirection_notify_error_msg = "redirection_notify_error_msg"
 
 type (
 	Builder struct {
-		s3Client  *s3.Client
-		baseURL   *url.URL
+		backend   Backend
+		maxHops   int
 		db        *gorm.DB
 		mb        *presets.ModelBuilder
 		publisher *publish.Builder
@@ -31,12 +29,42 @@ type (
 	}
 )
 
+// New creates the builder redirecting with the S3 static website of s3Client,
+// use Backend to serve the redirections in other ways.
 func New(s3Client *s3.Client, db *gorm.DB, publisher *publish.Builder) *Builder {
-	return &Builder{
-		s3Client:  s3Client,
+	b := &Builder{
+		maxHops:   defaultMaxHops,
 		db:        db,
 		publisher: publisher,
 	}
+	if s3Client != nil && s3Client.Config.Endpoint != "" {
+		b.backend = NewS3Backend(s3Client)
+	}
+	return b
+}
+
+// Backend sets where the redirections are applied, such as S3Backend and MiddlewareBackend
+func (b *Builder) Backend(v Backend) (r *Builder) {
+	b.backend = v
+	return b
+}
+
+func (b *Builder) GetBackend() Backend {
+	return b.backend
+}
+
+// MaxHops sets how many redirections a request can follow before reaching the final page,
+// the uploaded redirections making longer chains are rejected.
+func (b *Builder) MaxHops(v int) (r *Builder) {
+	b.maxHops = v
+	return b
+}
+
+func (b *Builder) getMaxHops() int {
+	if b.maxHops <= 0 {
+		return defaultMaxHops
+	}
+	return b.maxHops
 }
 
 func (b *Builder) AutoMigrate() *Builder {
@@ -47,10 +75,7 @@ func (b *Builder) AutoMigrate() *Builder {
 }
 
 func (b *Builder) Install(pb *presets.Builder) (err error) {
-	if b.s3Client == nil || b.s3Client.Config.Endpoint == "" {
-		return
-	}
-	if b.baseURL, err = url.Parse(b.s3Client.Config.Endpoint); err != nil {
+	if b.backend == nil {
 		return
 	}
 	pb.GetI18n().
@@ -61,7 +86,9 @@ func (b *Builder) Install(pb *presets.Builder) (err error) {
 	m := &Redirection{}
 	b.mb = pb.Model(m).MenuIcon("mdi-link")
 	b.mb.RegisterEventFunc(UploadFileEvent, b.uploadFile)
-	listing := b.mb.Listing("Source", "Target")
+	b.mb.RegisterEventFunc(TestURLDialogEvent, b.testURLDialog)
+	b.mb.RegisterEventFunc(TestURLEvent, b.testURL)
+	listing := b.mb.Listing("Source", "MatchType", "Target", "StatusCode", "PreserveQuery", "StartAt", "ExpireAt")
 	listing.CellWrapperFunc(func(cell h.MutableAttrHTMLComponent, id string, obj interface{}, dataTableID string) h.HTMLComponent {
 		cell.SetAttr("@click", "")
 		return cell
@@ -75,6 +102,9 @@ func (b *Builder) Install(pb *presets.Builder) (err error) {
 			vx.VXDialog(
 				h.P(h.Text("{{xLocals.text}}")).Style("white-space: pre-line;"),
 			).Title(msgr.ErrorTips).HideFooter(true).Type(vx.DialogError).Attr("v-model", "xLocals.dialog"),
+			vx.VXBtn(msgr.TestURL).
+				PrependIcon("mdi-link-variant").Variant(v.VariantOutlined).Class("mr-2").
+				Attr("@click", web.Plaid().EventFunc(TestURLDialogEvent).Go()),
 			vx.VXBtn("UploadFile").
 				Attr(":loading", "xLocals.loading").
 				PrependIcon("mdi-upload").Color(v.ColorPrimary).
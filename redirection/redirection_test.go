@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/credentials"
@@ -91,6 +95,13 @@ func TestCheckRecords(t *testing.T) {
 		{Name: "Target is Reachable", Item: Redirection{Source: "/3/index.html", Target: successUrl}, Except: true},
 		{Name: "Source Invalid Format", Item: Redirection{Source: "3/index.html", Target: failedUrl}, Except: false},
 		{Name: "Target Invalid Format", Item: Redirection{Source: "/3/index.html", Target: "index2.html"}, Except: false},
+		{Name: "Status Code Not Supported", Item: Redirection{Source: "/3/index.html", Target: successUrl, StatusCode: http.StatusSeeOther}, Except: false},
+		{Name: "Status Code Supported", Item: Redirection{Source: "/3/index.html", Target: successUrl, StatusCode: http.StatusTemporaryRedirect}, Except: true},
+		{Name: "Match Type Not Supported", Item: Redirection{Source: "/3/", Target: "/4/", MatchType: "glob"}, Except: false},
+		{Name: "Regex Source Invalid Pattern", Item: Redirection{Source: "^/3/(.*$", Target: "/4/$1", MatchType: MatchRegex}, Except: false},
+		{Name: "Regex Source Without Slash", Item: Redirection{Source: "^/3/(.*)$", Target: "/4/$1", MatchType: MatchRegex}, Except: true},
+		{Name: "Wildcard Target With Captures", Item: Redirection{Source: "/3/*.html", Target: successUrl + "?p=$1", MatchType: MatchWildcard}, Except: true},
+		{Name: "Start After Expire", Item: Redirection{Source: "/3/index.html", Target: successUrl, StartAt: timePtr(2024, 2, 1), ExpireAt: timePtr(2024, 1, 1)}, Except: false},
 	}
 	var (
 		passed bool
@@ -106,13 +117,191 @@ func TestCheckRecords(t *testing.T) {
 	}
 }
 
+func TestCheckRecordsS3Backend(t *testing.T) {
+	dbr, _ := TestDB.DB()
+	redirectionData.TruncatePut(dbr)
+	builder := &Builder{db: TestDB, backend: NewS3Backend(nil)}
+	items := []CheckItems{
+		{Name: "Exact", Item: Redirection{Source: "/3/index.html", Target: "/4/index.html"}, Except: true},
+		{Name: "Prefix", Item: Redirection{Source: "/3/", Target: "/4/", MatchType: MatchPrefix}, Except: false},
+		{Name: "Wildcard", Item: Redirection{Source: "/3/*.html", Target: "/4/$1.html", MatchType: MatchWildcard}, Except: false},
+		{Name: "Regex", Item: Redirection{Source: "^/3/(.*)$", Target: "/4/$1", MatchType: MatchRegex}, Except: false},
+		{Name: "Start At", Item: Redirection{Source: "/3/index.html", Target: "/4/index.html", StartAt: timePtr(2024, 1, 1)}, Except: false},
+		{Name: "Expire At", Item: Redirection{Source: "/3/index.html", Target: "/4/index.html", ExpireAt: timePtr(2024, 1, 1)}, Except: false},
+	}
+	for _, item := range items {
+		t.Run(item.Name, func(t *testing.T) {
+			var r web.EventResponse
+			passed := builder.checkRecords(&r, Messages_en_US, []Redirection{item.Item})
+			if passed != item.Except {
+				t.Errorf("Expected %t, got %t", item.Except, passed)
+			}
+			if !passed && !strings.Contains(r.RunScript, Messages_en_US.UnsupportedByS3Backend) {
+				t.Errorf("the message %q does not explain the rejection", r.RunScript)
+			}
+		})
+	}
+}
+
+func timePtr(year int, month time.Month, day int) *time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func TestCheckRecordsHops(t *testing.T) {
+	dbr, _ := TestDB.DB()
+	redirectionData.TruncatePut(dbr)
+	if err := TestDB.Create(&Redirection{Source: "/saved.html", Target: "/a.html"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name    string
+		records []Redirection
+		loops   []string
+		chains  []string
+	}{
+		{
+			name:    "no loop",
+			records: []Redirection{{Source: "/a.html", Target: "/b.html"}, {Source: "/c.html", Target: "/b.html"}},
+		},
+		{
+			name:    "loop",
+			records: []Redirection{{Source: "/a.html", Target: "/b/"}, {Source: "/b/index.html", Target: "/a.html"}, {Source: "/c.html", Target: "/d.html"}},
+			loops:   []string{"1", "2"},
+		},
+		{
+			name:    "loop with saved redirection",
+			records: []Redirection{{Source: "/a.html", Target: "/saved.html"}},
+			loops:   []string{"1"},
+		},
+		{
+			name:    "uploaded redirection replaces saved one",
+			records: []Redirection{{Source: "/saved.html", Target: "/x.html"}, {Source: "/a.html", Target: "/saved.html"}},
+		},
+		{
+			name: "chain too long",
+			records: []Redirection{
+				{Source: "/a.html", Target: "/b.html"},
+				{Source: "/b.html", Target: "/c.html"},
+				{Source: "/c.html", Target: "/d.html"},
+			},
+			chains: []string{"1", "2", "3"},
+		},
+		{
+			name:    "prefix growing forever",
+			records: []Redirection{{Source: "/p/", Target: "/p/p/", MatchType: MatchPrefix}},
+			chains:  []string{"1"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			loops, chains, err := b.checkHops(c.records)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(loops, c.loops) {
+				t.Errorf("loops = %v, want %v", loops, c.loops)
+			}
+			if !slices.Equal(chains, c.chains) {
+				t.Errorf("chains = %v, want %v", chains, c.chains)
+			}
+		})
+	}
+}
+
+func TestRuleSetMatch(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	records := []*Redirection{
+		{Model: gorm.Model{ID: 1}, Source: "/docs/", Target: "/guide/", MatchType: MatchPrefix},
+		{Model: gorm.Model{ID: 2}, Source: "/docs/v1/", Target: "/archive/v1/", MatchType: MatchPrefix, PreserveQuery: true},
+		{Model: gorm.Model{ID: 3}, Source: "/blog/*/*.html", Target: "/posts/$1-$2", MatchType: MatchWildcard, StatusCode: http.StatusFound},
+		{Model: gorm.Model{ID: 4}, Source: `^/item/(?P<id>\d+)$`, Target: "https://shop.example.com/p?id=${id}", MatchType: MatchRegex, PreserveQuery: true},
+		{Model: gorm.Model{ID: 5}, Source: "/docs/index.html", Target: "/home.html"},
+		{Model: gorm.Model{ID: 6}, Source: "/sale.html", Target: "/sale-2024.html", StartAt: timePtr(2024, 5, 1), ExpireAt: timePtr(2024, 7, 1)},
+		{Model: gorm.Model{ID: 7}, Source: "/old-sale.html", Target: "/sale-2023.html", ExpireAt: timePtr(2024, 1, 1)},
+		{Model: gorm.Model{ID: 8}, Source: "/future.html", Target: "/soon.html", StartAt: timePtr(2025, 1, 1)},
+		{Model: gorm.Model{ID: 9}, Source: "/shop", Target: "/store", MatchType: MatchPrefix},
+	}
+	rules, errs := newRuleSet(records)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	cases := []struct {
+		url      string
+		id       uint
+		location string
+		code     int
+	}{
+		{url: "/docs/", id: 5, location: "/home.html", code: http.StatusMovedPermanently},
+		{url: "/docs/a/b.html?x=1", id: 1, location: "/guide/a/b.html", code: http.StatusMovedPermanently},
+		{url: "/docs/v1/a.html?x=1", id: 2, location: "/archive/v1/a.html?x=1"},
+		{url: "/blog/2024/hello.html", id: 3, location: "/posts/2024-hello", code: http.StatusFound},
+		{url: "/item/42?ref=mail", id: 4, location: "https://shop.example.com/p?id=42&ref=mail"},
+		{url: "/item/abc"},
+		{url: "/sale.html", id: 6, location: "/sale-2024.html"},
+		{url: "/old-sale.html"},
+		{url: "/future.html"},
+		{url: "/other.html"},
+		{url: "/shop", id: 9, location: "/store"},
+		{url: "/shop/a.html", id: 9, location: "/store/a.html"},
+		{url: "/shopping"},
+		{url: "/docsx/a.html"},
+	}
+	for _, c := range cases {
+		t.Run(c.url, func(t *testing.T) {
+			u, _ := url.Parse(c.url)
+			m := rules.match(u, now)
+			if c.id == 0 {
+				if m != nil {
+					t.Fatalf("matched %d, want no match", m.Redirection.ID)
+				}
+				return
+			}
+			if m == nil {
+				t.Fatalf("no match, want %d", c.id)
+			}
+			if m.Redirection.ID != c.id {
+				t.Errorf("matched %d, want %d", m.Redirection.ID, c.id)
+			}
+			if m.Location != c.location {
+				t.Errorf("location = %q, want %q", m.Location, c.location)
+			}
+			if c.code != 0 && m.StatusCode != c.code {
+				t.Errorf("status code = %d, want %d", m.StatusCode, c.code)
+			}
+		})
+	}
+}
+
 var redirectionData = gofixtures.Data(gofixtures.Sql(`
 `, []string{"redirections"}))
 
 func TestCreateEmptyTargetRecord(t *testing.T) {
 	dbr, _ := TestDB.DB()
 	redirectionData.TruncatePut(dbr)
-	b.createEmptyTargetRecord("/index_empty.html")
+	backend := NewMiddlewareBackend(TestDB)
+	builder := &Builder{db: TestDB, backend: backend}
+	for _, r := range []*Redirection{
+		{Source: "/index_empty.html", Target: "/new.html"},
+		{Source: "/docs/", Target: "/guide/", MatchType: MatchPrefix},
+	} {
+		if err := builder.saver(context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	match := func(backend *MiddlewareBackend, p string) *Match {
+		t.Helper()
+		m, err := backend.Match(context.Background(), &url.URL{Path: p}, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	if match(backend, "/index_empty.html") == nil {
+		t.Fatal("no redirection before the object is put")
+	}
+
+	builder.createEmptyTargetRecord(context.Background(), "/index_empty.html")
 	m := Redirection{}
 	TestDB.Order("id desc").First(&m)
 	if m.Source != "/index_empty.html" {
@@ -123,6 +312,23 @@ func TestCreateEmptyTargetRecord(t *testing.T) {
 		t.Fatalf("create record failed targe:%v", m.Target)
 		return
 	}
+	// the cache and the redirections reloaded are both resolved to the latest record of the source
+	for name, backend := range map[string]*MiddlewareBackend{"cached": backend, "reloaded": NewMiddlewareBackend(TestDB)} {
+		if got := match(backend, "/index_empty.html"); got != nil {
+			t.Errorf("%s: the redirection %d is still served after the object is put", name, got.Redirection.ID)
+		}
+		if match(backend, "/docs/a.html") == nil {
+			t.Errorf("%s: the other redirections are removed", name)
+		}
+	}
+
+	// a later redirection of the source is served again
+	if err := builder.saver(context.Background(), &Redirection{Source: "/index_empty.html", Target: "/newer.html"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := match(NewMiddlewareBackend(TestDB), "/index_empty.html"); got == nil || got.Location != "/newer.html" {
+		t.Errorf("match = %v, want the redirection to /newer.html", got)
+	}
 }
 
 func TestCheckObjects(t *testing.T) {
@@ -163,7 +369,7 @@ func TestCheckTargetExists(t *testing.T) {
 		}
 		w.WriteHeader(http.StatusNotFound)
 	}))
-	builder := &Builder{s3Client: client}
+	builder := &Builder{backend: NewS3Backend(client)}
 
 	cases := []struct {
 		name   string
@@ -199,7 +405,7 @@ func TestRedirectionKeepsDirectoryTargetSlash(t *testing.T) {
 			w.WriteHeader(http.StatusNotFound)
 		}
 	}))
-	builder := &Builder{s3Client: client}
+	builder := &Builder{backend: NewS3Backend(client)}
 
 	cases := []struct {
 		name   string
@@ -223,3 +429,94 @@ func TestRedirectionKeepsDirectoryTargetSlash(t *testing.T) {
 		})
 	}
 }
+
+func TestMiddlewareBackend(t *testing.T) {
+	dbr, _ := TestDB.DB()
+	redirectionData.TruncatePut(dbr)
+	backend := NewMiddlewareBackend(TestDB)
+	builder := &Builder{db: TestDB, backend: backend}
+	if err := builder.saver(context.Background(), &Redirection{Source: "/old/index.html", Target: "/new/"}); err != nil {
+		t.Fatal(err)
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := backend.Middleware(next)
+
+	serve := func(p string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, p, http.NoBody))
+		return w
+	}
+	// the cache is loaded by the first request and updated by the saved records
+	serve("/")
+	if err := builder.saver(context.Background(), &Redirection{Source: "/temp.html", Target: "https://example.com/x", StatusCode: http.StatusFound}); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name     string
+		path     string
+		code     int
+		location string
+	}{
+		{name: "file form of source", path: "/old/index.html", code: http.StatusMovedPermanently, location: "/new/"},
+		{name: "directory form of source", path: "/old/", code: http.StatusMovedPermanently, location: "/new/"},
+		{name: "status code of record", path: "/temp.html", code: http.StatusFound, location: "https://example.com/x"},
+		{name: "no redirection", path: "/other.html", code: http.StatusOK},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := serve(c.path)
+			if w.Code != c.code {
+				t.Errorf("status code = %d, want %d", w.Code, c.code)
+			}
+			if got := w.Header().Get("Location"); got != c.location {
+				t.Errorf("location = %q, want %q", got, c.location)
+			}
+		})
+	}
+
+	if err := builder.saver(context.Background(), &Redirection{Source: "/other.html", Target: "/new/", StatusCode: http.StatusPermanentRedirect}); err != nil {
+		t.Fatal(err)
+	}
+	if w := serve("/other.html"); w.Code != http.StatusPermanentRedirect {
+		t.Errorf("status code after saving = %d, want %d", w.Code, http.StatusPermanentRedirect)
+	}
+}
+
+func TestMiddlewareBackendCommit(t *testing.T) {
+	dbr, _ := TestDB.DB()
+	redirectionData.TruncatePut(dbr)
+	backend := NewMiddlewareBackend(TestDB).RefreshInterval(10 * time.Millisecond)
+	builder := &Builder{db: TestDB, backend: backend}
+	serve := func(p string) int {
+		w := httptest.NewRecorder()
+		backend.Middleware(http.NotFoundHandler()).ServeHTTP(w, httptest.NewRequest(http.MethodGet, p, http.NoBody))
+		return w.Code
+	}
+	serve("/")
+
+	// the record rolled back is not served
+	if err := builder.saver(context.Background(), &Redirection{Source: "/news/(", Target: "/new/", MatchType: MatchRegex}); err == nil {
+		t.Fatal("saving the invalid regex succeeded")
+	}
+	var count int64
+	if err := TestDB.Model(&Redirection{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("saved %d records, want the invalid one rolled back", count)
+	}
+
+	// the record saved by another instance is served after the cache is reloaded
+	if err := TestDB.Create(&Redirection{Source: "/other.html", Target: "/new/", StatusCode: http.StatusFound}).Error; err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for serve("/other.html") != http.StatusFound {
+		if time.Now().After(deadline) {
+			t.Fatal("the redirection saved by another instance is not served")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,220 @@
+package redirection
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const defaultMaxHops = 3
+
+// Match is the redirection matching a request and where the request is redirected to
+type Match struct {
+	Redirection *Redirection
+	Location    string
+	StatusCode  int
+}
+
+// rule is a redirection compiled for matching
+type rule struct {
+	*Redirection
+	re *regexp.Regexp
+}
+
+func compileRule(r *Redirection) (ru *rule, err error) {
+	ru = &rule{Redirection: r}
+	switch r.matchType() {
+	case MatchExact, MatchPrefix:
+	case MatchWildcard:
+		parts := strings.Split(r.Source, "*")
+		for i := range parts {
+			parts[i] = regexp.QuoteMeta(parts[i])
+		}
+		ru.re, err = regexp.Compile("^" + strings.Join(parts, "(.*)") + "$")
+	case MatchRegex:
+		ru.re, err = regexp.Compile(r.Source)
+	default:
+		err = fmt.Errorf("unknown match type %s", r.MatchType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// location returns where the path is redirected to by the rule, ok is false if the path does not match
+func (ru *rule) location(p string) (target string, ok bool) {
+	switch ru.matchType() {
+	case MatchExact:
+		if redirectKey(p) != redirectKey(ru.Source) {
+			return
+		}
+		return internalTarget(ru.Target), true
+	case MatchPrefix:
+		rest, found := strings.CutPrefix(p, ru.Source)
+		// the prefix matches whole path segments, "/blog" matches "/blog/a.html" but not "/blogger"
+		if !found || (rest != "" && !strings.HasSuffix(ru.Source, "/") && !strings.HasPrefix(rest, "/")) {
+			return
+		}
+		return internalTarget(ru.Target) + rest, true
+	default:
+		m := ru.re.FindStringSubmatchIndex(p)
+		if m == nil {
+			return
+		}
+		return internalTarget(string(ru.re.ExpandString(nil, ru.Target, p, m))), true
+	}
+}
+
+// ruleSet matches the exact rules by the path, and then the pattern rules in order,
+// the longer prefixes first, then the wildcards and the regular expressions in the order they are created.
+type ruleSet struct {
+	exact    map[string]*rule
+	patterns []*rule
+	// anyTime matches the rules regardless of their start and expiry dates
+	anyTime bool
+}
+
+func newRuleSet(records []*Redirection) (s *ruleSet, errs map[*Redirection]error) {
+	s = &ruleSet{exact: make(map[string]*rule)}
+	for _, r := range records {
+		if err := s.set(r); err != nil {
+			if errs == nil {
+				errs = make(map[*Redirection]error)
+			}
+			errs[r] = err
+		}
+	}
+	return
+}
+
+// set adds the rule of the record, the rule with the same id is replaced,
+// the record with an empty target removes the redirection of its source.
+func (s *ruleSet) set(r *Redirection) error {
+	if r.ID != 0 {
+		for k, ru := range s.exact {
+			if ru.ID == r.ID {
+				delete(s.exact, k)
+			}
+		}
+		s.patterns = slices.DeleteFunc(s.patterns, func(ru *rule) bool { return ru.ID == r.ID })
+	}
+	if r.DeletedAt.Valid {
+		return nil
+	}
+	if r.Target == "" {
+		s.remove(r)
+		return nil
+	}
+	ru, err := compileRule(r)
+	if err != nil {
+		return err
+	}
+	if r.matchType() == MatchExact {
+		s.exact[redirectKey(r.Source)] = ru
+		return nil
+	}
+	// the later redirection of the same source replaces the earlier one like the exact ones do
+	s.remove(r)
+	s.patterns = append(s.patterns, ru)
+	order := map[string]int{MatchPrefix: 0, MatchWildcard: 1, MatchRegex: 2}
+	slices.SortStableFunc(s.patterns, func(a, b *rule) int {
+		if c := cmp.Compare(order[a.matchType()], order[b.matchType()]); c != 0 {
+			return c
+		}
+		if a.matchType() == MatchPrefix {
+			return cmp.Compare(len(b.Source), len(a.Source))
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return nil
+}
+
+// remove removes the rule of the same source and match type as the record
+func (s *ruleSet) remove(r *Redirection) {
+	if r.matchType() == MatchExact {
+		delete(s.exact, redirectKey(r.Source))
+		return
+	}
+	s.patterns = slices.DeleteFunc(s.patterns, func(p *rule) bool {
+		return p.matchType() == r.matchType() && p.Source == r.Source
+	})
+}
+
+func (s *ruleSet) active(ru *rule, now time.Time) bool {
+	return s.anyTime || ru.Active(now)
+}
+
+// match returns the rule matching the url and the location it is redirected to
+func (s *ruleSet) match(u *url.URL, now time.Time) *Match {
+	var (
+		matched  *rule
+		location string
+	)
+	if ru, ok := s.exact[redirectKey(u.Path)]; ok && s.active(ru, now) {
+		matched, location = ru, internalTarget(ru.Target)
+	} else {
+		for _, ru := range s.patterns {
+			if !s.active(ru, now) {
+				continue
+			}
+			if l, ok := ru.location(u.Path); ok {
+				matched, location = ru, l
+				break
+			}
+		}
+	}
+	if matched == nil {
+		return nil
+	}
+	if matched.PreserveQuery && u.RawQuery != "" {
+		sep := "?"
+		if strings.Contains(location, "?") {
+			sep = "&"
+		}
+		location += sep + u.RawQuery
+	}
+	return &Match{
+		Redirection: matched.Redirection,
+		Location:    location,
+		StatusCode:  matched.statusCode(),
+	}
+}
+
+// trace follows the redirections from the url, it stops at an external location, a loop or after maxHops + 1 hops
+func (s *ruleSet) trace(u *url.URL, now time.Time, maxHops int) (hops []*Match, loop bool) {
+	visited := map[string]bool{redirectKey(u.Path): true}
+	for len(hops) <= maxHops {
+		m := s.match(u, now)
+		if m == nil {
+			return
+		}
+		hops = append(hops, m)
+		next, err := url.Parse(m.Location)
+		if err != nil || next.IsAbs() {
+			return
+		}
+		key := redirectKey(next.Path)
+		if visited[key] {
+			return hops, true
+		}
+		visited[key] = true
+		u = next
+	}
+	return
+}
+
+// samplePath returns a path matched by the source of the record, it is empty for the regular expressions
+func samplePath(r *Redirection) string {
+	switch r.matchType() {
+	case MatchExact, MatchPrefix:
+		return r.Source
+	case MatchWildcard:
+		return strings.ReplaceAll(r.Source, "*", "")
+	}
+	return ""
+}
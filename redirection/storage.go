@@ -1,21 +1,17 @@
 package redirection
 
 import (
-	"bytes"
 	"context"
 	"io"
+	"log"
 	"mime/multipart"
 	"net/http"
 	"os"
-	"path"
-	"strings"
 	"sync"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/qor5/x/v3/oss"
+	"gorm.io/gorm"
 )
 
 const (
@@ -39,7 +35,7 @@ func (b *Builder) GetStream(ctx context.Context, path string) (io.ReadCloser, er
 func (b *Builder) Put(ctx context.Context, path string, reader io.Reader) (obj *oss.Object, err error) {
 	defer func() {
 		if err == nil {
-			b.createEmptyTargetRecord(path)
+			b.createEmptyTargetRecord(ctx, path)
 		}
 	}()
 	return b.storage.Put(ctx, path, reader)
@@ -61,97 +57,37 @@ func (b *Builder) GetEndpoint(ctx context.Context) string {
 	return b.storage.GetEndpoint(ctx)
 }
 
-func (b *Builder) createEmptyTargetRecord(path string) {
-	var (
-		tx  = b.db.Begin()
-		err error
-		// m   Redirection
-	)
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-		} else {
-			tx.Commit()
-		}
-	}()
-	err = tx.Create(&Redirection{
-		Source: path,
-		Target: "",
-	}).Error
+// createEmptyTargetRecord removes the redirection of the path, which is served by the object put there now
+func (b *Builder) createEmptyTargetRecord(ctx context.Context, path string) {
+	if err := b.saver(ctx, &Redirection{Source: path}); err != nil {
+		log.Printf("redirection: failed to remove the redirection of %s: %v", path, err)
+	}
 }
 
 func (b *Builder) saver(ctx context.Context, record *Redirection) (err error) {
-	tx := b.db.Begin()
-	defer func() {
-		if err == nil {
-			tx.Commit()
-		} else {
-			tx.Rollback()
+	if record.StatusCode == 0 {
+		record.StatusCode = http.StatusMovedPermanently
+	}
+	if err = b.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(record).Error; err != nil {
+			return err
 		}
-	}()
-
-	if err = tx.Save(&record).Error; err != nil {
+		return b.redirection(ctx, record)
+	}); err != nil {
 		return
 	}
-	err = b.redirection(ctx, record)
+	if c, ok := b.backend.(committedRedirector); ok {
+		c.redirectCommitted(record)
+	}
 	return
 }
 
 func (b *Builder) redirection(ctx context.Context, record *Redirection) (err error) {
-	var (
-		client = b.s3Client
-		bucket = client.Config.Bucket
-		source = strings.TrimPrefix(record.Source, "/")
-	)
-	target := record.Target
-	if !strings.HasPrefix(target, "http") {
-		target = path.Join("/", record.Target)
-		// path.Join drops the trailing slash of a directory-form target; restore
-		// it so the redirect goes straight to the directory URL without an extra
-		// redirect hop on the website endpoint.
-		if strings.HasSuffix(record.Target, "/") && target != "/" {
-			target += "/"
-		}
-	}
-	if b.checkObjectExists(ctx, source) {
-		_, err = client.S3.CopyObject(ctx, &s3.CopyObjectInput{
-			Bucket:                  aws.String(bucket),
-			CopySource:              aws.String(path.Join(bucket, record.Source)),
-			Key:                     aws.String(strings.TrimPrefix(record.Source, "/")),
-			WebsiteRedirectLocation: aws.String(target),
-		})
-	} else {
-		params := &s3.PutObjectInput{
-			Bucket:                  aws.String(client.Config.Bucket),
-			Key:                     aws.String(client.ToS3Key(source)),
-			ACL:                     types.ObjectCannedACL(client.Config.ACL),
-			Body:                    bytes.NewReader([]byte{}),
-			WebsiteRedirectLocation: aws.String(target),
-		}
-		if client.Config.CacheControl != "" {
-			params.CacheControl = aws.String(client.Config.CacheControl)
-		}
-		_, err = client.S3.PutObject(ctx, params)
-	}
-	return
+	return b.backend.Redirect(ctx, record)
 }
 
-// checkTargetExists reports whether an internal redirect target resolves to an
-// existing object. A directory-form target (trailing "/") resolves to its index
-// document, matching the behavior of the S3 website endpoint.
 func (b *Builder) checkTargetExists(ctx context.Context, target string) bool {
-	if strings.HasSuffix(target, "/") {
-		target += indexDocument
-	}
-	return b.checkObjectExists(ctx, target)
-}
-
-func (b *Builder) checkObjectExists(ctx context.Context, key string) bool {
-	_, err := b.s3Client.S3.HeadObject(ctx, &s3.HeadObjectInput{
-		Bucket: aws.String(b.s3Client.Config.Bucket),
-		Key:    aws.String(strings.TrimPrefix(key, "/")),
-	})
-	return err == nil
+	return b.backend.TargetExists(ctx, target)
 }
 
 // checkURL checks if a single URL is reachable.
@@ -0,0 +1,111 @@
+package redirection
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/i18n"
+	v "github.com/qor5/x/v3/ui/vuetify"
+	vx "github.com/qor5/x/v3/ui/vuetifyx"
+	h "github.com/theplant/htmlgo"
+
+	"github.com/qor5/admin/v3/presets"
+)
+
+const (
+	TestURLDialogEvent = "redirection_TestURLDialogEvent"
+	TestURLEvent       = "redirection_TestURLEvent"
+
+	testURLResultPortal = "redirection_TestURLResultPortal"
+)
+
+func (b *Builder) testURLDialog(ctx *web.EventContext) (r web.EventResponse, err error) {
+	var (
+		msgr        = i18n.MustGetModuleMessages(ctx.R, I18nRedirectionKey, Messages_en_US).(*Messages)
+		presetsMsgr = presets.MustGetMessages(ctx.R)
+	)
+	r.UpdatePortals = append(r.UpdatePortals, &web.PortalUpdate{
+		Name: presets.DialogPortalName,
+		Body: web.Scope(
+			vx.VXDialog(
+				h.Div(
+					vx.VXField().
+						Attr(web.VField("TestURL", "")...).
+						Placeholder(msgr.TestURLPlaceholder).
+						Attr("@keyup.enter", web.Plaid().EventFunc(TestURLEvent).Go()).
+						Class("flex-grow-1 mr-2"),
+					vx.VXBtn(msgr.Test).Color(v.ColorPrimary).
+						Attr("@click", web.Plaid().EventFunc(TestURLEvent).Go()),
+				).Class("d-flex align-center"),
+				web.Portal().Name(testURLResultPortal),
+			).
+				Title(msgr.TestURL).
+				Width(960).
+				CancelText(presetsMsgr.Cancel).
+				HideOk(true).
+				Attr("v-model", "dialogLocals.dialog"),
+		).VSlot("{locals:dialogLocals}").Init("{dialog:true}"),
+	})
+	return
+}
+
+// testURL shows the redirections followed from the url, with the saved redirections active now
+func (b *Builder) testURL(ctx *web.EventContext) (r web.EventResponse, err error) {
+	msgr := i18n.MustGetModuleMessages(ctx.R, I18nRedirectionKey, Messages_en_US).(*Messages)
+	u, err := url.Parse(strings.TrimSpace(ctx.R.FormValue("TestURL")))
+	if err != nil || u.Path == "" {
+		presets.ShowMessage(&r, msgr.TargetInvalidFormat(ctx.R.FormValue("TestURL")), v.ColorError)
+		return r, nil
+	}
+	var records []*Redirection
+	if err = b.db.WithContext(ctx.R.Context()).Order("id ASC").Find(&records).Error; err != nil {
+		return
+	}
+	rules, _ := newRuleSet(records)
+	hops, loop := rules.trace(&url.URL{Path: u.Path, RawQuery: u.RawQuery}, time.Now(), b.getMaxHops())
+
+	r.UpdatePortals = append(r.UpdatePortals, &web.PortalUpdate{
+		Name: testURLResultPortal,
+		Body: b.testURLResult(msgr, hops, loop),
+	})
+	return
+}
+
+func (b *Builder) testURLResult(msgr *Messages, hops []*Match, loop bool) h.HTMLComponent {
+	if len(hops) == 0 {
+		return h.Div(h.Text(msgr.NoRedirectionMatched)).Class("mt-4")
+	}
+	rows := h.Tbody()
+	for i, m := range hops {
+		rows.AppendChildren(h.Tr(
+			h.Td(h.Text(strconv.Itoa(i+1))),
+			h.Td(h.Text(m.Redirection.Source)),
+			h.Td(h.Text(m.Redirection.matchType())),
+			h.Td(h.Text(m.Location)),
+			h.Td(h.Text(strconv.Itoa(m.StatusCode))),
+		))
+	}
+	var tips h.HTMLComponent
+	switch {
+	case loop:
+		tips = h.Div(h.Text(msgr.TestLoopTips)).Class("text-error mt-2")
+	case len(hops) > b.getMaxHops():
+		tips = h.Div(h.Text(msgr.TestChainTooLong(b.getMaxHops()))).Class("text-error mt-2")
+	}
+	return h.Div(
+		v.VTable(
+			h.Thead(h.Tr(
+				h.Th("#"),
+				h.Th(msgr.TestSource),
+				h.Th(msgr.TestMatchType),
+				h.Th(msgr.TestLocation),
+				h.Th(msgr.TestStatusCode),
+			)),
+			rows,
+		).Density(v.DensityCompact),
+		tips,
+	).Class("mt-4")
+}
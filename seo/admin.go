@@ -3,6 +3,7 @@ package seo
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
@@ -66,6 +67,7 @@ func (b *Builder) Install(pb *presets.Builder) error {
 			NotificationCenterInvisible: true,
 		})
 	b.mb = seoModel
+	seoModel.RegisterEventFunc(socialPreviewEvent, b.socialPreviewEvent)
 
 	// Configure Listing Page
 	b.configListing(seoModel)
@@ -73,6 +75,13 @@ func (b *Builder) Install(pb *presets.Builder) error {
 	b.configEditing(seoModel)
 	// b.ConfigDetailing(pb)
 
+	if b.sitemapStorage != nil {
+		b.configRobots(pb)
+	}
+	if b.auditEnabled {
+		b.installAuditReport(pb)
+	}
+
 	pb.GetI18n().
 		RegisterForModule(language.English, I18nSeoKey, Messages_en_US).
 		RegisterForModule(language.SimplifiedChinese, I18nSeoKey, Messages_zh_CN).
@@ -198,17 +207,28 @@ func (b *Builder) configEditing(seoModel *presets.ModelBuilder) {
 	editing.Field("Setting").ComponentFunc(
 		func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) h.HTMLComponent {
 			seoSetting := obj.(*QorSEOSetting)
-			return b.vseo("Setting", field, b.GetSEO(seoSetting.Name), &seoSetting.Setting, ctx.R)
+			return b.vseo(seoSetting, "Setting", seoSetting.LocaleCode, field, b.GetSEO(seoSetting.Name), &seoSetting.Setting, ctx.R)
 		},
 	)
 }
 
 func EditSetterFunc(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) (err error) {
-	var setting Setting
+	setting, err := settingFromForm(ctx, field.Name)
+	if err != nil {
+		return
+	}
+	if err = reflectutils.Set(obj, field.Name, setting); err != nil {
+		return
+	}
+	return validateStructuredDataSetting(&setting, ctx)
+}
+
+// settingFromForm reads the Setting posted by vseo with the fieldPrefix
+func settingFromForm(ctx *web.EventContext, fieldPrefix string) (setting Setting, err error) {
 	mediaBox := media_library.MediaBox{}
 	for fieldWithPrefix := range ctx.R.Form {
 		// make sure OpenGraphImageFromMediaLibrary.Description set after OpenGraphImageFromMediaLibrary.Values
-		if fieldWithPrefix == fmt.Sprintf("%s.%s", field.Name, "OpenGraphImageFromMediaLibrary.Values") {
+		if fieldWithPrefix == fmt.Sprintf("%s.%s", fieldPrefix, "OpenGraphImageFromMediaLibrary.Values") {
 			err = mediaBox.Scan(ctx.R.FormValue(fieldWithPrefix))
 			if err != nil {
 				return
@@ -217,23 +237,31 @@ func EditSetterFunc(obj interface{}, field *presets.FieldContext, ctx *web.Event
 		}
 	}
 	for fieldWithPrefix := range ctx.R.Form {
-		if strings.HasPrefix(fieldWithPrefix, fmt.Sprintf("%s.%s", field.Name, "OpenGraphImageFromMediaLibrary")) {
-			if fieldWithPrefix == fmt.Sprintf("%s.%s", field.Name, "OpenGraphImageFromMediaLibrary.Description") {
+		if strings.HasPrefix(fieldWithPrefix, fmt.Sprintf("%s.%s", fieldPrefix, "OpenGraphImageFromMediaLibrary")) {
+			if fieldWithPrefix == fmt.Sprintf("%s.%s", fieldPrefix, "OpenGraphImageFromMediaLibrary.Description") {
 				mediaBox.Description = ctx.R.Form.Get(fieldWithPrefix)
 				setting.OpenGraphImageFromMediaLibrary = mediaBox
 			}
 			continue
 		}
-		if fieldWithPrefix == fmt.Sprintf("%s.%s", field.Name, "OpenGraphMetadataString") {
+		if fieldWithPrefix == fmt.Sprintf("%s.%s", fieldPrefix, "OpenGraphMetadataString") {
 			metadata := GetOpenGraphMetadata(ctx.R.Form.Get(fieldWithPrefix))
 			setting.OpenGraphMetadata = metadata
 			continue
 		}
-		if strings.HasPrefix(fieldWithPrefix, fmt.Sprintf("%s.", field.Name)) {
-			reflectutils.Set(&setting, strings.TrimPrefix(fieldWithPrefix, fmt.Sprintf("%s.", field.Name)), ctx.R.Form.Get(fieldWithPrefix))
+		if strings.HasPrefix(fieldWithPrefix, fmt.Sprintf("%s.", fieldPrefix)) {
+			reflectutils.Set(&setting, strings.TrimPrefix(fieldWithPrefix, fmt.Sprintf("%s.", fieldPrefix)), ctx.R.Form.Get(fieldWithPrefix))
 		}
 	}
-	return reflectutils.Set(obj, field.Name, setting)
+	return
+}
+
+func validateStructuredDataSetting(setting *Setting, ctx *web.EventContext) error {
+	if err := ValidateStructuredData(setting.StructuredData); err != nil {
+		msgr := i18n.MustGetModuleMessages(ctx.R, I18nSeoKey, Messages_en_US).(*Messages)
+		return errors.New(msgr.InvalidStructuredData(err.Error()))
+	}
+	return nil
 }
 
 func (b *Builder) EditingComponentFunc(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) h.HTMLComponent {
@@ -270,14 +298,14 @@ func (b *Builder) EditingComponentFunc(obj interface{}, field *presets.FieldCont
 				Attr(web.VField(customizeForm, setting.EnabledCustomize)...).
 				Attr("@update:model-value", "locals.enabledCustomize=$event"),
 			h.Div(
-				b.vseo(fieldPrefix, field, seo, &setting, ctx.R),
+				b.vseo(obj, fieldPrefix, locale, field, seo, &setting, ctx.R),
 			).Attr("v-show", "locals.enabledCustomize"),
 		).Class("pb-4"),
 	).Init(fmt.Sprintf(`{enabledCustomize: %t}`, setting.EnabledCustomize)).
 		VSlot("{ locals }")
 }
 
-func (b *Builder) vseo(fieldPrefix string, field *presets.FieldContext, seo *SEO, setting *Setting, req *http.Request) h.HTMLComponent {
+func (b *Builder) vseo(obj interface{}, fieldPrefix, locale string, field *presets.FieldContext, seo *SEO, setting *Setting, req *http.Request) h.HTMLComponent {
 	var (
 		msgr = i18n.MustGetModuleMessages(req, I18nSeoKey, Messages_en_US).(*Messages)
 		db   = b.db
@@ -302,7 +330,8 @@ func (b *Builder) vseo(fieldPrefix string, field *presets.FieldContext, seo *SEO
 		image.ID = json.Number("")
 	}
 	refPrefix := strings.ReplaceAll(strings.ToLower(fieldPrefix), " ", "_")
-	return VXSendVariables(
+	preview := b.previewSetting(obj, seo, locale, *setting, req)
+	return b.socialPreviewScope(obj, fieldPrefix, locale, seo, VXSendVariables(
 		h.Div(
 			h.Span(msgr.Basic).Class("text-subtitle-1 px-2 py-1 rounded", "bg-"+ColorGreyLighten3),
 		),
@@ -350,7 +379,27 @@ func (b *Builder) vseo(fieldPrefix string, field *presets.FieldContext, seo *SEO
 				},
 			}),
 		VXField().Disabled(field.Disabled).Type("textarea").Attr(web.VField(fmt.Sprintf("%s.%s", fieldPrefix, "OpenGraphMetadataString"), GetOpenGraphMetadataString(setting.OpenGraphMetadata))...).Label(msgr.OpenGraphMetadata).Attr("@focus", fmt.Sprintf("$refs.seo.tagInputsFocus($refs.%s)", fmt.Sprintf("%s_og_metadata", refPrefix))).Attr("ref", fmt.Sprintf("%s_og_metadata", refPrefix)),
-	).Attr("ref", "seo")
+
+		h.Div(
+			h.Span(msgr.TwitterCardInformation).Class("text-subtitle-1 px-2 py-1 rounded", "bg-"+ColorGreyLighten3),
+		).Class("mb-6 mt-6"),
+		VSelect().Disabled(field.Disabled).Items([]string{TwitterCardSummary, TwitterCardSummaryLargeImage}).Clearable(true).Variant(VariantOutlined).Density(DensityCompact).Attr(web.VField(fmt.Sprintf("%s.%s", fieldPrefix, "TwitterCard"), setting.TwitterCard)...).Label(msgr.TwitterCard),
+		VXField().Disabled(field.Disabled).Attr(web.VField(fmt.Sprintf("%s.%s", fieldPrefix, "TwitterSite"), setting.TwitterSite)...).Label(msgr.TwitterSite).Attr("@focus", fmt.Sprintf("$refs.seo.tagInputsFocus($refs.%s)", fmt.Sprintf("%s_twitter_site", refPrefix))).Attr("ref", fmt.Sprintf("%s_twitter_site", refPrefix)),
+		VXField().Disabled(field.Disabled).Attr(web.VField(fmt.Sprintf("%s.%s", fieldPrefix, "TwitterCreator"), setting.TwitterCreator)...).Label(msgr.TwitterCreator).Attr("@focus", fmt.Sprintf("$refs.seo.tagInputsFocus($refs.%s)", fmt.Sprintf("%s_twitter_creator", refPrefix))).Attr("ref", fmt.Sprintf("%s_twitter_creator", refPrefix)),
+		VXField().Disabled(field.Disabled).Attr(web.VField(fmt.Sprintf("%s.%s", fieldPrefix, "TwitterTitle"), setting.TwitterTitle)...).Label(msgr.TwitterTitle).Attr("@focus", fmt.Sprintf("$refs.seo.tagInputsFocus($refs.%s)", fmt.Sprintf("%s_twitter_title", refPrefix))).Attr("ref", fmt.Sprintf("%s_twitter_title", refPrefix)),
+		VXField().Disabled(field.Disabled).Attr(web.VField(fmt.Sprintf("%s.%s", fieldPrefix, "TwitterDescription"), setting.TwitterDescription)...).Label(msgr.TwitterDescription).Attr("@focus", fmt.Sprintf("$refs.seo.tagInputsFocus($refs.%s)", fmt.Sprintf("%s_twitter_description", refPrefix))).Attr("ref", fmt.Sprintf("%s_twitter_description", refPrefix)),
+		VXField().Disabled(field.Disabled).Attr(web.VField(fmt.Sprintf("%s.%s", fieldPrefix, "TwitterImageURL"), setting.TwitterImageURL)...).Label(msgr.TwitterImageURL).Attr("@focus", fmt.Sprintf("$refs.seo.tagInputsFocus($refs.%s)", fmt.Sprintf("%s_twitter_imageurl", refPrefix))).Attr("ref", fmt.Sprintf("%s_twitter_imageurl", refPrefix)),
+
+		h.Div(
+			h.Span(msgr.StructuredData).Class("text-subtitle-1 px-2 py-1 rounded", "bg-"+ColorGreyLighten3),
+		).Class("mb-6 mt-6"),
+		VXField().Disabled(field.Disabled).Type("textarea").Attr(web.VField(fmt.Sprintf("%s.%s", fieldPrefix, "StructuredData"), setting.StructuredData)...).Label(msgr.StructuredDataJSONLD).Placeholder(seo.getFinalStructuredData()).Tips(msgr.StructuredDataTips).ErrorMessages(field.Errors...).Attr("@focus", fmt.Sprintf("$refs.seo.tagInputsFocus($refs.%s)", fmt.Sprintf("%s_structured_data", refPrefix))).Attr("ref", fmt.Sprintf("%s_structured_data", refPrefix)),
+
+		h.Div(
+			h.Span(msgr.Preview).Class("text-subtitle-1 px-2 py-1 rounded", "bg-"+ColorGreyLighten3),
+		).Class("mb-6 mt-6"),
+		web.Portal(b.socialPreviewCards(&preview, req)).Name(socialPreviewPortalName(fieldPrefix)),
+	).Attr("ref", "seo"))
 }
 
 func (b *Builder) vSeoReadonly(obj interface{}, fieldPrefix, locale string, seo *SEO, setting *Setting, req *http.Request) h.HTMLComponent {
@@ -362,7 +411,8 @@ func (b *Builder) vSeoReadonly(obj interface{}, fieldPrefix, locale string, seo
 	if image.ID.String() == "0" {
 		image.ID = json.Number("")
 	}
-	localeFinalSeoSetting := seo.getLocaleFinalQorSEOSetting(locale, b.db)
+	preview := b.previewSetting(obj, seo, locale, *setting, req)
+	localeFinalSeoSetting := seo.getLocaleFinalQorSEOSetting(locale, b.db, b.getLocaleFallbacks(locale)...)
 	variables := localeFinalSeoSetting.Variables
 	finalContextVars := seo.getFinalContextVars()
 	// execute function for context var
@@ -388,6 +438,12 @@ func (b *Builder) vSeoReadonly(obj interface{}, fieldPrefix, locale string, seo
 		seoFieldPortal(msgr.OpenGraphDescription, setting.OpenGraphDescription),
 		seoFieldPortal(msgr.OpenGraphURL, setting.OpenGraphURL),
 		seoFieldPortal(msgr.OpenGraphImageURL, setting.OpenGraphImageURL),
+		seoFieldPortal(msgr.TwitterCard, setting.TwitterCard),
+		seoFieldPortal(msgr.TwitterSite, setting.TwitterSite),
+		seoFieldPortal(msgr.TwitterCreator, setting.TwitterCreator),
+		seoFieldPortal(msgr.TwitterTitle, setting.TwitterTitle),
+		seoFieldPortal(msgr.TwitterDescription, setting.TwitterDescription),
+		seoFieldPortal(msgr.TwitterImageURL, setting.TwitterImageURL),
 		h.Div(
 			h.Span(msgr.OpenGraphImage).Class("text-subtitle-1 px-2 py-1 rounded", "bg-"+ColorGreyLighten3),
 		).Class("mt-10 mb-2"),
@@ -424,10 +480,26 @@ func (b *Builder) vSeoReadonly(obj interface{}, fieldPrefix, locale string, seo
 				GetOpenGraphMetadataString(setting.OpenGraphMetadata),
 			).Style("margin: 0; font-family: inherit;"),
 		).Class("mt-4 px-3"),
+		h.Div(
+			h.Span(msgr.StructuredData).Class("text-subtitle-1 px-2 py-1 rounded", "bg-"+ColorGreyLighten3),
+		).Class("mt-10"),
+		h.Div(
+			h.Pre(setting.StructuredData).Style("margin: 0; white-space: pre-wrap;"),
+		).Class("mt-4 px-3"),
+		h.Div(
+			h.Span(msgr.Preview).Class("text-subtitle-1 px-2 py-1 rounded", "bg-"+ColorGreyLighten3),
+		).Class("mt-10 mb-4"),
+		b.socialPreviewCards(&preview, req),
 	)
 }
 
 func (b *Builder) ModelInstall(pb *presets.Builder, mb *presets.ModelBuilder) error {
+	if seo := b.GetSEO(mb.NewModel()); seo != nil {
+		if b.seoModelBuilders == nil {
+			b.seoModelBuilders = make(map[string]*presets.ModelBuilder)
+		}
+		b.seoModelBuilders[seo.name] = mb
+	}
 	b.configDetailing(mb)
 	return nil
 }
@@ -441,6 +513,21 @@ func (b *Builder) configDetailing(mb *presets.ModelBuilder) {
 			SetterFunc(b.detailSaver).
 			ViewComponentFunc(b.detailShowComponent).
 			EditComponentFunc(b.EditingComponentFunc)
+		// the errors of the setter are not reported by the section
+		seoSection.WrapValidator(func(in presets.ValidateFunc) presets.ValidateFunc {
+			return func(obj interface{}, ctx *web.EventContext) (err web.ValidationErrors) {
+				if in != nil {
+					err = in(obj, ctx)
+				}
+				if v, gErr := reflectutils.Get(obj, SeoDetailFieldName); gErr == nil {
+					setting, _ := v.(Setting)
+					if vErr := validateStructuredDataSetting(&setting, ctx); vErr != nil {
+						err.GlobalError(vErr.Error())
+					}
+				}
+				return
+			}
+		})
 		pd.Section(seoSection)
 	}
 }
@@ -471,7 +558,12 @@ func (b *Builder) detailShowComponent(obj interface{}, _ *presets.FieldContext,
 		setting = modelSetting.Setting
 	}
 
+	var audit h.HTMLComponent
+	if b.auditEnabled {
+		audit = b.auditPanel(obj, seo, locale)
+	}
 	return h.Div(
+		audit,
 		b.vSeoReadonly(obj, fieldPrefix, locale, seo, &setting, ctx.R),
 	).Class("pb-4")
 }
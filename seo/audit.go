@@ -0,0 +1,138 @@
+package seo
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	AuditSeverityError   = "error"
+	AuditSeverityWarning = "warning"
+
+	AuditTitleMissing          = "title_missing"
+	AuditTitleTooShort         = "title_too_short"
+	AuditTitleTooLong          = "title_too_long"
+	AuditTitleDuplicated       = "title_duplicated"
+	AuditDescriptionMissing    = "description_missing"
+	AuditDescriptionTooShort   = "description_too_short"
+	AuditDescriptionTooLong    = "description_too_long"
+	AuditOpenGraphImageMissing = "og_image_missing"
+	AuditCanonicalMissing      = "canonical_missing"
+	AuditNoIndexOnline         = "noindex_online"
+	AuditH1Missing             = "h1_missing"
+	AuditImageAltMissing       = "image_alt_missing"
+
+	auditTitleMinLength       = 30
+	auditTitleMaxLength       = 60
+	auditDescriptionMinLength = 70
+	auditDescriptionMaxLength = 160
+)
+
+// AuditIssue is an issue found by Audit
+type AuditIssue struct {
+	Code     string
+	Severity string
+	// Value measures the issue, such as the length of the title or the number of images without alt text
+	Value int
+}
+
+// AuditInput is what Audit checks
+type AuditInput struct {
+	// Setting is the resolved setting of the object
+	Setting *Setting
+	// HTML is the rendered page, the page is not checked if it is empty
+	HTML string
+	// Online reports whether the object is published
+	Online bool
+}
+
+// Audit checks the SEO setting and the page of an object, the duplicated
+// titles are checked across the objects by the audit job.
+func Audit(in *AuditInput) (issues []*AuditIssue) {
+	add := func(code, severity string, value int) {
+		issues = append(issues, &AuditIssue{Code: code, Severity: severity, Value: value})
+	}
+	s := in.Setting
+
+	switch n := utf8.RuneCountInString(strings.TrimSpace(s.Title)); {
+	case n == 0:
+		add(AuditTitleMissing, AuditSeverityError, 0)
+	case n < auditTitleMinLength:
+		add(AuditTitleTooShort, AuditSeverityWarning, n)
+	case n > auditTitleMaxLength:
+		add(AuditTitleTooLong, AuditSeverityWarning, n)
+	}
+	switch n := utf8.RuneCountInString(strings.TrimSpace(s.Description)); {
+	case n == 0:
+		add(AuditDescriptionMissing, AuditSeverityError, 0)
+	case n < auditDescriptionMinLength:
+		add(AuditDescriptionTooShort, AuditSeverityWarning, n)
+	case n > auditDescriptionMaxLength:
+		add(AuditDescriptionTooLong, AuditSeverityWarning, n)
+	}
+	if s.OpenGraphImageURL == "" && s.OpenGraphImageFromMediaLibrary.Url == "" {
+		add(AuditOpenGraphImageMissing, AuditSeverityWarning, 0)
+	}
+	if s.CanonicalPath == "" {
+		add(AuditCanonicalMissing, AuditSeverityWarning, 0)
+	}
+	if s.NoIndex && in.Online {
+		add(AuditNoIndexOnline, AuditSeverityError, 0)
+	}
+
+	if in.HTML == "" {
+		return
+	}
+	h1s, imagesWithoutAlt := inspectPageHTML(in.HTML)
+	if h1s == 0 {
+		add(AuditH1Missing, AuditSeverityError, 0)
+	}
+	if imagesWithoutAlt > 0 {
+		add(AuditImageAltMissing, AuditSeverityWarning, imagesWithoutAlt)
+	}
+	return
+}
+
+// AuditScore scores the issues from 0 to 100
+func AuditScore(issues []*AuditIssue) int {
+	score := 100
+	for _, issue := range issues {
+		if issue.Severity == AuditSeverityError {
+			score -= 15
+		} else {
+			score -= 5
+		}
+	}
+	return max(score, 0)
+}
+
+// inspectPageHTML counts the h1 headings and the images without alt attribute,
+// an empty alt is fine for decorative images.
+func inspectPageHTML(content string) (h1s, imagesWithoutAlt int) {
+	z := html.NewTokenizer(strings.NewReader(content))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "h1":
+				h1s++
+			case "img":
+				hasAlt := false
+				for _, attr := range t.Attr {
+					if attr.Key == "alt" {
+						hasAlt = true
+						break
+					}
+				}
+				if !hasAlt {
+					imagesWithoutAlt++
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,446 @@
+package seo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/i18n"
+	. "github.com/qor5/x/v3/ui/vuetify"
+	h "github.com/theplant/htmlgo"
+	"github.com/theplant/relay"
+	"gorm.io/gorm"
+
+	"github.com/qor5/admin/v3/l10n"
+	"github.com/qor5/admin/v3/presets"
+	"github.com/qor5/admin/v3/publish"
+	"github.com/qor5/admin/v3/worker"
+)
+
+const (
+	AuditJobName = "SEOAudit"
+
+	auditPanelEvent = "seo_AuditPanelEvent"
+	paramAuditRun   = "seoAuditRun"
+)
+
+// QorSEOIssue is a row of the report produced by the audit job,
+// one for every issue found in the objects with SEO.
+type QorSEOIssue struct {
+	gorm.Model
+	SEOName     string `gorm:"index"`
+	ObjectSlug  string
+	ObjectTitle string
+	LocaleCode  string
+	Score       int
+	Code        string
+	Severity    string
+	Value       int
+}
+
+// PageHTMLFunc renders the page of obj for the audit, empty if it does not render obj
+type PageHTMLFunc func(ctx context.Context, obj interface{}) (string, error)
+
+// PageHTMLFuncs adds the renderers of the pages checked by the audit, such as the page builder
+func (b *Builder) PageHTMLFuncs(vs ...PageHTMLFunc) *Builder {
+	b.pageHTMLFuncs = append(b.pageHTMLFuncs, vs...)
+	return b
+}
+
+func (b *Builder) pageHTML(ctx context.Context, obj interface{}) (r string, err error) {
+	for _, f := range b.pageHTMLFuncs {
+		if r, err = f(ctx, obj); err != nil || r != "" {
+			return
+		}
+	}
+	return
+}
+
+// AuditJob registers the job that audits the objects with SEO into the SEO issues report,
+// it enables the report and the audit panel in the SEO section of the detail pages.
+func (b *Builder) AuditJob(w *worker.Builder) *worker.JobBuilder {
+	if !b.auditEnabled {
+		b.auditEnabled = true
+		// the report is installed with the builder if it is not installed yet
+		if b.mb != nil {
+			b.installAuditReport(b.mb.GetPresetsBuilder())
+		}
+	}
+	return w.NewJob(AuditJobName).Handler(b.runAudit)
+}
+
+type auditedObject struct {
+	seo    *SEO
+	obj    interface{}
+	slug   string
+	locale string
+	title  string
+	issues []*AuditIssue
+}
+
+func objectSlug(obj interface{}) string {
+	if s, ok := obj.(presets.SlugEncoder); ok {
+		return s.PrimarySlug()
+	}
+	if v := reflect.Indirect(reflect.ValueOf(obj)).FieldByName("ID"); v.IsValid() {
+		return fmt.Sprint(v.Interface())
+	}
+	return ""
+}
+
+func objectLocale(obj interface{}) string {
+	if l, ok := obj.(l10n.LocaleInterface); ok {
+		return l.EmbedLocale().LocaleCode
+	}
+	return ""
+}
+
+func objectIsOnline(obj interface{}) bool {
+	s, ok := obj.(publish.StatusInterface)
+	return ok && s.EmbedStatus().Status == publish.StatusOnline
+}
+
+// auditObject audits obj with its resolved setting, defaultSetting is the final setting of its SEO in its locale
+func (b *Builder) auditObject(ctx context.Context, req *http.Request, seo *SEO, obj interface{}, defaultSetting *QorSEOSetting) (setting Setting, issues []*AuditIssue, err error) {
+	setting = b.resolveSetting(obj, defaultSetting, seo, req)
+	content, err := b.pageHTML(ctx, obj)
+	if err != nil {
+		return
+	}
+	issues = Audit(&AuditInput{Setting: &setting, HTML: content, Online: objectIsOnline(obj)})
+	return
+}
+
+func (b *Builder) runAudit(ctx context.Context, job worker.QorJobInterface) (err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/", http.NoBody)
+	if err != nil {
+		return
+	}
+	type registered struct {
+		seo       *SEO
+		modelType reflect.Type
+	}
+	var models []*registered
+	for key, seo := range b.registeredSEO {
+		if t, ok := key.(reflect.Type); ok {
+			models = append(models, &registered{seo: seo, modelType: t})
+		}
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].seo.name < models[j].seo.name })
+
+	var objects []*auditedObject
+	for i, m := range models {
+		seo, modelType := m.seo, m.modelType
+		records := reflect.New(reflect.SliceOf(reflect.PointerTo(modelType)))
+		db := b.db.WithContext(ctx)
+		// the drafts and the old versions would be reported as duplicated titles
+		if _, ok := reflect.New(modelType).Interface().(publish.StatusInterface); ok {
+			db = db.Where("status = ?", publish.StatusOnline)
+		}
+		if err = db.Find(records.Interface()).Error; err != nil {
+			return
+		}
+		finalSettings := seo.getFinalQorSEOSetting(b.db)
+		records = records.Elem()
+		for j := 0; j < records.Len(); j++ {
+			obj := records.Index(j).Interface()
+			o := &auditedObject{seo: seo, obj: obj, slug: objectSlug(obj), locale: objectLocale(obj)}
+			if o.locale == "" && len(b.locales) == 1 {
+				o.locale = b.locales[0]
+			}
+			defaultSetting := finalSettings[o.locale]
+			if defaultSetting == nil {
+				continue
+			}
+			var setting Setting
+			if setting, o.issues, err = b.auditObject(ctx, req, seo, obj, defaultSetting); err != nil {
+				return
+			}
+			o.title = setting.Title
+			objects = append(objects, o)
+		}
+		if err = job.SetProgress(uint((i + 1) * 90 / len(models))); err != nil {
+			return
+		}
+	}
+	markDuplicatedTitles(objects)
+
+	var results []*QorSEOIssue
+	for _, o := range objects {
+		score := AuditScore(o.issues)
+		title := o.title
+		if title == "" {
+			title = o.slug
+		}
+		for _, issue := range o.issues {
+			results = append(results, &QorSEOIssue{
+				SEOName:     o.seo.name,
+				ObjectSlug:  o.slug,
+				ObjectTitle: title,
+				LocaleCode:  o.locale,
+				Score:       score,
+				Code:        issue.Code,
+				Severity:    issue.Severity,
+				Value:       issue.Value,
+			})
+		}
+	}
+	err = b.db.Transaction(func(tx *gorm.DB) (dbErr error) {
+		if dbErr = tx.Unscoped().Where("1 = 1").Delete(&QorSEOIssue{}).Error; dbErr != nil {
+			return
+		}
+		if len(results) == 0 {
+			return
+		}
+		return tx.CreateInBatches(results, 100).Error
+	})
+	if err != nil {
+		return
+	}
+	return errors.Join(
+		job.AddLogf("audited %d objects, found %d issues", len(objects), len(results)),
+		job.SetProgress(100),
+	)
+}
+
+// markDuplicatedTitles adds the duplicated title issue to the objects sharing a title in a locale
+func markDuplicatedTitles(objects []*auditedObject) {
+	groups := map[string][]*auditedObject{}
+	for _, o := range objects {
+		if title := strings.TrimSpace(o.title); title != "" {
+			key := o.locale + "\x00" + strings.ToLower(title)
+			groups[key] = append(groups[key], o)
+		}
+	}
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		for _, o := range group {
+			o.issues = append(o.issues, &AuditIssue{Code: AuditTitleDuplicated, Severity: AuditSeverityWarning, Value: len(group)})
+		}
+	}
+}
+
+func auditIssueMessage(msgr *Messages, code string, value int) string {
+	switch code {
+	case AuditTitleMissing:
+		return msgr.AuditTitleMissing
+	case AuditTitleTooShort:
+		return msgr.AuditTitleTooShort(value, auditTitleMinLength)
+	case AuditTitleTooLong:
+		return msgr.AuditTitleTooLong(value, auditTitleMaxLength)
+	case AuditTitleDuplicated:
+		return msgr.AuditTitleDuplicated(value)
+	case AuditDescriptionMissing:
+		return msgr.AuditDescriptionMissing
+	case AuditDescriptionTooShort:
+		return msgr.AuditDescriptionTooShort(value, auditDescriptionMinLength)
+	case AuditDescriptionTooLong:
+		return msgr.AuditDescriptionTooLong(value, auditDescriptionMaxLength)
+	case AuditOpenGraphImageMissing:
+		return msgr.AuditOpenGraphImageMissing
+	case AuditCanonicalMissing:
+		return msgr.AuditCanonicalMissing
+	case AuditNoIndexOnline:
+		return msgr.AuditNoIndexOnline
+	case AuditH1Missing:
+		return msgr.AuditH1Missing
+	case AuditImageAltMissing:
+		return msgr.AuditImageAltMissing(value)
+	}
+	return code
+}
+
+func auditScoreColor(score int) string {
+	switch {
+	case score >= 80:
+		return ColorSuccess
+	case score >= 50:
+		return ColorWarning
+	}
+	return ColorError
+}
+
+func auditSeverityIcon(severity string) h.HTMLComponent {
+	if severity == AuditSeverityError {
+		return VIcon("mdi-alert-circle").Color(ColorError).Size(SizeSmall)
+	}
+	return VIcon("mdi-alert").Color(ColorWarning).Size(SizeSmall)
+}
+
+func auditPanelPortalName(seo *SEO, slug string) string {
+	return fmt.Sprintf("seo_audit_panel_%s_%s", strings.ReplaceAll(strings.ToLower(seo.name), " ", "_"), slug)
+}
+
+// auditPanel shows the score and the issues of obj in the SEO section of its detail page, it is loaded
+// by auditPanelEvent since the audit of the current content renders the page.
+func (b *Builder) auditPanel(obj interface{}, seo *SEO, locale string) h.HTMLComponent {
+	slug := objectSlug(obj)
+	return web.Portal().Name(auditPanelPortalName(seo, slug)).Loader(
+		web.Plaid().URL(b.mb.Info().ListingHref()).
+			EventFunc(auditPanelEvent).
+			Query(paramPreviewSEOName, seo.name).
+			Query(paramPreviewObjectID, slug).
+			Query(paramPreviewLocale, locale),
+	)
+}
+
+// auditPanelEvent renders the result of the last audit job, or audits the current content of the object
+// when paramAuditRun is set.
+func (b *Builder) auditPanelEvent(ctx *web.EventContext) (r web.EventResponse, err error) {
+	var (
+		msgr   = i18n.MustGetModuleMessages(ctx.R, I18nSeoKey, Messages_en_US).(*Messages)
+		slug   = ctx.R.FormValue(paramPreviewObjectID)
+		locale = ctx.R.FormValue(paramPreviewLocale)
+		run    = ctx.R.FormValue(paramAuditRun) != ""
+		seo    = b.GetSEO(ctx.R.FormValue(paramPreviewSEOName))
+	)
+	if seo == nil || slug == "" {
+		return
+	}
+	if locale == "" && len(b.locales) == 1 {
+		locale = b.locales[0]
+	}
+
+	var (
+		issues    []*AuditIssue
+		checkedAt string
+	)
+	if run {
+		mb := b.seoModelBuilders[seo.name]
+		defaultSetting := seo.getFinalQorSEOSetting(b.db)[locale]
+		if mb == nil || defaultSetting == nil {
+			return
+		}
+		var obj interface{}
+		if obj, err = mb.Editing().Fetcher(mb.NewModel(), slug, ctx); err != nil {
+			return
+		}
+		if _, issues, err = b.auditObject(ctx.R.Context(), ctx.R, seo, obj, defaultSetting); err != nil {
+			return
+		}
+		// the duplicated titles are only known by the audit job
+		var duplicated QorSEOIssue
+		if err = b.db.Where("seo_name = ? AND object_slug = ? AND locale_code = ? AND code = ?",
+			seo.name, slug, locale, AuditTitleDuplicated).First(&duplicated).Error; err == nil {
+			issues = append(issues, &AuditIssue{Code: duplicated.Code, Severity: duplicated.Severity, Value: duplicated.Value})
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return
+		}
+		err = nil
+	} else {
+		var stored []*QorSEOIssue
+		if err = b.db.Where("seo_name = ? AND object_slug = ? AND locale_code = ?", seo.name, slug, locale).
+			Order("id").Find(&stored).Error; err != nil {
+			return
+		}
+		for _, issue := range stored {
+			issues = append(issues, &AuditIssue{Code: issue.Code, Severity: issue.Severity, Value: issue.Value})
+			checkedAt = issue.UpdatedAt.Local().Format("2006-01-02 15:04:05")
+		}
+	}
+	score := AuditScore(issues)
+
+	var items []h.HTMLComponent
+	for _, issue := range issues {
+		items = append(items, h.Div(
+			auditSeverityIcon(issue.Severity),
+			h.Span(auditIssueMessage(msgr, issue.Code, issue.Value)).Class("ml-2"),
+		).Class("d-flex align-center py-1"))
+	}
+	if len(items) == 0 {
+		items = append(items, h.Div(h.Text(msgr.AuditNoIssues)).Class("text-medium-emphasis py-1"))
+	}
+	body := h.Div(
+		h.Div(
+			h.Span(msgr.AuditScore).Class("text-subtitle-1 px-2 py-1 rounded", "bg-"+ColorGreyLighten3),
+			VChip(h.Text(fmt.Sprint(score))).Color(auditScoreColor(score)).Size(SizeSmall).Class("ml-2"),
+			h.If(checkedAt != "",
+				h.Span(fmt.Sprintf("%s: %s", msgr.AuditCheckedAt, checkedAt)).Class("text-caption text-medium-emphasis ml-2"),
+			),
+			VSpacer(),
+			VBtn(msgr.AuditNow).Size(SizeSmall).Variant(VariantTonal).Attr("@click",
+				web.Plaid().URL(b.mb.Info().ListingHref()).
+					EventFunc(auditPanelEvent).
+					Query(paramPreviewSEOName, seo.name).
+					Query(paramPreviewObjectID, slug).
+					Query(paramPreviewLocale, locale).
+					Query(paramAuditRun, true).
+					Go()),
+		).Class("d-flex align-center"),
+		h.Div(items...).Class("mt-2 px-2"),
+	).Class("mb-8")
+	if run {
+		r.UpdatePortals = append(r.UpdatePortals, &web.PortalUpdate{Name: auditPanelPortalName(seo, slug), Body: body})
+		return
+	}
+	r.Body = body
+	return
+}
+
+func (b *Builder) installAuditReport(pb *presets.Builder) {
+	b.mb.RegisterEventFunc(auditPanelEvent, b.auditPanelEvent)
+	pm := pb.Model(&QorSEOIssue{}).URIName("qor-seo-issues").Label("SEO Issues").MenuIcon("mdi-clipboard-alert-outline")
+	pm.LabelName(func(evCtx *web.EventContext, singular bool) string {
+		msgr := i18n.MustGetModuleMessages(evCtx.R, I18nSeoKey, Messages_en_US).(*Messages)
+		if singular {
+			return msgr.SEOIssue
+		}
+		return msgr.SEOIssues
+	})
+
+	lb := pm.Listing("ObjectTitle", "SEOName", "LocaleCode", "Severity", "Code", "Score", "UpdatedAt").
+		SearchColumns("object_title").
+		DefaultOrderBy(
+			relay.Order{Field: "Score", Direction: relay.OrderDirectionAsc},
+			relay.Order{Field: "ObjectTitle", Direction: relay.OrderDirectionAsc},
+		)
+	lb.WrapColumns(presets.CustomizeColumnLabel(func(evCtx *web.EventContext) (map[string]string, error) {
+		msgr := i18n.MustGetModuleMessages(evCtx.R, I18nSeoKey, Messages_en_US).(*Messages)
+		return map[string]string{
+			"ObjectTitle": msgr.AuditObject,
+			"SEOName":     msgr.Seo,
+			"LocaleCode":  msgr.AuditLocale,
+			"Severity":    msgr.AuditSeverity,
+			"Code":        msgr.AuditIssue,
+			"Score":       msgr.AuditScore,
+			"UpdatedAt":   msgr.AuditCheckedAt,
+		}, nil
+	}))
+	lb.NewButtonFunc(func(ctx *web.EventContext) h.HTMLComponent { return nil })
+	lb.RowMenu().Empty()
+	lb.Field("Severity").ComponentFunc(func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) h.HTMLComponent {
+		msgr := i18n.MustGetModuleMessages(ctx.R, I18nSeoKey, Messages_en_US).(*Messages)
+		issue := obj.(*QorSEOIssue)
+		label := msgr.AuditSeverityWarning
+		if issue.Severity == AuditSeverityError {
+			label = msgr.AuditSeverityError
+		}
+		return h.Td(h.Div(auditSeverityIcon(issue.Severity), h.Span(label).Class("ml-1")).Class("d-flex align-center"))
+	})
+	lb.Field("Code").ComponentFunc(func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) h.HTMLComponent {
+		msgr := i18n.MustGetModuleMessages(ctx.R, I18nSeoKey, Messages_en_US).(*Messages)
+		issue := obj.(*QorSEOIssue)
+		return h.Td(h.Text(auditIssueMessage(msgr, issue.Code, issue.Value)))
+	})
+	lb.Field("Score").ComponentFunc(func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) h.HTMLComponent {
+		score := obj.(*QorSEOIssue).Score
+		return h.Td(VChip(h.Text(fmt.Sprint(score))).Color(auditScoreColor(score)).Size(SizeSmall))
+	})
+	lb.Field("UpdatedAt").ComponentFunc(func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) h.HTMLComponent {
+		return h.Td(h.Text(obj.(*QorSEOIssue).UpdatedAt.Format("2006-01-02 15:04:05")))
+	})
+	lb.CellWrapperFunc(func(cell h.MutableAttrHTMLComponent, id string, obj interface{}, dataTableID string) h.HTMLComponent {
+		issue := obj.(*QorSEOIssue)
+		if mb := b.seoModelBuilders[issue.SEOName]; mb != nil && issue.ObjectSlug != "" {
+			cell.SetAttr("@click", web.Plaid().URL(mb.Info().DetailingHref(issue.ObjectSlug)).PushState(true).Go())
+		}
+		return cell
+	})
+}
@@ -0,0 +1,182 @@
+package seo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/qor5/web/v3"
+	h "github.com/theplant/htmlgo"
+	"github.com/theplant/testingutils"
+
+	"github.com/qor5/admin/v3/presets"
+	"github.com/qor5/admin/v3/presets/gorm2op"
+)
+
+func TestAudit(t *testing.T) {
+	cases := []struct {
+		name     string
+		in       *AuditInput
+		expected []*AuditIssue
+	}{
+		{
+			name: "empty setting",
+			in:   &AuditInput{Setting: &Setting{}},
+			expected: []*AuditIssue{
+				{Code: AuditTitleMissing, Severity: AuditSeverityError},
+				{Code: AuditDescriptionMissing, Severity: AuditSeverityError},
+				{Code: AuditOpenGraphImageMissing, Severity: AuditSeverityWarning},
+				{Code: AuditCanonicalMissing, Severity: AuditSeverityWarning},
+			},
+		},
+		{
+			name: "short title, long description and noindex online",
+			in: &AuditInput{
+				Setting: &Setting{
+					Title:             "Short",
+					Description:       strings.Repeat("d", 161),
+					OpenGraphImageURL: "https://example.com/og.png",
+					CanonicalPath:     "/about",
+					NoIndex:           true,
+				},
+				Online: true,
+			},
+			expected: []*AuditIssue{
+				{Code: AuditTitleTooShort, Severity: AuditSeverityWarning, Value: 5},
+				{Code: AuditDescriptionTooLong, Severity: AuditSeverityWarning, Value: 161},
+				{Code: AuditNoIndexOnline, Severity: AuditSeverityError},
+			},
+		},
+		{
+			name: "page html",
+			in: &AuditInput{
+				Setting: &Setting{
+					Title:             "About the company and its products",
+					Description:       strings.Repeat("描", 80),
+					OpenGraphImageURL: "https://example.com/og.png",
+					CanonicalPath:     "/about",
+					NoIndex:           true,
+				},
+				HTML: `<h2>About</h2><img src="a.png"><img src="b.png" alt=""><img src="c.png"/>`,
+			},
+			expected: []*AuditIssue{
+				{Code: AuditH1Missing, Severity: AuditSeverityError},
+				{Code: AuditImageAltMissing, Severity: AuditSeverityWarning, Value: 2},
+			},
+		},
+	}
+	for _, c := range cases {
+		if diff := testingutils.PrettyJsonDiff(c.expected, Audit(c.in)); diff != "" {
+			t.Errorf("%s: %s", c.name, diff)
+		}
+	}
+}
+
+func TestAuditScore(t *testing.T) {
+	if score := AuditScore(nil); score != 100 {
+		t.Errorf("unexpected score %d", score)
+	}
+	issues := []*AuditIssue{{Severity: AuditSeverityError}, {Severity: AuditSeverityWarning}}
+	if score := AuditScore(issues); score != 80 {
+		t.Errorf("unexpected score %d", score)
+	}
+	for i := 0; i < 5; i++ {
+		issues = append(issues, issues...)
+	}
+	if score := AuditScore(issues); score != 0 {
+		t.Errorf("unexpected score %d", score)
+	}
+}
+
+func TestMarkDuplicatedTitles(t *testing.T) {
+	objects := []*auditedObject{
+		{slug: "1", locale: "Japan", title: "About"},
+		{slug: "2", locale: "Japan", title: "about "},
+		{slug: "3", locale: "China", title: "About"},
+		{slug: "4", locale: "Japan"},
+		{slug: "5", locale: "Japan"},
+	}
+	markDuplicatedTitles(objects)
+	for i, o := range objects {
+		duplicated := len(o.issues) > 0
+		if duplicated != (i < 2) {
+			t.Errorf("object %s duplicated %v", o.slug, duplicated)
+		}
+	}
+	if v := objects[0].issues[0].Value; v != 2 {
+		t.Errorf("unexpected count %d", v)
+	}
+}
+
+type auditTestPost struct {
+	ID  uint
+	SEO Setting
+}
+
+func TestAuditPanelEvent(t *testing.T) {
+	if err := dbForTest.AutoMigrate(&auditTestPost{}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := dbForTest.Migrator().DropTable(&auditTestPost{}); err != nil {
+			t.Errorf("drop posts table: %v", err)
+		}
+	}()
+	dbForTest.Exec("DELETE FROM qor_seo_settings")
+	dbForTest.Exec("DELETE FROM qor_seo_issues")
+	if err := dbForTest.Create(&auditTestPost{ID: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	renderErr := errors.New("render failed")
+	var rendered int
+	b := New(dbForTest, WithLocales("en")).AutoMigrate().PageHTMLFuncs(func(context.Context, interface{}) (string, error) {
+		rendered++
+		return "", renderErr
+	})
+	b.RegisterSEO("Post", &auditTestPost{})
+	b.auditEnabled = true
+	pb := presets.New().DataOperator(gorm2op.DataOperator(dbForTest))
+	mb := pb.Model(&auditTestPost{})
+	if err := b.Install(pb); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.ModelInstall(pb, mb); err != nil {
+		t.Fatal(err)
+	}
+	if err := dbForTest.Create(&QorSEOIssue{SEOName: "Post", ObjectSlug: "1", LocaleCode: "en", Code: AuditH1Missing, Severity: AuditSeverityError}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	event := func(run bool) (web.EventResponse, error) {
+		t.Helper()
+		q := url.Values{paramPreviewSEOName: {"Post"}, paramPreviewObjectID: {"1"}, paramPreviewLocale: {"en"}}
+		if run {
+			q.Set(paramAuditRun, "true")
+		}
+		req := httptest.NewRequest(http.MethodPost, "/?"+q.Encode(), http.NoBody)
+		return b.auditPanelEvent(&web.EventContext{R: req, W: httptest.NewRecorder()})
+	}
+
+	r, err := event(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := h.MustString(r.Body, context.Background()); !strings.Contains(body, Messages_en_US.AuditH1Missing) {
+		t.Errorf("expect the issues of the last audit, got %s", body)
+	}
+	if rendered != 0 {
+		t.Errorf("expect the page not rendered to show the last audit, rendered %d times", rendered)
+	}
+
+	if _, err = event(true); !errors.Is(err, renderErr) {
+		t.Errorf("expect the error of the audit returned, got %v", err)
+	}
+	if rendered != 1 {
+		t.Errorf("expect the page rendered by the audit, rendered %d times", rendered)
+	}
+}
@@ -9,10 +9,12 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/qor5/admin/v3/l10n"
 	"github.com/qor5/admin/v3/presets"
 
+	"github.com/qor5/x/v3/oss"
 	h "github.com/theplant/htmlgo"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -39,6 +41,14 @@ func WithLocales(locales ...string) Option {
 	}
 }
 
+// WithLocaleFallbacks sets the fallback locales of a locale, the settings missing in a locale are resolved from them,
+// such as l10n.Builder.GetLocaleFallbacks.
+func WithLocaleFallbacks(v func(locale string) []string) Option {
+	return func(b *Builder) {
+		b.localeFallbacks = v
+	}
+}
+
 func WithGlobalSEOName(name string) Option {
 	return func(b *Builder) {
 		name = strings.TrimSpace(name)
@@ -74,16 +84,38 @@ type Builder struct {
 	// key == val.Name
 	registeredSEO map[interface{}]*SEO
 
-	locales   []string
-	db        *gorm.DB
-	seoRoot   *SEO
-	inherited bool
-	afterSave func(ctx context.Context, settingName string, locale string) error // hook called after saving
-	mb        *presets.ModelBuilder
+	locales         []string
+	localeFallbacks func(locale string) []string
+	db              *gorm.DB
+	seoRoot         *SEO
+	inherited       bool
+	afterSave       func(ctx context.Context, settingName string, locale string) error // hook called after saving
+	mb              *presets.ModelBuilder
+
+	sitemapStorage   oss.StorageInterface
+	sitemapBaseURL   string
+	sitemapProviders []SitemapProvider
+	hreflangs        map[string]string
+
+	// the sitemaps regenerated in the background after publishing, requests during a run are coalesced
+	sitemapMu         sync.Mutex
+	sitemapGenerating bool
+	sitemapPending    bool
+
+	pageHTMLFuncs    []PageHTMLFunc
+	auditEnabled     bool
+	seoModelBuilders map[string]*presets.ModelBuilder
 }
 
 // @snippet_end
 
+func (b *Builder) getLocaleFallbacks(locale string) []string {
+	if b.localeFallbacks == nil || locale == "" {
+		return nil
+	}
+	return b.localeFallbacks(locale)
+}
+
 // RegisterSEO registers a SEO through name or model.
 // There are two types of SEOs, one is SEO with model, the other is SEO without model aka 'non-model seo'.
 // if you want to register a non-model SEO, you can call RegisterSEO method like this:
@@ -314,7 +346,7 @@ func (b *Builder) Render(obj interface{}, req *http.Request) h.HTMLComponent {
 	if locale == "" && len(b.locales) == 1 {
 		locale = b.locales[0]
 	}
-	localeFinalSeoSetting := seo.getLocaleFinalQorSEOSetting(locale, b.db)
+	localeFinalSeoSetting := seo.getLocaleFinalQorSEOSetting(locale, b.db, b.getLocaleFallbacks(locale)...)
 	return b.render(obj, localeFinalSeoSetting, seo, req)
 }
 
@@ -353,6 +385,7 @@ func (b *Builder) BatchRender(objs interface{}, req *http.Request) []h.HTMLCompo
 	}
 
 	finalSeoSettings := seo.getFinalQorSEOSetting(b.db)
+	resolved := make(map[string]bool)
 	comps := make([]h.HTMLComponent, 0, v.Len())
 	for i := 0; i < v.Len(); i++ {
 		objV := reflect.Indirect(v.Index(i))
@@ -378,6 +411,11 @@ func (b *Builder) BatchRender(objs interface{}, req *http.Request) []h.HTMLCompo
 			locale = b.locales[0]
 		}
 
+		if fallbacks := b.getLocaleFallbacks(locale); len(fallbacks) > 0 && !resolved[locale] {
+			// the settings of the locales with fallbacks are resolved along their chains
+			finalSeoSettings[locale] = seo.getLocaleFinalQorSEOSetting(locale, b.db, fallbacks...)
+			resolved[locale] = true
+		}
 		defaultSetting := finalSeoSettings[locale]
 		if defaultSetting == nil {
 			panic(fmt.Sprintf("There are no available seo configuration for %v locale", locale))
@@ -459,7 +497,7 @@ func (b *Builder) RenderCanonical(obj interface{}, req *http.Request) h.HTMLComp
 	if locale == "" && len(b.locales) == 1 {
 		locale = b.locales[0]
 	}
-	localeFinalSeoSetting := seo.getLocaleFinalQorSEOSetting(locale, b.db)
+	localeFinalSeoSetting := seo.getLocaleFinalQorSEOSetting(locale, b.db, b.getLocaleFallbacks(locale)...)
 	setting := b.resolveSetting(obj, localeFinalSeoSetting, seo, req)
 	if setting.CanonicalPath == "" {
 		return nil
@@ -470,13 +508,16 @@ func (b *Builder) RenderCanonical(obj interface{}, req *http.Request) h.HTMLComp
 var regex = regexp.MustCompile("{{([a-zA-Z0-9]*)}}")
 
 func replaceVariables(setting Setting, values map[string]string) Setting {
-	replace := func(str string) string {
+	replaceWith := func(str string, values map[string]string, escape func(string) string) string {
 		matches := regex.FindAllStringSubmatch(str, -1)
 		for _, match := range matches {
-			str = strings.Replace(str, match[0], values[match[1]], 1)
+			str = strings.Replace(str, match[0], escape(values[match[1]]), 1)
 		}
 		return str
 	}
+	replace := func(str string) string {
+		return replaceWith(str, values, func(v string) string { return v })
+	}
 
 	setting.Title = replace(setting.Title)
 	setting.Description = replace(setting.Description)
@@ -487,6 +528,11 @@ func replaceVariables(setting Setting, values map[string]string) Setting {
 	setting.OpenGraphURL = replace(setting.OpenGraphURL)
 	setting.OpenGraphType = replace(setting.OpenGraphType)
 	setting.OpenGraphImageURL = replace(setting.OpenGraphImageURL)
+	setting.TwitterSite = replace(setting.TwitterSite)
+	setting.TwitterCreator = replace(setting.TwitterCreator)
+	setting.TwitterTitle = replace(setting.TwitterTitle)
+	setting.TwitterDescription = replace(setting.TwitterDescription)
+	setting.TwitterImageURL = replace(setting.TwitterImageURL)
 	var metadata []OpenGraphMetadata
 	for _, m := range setting.OpenGraphMetadata {
 		metadata = append(metadata, OpenGraphMetadata{
@@ -495,6 +541,8 @@ func replaceVariables(setting Setting, values map[string]string) Setting {
 		})
 	}
 	setting.OpenGraphMetadata = metadata
+	// the values are substituted into the JSON strings of the structured data
+	setting.StructuredData = replaceWith(setting.StructuredData, structuredDataValues(&setting, values), jsonEscape)
 	return setting
 }
 
@@ -537,7 +585,7 @@ func (b *Builder) AutoMigrate() (r *Builder) {
 
 // Deprecated: use Migrate instead.
 func AutoMigrate(b *Builder, db *gorm.DB) (err error) {
-	if err = db.AutoMigrate(&QorSEOSetting{}); err != nil {
+	if err = db.AutoMigrate(&QorSEOSetting{}, &QorSEORobots{}, &QorSEOIssue{}); err != nil {
 		panic(err)
 	}
 	// NOTE: do not replace b.seoRoot.name with defaultGlobalSEOName.
@@ -549,5 +597,5 @@ func AutoMigrate(b *Builder, db *gorm.DB) (err error) {
 }
 
 func Migrate(db *gorm.DB) error {
-	return db.AutoMigrate(&QorSEOSetting{})
+	return db.AutoMigrate(&QorSEOSetting{}, &QorSEORobots{}, &QorSEOIssue{})
 }
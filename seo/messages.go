@@ -1,5 +1,7 @@
 package seo
 
+import "fmt"
+
 type Messages struct {
 	Variable                      string
 	VariableDescription           string
@@ -21,6 +23,47 @@ type Messages struct {
 	Seo                           string
 	Customize                     string
 	BlankOpenGraphInformationTips string
+	RobotsContent                 string
+	RobotsContentHint             string
+	StructuredData                string
+	StructuredDataJSONLD          string
+	StructuredDataTips            string
+	InvalidStructuredData         func(reason string) string
+	SEOIssue                      string
+	SEOIssues                     string
+	AuditObject                   string
+	AuditLocale                   string
+	AuditSeverity                 string
+	AuditIssue                    string
+	AuditScore                    string
+	AuditCheckedAt                string
+	AuditNoIssues                 string
+	AuditNow                      string
+	AuditSeverityError            string
+	AuditSeverityWarning          string
+	AuditTitleMissing             string
+	AuditTitleTooShort            func(length, min int) string
+	AuditTitleTooLong             func(length, max int) string
+	AuditTitleDuplicated          func(count int) string
+	AuditDescriptionMissing       string
+	AuditDescriptionTooShort      func(length, min int) string
+	AuditDescriptionTooLong       func(length, max int) string
+	AuditOpenGraphImageMissing    string
+	AuditCanonicalMissing         string
+	AuditNoIndexOnline            string
+	AuditH1Missing                string
+	AuditImageAltMissing          func(count int) string
+	TwitterCardInformation        string
+	TwitterCard                   string
+	TwitterSite                   string
+	TwitterCreator                string
+	TwitterTitle                  string
+	TwitterDescription            string
+	TwitterImageURL               string
+	Preview                       string
+	PreviewSearchResult           string
+	PreviewOpenGraph              string
+	PreviewTwitter                string
 }
 
 var Messages_en_US = &Messages{
@@ -43,6 +86,61 @@ var Messages_en_US = &Messages{
 	Seo:                           "SEO",
 	Customize:                     "Customize",
 	BlankOpenGraphInformationTips: `The "Open Graph Information" are blank. The default values will be used on the page.`,
+	RobotsContent:                 "Content",
+	RobotsContentHint:             "The sitemap index is added unless there is a Sitemap line.",
+	StructuredData:                "Structured Data",
+	StructuredDataJSONLD:          "JSON-LD",
+	StructuredDataTips:            "schema.org markup rendered as JSON-LD, {{Title}}, {{Description}}, {{ImageURL}} and {{URL}} are the values of the setting",
+	InvalidStructuredData: func(reason string) string {
+		return fmt.Sprintf("Invalid structured data: %s", reason)
+	},
+	SEOIssue:             "SEO Issue",
+	SEOIssues:            "SEO Issues",
+	AuditObject:          "Object",
+	AuditLocale:          "Locale",
+	AuditSeverity:        "Severity",
+	AuditIssue:           "Issue",
+	AuditScore:           "SEO Score",
+	AuditCheckedAt:       "Checked At",
+	AuditNoIssues:        "No issues found",
+	AuditNow:             "Audit Now",
+	AuditSeverityError:   "Error",
+	AuditSeverityWarning: "Warning",
+	AuditTitleMissing:    "The title is missing",
+	AuditTitleTooShort: func(length, min int) string {
+		return fmt.Sprintf("The title has %d characters, it should have at least %d", length, min)
+	},
+	AuditTitleTooLong: func(length, max int) string {
+		return fmt.Sprintf("The title has %d characters, it should have at most %d", length, max)
+	},
+	AuditTitleDuplicated: func(count int) string {
+		return fmt.Sprintf("The title is shared by %d pages", count)
+	},
+	AuditDescriptionMissing: "The description is missing",
+	AuditDescriptionTooShort: func(length, min int) string {
+		return fmt.Sprintf("The description has %d characters, it should have at least %d", length, min)
+	},
+	AuditDescriptionTooLong: func(length, max int) string {
+		return fmt.Sprintf("The description has %d characters, it should have at most %d", length, max)
+	},
+	AuditOpenGraphImageMissing: "The open graph image is missing",
+	AuditCanonicalMissing:      "The canonical URL is missing",
+	AuditNoIndexOnline:         "The page is published with noindex",
+	AuditH1Missing:             "The page has no H1 heading",
+	AuditImageAltMissing: func(count int) string {
+		return fmt.Sprintf("%d images have no alt text", count)
+	},
+	TwitterCardInformation: "X (Twitter) Card",
+	TwitterCard:            "Card Type",
+	TwitterSite:            "Site Account",
+	TwitterCreator:         "Creator Account",
+	TwitterTitle:           "X (Twitter) Title",
+	TwitterDescription:     "X (Twitter) Description",
+	TwitterImageURL:        "X (Twitter) Image URL",
+	Preview:                "Preview",
+	PreviewSearchResult:    "Google Search",
+	PreviewOpenGraph:       "Facebook / LinkedIn",
+	PreviewTwitter:         "X (Twitter)",
 }
 
 var Messages_zh_CN = &Messages{
@@ -65,6 +163,61 @@ var Messages_zh_CN = &Messages{
 	Seo:                           "搜索引擎优化",
 	Customize:                     "自定义",
 	BlankOpenGraphInformationTips: `"OG 信息"w为空。页面将使用默认值。`,
+	RobotsContent:                 "内容",
+	RobotsContentHint:             "如果没有 Sitemap 行，将自动添加站点地图索引。",
+	StructuredData:                "结构化数据",
+	StructuredDataJSONLD:          "JSON-LD",
+	StructuredDataTips:            "以 JSON-LD 输出的 schema.org 标记，{{Title}}、{{Description}}、{{ImageURL}} 和 {{URL}} 为设置中的值",
+	InvalidStructuredData: func(reason string) string {
+		return fmt.Sprintf("无效的结构化数据：%s", reason)
+	},
+	SEOIssue:             "SEO 问题",
+	SEOIssues:            "SEO 问题",
+	AuditObject:          "对象",
+	AuditLocale:          "语言",
+	AuditSeverity:        "严重程度",
+	AuditIssue:           "问题",
+	AuditScore:           "SEO 得分",
+	AuditCheckedAt:       "检查时间",
+	AuditNoIssues:        "未发现问题",
+	AuditNow:             "立即检查",
+	AuditSeverityError:   "错误",
+	AuditSeverityWarning: "警告",
+	AuditTitleMissing:    "缺少标题",
+	AuditTitleTooShort: func(length, min int) string {
+		return fmt.Sprintf("标题有 %d 个字符，应至少 %d 个", length, min)
+	},
+	AuditTitleTooLong: func(length, max int) string {
+		return fmt.Sprintf("标题有 %d 个字符，应不超过 %d 个", length, max)
+	},
+	AuditTitleDuplicated: func(count int) string {
+		return fmt.Sprintf("该标题被 %d 个页面共用", count)
+	},
+	AuditDescriptionMissing: "缺少描述",
+	AuditDescriptionTooShort: func(length, min int) string {
+		return fmt.Sprintf("描述有 %d 个字符，应至少 %d 个", length, min)
+	},
+	AuditDescriptionTooLong: func(length, max int) string {
+		return fmt.Sprintf("描述有 %d 个字符，应不超过 %d 个", length, max)
+	},
+	AuditOpenGraphImageMissing: "缺少 Open Graph 图片",
+	AuditCanonicalMissing:      "缺少规范 URL",
+	AuditNoIndexOnline:         "页面已发布但设置了 noindex",
+	AuditH1Missing:             "页面没有 H1 标题",
+	AuditImageAltMissing: func(count int) string {
+		return fmt.Sprintf("%d 张图片没有替代文本", count)
+	},
+	TwitterCardInformation: "X (Twitter) 卡片",
+	TwitterCard:            "卡片类型",
+	TwitterSite:            "网站账号",
+	TwitterCreator:         "作者账号",
+	TwitterTitle:           "X (Twitter) 标题",
+	TwitterDescription:     "X (Twitter) 描述",
+	TwitterImageURL:        "X (Twitter) 图片 URL",
+	Preview:                "预览",
+	PreviewSearchResult:    "Google 搜索",
+	PreviewOpenGraph:       "Facebook / LinkedIn",
+	PreviewTwitter:         "X (Twitter)",
 }
 
 var Messages_ja_JP = &Messages{
@@ -87,4 +240,59 @@ var Messages_ja_JP = &Messages{
 	Seo:                           "SEO",
 	Customize:                     "カスタマイズ",
 	BlankOpenGraphInformationTips: `「Open Graph 情報」が空です。ページではデフォルトの値が使用されます。`,
+	RobotsContent:                 "内容",
+	RobotsContentHint:             "Sitemap 行がない場合は、サイトマップインデックスが追加されます。",
+	StructuredData:                "構造化データ",
+	StructuredDataJSONLD:          "JSON-LD",
+	StructuredDataTips:            "JSON-LD として出力される schema.org マークアップ。{{Title}}、{{Description}}、{{ImageURL}}、{{URL}} は設定の値です",
+	InvalidStructuredData: func(reason string) string {
+		return fmt.Sprintf("無効な構造化データ：%s", reason)
+	},
+	SEOIssue:             "SEO の問題",
+	SEOIssues:            "SEO の問題",
+	AuditObject:          "オブジェクト",
+	AuditLocale:          "ロケール",
+	AuditSeverity:        "重大度",
+	AuditIssue:           "問題",
+	AuditScore:           "SEO スコア",
+	AuditCheckedAt:       "チェック日時",
+	AuditNoIssues:        "問題は見つかりませんでした",
+	AuditNow:             "今すぐチェック",
+	AuditSeverityError:   "エラー",
+	AuditSeverityWarning: "警告",
+	AuditTitleMissing:    "タイトルがありません",
+	AuditTitleTooShort: func(length, min int) string {
+		return fmt.Sprintf("タイトルは %d 文字です。%d 文字以上にしてください", length, min)
+	},
+	AuditTitleTooLong: func(length, max int) string {
+		return fmt.Sprintf("タイトルは %d 文字です。%d 文字以下にしてください", length, max)
+	},
+	AuditTitleDuplicated: func(count int) string {
+		return fmt.Sprintf("このタイトルは %d ページで重複しています", count)
+	},
+	AuditDescriptionMissing: "説明がありません",
+	AuditDescriptionTooShort: func(length, min int) string {
+		return fmt.Sprintf("説明は %d 文字です。%d 文字以上にしてください", length, min)
+	},
+	AuditDescriptionTooLong: func(length, max int) string {
+		return fmt.Sprintf("説明は %d 文字です。%d 文字以下にしてください", length, max)
+	},
+	AuditOpenGraphImageMissing: "Open Graph 画像がありません",
+	AuditCanonicalMissing:      "正規 URL がありません",
+	AuditNoIndexOnline:         "ページは noindex で公開されています",
+	AuditH1Missing:             "ページに H1 見出しがありません",
+	AuditImageAltMissing: func(count int) string {
+		return fmt.Sprintf("%d 枚の画像に代替テキストがありません", count)
+	},
+	TwitterCardInformation: "X (Twitter) カード",
+	TwitterCard:            "カードタイプ",
+	TwitterSite:            "サイトアカウント",
+	TwitterCreator:         "作成者アカウント",
+	TwitterTitle:           "X (Twitter) タイトル",
+	TwitterDescription:     "X (Twitter) 説明",
+	TwitterImageURL:        "X (Twitter) 画像 URL",
+	Preview:                "プレビュー",
+	PreviewSearchResult:    "Google 検索",
+	PreviewOpenGraph:       "Facebook / LinkedIn",
+	PreviewTwitter:         "X (Twitter)",
 }
@@ -57,6 +57,13 @@ type Setting struct {
 	OpenGraphImageURL              string                 `json:",omitempty"`
 	OpenGraphImageFromMediaLibrary media_library.MediaBox `json:",omitempty"`
 	OpenGraphMetadata              []OpenGraphMetadata    `json:",omitempty"`
+	TwitterCard                    string                 `json:",omitempty"`
+	TwitterSite                    string                 `json:",omitempty"`
+	TwitterCreator                 string                 `json:",omitempty"`
+	TwitterTitle                   string                 `json:",omitempty"`
+	TwitterDescription             string                 `json:",omitempty"`
+	TwitterImageURL                string                 `json:",omitempty"`
+	StructuredData                 string                 `json:",omitempty"`
 	EnabledCustomize               bool                   `json:",omitempty"`
 }
 
@@ -95,7 +102,10 @@ func (setting *Setting) IsEmpty() bool {
 		setting.CanonicalPath == "" && !setting.NoIndex && !setting.NoFollow &&
 		setting.OpenGraphTitle == "" && setting.OpenGraphDescription == "" &&
 		setting.OpenGraphURL == "" && setting.OpenGraphType == "" && setting.OpenGraphImageURL == "" &&
-		setting.OpenGraphImageFromMediaLibrary.Url == "" && len(setting.OpenGraphMetadata) == 0
+		setting.OpenGraphImageFromMediaLibrary.Url == "" && len(setting.OpenGraphMetadata) == 0 &&
+		setting.TwitterCard == "" && setting.TwitterSite == "" && setting.TwitterCreator == "" &&
+		setting.TwitterTitle == "" && setting.TwitterDescription == "" && setting.TwitterImageURL == "" &&
+		setting.StructuredData == ""
 }
 
 type Variables map[string]string
@@ -133,6 +143,20 @@ func (setting *Setting) HTMLComponent(metaProperties map[string]string) h.HTMLCo
 		"og:image":       setting.OpenGraphImageURL,
 	}
 
+	// the twitter cards fall back to the open graph properties, so only the set ones are rendered
+	for key, value := range map[string]string{
+		"twitter:card":        setting.TwitterCard,
+		"twitter:site":        setting.TwitterSite,
+		"twitter:creator":     setting.TwitterCreator,
+		"twitter:title":       setting.TwitterTitle,
+		"twitter:description": setting.TwitterDescription,
+		"twitter:image":       setting.TwitterImageURL,
+	} {
+		if value != "" {
+			metaPropertyData[key] = value
+		}
+	}
+
 	for _, meta := range setting.OpenGraphMetadata {
 		metaPropertyData[meta.Property] = meta.Content
 	}
@@ -185,6 +209,10 @@ func (setting *Setting) HTMLComponent(metaProperties map[string]string) h.HTMLCo
 
 	components = append(components, metaPropertyComponents)
 
+	if script := structuredDataScript(setting.StructuredData); script != nil {
+		components = append(components, script)
+	}
+
 	return components
 }
 
@@ -0,0 +1,265 @@
+package seo
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/i18n"
+	. "github.com/qor5/x/v3/ui/vuetify"
+	h "github.com/theplant/htmlgo"
+)
+
+const (
+	socialPreviewEvent = "seo_SocialPreviewEvent"
+
+	paramPreviewSEOName     = "seoName"
+	paramPreviewFieldPrefix = "seoFieldPrefix"
+	paramPreviewObjectID    = "seoObjectID"
+	paramPreviewLocale      = "seoLocale"
+	paramPreviewSEOSetting  = "seoSetting"
+
+	serpTitleMaxLength       = 60
+	serpDescriptionMaxLength = 160
+
+	TwitterCardSummary           = "summary"
+	TwitterCardSummaryLargeImage = "summary_large_image"
+)
+
+// socialPreview is what the search engines and the social networks show for a resolved setting
+type socialPreview struct {
+	URL    string
+	Domain string
+
+	Title       string
+	Description string
+
+	OpenGraphTitle       string
+	OpenGraphDescription string
+	OpenGraphImageURL    string
+
+	TwitterCard        string
+	TwitterTitle       string
+	TwitterDescription string
+	TwitterImageURL    string
+	TwitterSite        string
+}
+
+// newSocialPreview applies the fallbacks of the crawlers to the resolved setting,
+// the relative paths are resolved against baseURL.
+func newSocialPreview(setting *Setting, baseURL string) *socialPreview {
+	p := &socialPreview{
+		URL:                  firstNonEmpty(setting.CanonicalPath, setting.OpenGraphURL),
+		Title:                truncate(setting.Title, serpTitleMaxLength),
+		Description:          truncate(setting.Description, serpDescriptionMaxLength),
+		OpenGraphTitle:       firstNonEmpty(setting.OpenGraphTitle, setting.Title),
+		OpenGraphDescription: firstNonEmpty(setting.OpenGraphDescription, setting.Description),
+		OpenGraphImageURL:    firstNonEmpty(setting.OpenGraphImageURL, setting.OpenGraphImageFromMediaLibrary.URL("og")),
+		TwitterCard:          firstNonEmpty(setting.TwitterCard, TwitterCardSummary),
+		TwitterSite:          setting.TwitterSite,
+	}
+	p.TwitterTitle = firstNonEmpty(setting.TwitterTitle, p.OpenGraphTitle)
+	p.TwitterDescription = firstNonEmpty(setting.TwitterDescription, p.OpenGraphDescription)
+	p.TwitterImageURL = firstNonEmpty(setting.TwitterImageURL, p.OpenGraphImageURL)
+
+	if !isAbsoluteURL(p.URL) && baseURL != "" {
+		p.URL = strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(p.URL, "/")
+	}
+	if u, err := url.Parse(p.URL); err == nil {
+		p.Domain = u.Host
+	}
+	return p
+}
+
+func firstNonEmpty(vs ...string) string {
+	for _, v := range vs {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// truncate cuts s to max characters like the search results
+func truncate(s string, max int) string {
+	s = strings.TrimSpace(s)
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return strings.TrimSpace(string([]rune(s)[:max-1])) + "…"
+}
+
+func (b *Builder) previewBaseURL(req *http.Request) string {
+	if b.sitemapBaseURL != "" {
+		return b.sitemapBaseURL
+	}
+	if b.sitemapStorage != nil {
+		return b.sitemapStorage.GetEndpoint(req.Context())
+	}
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + req.Host
+}
+
+// previewSetting resolves the setting edited in vseo like it is rendered. The setting of a SEO inherits
+// the ones of its ancestors and keeps the context variables, which depend on the objects.
+func (b *Builder) previewSetting(obj interface{}, seo *SEO, locale string, setting Setting, req *http.Request) Setting {
+	if seoSetting, ok := obj.(*QorSEOSetting); ok {
+		variables := make(Variables)
+		if parent := seo.parent.getLocaleFinalQorSEOSetting(locale, b.db, b.getLocaleFallbacks(locale)...); parent != nil {
+			mergeSetting(&parent.Setting, &setting)
+			maps.Copy(variables, parent.Variables)
+		}
+		maps.Copy(variables, seoSetting.Variables)
+		for varName := range seo.getFinalContextVars() {
+			variables[varName] = fmt.Sprintf("{{%s}}", varName)
+		}
+		return replaceVariables(setting, variables)
+	}
+
+	defaultSetting := seo.getLocaleFinalQorSEOSetting(locale, b.db, b.getLocaleFallbacks(locale)...)
+	if !setting.EnabledCustomize {
+		setting = defaultSetting.Setting
+	} else if b.inherited {
+		mergeSetting(&defaultSetting.Setting, &setting)
+	}
+	variables := defaultSetting.Variables
+	for varName, varFunc := range seo.getFinalContextVars() {
+		variables[varName] = varFunc(obj, &setting, req)
+	}
+	return replaceVariables(setting, variables)
+}
+
+func socialPreviewPortalName(fieldPrefix string) string {
+	return fmt.Sprintf("seo_social_preview_%s", strings.ReplaceAll(strings.ToLower(fieldPrefix), " ", "_"))
+}
+
+// socialPreviewScope refreshes the preview cards of vseo when the form is changed
+func (b *Builder) socialPreviewScope(obj interface{}, fieldPrefix, locale string, seo *SEO, comps ...h.HTMLComponent) h.HTMLComponent {
+	event := web.Plaid().URL(b.mb.Info().ListingHref()).
+		EventFunc(socialPreviewEvent).
+		Query(paramPreviewSEOName, seo.name).
+		Query(paramPreviewFieldPrefix, fieldPrefix).
+		Query(paramPreviewLocale, locale)
+	if _, ok := obj.(*QorSEOSetting); ok {
+		event.Query(paramPreviewSEOSetting, true)
+	} else if slug := objectSlug(obj); slug != "" {
+		event.Query(paramPreviewObjectID, slug)
+	}
+	return web.Scope(comps...).OnChange(event.Go()).UseDebounce(500)
+}
+
+func (b *Builder) socialPreviewEvent(ctx *web.EventContext) (r web.EventResponse, err error) {
+	var (
+		fieldPrefix = ctx.R.FormValue(paramPreviewFieldPrefix)
+		locale      = ctx.R.FormValue(paramPreviewLocale)
+		seo         = b.GetSEO(ctx.R.FormValue(paramPreviewSEOName))
+	)
+	if seo == nil {
+		return
+	}
+	setting, err := settingFromForm(ctx, fieldPrefix)
+	if err != nil {
+		return
+	}
+
+	var obj interface{} = seo.name
+	if ctx.R.FormValue(paramPreviewSEOSetting) != "" {
+		seoSetting := &QorSEOSetting{Name: seo.name, Variables: make(Variables)}
+		for key, vs := range ctx.R.Form {
+			if varName, ok := strings.CutPrefix(key, "Variables."); ok && len(vs) > 0 {
+				seoSetting.Variables[varName] = vs[0]
+			}
+		}
+		obj = seoSetting
+	} else if mb := b.seoModelBuilders[seo.name]; mb != nil {
+		obj = mb.NewModel()
+		if id := ctx.R.FormValue(paramPreviewObjectID); id != "" {
+			if obj, err = mb.Editing().Fetcher(mb.NewModel(), id, ctx); err != nil {
+				return
+			}
+		}
+	}
+
+	setting = b.previewSetting(obj, seo, locale, setting, ctx.R)
+	r.UpdatePortals = append(r.UpdatePortals, &web.PortalUpdate{
+		Name: socialPreviewPortalName(fieldPrefix),
+		Body: b.socialPreviewCards(&setting, ctx.R),
+	})
+	return
+}
+
+// socialPreviewCards renders the Google result, the Open Graph card of Facebook and LinkedIn and the X card
+func (b *Builder) socialPreviewCards(setting *Setting, req *http.Request) h.HTMLComponent {
+	msgr := i18n.MustGetModuleMessages(req, I18nSeoKey, Messages_en_US).(*Messages)
+	p := newSocialPreview(setting, b.previewBaseURL(req))
+
+	label := func(text string) h.HTMLComponent {
+		return h.Div(h.Text(text)).Class("text-caption text-medium-emphasis mb-1")
+	}
+	image := func(src string, aspectRatio string) h.HTMLComponent {
+		if src == "" {
+			return h.Div(VIcon("mdi-image-off-outline").Color(ColorGrey)).
+				Class("d-flex align-center justify-center", "bg-"+ColorGreyLighten3).
+				Style(fmt.Sprintf("aspect-ratio: %s;", aspectRatio))
+		}
+		return VImg().Src(src).Cover(true).AspectRatio(aspectRatio)
+	}
+	oneLine := "overflow: hidden; text-overflow: ellipsis; white-space: nowrap;"
+
+	serp := h.Div(
+		label(msgr.PreviewSearchResult),
+		VCard(
+			VCardText(
+				h.Div(h.Text(p.URL)).Style("font-size: 12px; color: #202124;"+oneLine),
+				h.Div(h.Text(p.Title)).Style("font-size: 20px; line-height: 26px; color: #1a0dab;"+oneLine),
+				h.Div(h.Text(p.Description)).Style("font-size: 14px; line-height: 22px; color: #4d5156;"),
+			),
+		).Variant(VariantOutlined),
+	)
+
+	openGraph := h.Div(
+		label(msgr.PreviewOpenGraph),
+		VCard(
+			image(p.OpenGraphImageURL, "1.91"),
+			VCardText(
+				h.Div(h.Text(strings.ToUpper(p.Domain))).Class("text-caption text-medium-emphasis"),
+				h.Div(h.Text(p.OpenGraphTitle)).Class("font-weight-bold").Style(oneLine),
+				h.Div(h.Text(p.OpenGraphDescription)).Class("text-body-2 text-medium-emphasis").Style(oneLine),
+			).Class("bg-"+ColorGreyLighten4),
+		).Variant(VariantOutlined).Rounded("0"),
+	)
+
+	twitterText := h.Div(
+		h.Div(h.Text(p.Domain)).Class("text-caption text-medium-emphasis"),
+		h.Div(h.Text(p.TwitterTitle)).Style(oneLine),
+		h.Div(h.Text(p.TwitterDescription)).Class("text-body-2 text-medium-emphasis").Style(oneLine),
+	).Class("pa-3").Style("min-width: 0;")
+	var twitterCard h.HTMLComponent
+	if p.TwitterCard == TwitterCardSummaryLargeImage {
+		twitterCard = VCard(image(p.TwitterImageURL, "2"), twitterText).Variant(VariantOutlined).Rounded("lg")
+	} else {
+		twitterCard = VCard(
+			h.Div(
+				h.Div(image(p.TwitterImageURL, "1")).Style("width: 125px; flex-shrink: 0;"),
+				twitterText,
+			).Class("d-flex align-center"),
+		).Variant(VariantOutlined).Rounded("lg")
+	}
+	twitter := h.Div(label(msgr.PreviewTwitter), twitterCard)
+	if p.TwitterSite != "" {
+		twitter.AppendChildren(h.Div(h.Text(p.TwitterSite)).Class("text-caption text-medium-emphasis mt-1"))
+	}
+
+	return VRow(
+		VCol(serp).Cols(12),
+		VCol(openGraph).Cols(12).Md(6),
+		VCol(twitter).Cols(12).Md(6),
+	)
+}
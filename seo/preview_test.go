@@ -0,0 +1,88 @@
+package seo
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	h "github.com/theplant/htmlgo"
+	"github.com/theplant/testingutils"
+)
+
+func TestNewSocialPreview(t *testing.T) {
+	cases := []struct {
+		name     string
+		setting  *Setting
+		expected *socialPreview
+	}{
+		{
+			name: "fallbacks",
+			setting: &Setting{
+				Title:             "About",
+				Description:       "About us",
+				CanonicalPath:     "/about",
+				OpenGraphImageURL: "https://cdn.example.com/og.png",
+			},
+			expected: &socialPreview{
+				URL:                  "https://example.com/about",
+				Domain:               "example.com",
+				Title:                "About",
+				Description:          "About us",
+				OpenGraphTitle:       "About",
+				OpenGraphDescription: "About us",
+				OpenGraphImageURL:    "https://cdn.example.com/og.png",
+				TwitterCard:          TwitterCardSummary,
+				TwitterTitle:         "About",
+				TwitterDescription:   "About us",
+				TwitterImageURL:      "https://cdn.example.com/og.png",
+			},
+		},
+		{
+			name: "own values",
+			setting: &Setting{
+				Title:                strings.Repeat("t", 70),
+				OpenGraphTitle:       "OG",
+				OpenGraphDescription: "OG description",
+				OpenGraphURL:         "https://other.example.com/about",
+				TwitterCard:          TwitterCardSummaryLargeImage,
+				TwitterSite:          "@qor5",
+				TwitterTitle:         "Tweet",
+				TwitterImageURL:      "https://cdn.example.com/x.png",
+			},
+			expected: &socialPreview{
+				URL:                  "https://other.example.com/about",
+				Domain:               "other.example.com",
+				Title:                strings.Repeat("t", 59) + "…",
+				OpenGraphTitle:       "OG",
+				OpenGraphDescription: "OG description",
+				TwitterCard:          TwitterCardSummaryLargeImage,
+				TwitterTitle:         "Tweet",
+				TwitterDescription:   "OG description",
+				TwitterImageURL:      "https://cdn.example.com/x.png",
+				TwitterSite:          "@qor5",
+			},
+		},
+	}
+	for _, c := range cases {
+		if diff := testingutils.PrettyJsonDiff(c.expected, newSocialPreview(c.setting, "https://example.com/")); diff != "" {
+			t.Errorf("%s: %s", c.name, diff)
+		}
+	}
+}
+
+func TestTwitterMetaTags(t *testing.T) {
+	setting := &Setting{Title: "About", TwitterCard: TwitterCardSummary, TwitterSite: "@qor5"}
+	out := h.MustString(setting.HTMLComponent(map[string]string{"twitter:title": "From property"}), context.TODO())
+	for _, expected := range []string{
+		`<meta property='twitter:card' name='twitter:card' content='summary'>`,
+		`<meta property='twitter:site' name='twitter:site' content='@qor5'>`,
+		`<meta property='twitter:title' name='twitter:title' content='From property'>`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("%s is not rendered in %s", expected, out)
+		}
+	}
+	if strings.Contains(out, "twitter:description") {
+		t.Errorf("empty twitter properties are rendered: %s", out)
+	}
+}
@@ -0,0 +1,89 @@
+package seo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/qor5/web/v3"
+	"github.com/qor5/x/v3/i18n"
+	. "github.com/qor5/x/v3/ui/vuetify"
+	h "github.com/theplant/htmlgo"
+	"gorm.io/gorm"
+
+	"github.com/qor5/admin/v3/presets"
+)
+
+const (
+	RobotsPath = "/robots.txt"
+
+	defaultRobotsContent = "User-agent: *\nAllow: /\n"
+)
+
+// QorSEORobots is the content of robots.txt edited in the admin
+type QorSEORobots struct {
+	ID        uint `gorm:"primarykey"`
+	Content   string
+	UpdatedAt time.Time
+}
+
+// robotsContent returns the content of robots.txt, the sitemap index is
+// added unless the content has its own Sitemap line.
+func robotsContent(content, sitemapURL string) string {
+	if strings.TrimSpace(content) == "" {
+		content = defaultRobotsContent
+	}
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(line)), "sitemap:") {
+			return content
+		}
+	}
+	return content + "\nSitemap: " + sitemapURL + "\n"
+}
+
+// RobotsTxt returns the content of robots.txt
+func (b *Builder) RobotsTxt(ctx context.Context) (r string, err error) {
+	var robots QorSEORobots
+	if err = b.db.WithContext(ctx).Order("id").First(&robots).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+	return robotsContent(robots.Content, b.sitemapURL(ctx, SitemapIndexPath)), nil
+}
+
+func (b *Builder) publishRobots(ctx context.Context) (err error) {
+	content, err := b.RobotsTxt(ctx)
+	if err != nil {
+		return
+	}
+	_, err = b.sitemapStorage.Put(ctx, RobotsPath, strings.NewReader(content))
+	return
+}
+
+func (b *Builder) configRobots(pb *presets.Builder) {
+	mb := pb.Model(&QorSEORobots{}).Singleton(true).Label("Robots.txt")
+	editing := mb.Editing("Content")
+	editing.Field("Content").ComponentFunc(func(obj interface{}, field *presets.FieldContext, ctx *web.EventContext) h.HTMLComponent {
+		msgr := i18n.MustGetModuleMessages(ctx.R, I18nSeoKey, Messages_en_US).(*Messages)
+		return VTextarea().
+			Attr(web.VField(field.Name, field.Value(obj))...).
+			Label(msgr.RobotsContent).
+			Hint(msgr.RobotsContentHint).PersistentHint(true).
+			Placeholder(defaultRobotsContent).
+			Rows(16).
+			Disabled(field.Disabled).
+			Class("font-monospace")
+	})
+	// robots.txt is uploaded once it is saved, it is not worth waiting for the next publishing
+	editing.WrapSaveFunc(func(in presets.SaveFunc) presets.SaveFunc {
+		return func(obj interface{}, id string, ctx *web.EventContext) (err error) {
+			if err = in(obj, id, ctx); err != nil {
+				return
+			}
+			return b.publishRobots(ctx.R.Context())
+		}
+	})
+}
@@ -3,6 +3,7 @@ package seo
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	"gorm.io/gorm"
@@ -36,6 +37,9 @@ type SEO struct {
 	// For example, if the variable field in the database contains a:"b", then {{a}} will be replaced with b.
 	settingVars map[string]struct{}
 
+	// The JSON-LD template, it is the default of the structured data setting.
+	structuredData string
+
 	finalContextVarsCache   map[string]ContextVarFunc
 	finalMetaPropsCache     map[string]ContextVarFunc
 	finalAvailableVarsCache map[string]struct{}
@@ -251,17 +255,19 @@ func (seo *SEO) getAvailableVars() map[string]struct{} {
 	return seo.finalAvailableVarsCache
 }
 
-func (seo *SEO) getLocaleFinalQorSEOSetting(locale string, db *gorm.DB) *QorSEOSetting {
+func (seo *SEO) getLocaleFinalQorSEOSetting(locale string, db *gorm.DB, fallbacks ...string) *QorSEOSetting {
 	if seo == nil || seo.name == "" {
 		return nil
 	}
-	seoSetting := &QorSEOSetting{}
 	var lowPSetting *Setting
-	seoSettingOfParent := seo.parent.getLocaleFinalQorSEOSetting(locale, db)
-	err := db.Where("name = ? and locale_code = ?", seo.name, locale).First(seoSetting).Error
+	seoSettingOfParent := seo.parent.getLocaleFinalQorSEOSetting(locale, db, fallbacks...)
+	seoSetting, err := seo.getLocaleQorSEOSetting(locale, db, fallbacks)
 	if err != nil {
 		panic(err)
 	}
+	if seoSetting.Setting.StructuredData == "" {
+		seoSetting.Setting.StructuredData = seo.structuredData
+	}
 	highPSetting := &seoSetting.Setting
 	if seoSettingOfParent != nil {
 		lowPSetting = &seoSettingOfParent.Setting
@@ -298,6 +304,9 @@ func (seo *SEO) getFinalQorSEOSetting(db *gorm.DB) map[string]*QorSEOSetting {
 	r := make(map[string]*QorSEOSetting)
 	for _, seoSet := range seoSets {
 		locale := seoSet.Locale.LocaleCode
+		if seoSet.Setting.StructuredData == "" {
+			seoSet.Setting.StructuredData = seo.structuredData
+		}
 		setsOfParent := seoSetsOfParent[locale]
 		if setsOfParent == nil {
 			r[locale] = seoSet
@@ -361,6 +370,47 @@ func (seo *SEO) checkConflict(varName string, isContextVar bool) {
 	}
 }
 
+// getLocaleQorSEOSetting returns the setting of the SEO in the locale, the empty fields are taken
+// from the settings in the fallback locales, the setting in the nearest fallback is used if it is missing.
+func (seo *SEO) getLocaleQorSEOSetting(locale string, db *gorm.DB, fallbacks []string) (*QorSEOSetting, error) {
+	seoSetting := &QorSEOSetting{}
+	if len(fallbacks) == 0 {
+		if err := db.Where("name = ? and locale_code = ?", seo.name, locale).First(seoSetting).Error; err != nil {
+			return nil, err
+		}
+		return seoSetting, nil
+	}
+	chain := append([]string{locale}, fallbacks...)
+	var settings []*QorSEOSetting
+	if err := db.Where("name = ? and locale_code IN ?", seo.name, chain).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	seoSetting = nil
+	for _, code := range chain {
+		i := slices.IndexFunc(settings, func(s *QorSEOSetting) bool { return s.LocaleCode == code })
+		if i < 0 {
+			continue
+		}
+		if seoSetting == nil {
+			seoSetting = settings[i]
+			continue
+		}
+		mergeSetting(&settings[i].Setting, &seoSetting.Setting)
+		if seoSetting.Variables == nil {
+			seoSetting.Variables = make(Variables)
+		}
+		for varName, val := range settings[i].Variables {
+			if _, isExist := seoSetting.Variables[varName]; !isExist {
+				seoSetting.Variables[varName] = val
+			}
+		}
+	}
+	if seoSetting == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return seoSetting, nil
+}
+
 func mergeSetting(lowPSetting, highPSetting *Setting) {
 	if lowPSetting == nil {
 		return
@@ -407,4 +457,25 @@ func mergeSetting(lowPSetting, highPSetting *Setting) {
 	if len(highPSetting.OpenGraphMetadata) == 0 {
 		highPSetting.OpenGraphMetadata = lowPSetting.OpenGraphMetadata
 	}
+	if highPSetting.TwitterCard == "" {
+		highPSetting.TwitterCard = lowPSetting.TwitterCard
+	}
+	if highPSetting.TwitterSite == "" {
+		highPSetting.TwitterSite = lowPSetting.TwitterSite
+	}
+	if highPSetting.TwitterCreator == "" {
+		highPSetting.TwitterCreator = lowPSetting.TwitterCreator
+	}
+	if highPSetting.TwitterTitle == "" {
+		highPSetting.TwitterTitle = lowPSetting.TwitterTitle
+	}
+	if highPSetting.TwitterDescription == "" {
+		highPSetting.TwitterDescription = lowPSetting.TwitterDescription
+	}
+	if highPSetting.TwitterImageURL == "" {
+		highPSetting.TwitterImageURL = lowPSetting.TwitterImageURL
+	}
+	if highPSetting.StructuredData == "" {
+		highPSetting.StructuredData = lowPSetting.StructuredData
+	}
 }
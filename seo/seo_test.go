@@ -535,6 +535,73 @@ func TestSEO_getLocaleFinalQorSEOSetting(t *testing.T) {
 	}
 }
 
+func TestSEO_getLocaleFinalQorSEOSettingWithFallbacks(t *testing.T) {
+	resetDB()
+	seoSettings := []*QorSEOSetting{
+		{
+			Name:    "nodeA",
+			Setting: Setting{Description: "Bonjour du Canada"},
+			Locale:  l10n.Locale{LocaleCode: "fr-CA"},
+		},
+		{
+			Name:    "nodeA",
+			Setting: Setting{Title: "Bonjour"},
+			Locale:  l10n.Locale{LocaleCode: "fr"},
+		},
+		{
+			Name:    "nodeA",
+			Setting: Setting{Title: "Hello", Keywords: "hello"},
+			Locale:  l10n.Locale{LocaleCode: "en"},
+		},
+	}
+	if err := dbForTest.Create(seoSettings).Error; err != nil {
+		panic(err)
+	}
+	seoRoot := &SEO{}
+	nodeA := &SEO{name: "nodeA"}
+	seoRoot.AppendChildren(nodeA)
+
+	cases := []struct {
+		name      string
+		locale    string
+		fallbacks []string
+		expected  Setting
+	}{
+		{
+			name:     "without_fallbacks",
+			locale:   "fr-CA",
+			expected: Setting{Description: "Bonjour du Canada"},
+		},
+		{
+			name:      "empty_fields_from_fallbacks",
+			locale:    "fr-CA",
+			fallbacks: []string{"fr", "en"},
+			expected:  Setting{Title: "Bonjour", Description: "Bonjour du Canada", Keywords: "hello"},
+		},
+		{
+			name:      "missing_locale",
+			locale:    "fr-BE",
+			fallbacks: []string{"fr", "en"},
+			expected:  Setting{Title: "Bonjour", Keywords: "hello"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			seoSetting := nodeA.getLocaleFinalQorSEOSetting(c.locale, dbForTest, c.fallbacks...)
+			actual := Setting{
+				Title:       seoSetting.Setting.Title,
+				Description: seoSetting.Setting.Description,
+				Keywords:    seoSetting.Setting.Keywords,
+			}
+			r := testingutils.PrettyJsonDiff(c.expected, actual)
+			if r != "" {
+				t.Error(r)
+			}
+		})
+	}
+}
+
 func TestSEO_getFinalQorSEOSetting(t *testing.T) {
 	cases := []struct {
 		name      string
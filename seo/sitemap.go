@@ -0,0 +1,474 @@
+package seo
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qor5/x/v3/oss"
+	"github.com/sunfmin/reflectutils"
+
+	"github.com/qor5/admin/v3/l10n"
+	"github.com/qor5/admin/v3/publish"
+)
+
+const (
+	SitemapIndexPath = "/sitemap.xml"
+	SitemapDir       = "/sitemaps"
+
+	// sitemapMaxURLs is the limit of urls in a sitemap file by the sitemaps protocol
+	sitemapMaxURLs = 50000
+
+	sitemapXMLNS      = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	sitemapXHTMLXMLNS = "http://www.w3.org/1999/xhtml"
+)
+
+// SitemapEntry is a page listed in the sitemaps
+type SitemapEntry struct {
+	// Loc is the URL of the page, a path is joined to the base URL of the sitemaps
+	Loc        string
+	LastMod    time.Time
+	ChangeFreq string
+	Priority   float64
+
+	// Locale and Group link the translations of a page,
+	// the entries of a group list each other as hreflang alternates.
+	Locale string
+	Group  string
+
+	// Object is the record of the page, the entry is left out if its SEO setting is NoIndex
+	Object interface{}
+}
+
+// SitemapProvider lists the entries of a sitemap, the name is the name of its file
+type SitemapProvider interface {
+	SitemapName() string
+	SitemapEntries(ctx context.Context) ([]*SitemapEntry, error)
+}
+
+// SitemapRecordProvider is a SitemapProvider that reports the records it lists,
+// RegenerateSitemapsOnPublish only regenerates the sitemaps for those records
+type SitemapRecordProvider interface {
+	SitemapProvider
+	SitemapListsRecord(record any) bool
+}
+
+type sitemapProviderFunc struct {
+	name    string
+	entries func(ctx context.Context) ([]*SitemapEntry, error)
+}
+
+func (p *sitemapProviderFunc) SitemapName() string {
+	return p.name
+}
+
+func (p *sitemapProviderFunc) SitemapEntries(ctx context.Context) ([]*SitemapEntry, error) {
+	return p.entries(ctx)
+}
+
+// SitemapProviderFunc creates a SitemapProvider named name that lists the entries returned by f
+func SitemapProviderFunc(name string, f func(ctx context.Context) ([]*SitemapEntry, error)) SitemapProvider {
+	return &sitemapProviderFunc{name: name, entries: f}
+}
+
+// SitemapModel is a model listed in the sitemaps by ModelSitemapProvider
+type SitemapModel interface {
+	SitemapURL() string
+	SitemapLastMod() time.Time
+}
+
+// Sitemap enables the sitemaps and robots.txt, they are uploaded to storage
+// by GenerateSitemaps, and robots.txt could be edited in the admin.
+func (b *Builder) Sitemap(storage oss.StorageInterface) *Builder {
+	b.sitemapStorage = storage
+	return b
+}
+
+// SitemapBaseURL is the URL the paths in the sitemaps are joined to,
+// it defaults to the endpoint of the sitemap storage.
+func (b *Builder) SitemapBaseURL(v string) *Builder {
+	b.sitemapBaseURL = v
+	return b
+}
+
+// SitemapProviders adds the providers of the sitemaps, every provider is a sitemap file in the sitemap index
+func (b *Builder) SitemapProviders(vs ...SitemapProvider) *Builder {
+	b.sitemapProviders = append(b.sitemapProviders, vs...)
+	return b
+}
+
+// Hreflangs maps the locale codes to the hreflang values of the alternates,
+// such as "Japan" to "ja-JP". The locale code is used if it is not mapped.
+func (b *Builder) Hreflangs(v map[string]string) *Builder {
+	b.hreflangs = v
+	return b
+}
+
+// RegenerateSitemapsOnPublish regenerates the sitemaps after p publishes or unpublishes a record
+// listed by a SitemapRecordProvider. They are regenerated in the background, the publishing does
+// not wait for them and their failures are logged.
+func (b *Builder) RegenerateSitemapsOnPublish(p *publish.Builder) *Builder {
+	p.WrapPublish(func(in publish.PublishFunc) publish.PublishFunc {
+		return func(ctx context.Context, record any) (err error) {
+			if err = in(ctx, record); err != nil {
+				return
+			}
+			if b.sitemapsListRecord(record) {
+				b.regenerateSitemapsInBackground(ctx)
+			}
+			return
+		}
+	})
+	p.WrapUnPublish(func(in publish.UnPublishFunc) publish.UnPublishFunc {
+		return func(ctx context.Context, record any) (err error) {
+			if err = in(ctx, record); err != nil {
+				return
+			}
+			if b.sitemapsListRecord(record) {
+				b.regenerateSitemapsInBackground(ctx)
+			}
+			return
+		}
+	})
+	return b
+}
+
+func (b *Builder) sitemapsListRecord(record any) bool {
+	for _, p := range b.sitemapProviders {
+		if rp, ok := p.(SitemapRecordProvider); ok && rp.SitemapListsRecord(record) {
+			return true
+		}
+	}
+	return false
+}
+
+// regenerateSitemapsInBackground generates the sitemaps in a goroutine, a request coming
+// while they are generated runs the generation once more after it
+func (b *Builder) regenerateSitemapsInBackground(ctx context.Context) {
+	ctx = context.WithoutCancel(ctx)
+	b.sitemapMu.Lock()
+	defer b.sitemapMu.Unlock()
+	if b.sitemapGenerating {
+		b.sitemapPending = true
+		return
+	}
+	b.sitemapGenerating = true
+	go func() {
+		for {
+			if err := b.GenerateSitemaps(ctx); err != nil {
+				log.Printf("regenerate sitemaps error: %v\n", err)
+			}
+			b.sitemapMu.Lock()
+			if !b.sitemapPending {
+				b.sitemapGenerating = false
+				b.sitemapMu.Unlock()
+				return
+			}
+			b.sitemapPending = false
+			b.sitemapMu.Unlock()
+		}
+	}()
+}
+
+type modelSitemapProvider struct {
+	SitemapProvider
+	modelType reflect.Type
+}
+
+func (p *modelSitemapProvider) SitemapListsRecord(record any) bool {
+	return indirectType(reflect.TypeOf(record)) == p.modelType
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// ModelSitemapProvider lists all the records of model, only the online ones
+// if it is published by the publisher. The translations of a record are
+// linked by its ID. Publishing the records regenerates the sitemaps with
+// RegenerateSitemapsOnPublish.
+func (b *Builder) ModelSitemapProvider(name string, model SitemapModel) SitemapProvider {
+	modelType := reflect.TypeOf(model)
+	provider := SitemapProviderFunc(name, func(ctx context.Context) (entries []*SitemapEntry, err error) {
+		records := reflect.New(reflect.SliceOf(modelType))
+		db := b.db.WithContext(ctx)
+		if _, ok := model.(publish.StatusInterface); ok {
+			db = db.Where("status = ?", publish.StatusOnline)
+		}
+		if err = db.Find(records.Interface()).Error; err != nil {
+			return
+		}
+		records = records.Elem()
+		for i := 0; i < records.Len(); i++ {
+			record := records.Index(i).Interface().(SitemapModel)
+			entry := &SitemapEntry{
+				Loc:     record.SitemapURL(),
+				LastMod: record.SitemapLastMod(),
+				Object:  record,
+			}
+			if l, ok := record.(l10n.LocaleInterface); ok && l.EmbedLocale().LocaleCode != "" {
+				entry.Locale = l.EmbedLocale().LocaleCode
+				if id, gErr := reflectutils.Get(record, "ID"); gErr == nil {
+					entry.Group = fmt.Sprint(id)
+				}
+			}
+			entries = append(entries, entry)
+		}
+		return
+	})
+	return &modelSitemapProvider{SitemapProvider: provider, modelType: indirectType(modelType)}
+}
+
+func (b *Builder) sitemapURL(ctx context.Context, p string) string {
+	if isAbsoluteURL(p) {
+		return p
+	}
+	baseURL := b.sitemapBaseURL
+	if baseURL == "" {
+		baseURL = b.sitemapStorage.GetEndpoint(ctx)
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(p, "/")
+}
+
+func (b *Builder) hreflang(locale string) string {
+	if v, ok := b.hreflangs[locale]; ok {
+		return v
+	}
+	return locale
+}
+
+// GenerateSitemaps generates the sitemaps of the providers and the sitemap
+// index referencing them, and uploads them with robots.txt to the storage.
+func (b *Builder) GenerateSitemaps(ctx context.Context) (err error) {
+	if b.sitemapStorage == nil {
+		return errors.New("sitemap storage is not configured")
+	}
+	noIndex := b.noIndexResolver()
+	var (
+		sitemaps []*sitemapIndexItem
+		written  = map[string]bool{}
+	)
+	for _, p := range b.sitemapProviders {
+		var entries []*SitemapEntry
+		if entries, err = p.SitemapEntries(ctx); err != nil {
+			return
+		}
+		var urls []*sitemapURL
+		if urls, err = buildSitemapURLs(entries, noIndex, func(loc string) string { return b.sitemapURL(ctx, loc) }, b.hreflang); err != nil {
+			return
+		}
+		for i := 0; i == 0 || i*sitemapMaxURLs < len(urls); i++ {
+			chunk := urls[i*sitemapMaxURLs : min((i+1)*sitemapMaxURLs, len(urls))]
+			name := p.SitemapName()
+			if i > 0 {
+				name = fmt.Sprintf("%s-%d", name, i+1)
+			}
+			filePath := fmt.Sprintf("%s/%s.xml", SitemapDir, name)
+			if err = b.putXML(ctx, filePath, newSitemapURLSet(chunk)); err != nil {
+				return
+			}
+			written[filePath] = true
+			sitemaps = append(sitemaps, &sitemapIndexItem{Loc: b.sitemapURL(ctx, filePath), LastMod: lastModOf(chunk)})
+		}
+	}
+	if err = b.putXML(ctx, SitemapIndexPath, &sitemapIndex{XMLNS: sitemapXMLNS, Sitemaps: sitemaps}); err != nil {
+		return
+	}
+	if err = b.removeStaleSitemapChunks(ctx, written); err != nil {
+		return
+	}
+	return b.publishRobots(ctx)
+}
+
+// removeStaleSitemapChunks deletes the chunks of the providers that are not written this time,
+// they are left when a sitemap has less chunks than it had.
+func (b *Builder) removeStaleSitemapChunks(ctx context.Context, written map[string]bool) (err error) {
+	objects, err := b.sitemapStorage.List(ctx, SitemapDir)
+	if err != nil {
+		return
+	}
+	for _, o := range objects {
+		p := "/" + strings.TrimPrefix(o.Path, "/")
+		if written[p] || path.Dir(p) != SitemapDir || !b.isSitemapChunk(path.Base(p)) {
+			continue
+		}
+		if err = b.sitemapStorage.Delete(ctx, o.Path); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// isSitemapChunk reports whether the file is a chunk after the first one of a provider, such as "pages-2.xml"
+func (b *Builder) isSitemapChunk(name string) bool {
+	for _, p := range b.sitemapProviders {
+		n, ok := strings.CutPrefix(name, p.SitemapName()+"-")
+		if !ok {
+			continue
+		}
+		if n, ok = strings.CutSuffix(n, ".xml"); !ok {
+			continue
+		}
+		if i, aErr := strconv.Atoi(n); aErr == nil && i > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Builder) putXML(ctx context.Context, p string, v interface{}) (err error) {
+	buf := bytes.NewBufferString(xml.Header)
+	enc := xml.NewEncoder(buf)
+	enc.Indent("", "  ")
+	if err = enc.Encode(v); err != nil {
+		return
+	}
+	_, err = b.sitemapStorage.Put(ctx, p, buf)
+	return
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name            `xml:"sitemapindex"`
+	XMLNS    string              `xml:"xmlns,attr"`
+	Sitemaps []*sitemapIndexItem `xml:"sitemap"`
+}
+
+type sitemapIndexItem struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName    xml.Name      `xml:"urlset"`
+	XMLNS      string        `xml:"xmlns,attr"`
+	XHTMLXMLNS string        `xml:"xmlns:xhtml,attr,omitempty"`
+	URLs       []*sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc        string              `xml:"loc"`
+	LastMod    string              `xml:"lastmod,omitempty"`
+	ChangeFreq string              `xml:"changefreq,omitempty"`
+	Priority   string              `xml:"priority,omitempty"`
+	Alternates []*sitemapXHTMLLink `xml:"xhtml:link"`
+}
+
+type sitemapXHTMLLink struct {
+	Rel      string `xml:"rel,attr"`
+	Hreflang string `xml:"hreflang,attr"`
+	Href     string `xml:"href,attr"`
+}
+
+func newSitemapURLSet(urls []*sitemapURL) *sitemapURLSet {
+	set := &sitemapURLSet{XMLNS: sitemapXMLNS, URLs: urls}
+	for _, u := range urls {
+		if len(u.Alternates) > 0 {
+			set.XHTMLXMLNS = sitemapXHTMLXMLNS
+			break
+		}
+	}
+	return set
+}
+
+// lastModOf returns the latest lastmod of the urls, lastmods are formatted in UTC so they compare as strings
+func lastModOf(urls []*sitemapURL) (r string) {
+	for _, u := range urls {
+		if u.LastMod > r {
+			r = u.LastMod
+		}
+	}
+	return
+}
+
+// buildSitemapURLs converts the entries to sitemap urls, leaving out the NoIndex ones,
+// and links the entries of a group as hreflang alternates of each other.
+func buildSitemapURLs(entries []*SitemapEntry, noIndex func(obj interface{}, locale string) bool, absURL func(loc string) string, hreflang func(locale string) string) (urls []*sitemapURL, err error) {
+	var (
+		groups = map[string][]*sitemapXHTMLLink{}
+		listed []*SitemapEntry
+	)
+	for _, e := range entries {
+		if e.Loc == "" {
+			return nil, fmt.Errorf("sitemap entry %v has no loc", e.Object)
+		}
+		if e.Object != nil && noIndex(e.Object, e.Locale) {
+			continue
+		}
+		listed = append(listed, e)
+		if e.Group != "" && e.Locale != "" {
+			groups[e.Group] = append(groups[e.Group], &sitemapXHTMLLink{Rel: "alternate", Hreflang: hreflang(e.Locale), Href: absURL(e.Loc)})
+		}
+	}
+	for _, e := range listed {
+		u := &sitemapURL{
+			Loc:        absURL(e.Loc),
+			ChangeFreq: e.ChangeFreq,
+		}
+		if !e.LastMod.IsZero() {
+			u.LastMod = e.LastMod.UTC().Format(time.RFC3339)
+		}
+		if e.Priority > 0 {
+			u.Priority = fmt.Sprintf("%.1f", e.Priority)
+		}
+		// a page without translations has no alternates
+		if links := groups[e.Group]; e.Group != "" && len(links) > 1 {
+			u.Alternates = links
+		}
+		urls = append(urls, u)
+	}
+	return
+}
+
+// noIndexResolver resolves the NoIndex of the records by their SEO settings,
+// the settings of every SEO are loaded once.
+func (b *Builder) noIndexResolver() func(obj interface{}, locale string) bool {
+	settings := map[*SEO]map[string]*QorSEOSetting{}
+	return func(obj interface{}, locale string) bool {
+		var setting *Setting
+		value := reflect.Indirect(reflect.ValueOf(obj))
+		if value.Kind() == reflect.Struct {
+			for i := 0; i < value.NumField(); i++ {
+				if s, ok := value.Field(i).Interface().(Setting); ok {
+					if s.EnabledCustomize {
+						setting = &s
+					}
+					break
+				}
+			}
+		}
+		if setting != nil && setting.NoIndex {
+			return true
+		}
+		if setting != nil && !b.inherited {
+			return false
+		}
+		seo := b.registeredSEO[value.Type()]
+		if seo == nil {
+			return false
+		}
+		if _, ok := settings[seo]; !ok {
+			settings[seo] = seo.getFinalQorSEOSetting(b.db)
+		}
+		if l, ok := obj.(l10n.LocaleInterface); ok && locale == "" {
+			locale = l.EmbedLocale().LocaleCode
+		}
+		if locale == "" && len(b.locales) == 1 {
+			locale = b.locales[0]
+		}
+		if s := settings[seo][locale]; s != nil {
+			return s.Setting.NoIndex
+		}
+		return false
+	}
+}
@@ -0,0 +1,234 @@
+package seo
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/qor5/x/v3/oss/filesystem"
+	"github.com/theplant/testingutils"
+
+	"github.com/qor5/admin/v3/publish"
+)
+
+type sitemapPage struct {
+	ID uint
+}
+
+func TestBuildSitemapURLs(t *testing.T) {
+	lastMod := time.Date(2024, 5, 1, 10, 0, 0, 0, time.FixedZone("JST", 9*3600))
+	hidden := &sitemapPage{ID: 3}
+	entries := []*SitemapEntry{
+		{Loc: "/jp/about", Locale: "Japan", Group: "1", LastMod: lastMod, Object: &sitemapPage{ID: 1}},
+		{Loc: "/cn/about", Locale: "China", Group: "1", Priority: 0.8},
+		{Loc: "/jp/news", Locale: "Japan", Group: "2", ChangeFreq: "daily"},
+		{Loc: "https://other.example.com/", Object: hidden},
+	}
+	urls, err := buildSitemapURLs(entries,
+		func(obj interface{}, _ string) bool { return obj == hidden },
+		func(loc string) string { return "https://example.com" + loc },
+		(&Builder{hreflangs: map[string]string{"Japan": "ja-JP"}}).hreflang,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alternates := []*sitemapXHTMLLink{
+		{Rel: "alternate", Hreflang: "ja-JP", Href: "https://example.com/jp/about"},
+		{Rel: "alternate", Hreflang: "China", Href: "https://example.com/cn/about"},
+	}
+	expected := []*sitemapURL{
+		{Loc: "https://example.com/jp/about", LastMod: "2024-05-01T01:00:00Z", Alternates: alternates},
+		{Loc: "https://example.com/cn/about", Priority: "0.8", Alternates: alternates},
+		{Loc: "https://example.com/jp/news", ChangeFreq: "daily"},
+	}
+	if diff := testingutils.PrettyJsonDiff(expected, urls); diff != "" {
+		t.Error(diff)
+	}
+	if lastModOf(urls) != "2024-05-01T01:00:00Z" {
+		t.Errorf("unexpected lastmod %q", lastModOf(urls))
+	}
+
+	if _, err = buildSitemapURLs([]*SitemapEntry{{}}, nil, nil, nil); err == nil {
+		t.Error("an entry without loc should fail")
+	}
+}
+
+func TestSitemapURLSetXML(t *testing.T) {
+	set := newSitemapURLSet([]*sitemapURL{{
+		Loc:        "https://example.com/jp/about",
+		Alternates: []*sitemapXHTMLLink{{Rel: "alternate", Hreflang: "ja-JP", Href: "https://example.com/jp/about"}},
+	}})
+	out, err := xml.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:xhtml="http://www.w3.org/1999/xhtml">` +
+		`<url><loc>https://example.com/jp/about</loc>` +
+		`<xhtml:link rel="alternate" hreflang="ja-JP" href="https://example.com/jp/about"></xhtml:link></url></urlset>`
+	if string(out) != expected {
+		t.Errorf("unexpected xml %s", out)
+	}
+
+	out, err = xml.Marshal(newSitemapURLSet([]*sitemapURL{{Loc: "https://example.com/"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "xhtml") {
+		t.Errorf("the xhtml namespace is declared without alternates: %s", out)
+	}
+}
+
+func TestRobotsContent(t *testing.T) {
+	sitemapURL := "https://example.com/sitemap.xml"
+	cases := []struct {
+		content  string
+		expected string
+	}{
+		{"", "User-agent: *\nAllow: /\n\nSitemap: https://example.com/sitemap.xml\n"},
+		{"User-agent: *\nDisallow: /admin", "User-agent: *\nDisallow: /admin\n\nSitemap: https://example.com/sitemap.xml\n"},
+		{"User-agent: *\nsitemap: https://cdn.example.com/sitemap.xml\n", "User-agent: *\nsitemap: https://cdn.example.com/sitemap.xml\n"},
+	}
+	for _, c := range cases {
+		if actual := robotsContent(c.content, sitemapURL); actual != c.expected {
+			t.Errorf("robotsContent(%q) = %q, want %q", c.content, actual, c.expected)
+		}
+	}
+}
+
+type sitemapPost struct {
+	ID uint
+}
+
+func (*sitemapPost) SitemapURL() string { return "/posts" }
+
+func (*sitemapPost) SitemapLastMod() time.Time { return time.Time{} }
+
+func TestRegenerateSitemapsOnPublish(t *testing.T) {
+	if err := dbForTest.AutoMigrate(&QorSEORobots{}); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		generated atomic.Int32
+		fail      atomic.Bool
+	)
+	b := New(dbForTest).Sitemap(filesystem.New(t.TempDir()))
+	posts := b.ModelSitemapProvider("posts", &sitemapPost{}).(SitemapRecordProvider)
+	b.SitemapProviders(&sitemapRecordProvider{
+		SitemapProvider: SitemapProviderFunc("posts", func(context.Context) ([]*SitemapEntry, error) {
+			generated.Add(1)
+			if fail.Load() {
+				return nil, errors.New("storage unavailable")
+			}
+			return []*SitemapEntry{{Loc: "/posts"}}, nil
+		}),
+		lists: posts.SitemapListsRecord,
+	})
+	p := publish.New(dbForTest, nil).
+		WrapPublish(func(publish.PublishFunc) publish.PublishFunc {
+			return func(context.Context, any) error { return nil }
+		}).
+		WrapUnPublish(func(publish.UnPublishFunc) publish.UnPublishFunc {
+			return func(context.Context, any) error { return nil }
+		})
+	b.RegenerateSitemapsOnPublish(p)
+	wait := func() {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			b.sitemapMu.Lock()
+			generating := b.sitemapGenerating
+			b.sitemapMu.Unlock()
+			if !generating {
+				return
+			}
+		}
+		t.Fatal("the sitemaps are still being generated")
+	}
+
+	if !posts.SitemapListsRecord(&sitemapPost{ID: 1}) || posts.SitemapListsRecord(&sitemapPage{ID: 1}) {
+		t.Fatal("the model provider lists only the records of its model")
+	}
+
+	ctx := context.Background()
+	if err := p.Publish(ctx, &sitemapPage{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	wait()
+	if n := generated.Load(); n != 0 {
+		t.Fatalf("a record without a sitemap regenerated the sitemaps %d times", n)
+	}
+
+	if err := p.Publish(ctx, &sitemapPost{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	wait()
+	if err := p.UnPublish(ctx, &sitemapPost{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	wait()
+	if n := generated.Load(); n != 2 {
+		t.Fatalf("expect the sitemaps regenerated twice, got %d", n)
+	}
+
+	// a failure is logged, the publishing succeeds
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	fail.Store(true)
+	if err := p.Publish(ctx, &sitemapPost{ID: 1}); err != nil {
+		t.Fatalf("the publishing failed with the sitemaps: %v", err)
+	}
+	wait()
+	if !strings.Contains(logs.String(), "storage unavailable") {
+		t.Errorf("the failure is not logged: %q", logs.String())
+	}
+}
+
+type sitemapRecordProvider struct {
+	SitemapProvider
+	lists func(record any) bool
+}
+
+func (p *sitemapRecordProvider) SitemapListsRecord(record any) bool {
+	return p.lists(record)
+}
+
+func TestGenerateSitemapsRemovesStaleChunks(t *testing.T) {
+	if err := dbForTest.AutoMigrate(&QorSEORobots{}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	storage := filesystem.New(t.TempDir())
+	// the chunks written when the sitemaps had more urls, and the files of other sitemaps
+	for _, p := range []string{"/sitemaps/posts-2.xml", "/sitemaps/posts-3.xml", "/sitemaps/news-2.xml", "/sitemaps/posts-archive.xml"} {
+		if _, err := storage.Put(ctx, p, strings.NewReader("<urlset/>")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b := New(dbForTest).Sitemap(storage).SitemapProviders(SitemapProviderFunc("posts", func(context.Context) ([]*SitemapEntry, error) {
+		return []*SitemapEntry{{Loc: "/posts"}}, nil
+	}))
+	if err := b.GenerateSitemaps(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	objects, err := storage.List(ctx, SitemapDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, o := range objects {
+		names = append(names, o.Name)
+	}
+	slices.Sort(names)
+	if expected := []string{"news-2.xml", "posts-archive.xml", "posts.xml"}; !slices.Equal(names, expected) {
+		t.Errorf("sitemap files = %v, want %v", names, expected)
+	}
+}
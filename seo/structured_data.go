@@ -0,0 +1,147 @@
+package seo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	h "github.com/theplant/htmlgo"
+)
+
+// The JSON-LD templates of the common schema.org types. Besides the variables of the SEO,
+// {{Title}}, {{Description}}, {{ImageURL}} and {{URL}} are the resolved values of the setting
+// unless there are variables of the same names.
+const (
+	StructuredDataArticle = `{
+  "@context": "https://schema.org",
+  "@type": "Article",
+  "headline": "{{Title}}",
+  "description": "{{Description}}",
+  "image": "{{ImageURL}}",
+  "mainEntityOfPage": "{{URL}}",
+  "publisher": {
+    "@type": "Organization",
+    "name": "{{SiteName}}"
+  }
+}`
+	StructuredDataProduct = `{
+  "@context": "https://schema.org",
+  "@type": "Product",
+  "name": "{{Title}}",
+  "description": "{{Description}}",
+  "image": "{{ImageURL}}",
+  "url": "{{URL}}"
+}`
+	StructuredDataBreadcrumbList = `{
+  "@context": "https://schema.org",
+  "@type": "BreadcrumbList",
+  "itemListElement": [
+    {
+      "@type": "ListItem",
+      "position": 1,
+      "name": "{{SiteName}}",
+      "item": "/"
+    },
+    {
+      "@type": "ListItem",
+      "position": 2,
+      "name": "{{Title}}",
+      "item": "{{URL}}"
+    }
+  ]
+}`
+	StructuredDataOrganization = `{
+  "@context": "https://schema.org",
+  "@type": "Organization",
+  "name": "{{SiteName}}",
+  "url": "{{URL}}",
+  "logo": "{{ImageURL}}"
+}`
+)
+
+// StructuredData sets the JSON-LD template of the SEO, such as StructuredDataArticle.
+// It is the default of the structured data setting, which could be changed in the admin
+// for the SEO and overridden by every object.
+func (seo *SEO) StructuredData(template string) *SEO {
+	if err := ValidateStructuredData(template); err != nil {
+		panic(fmt.Sprintf("invalid structured data of %v SEO: %v", seo.name, err))
+	}
+	seo.structuredData = template
+	return seo
+}
+
+// getFinalStructuredData returns the template of the SEO, or the one of the nearest ancestor
+func (seo *SEO) getFinalStructuredData() string {
+	for node := seo; node != nil; node = node.parent {
+		if node.structuredData != "" {
+			return node.structuredData
+		}
+	}
+	return ""
+}
+
+// ValidateStructuredData checks the JSON-LD is an object or an array of objects with a @type,
+// the variables are substituted by empty strings before.
+func ValidateStructuredData(s string) error {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	s = regex.ReplaceAllString(s, "")
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return err
+	}
+	objects, ok := v.([]interface{})
+	if !ok {
+		objects = []interface{}{v}
+	}
+	for _, o := range objects {
+		m, ok := o.(map[string]interface{})
+		if !ok {
+			return errors.New("must be an object or an array of objects")
+		}
+		if _, ok = m["@type"]; !ok {
+			return errors.New("@type is missing")
+		}
+	}
+	return nil
+}
+
+// structuredDataValues adds the resolved values of the setting to the variables of the structured data
+func structuredDataValues(setting *Setting, values map[string]string) map[string]string {
+	r := map[string]string{
+		"Title":       setting.Title,
+		"Description": setting.Description,
+		"ImageURL":    setting.OpenGraphImageURL,
+		"URL":         setting.CanonicalPath,
+	}
+	if r["URL"] == "" {
+		r["URL"] = setting.OpenGraphURL
+	}
+	for k, v := range values {
+		r[k] = v
+	}
+	return r
+}
+
+// jsonEscape escapes the value substituted into a JSON string
+func jsonEscape(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b[1 : len(b)-1])
+}
+
+// structuredDataScript renders the JSON-LD, compacted and escaped to be safe in a script tag.
+// Nothing is rendered if it is not valid.
+func structuredDataScript(s string) h.HTMLComponent {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	var compacted, escaped bytes.Buffer
+	if err := json.Compact(&compacted, []byte(s)); err != nil {
+		return nil
+	}
+	json.HTMLEscape(&escaped, compacted.Bytes())
+	return h.Tag("script").Attr("type", "application/ld+json").Children(h.RawHTML(escaped.String()))
+}
@@ -0,0 +1,80 @@
+package seo
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValidateStructuredData(t *testing.T) {
+	cases := []struct {
+		name    string
+		data    string
+		invalid bool
+	}{
+		{name: "empty", data: ""},
+		{name: "template", data: StructuredDataBreadcrumbList},
+		{name: "array", data: `[{"@type": "Organization"}, {"@type": "WebSite", "name": "{{SiteName}}"}]`},
+		{name: "not json", data: `{"@type": "Article",}`, invalid: true},
+		{name: "without type", data: `{"name": "{{Title}}"}`, invalid: true},
+		{name: "not object", data: `["Article"]`, invalid: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := ValidateStructuredData(c.data); (err != nil) != c.invalid {
+				t.Errorf("ValidateStructuredData() error = %v, invalid %v", err, c.invalid)
+			}
+		})
+	}
+}
+
+func TestReplaceStructuredDataVariables(t *testing.T) {
+	setting := replaceVariables(Setting{
+		Title:          "{{Name}} | {{SiteName}}",
+		CanonicalPath:  "https://example.com/posts/1",
+		StructuredData: `{"@type": "Article", "headline": "{{Title}}", "name": "{{Name}}", "url": "{{URL}}"}`,
+	}, map[string]string{"Name": `Say "hi"`, "SiteName": "Qor5"})
+
+	expected := `{"@type": "Article", "headline": "Say \"hi\" | Qor5", "name": "Say \"hi\"", "url": "https://example.com/posts/1"}`
+	if setting.StructuredData != expected {
+		t.Errorf("got %s, want %s", setting.StructuredData, expected)
+	}
+}
+
+func TestStructuredDataScript(t *testing.T) {
+	setting := &Setting{StructuredData: `{
+  "@type": "Article",
+  "headline": "</script><script>alert(1)</script>"
+}`}
+	out, err := setting.HTMLComponent(nil).MarshalHTML(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<script type='application/ld+json'>{"@type":"Article","headline":"\u003c/script\u003e\u003cscript\u003ealert(1)\u003c/script\u003e"}</script>`
+	if !strings.Contains(string(out), expected) {
+		t.Errorf("unexpected html %s", out)
+	}
+
+	setting.StructuredData = `{"@type": `
+	out, err = setting.HTMLComponent(nil).MarshalHTML(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "ld+json") {
+		t.Errorf("invalid structured data is rendered: %s", out)
+	}
+}
+
+func TestFinalStructuredData(t *testing.T) {
+	global := &SEO{name: "Global"}
+	post := &SEO{name: "Post"}
+	post.SetParent(global)
+	global.StructuredData(StructuredDataOrganization)
+	if post.getFinalStructuredData() != StructuredDataOrganization {
+		t.Error("the template of the parent should be used")
+	}
+	post.StructuredData(StructuredDataArticle)
+	if post.getFinalStructuredData() != StructuredDataArticle {
+		t.Error("the template of the SEO should be used")
+	}
+}
@@ -197,7 +197,7 @@ func (jb *JobBuilder) newJobInstance(
 		Job:      qorJobName,
 		Status:   JobStatusNew,
 	}
-	if jb.b.getCurrentUserIDFunc != nil {
+	if jb.b.getCurrentUserIDFunc != nil && r != nil {
 		inst.Operator = jb.b.getCurrentUserIDFunc(r)
 	}
 	err := jb.b.db.Create(&inst).Error
@@ -208,6 +208,33 @@ func (jb *JobBuilder) newJobInstance(
 	return jb.getJobInstance(qorJobID)
 }
 
+// Enqueue creates a job with the arguments and adds it to the queue, so code
+// could run jobs without the admin. r sets the operator of the job, it could be nil.
+func (jb *JobBuilder) Enqueue(r *http.Request, args interface{}) (j *QorJob, err error) {
+	if jb.h == nil {
+		return nil, fmt.Errorf("job %s has no handler", jb.name)
+	}
+	ctx := context.Background()
+	if r != nil {
+		ctx = r.Context()
+	}
+	err = jb.b.db.Transaction(func(tx *gorm.DB) error {
+		j = &QorJob{
+			Job:    jb.name,
+			Status: JobStatusNew,
+		}
+		if err := tx.Create(j).Error; err != nil {
+			return err
+		}
+		inst, err := jb.newJobInstance(r, j.ID, jb.name, args, map[string]interface{}{})
+		if err != nil {
+			return err
+		}
+		return jb.b.q.Add(ctx, inst)
+	})
+	return
+}
+
 type QueJobInterface interface {
 	QorJobInterface
 